@@ -0,0 +1,131 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+)
+
+// Override represents an explicitly specified type of a specific date.
+type Override struct {
+	Date    time.Time // date whose type is overridden (only its year, month and day are used)
+	DayType DayType   // type of the date
+}
+
+// Calendar determines the types of dates according to the Bulgarian official holidays and the decisions of the government. The zero value only takes the official holidays into account.
+type Calendar struct {
+	BridgeDays  []time.Time // working days declared days off by the government (e.g. ones between a holiday and a weekend), which are pre-holidays
+	WorkingDays []time.Time // days off declared working days by the government (usually Saturdays compensating for bridge days), which are weekdays
+	Overrides   []*Override // explicitly specified types of dates, which take precedence over all other rules
+}
+
+// DefaultCalendar is the calendar used by the package-level functions, which only takes the official holidays into account.
+var DefaultCalendar = &Calendar{}
+
+// GetSubstituteDaysOff returns the dates (at midnight in UTC) of the days off substituting for the official holidays in the specified year which fall on a Saturday or a Sunday in chronological order. Each such holiday (except for the ones related to Easter) makes the first following working day which is neither an official holiday nor a bridge day (and has not already been made a day off) a day off.
+func (c *Calendar) GetSubstituteDaysOff(year int) (daysOff []time.Time) {
+	holidays := GetOfficialHolidays(year)
+	for _, holiday := range fixedHolidays {
+		date := time.Date(year, holiday.month, holiday.day, 0, 0, 0, 0, time.UTC)
+		if !isWeekend(date) {
+			continue
+		}
+
+		for {
+			date = date.AddDate(0, 0, 1)
+			if !isWeekend(date) && !containsDate(holidays, date) && !containsDate(c.BridgeDays, date) && !containsDate(daysOff, date) {
+				break
+			}
+		}
+		daysOff = append(daysOff, date)
+	}
+	return
+}
+
+// GetDayType returns the type of the specified date. The rules are applied in the following order: the date has the type specified by an override if there is one; declared working days are weekdays; official holidays and Sundays are holidays; Saturdays, days off substituting for holidays and bridge days are pre-holidays; all other dates are weekdays.
+func (c *Calendar) GetDayType(date time.Time) DayType {
+	date = getDate(date)
+	for _, override := range c.Overrides {
+		if getDate(override.Date).Equal(date) {
+			return override.DayType
+		}
+	}
+
+	switch {
+	case containsDate(c.WorkingDays, date):
+		return DayTypeWeekday
+
+	case date.Weekday() == time.Sunday || IsOfficialHoliday(date):
+		return DayTypeHoliday
+
+	case date.Weekday() == time.Saturday || containsDate(c.GetSubstituteDaysOff(date.Year()), date) || containsDate(c.BridgeDays, date):
+		return DayTypePreHoliday
+
+	default:
+		return DayTypeWeekday
+	}
+}
+
+// GetOperationMode returns the operation mode of line (along with its routes) which is in effect on the specified date (or nil if there is none). If line has no operation mode for the type of the date, the one for holidays is used on pre-holidays and vice versa, since some lines do not distinguish between them.
+func (c *Calendar) GetOperationMode(line *schedule.Line, date time.Time) *schedule.OperationModeRoutes {
+	return getLineOperationMode(line, c.GetDayType(date))
+}
+
+// GetLineOperationModeDayTypes returns the types of days on which operationModeRoutes (one of the operation modes of line) is in effect. Besides the day types matching its name, it includes the ones for which it is used by GetOperationMode as a fallback because line has no operation mode of their own.
+func GetLineOperationModeDayTypes(line *schedule.Line, operationModeRoutes *schedule.OperationModeRoutes) (dayTypes []DayType) {
+	operationModeDayTypes := GetOperationModeDayTypes(operationModeRoutes.Name)
+	for _, dayType := range DayTypes {
+		if containsDayType(operationModeDayTypes, dayType) || getLineOperationMode(line, dayType) == operationModeRoutes {
+			dayTypes = append(dayTypes, dayType)
+		}
+	}
+	return
+}
+
+// getLineOperationMode returns the operation mode of line which is in effect on days of the specified dayType, falling back between holidays and pre-holidays (or nil if there is none).
+func getLineOperationMode(line *schedule.Line, dayType DayType) (operationModeRoutes *schedule.OperationModeRoutes) {
+	operationModeRoutes = getOperationModeByDayType(line, dayType)
+	if operationModeRoutes != nil {
+		return
+	}
+
+	switch dayType {
+	case DayTypePreHoliday:
+		operationModeRoutes = getOperationModeByDayType(line, DayTypeHoliday)
+
+	case DayTypeHoliday:
+		operationModeRoutes = getOperationModeByDayType(line, DayTypePreHoliday)
+	}
+	return
+}
+
+// getOperationModeByDayType returns the first operation mode of line which is in effect on days of the specified dayType (or nil if there is none).
+func getOperationModeByDayType(line *schedule.Line, dayType DayType) *schedule.OperationModeRoutes {
+	for _, operationModeRoutes := range line.OperationModeRoutesList {
+		for _, operationModeDayType := range GetOperationModeDayTypes(operationModeRoutes.Name) {
+			if operationModeDayType == dayType {
+				return operationModeRoutes
+			}
+		}
+	}
+	return nil
+}
+
+func containsDayType(dayTypes []DayType, dayType DayType) bool {
+	for _, d := range dayTypes {
+		if d == dayType {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDayType returns the type of the specified date using the DefaultCalendar.
+func GetDayType(date time.Time) DayType {
+	return DefaultCalendar.GetDayType(date)
+}
+
+// GetOperationMode returns the operation mode of line which is in effect on the specified date using the DefaultCalendar.
+func GetOperationMode(line *schedule.Line, date time.Time) *schedule.OperationModeRoutes {
+	return DefaultCalendar.GetOperationMode(line, date)
+}
@@ -0,0 +1,178 @@
+package calendar
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+)
+
+func formatDates(dates []time.Time) (formattedDates []string) {
+	for _, date := range dates {
+		formattedDates = append(formattedDates, date.Format(DateLayout))
+	}
+	return
+}
+
+func TestGetSubstituteDaysOff(t *testing.T) {
+	testCases := []struct {
+		name          string
+		calendar      *Calendar
+		year          int
+		expectedDates []string
+	}{
+		{
+			name:          "Christmas over a weekend and Labour Day on Easter Saturday",
+			calendar:      &Calendar{},
+			year:          2021,
+			expectedDates: []string{"2021-05-04", "2021-12-27", "2021-12-28"},
+		},
+		{
+			name:          "New Year's Day, Labour Day and Christmas Eve over weekends",
+			calendar:      &Calendar{},
+			year:          2022,
+			expectedDates: []string{"2022-01-03", "2022-05-02", "2022-12-27", "2022-12-28"},
+		},
+		{
+			name:          "bridge day",
+			calendar:      &Calendar{BridgeDays: []time.Time{time.Date(2021, time.December, 27, 0, 0, 0, 0, time.UTC)}},
+			year:          2021,
+			expectedDates: []string{"2021-05-04", "2021-12-28", "2021-12-29"},
+		},
+		{
+			name:          "Liberation Day and Independence Day on Sundays",
+			calendar:      &Calendar{},
+			year:          2024,
+			expectedDates: []string{"2024-03-04", "2024-09-23"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dates := formatDates(testCase.calendar.GetSubstituteDaysOff(testCase.year))
+			if !reflect.DeepEqual(dates, testCase.expectedDates) {
+				t.Errorf("expected days off %v, got %v", testCase.expectedDates, dates)
+			}
+		})
+	}
+}
+
+func TestCalendarGetDayType(t *testing.T) {
+	calendar := &Calendar{
+		BridgeDays:  []time.Time{time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		WorkingDays: []time.Time{time.Date(2024, time.May, 18, 0, 0, 0, 0, time.UTC)},
+		Overrides:   []*Override{{Date: time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC), DayType: DayTypeHoliday}, {Date: time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC), DayType: DayTypePreHoliday}},
+	}
+	testCases := []struct {
+		name            string
+		date            time.Time
+		expectedDayType DayType
+	}{
+		{name: "weekday", date: time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC), expectedDayType: DayTypeWeekday},
+		{name: "Saturday", date: time.Date(2024, time.May, 11, 0, 0, 0, 0, time.UTC), expectedDayType: DayTypePreHoliday},
+		{name: "Sunday", date: time.Date(2024, time.May, 12, 0, 0, 0, 0, time.UTC), expectedDayType: DayTypeHoliday},
+		{name: "official holiday", date: time.Date(2024, time.May, 24, 0, 0, 0, 0, time.UTC), expectedDayType: DayTypeHoliday},
+		{name: "substitute day off", date: time.Date(2022, time.January, 3, 0, 0, 0, 0, time.UTC), expectedDayType: DayTypePreHoliday},
+		{name: "declared working day", date: time.Date(2024, time.May, 18, 0, 0, 0, 0, time.UTC), expectedDayType: DayTypeWeekday},
+		{name: "override of a bridge day", date: time.Date(2024, time.May, 2, 0, 0, 0, 0, time.UTC), expectedDayType: DayTypeHoliday},
+		{name: "override of a weekday", date: time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC), expectedDayType: DayTypePreHoliday},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dayType := calendar.GetDayType(testCase.date)
+			if dayType != testCase.expectedDayType {
+				t.Errorf("expected %s, got %s", testCase.expectedDayType, dayType)
+			}
+		})
+	}
+}
+
+func newTestLine(operationModeNames ...string) (line *schedule.Line) {
+	line = &schedule.Line{VehicleType: schedule.VehicleTypeBus, LineNumber: "94"}
+	for index, name := range operationModeNames {
+		line.OperationModeRoutesList = append(line.OperationModeRoutesList, &schedule.OperationModeRoutes{OperationMode: &schedule.OperationMode{Code: strconv.Itoa(index + 1), Name: name}})
+	}
+	return
+}
+
+func TestGetLineOperationModeDayTypes(t *testing.T) {
+	testCases := []struct {
+		name             string
+		line             *schedule.Line
+		expectedDayTypes [][]DayType
+	}{
+		{
+			name:             "all day types",
+			line:             newTestLine("делник", "предпразник", "празник"),
+			expectedDayTypes: [][]DayType{{DayTypeWeekday}, {DayTypePreHoliday}, {DayTypeHoliday}},
+		},
+		{
+			name:             "holiday mode used on pre-holidays",
+			line:             newTestLine("делник", "празник"),
+			expectedDayTypes: [][]DayType{{DayTypeWeekday}, {DayTypePreHoliday, DayTypeHoliday}},
+		},
+		{
+			name:             "pre-holiday mode used on holidays",
+			line:             newTestLine("делник", "предпразник"),
+			expectedDayTypes: [][]DayType{{DayTypeWeekday}, {DayTypePreHoliday, DayTypeHoliday}},
+		},
+		{
+			name:             "no weekday fallback",
+			line:             newTestLine("предпразник, празник"),
+			expectedDayTypes: [][]DayType{{DayTypePreHoliday, DayTypeHoliday}},
+		},
+		{
+			name:             "unknown operation mode",
+			line:             newTestLine("делник", "лятно"),
+			expectedDayTypes: [][]DayType{{DayTypeWeekday}, nil},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var dayTypes [][]DayType
+			for _, operationModeRoutes := range testCase.line.OperationModeRoutesList {
+				dayTypes = append(dayTypes, GetLineOperationModeDayTypes(testCase.line, operationModeRoutes))
+			}
+			if !reflect.DeepEqual(dayTypes, testCase.expectedDayTypes) {
+				t.Errorf("expected day types %v, got %v", testCase.expectedDayTypes, dayTypes)
+			}
+
+			for _, dayType := range DayTypes {
+				operationModeRoutes := getLineOperationMode(testCase.line, dayType)
+				if operationModeRoutes != nil && !containsDayType(GetLineOperationModeDayTypes(testCase.line, operationModeRoutes), dayType) {
+					t.Errorf("expected operation mode %s to be in effect on days of type %s", operationModeRoutes.Name, dayType)
+				}
+			}
+		})
+	}
+}
+
+func TestCalendarGetOperationMode(t *testing.T) {
+	line := newTestLine("делник", "празник")
+	testCases := []struct {
+		name                  string
+		date                  time.Time
+		expectedOperationMode string
+	}{
+		{name: "weekday", date: time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC), expectedOperationMode: "делник"},
+		{name: "holiday", date: time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC), expectedOperationMode: "празник"},
+		{name: "pre-holiday", date: time.Date(2024, time.May, 11, 0, 0, 0, 0, time.UTC), expectedOperationMode: "празник"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			operationModeRoutes := GetOperationMode(line, testCase.date)
+			if operationModeRoutes == nil || operationModeRoutes.Name != testCase.expectedOperationMode {
+				t.Errorf("expected operation mode %s, got %v", testCase.expectedOperationMode, operationModeRoutes)
+			}
+		})
+	}
+
+	if operationModeRoutes := GetOperationMode(newTestLine("предпразник"), time.Date(2024, time.May, 14, 0, 0, 0, 0, time.UTC)); operationModeRoutes != nil {
+		t.Errorf("expected no operation mode on weekdays, got %v", operationModeRoutes)
+	}
+}
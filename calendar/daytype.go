@@ -0,0 +1,73 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+)
+
+// DayType represents a type of day which determines the operation mode of the urban transit lines in effect.
+type DayType int
+
+const (
+	// DayTypeWeekday represents a working day.
+	DayTypeWeekday DayType = iota
+	// DayTypePreHoliday represents a day off which is not a holiday (i.e. a Saturday by default).
+	DayTypePreHoliday
+	// DayTypeHoliday represents a holiday (i.e. a Sunday by default).
+	DayTypeHoliday
+)
+
+// DayTypes lists all day types.
+var DayTypes = []DayType{DayTypeWeekday, DayTypePreHoliday, DayTypeHoliday}
+
+// dayTypeNames maps each day type to the name of the corresponding operation mode in the reference language (i.e. English).
+var dayTypeNames = map[DayType]string{
+	DayTypeWeekday:    l10n.OperationModeWeekday,
+	DayTypePreHoliday: l10n.OperationModePreHoliday,
+	DayTypeHoliday:    l10n.OperationModeHoliday,
+}
+
+// String returns the name of the operation mode in effect on days of type dt in the reference language (i.e. English).
+func (dt DayType) String() string {
+	return dayTypeNames[dt]
+}
+
+// GetDefaultDayType returns the type of the specified date based solely on its day of the week, i.e. Mondays to Fridays are weekdays, Saturdays are pre-holidays and Sundays are holidays.
+func GetDefaultDayType(date time.Time) DayType {
+	return GetWeekdayDayType(date.Weekday())
+}
+
+// GetWeekdayDayType returns the default type of the days which fall on the specified weekday.
+func GetWeekdayDayType(weekday time.Weekday) DayType {
+	switch weekday {
+	case time.Saturday:
+		return DayTypePreHoliday
+
+	case time.Sunday:
+		return DayTypeHoliday
+
+	default:
+		return DayTypeWeekday
+	}
+}
+
+// GetOperationModeDayTypes returns the types of days on which the operation mode with the specified name (in Bulgarian) is in effect. The result is empty if the name is not recognized.
+func GetOperationModeDayTypes(operationModeName string) (dayTypes []DayType) {
+	name := strings.ToLower(operationModeName)
+	if strings.Contains(name, l10n.BulgarianTranslator[l10n.OperationModeWeekday]) {
+		dayTypes = append(dayTypes, DayTypeWeekday)
+	}
+
+	preHolidayName := l10n.BulgarianTranslator[l10n.OperationModePreHoliday]
+	if strings.Contains(name, preHolidayName) {
+		dayTypes = append(dayTypes, DayTypePreHoliday)
+	}
+
+	// the name of the pre-holiday operation mode contains the name of the holiday one
+	if strings.Contains(strings.ReplaceAll(name, preHolidayName, ""), l10n.BulgarianTranslator[l10n.OperationModeHoliday]) {
+		dayTypes = append(dayTypes, DayTypeHoliday)
+	}
+	return
+}
@@ -0,0 +1,4 @@
+/*
+Package calendar implements the Bulgarian service calendar, which determines the type of each date (taking into account the official holidays, the days off substituting for holidays falling on weekends, the bridge days declared by the government and custom overrides) and hence the operation mode of the urban transit lines in effect on it.
+*/
+package calendar
@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"sort"
+	"time"
+)
+
+// fixedHoliday represents an official holiday which falls on the same date every year.
+type fixedHoliday struct {
+	month time.Month
+	day   int
+}
+
+// fixedHolidays lists the official holidays which fall on the same date every year (as listed in art. 154 of the Labour Code).
+var fixedHolidays = []fixedHoliday{
+	{time.January, 1},    // New Year's Day
+	{time.March, 3},      // Liberation Day
+	{time.May, 1},        // Labour Day
+	{time.May, 6},        // St. George's Day
+	{time.May, 24},       // Day of the Bulgarian Alphabet, Education and Culture
+	{time.September, 6},  // Unification Day
+	{time.September, 22}, // Independence Day
+	{time.December, 24},  // Christmas Eve
+	{time.December, 25},  // Christmas Day
+	{time.December, 26},  // Christmas Day
+}
+
+// easterHolidayOffsets lists the offsets (in days) from Easter Sunday of the official holidays related to Easter (i.e. Good Friday, Holy Saturday, Easter Sunday and Easter Monday).
+var easterHolidayOffsets = []int{-2, -1, 0, 1}
+
+// getDate returns the date at midnight in UTC on which t falls (in its location), which is how dates are compared within the package.
+func getDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func isWeekend(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
+
+// GetOrthodoxEaster returns the date (at midnight in UTC) of Orthodox Easter Sunday in the specified year (according to the Gregorian calendar). Easter is computed using the Meeus algorithm for the Julian calendar, whose dates are converted to the Gregorian calendar (which is only correct for the years between 1900 and 2099).
+func GetOrthodoxEaster(year int) time.Time {
+	a := year % 4
+	b := year % 7
+	c := year % 19
+	d := (19*c + 15) % 30
+	e := (2*a + 4*b - d + 34) % 7
+	month := (d + e + 114) / 31
+	day := (d+e+114)%31 + 1
+	// the Julian calendar is 13 days behind the Gregorian one in the 20th and 21st centuries
+	return time.Date(year, time.Month(month), day+13, 0, 0, 0, 0, time.UTC)
+}
+
+// GetOfficialHolidays returns the dates (at midnight in UTC) of the official holidays in the specified year in chronological order. The days off substituting for holidays falling on weekends are not included (see Calendar.GetSubstituteDaysOff).
+func GetOfficialHolidays(year int) (holidays []time.Time) {
+	for _, holiday := range fixedHolidays {
+		holidays = append(holidays, time.Date(year, holiday.month, holiday.day, 0, 0, 0, 0, time.UTC))
+	}
+
+	easter := GetOrthodoxEaster(year)
+	for _, offset := range easterHolidayOffsets {
+		date := easter.AddDate(0, 0, offset)
+		if !containsDate(holidays, date) {
+			holidays = append(holidays, date)
+		}
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Before(holidays[j]) })
+	return
+}
+
+// IsOfficialHoliday reports whether the specified date is an official holiday.
+func IsOfficialHoliday(date time.Time) bool {
+	return containsDate(GetOfficialHolidays(date.Year()), getDate(date))
+}
+
+func containsDate(dates []time.Time, date time.Time) bool {
+	for _, d := range dates {
+		if getDate(d).Equal(date) {
+			return true
+		}
+	}
+	return false
+}
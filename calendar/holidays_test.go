@@ -0,0 +1,54 @@
+package calendar
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetOrthodoxEaster(t *testing.T) {
+	testCases := []struct {
+		year         int
+		expectedDate time.Time
+	}{
+		{year: 2021, expectedDate: time.Date(2021, time.May, 2, 0, 0, 0, 0, time.UTC)},
+		{year: 2022, expectedDate: time.Date(2022, time.April, 24, 0, 0, 0, 0, time.UTC)},
+		{year: 2023, expectedDate: time.Date(2023, time.April, 16, 0, 0, 0, 0, time.UTC)},
+		{year: 2024, expectedDate: time.Date(2024, time.May, 5, 0, 0, 0, 0, time.UTC)},
+		{year: 2025, expectedDate: time.Date(2025, time.April, 20, 0, 0, 0, 0, time.UTC)},
+		{year: 2026, expectedDate: time.Date(2026, time.April, 12, 0, 0, 0, 0, time.UTC)},
+		{year: 2027, expectedDate: time.Date(2027, time.May, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(strconv.Itoa(testCase.year), func(t *testing.T) {
+			date := GetOrthodoxEaster(testCase.year)
+			if !date.Equal(testCase.expectedDate) {
+				t.Errorf("expected %s, got %s", testCase.expectedDate.Format(DateLayout), date.Format(DateLayout))
+			}
+		})
+	}
+}
+
+func TestIsOfficialHoliday(t *testing.T) {
+	testCases := []struct {
+		name                      string
+		date                      time.Time
+		expectedIsOfficialHoliday bool
+	}{
+		{name: "fixed holiday", date: time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC), expectedIsOfficialHoliday: true},
+		{name: "Good Friday", date: time.Date(2024, time.May, 3, 0, 0, 0, 0, time.UTC), expectedIsOfficialHoliday: true},
+		{name: "Easter Monday", date: time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC), expectedIsOfficialHoliday: true},
+		{name: "local time during the holiday", date: time.Date(2024, time.December, 24, 23, 30, 0, 0, time.FixedZone("EET", 2*60*60)), expectedIsOfficialHoliday: true},
+		{name: "ordinary day", date: time.Date(2024, time.May, 7, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			isOfficialHoliday := IsOfficialHoliday(testCase.date)
+			if isOfficialHoliday != testCase.expectedIsOfficialHoliday {
+				t.Errorf("expected %t, got %t", testCase.expectedIsOfficialHoliday, isOfficialHoliday)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DateLayout is the layout of the dates in calendar files.
+	DateLayout = "2006-01-02"
+
+	// EntryKindBridgeDay marks a bridge day in a calendar file.
+	EntryKindBridgeDay = "bridge"
+	// EntryKindWorkingDay marks a declared working day in a calendar file.
+	EntryKindWorkingDay = "working"
+)
+
+// Read reads a calendar from CSV data in which each record consists of a date (in the YYYY-MM-DD format) and the kind of the entry, which is either EntryKindBridgeDay, EntryKindWorkingDay or the name of a day type (i.e. "weekday", "pre-holiday" or "holiday") overriding the type of the date. Lines starting with "#" are ignored.
+func Read(r io.Reader) (calendar *Calendar, err error) {
+	csvReader := csv.NewReader(r)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = 2
+	csvReader.TrimLeadingSpace = true
+
+	calendar = &Calendar{}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read the calendar: %w", err)
+		}
+
+		date, err := time.Parse(DateLayout, strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse calendar date %q: %w", record[0], err)
+		}
+
+		kind := strings.TrimSpace(record[1])
+		switch kind {
+		case EntryKindBridgeDay:
+			calendar.BridgeDays = append(calendar.BridgeDays, date)
+
+		case EntryKindWorkingDay:
+			calendar.WorkingDays = append(calendar.WorkingDays, date)
+
+		default:
+			dayType, ok := parseDayType(kind)
+			if !ok {
+				return nil, fmt.Errorf("could not parse the kind of calendar entry for %s: unknown kind %q", record[0], kind)
+			}
+
+			calendar.Overrides = append(calendar.Overrides, &Override{Date: date, DayType: dayType})
+		}
+	}
+	return
+}
+
+// ReadFile reads a calendar from the CSV file at the specified path (see Read).
+func ReadFile(path string) (calendar *Calendar, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// parseDayType returns the day type with the specified name in the reference language (i.e. English).
+func parseDayType(name string) (dayType DayType, ok bool) {
+	for _, dayType := range DayTypes {
+		if dayType.String() == name {
+			return dayType, true
+		}
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package calendar
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	testCases := []struct {
+		name                string
+		data                string
+		expectedBridgeDays  []string
+		expectedWorkingDays []string
+		expectedOverrides   []string
+		isErrorExpected     bool
+	}{
+		{
+			name:                "all kinds of entries",
+			data:                "# decisions for 2024\n2024-05-02,bridge\n2024-05-18, working\n2024-05-15,pre-holiday\n2024-12-31,holiday\n",
+			expectedBridgeDays:  []string{"2024-05-02"},
+			expectedWorkingDays: []string{"2024-05-18"},
+			expectedOverrides:   []string{"2024-05-15=pre-holiday", "2024-12-31=holiday"},
+		},
+		{name: "empty"},
+		{name: "invalid date", data: "02.05.2024,bridge\n", isErrorExpected: true},
+		{name: "unknown kind", data: "2024-05-02,vacation\n", isErrorExpected: true},
+		{name: "missing kind", data: "2024-05-02\n", isErrorExpected: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			calendar, err := Read(strings.NewReader(testCase.data))
+			if testCase.isErrorExpected {
+				if err == nil {
+					t.Errorf("expected an error, got %v", calendar)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			var overrides []string
+			for _, override := range calendar.Overrides {
+				overrides = append(overrides, override.Date.Format(DateLayout)+"="+override.DayType.String())
+			}
+			if !reflect.DeepEqual(formatDates(calendar.BridgeDays), testCase.expectedBridgeDays) || !reflect.DeepEqual(formatDates(calendar.WorkingDays), testCase.expectedWorkingDays) || !reflect.DeepEqual(overrides, testCase.expectedOverrides) {
+				t.Errorf("unexpected calendar: bridge days %v, working days %v, overrides %v", calendar.BridgeDays, calendar.WorkingDays, overrides)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rgeorgiev583/sofiatraffic/format"
+
+	_ "modernc.org/sqlite"
+)
+
+// DriverName is the name of the database/sql driver used to access the databases.
+const DriverName = "sqlite"
+
+// schema creates the tables of the database unless they already exist. Stops are identified by their code and the rest of the objects by a surrogate key; the vehicle types of the lines are the ones used by the schedule.
+const schema = `
+CREATE TABLE IF NOT EXISTS stops (
+	code    TEXT PRIMARY KEY,
+	name    TEXT NOT NULL,
+	name_en TEXT
+);
+
+CREATE TABLE IF NOT EXISTS lines (
+	id           INTEGER PRIMARY KEY,
+	vehicle_type TEXT NOT NULL,
+	line_number  TEXT NOT NULL,
+	UNIQUE (vehicle_type, line_number)
+);
+
+CREATE TABLE IF NOT EXISTS operation_modes (
+	id      INTEGER PRIMARY KEY,
+	line_id INTEGER NOT NULL REFERENCES lines (id),
+	code    TEXT NOT NULL,
+	name    TEXT NOT NULL,
+	UNIQUE (line_id, code)
+);
+
+-- routes from the schedule have an operation mode, a code and a name, while routes from the virtual timetable API have none of them
+CREATE TABLE IF NOT EXISTS routes (
+	id                INTEGER PRIMARY KEY,
+	line_id           INTEGER NOT NULL REFERENCES lines (id),
+	operation_mode_id INTEGER REFERENCES operation_modes (id),
+	code              TEXT,
+	name              TEXT
+);
+
+CREATE TABLE IF NOT EXISTS route_stops (
+	route_id   INTEGER NOT NULL REFERENCES routes (id),
+	stop_index INTEGER NOT NULL,
+	stop_code  TEXT NOT NULL REFERENCES stops (code),
+	PRIMARY KEY (route_id, stop_index)
+);
+
+CREATE INDEX IF NOT EXISTS route_stops_stop_code ON route_stops (stop_code);
+
+-- the time of a departure is the time of the day in the HH:MM format, while its minutes are measured from the start of the service day (so departures made after midnight have at least 1440 minutes)
+CREATE TABLE IF NOT EXISTS departures (
+	route_id  INTEGER NOT NULL REFERENCES routes (id),
+	stop_code TEXT NOT NULL REFERENCES stops (code),
+	time      TEXT NOT NULL,
+	minutes   INTEGER NOT NULL,
+	markers   TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS departures_route_id_stop_code ON departures (route_id, stop_code);
+`
+
+// Open opens the SQLite database stored in the file at the specified path (which is created if it does not exist) and creates its tables unless they already exist. Foreign key constraints are enforced.
+func Open(path string) (db *sql.DB, err error) {
+	db, err = sql.Open(DriverName, "file:"+path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		return
+	}
+
+	_, err = db.Exec(schema)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not create the database schema: %w", err)
+	}
+
+	return
+}
+
+// OpenReadOnly opens the existing SQLite database stored in the file at the specified path in read-only mode, so that queries run against it cannot modify it. An error matching fs.ErrNotExist is returned if the file does not exist.
+func OpenReadOnly(path string) (db *sql.DB, err error) {
+	_, err = os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the database: %w", err)
+	}
+
+	db, err = sql.Open(DriverName, "file:"+path+"?mode=ro&_pragma=foreign_keys(1)")
+	if err != nil {
+		return
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not open the database: %w", err)
+	}
+
+	return
+}
+
+// Result represents the result of an SQL query.
+type Result struct {
+	Columns []string        `json:"columns"` // names of the columns
+	Rows    [][]interface{} `json:"rows"`    // values of the cells in each row (NULL values are represented by nil)
+}
+
+// QueryContext runs the specified SQL query (with the specified arguments for its placeholders) against db and returns all rows of its result.
+func QueryContext(ctx context.Context, db *sql.DB, query string, args ...interface{}) (result *Result, err error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	result = &Result{Rows: [][]interface{}{}}
+	result.Columns, err = rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		row := make([]interface{}, len(result.Columns))
+		pointers := make([]interface{}, len(row))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, value := range row {
+			// text values may be returned as byte slices, which would otherwise be encoded in base64
+			if bytes, ok := value.([]byte); ok {
+				row[i] = string(bytes)
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// Query runs the specified SQL query against db and returns all rows of its result (see QueryContext).
+func Query(db *sql.DB, query string, args ...interface{}) (result *Result, err error) {
+	return QueryContext(context.Background(), db, query, args...)
+}
+
+// formatValue returns the display representation of a value from a result (which is empty for NULL values).
+func formatValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func (r *Result) String() string {
+	var builder strings.Builder
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(r.Columns, "\t"))
+	for _, row := range r.Rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			cells[i] = formatValue(value)
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+	writer.Flush()
+	return builder.String()
+}
+
+// Table returns the tabular representation of the result.
+func (r *Result) Table() *format.Table {
+	table := &format.Table{Header: r.Columns}
+	for _, row := range r.Rows {
+		cells := make([]string, len(row))
+		for i, value := range row {
+			cells[i] = formatValue(value)
+		}
+		table.Rows = append(table.Rows, cells)
+	}
+	return table
+}
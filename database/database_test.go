@@ -0,0 +1,60 @@
+package database
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sofiatraffic.db")
+	_, err := OpenReadOnly(path)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected errors.Is(err, fs.ErrNotExist) to be true for %v", err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	_, err = db.Exec("INSERT INTO stops (code, name) VALUES ('0001', 'ОБЕЛЯ')")
+	db.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	db, err = OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer db.Close()
+
+	result, err := Query(db, "SELECT code, name FROM stops")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expectedResult := &Result{Columns: []string{"code", "name"}, Rows: [][]interface{}{{"0001", "ОБЕЛЯ"}}}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Errorf("expected result %v, got %v", expectedResult, result)
+	}
+
+	_, err = db.Exec("DELETE FROM stops")
+	if err == nil {
+		t.Error("expected the database to be read-only")
+	}
+}
+
+func TestResultString(t *testing.T) {
+	result := &Result{Columns: []string{"code", "name_en"}, Rows: [][]interface{}{{"0001", nil}, {"0002", "Central Railway Station"}}}
+	expectedString := "code  name_en\n0001  \n0002  Central Railway Station\n"
+	if result.String() != expectedString {
+		t.Errorf("expected %q, got %q", expectedString, result.String())
+	}
+
+	table := result.Table()
+	if !reflect.DeepEqual(table.Header, result.Columns) || !reflect.DeepEqual(table.Rows, [][]string{{"0001", ""}, {"0002", "Central Railway Station"}}) {
+		t.Errorf("unexpected table %v", table)
+	}
+}
@@ -0,0 +1,4 @@
+/*
+Package database implements persisting the stops, lines, routes, operation modes and scheduled departures of the urban transit network into a normalized SQLite database (accessed through the pure-Go modernc.org/sqlite driver) and running ad-hoc SQL queries against it.
+*/
+package database
@@ -0,0 +1,307 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// tables lists the tables of the database in an order in which they can be cleared without violating foreign key constraints.
+var tables = []string{"departures", "route_stops", "routes", "operation_modes", "lines", "stops"}
+
+// Importer imports information about the urban transit network into databases.
+type Importer struct {
+	Source              datasource.DataSource // source of the stops, routes, schedule lines and schedule timetables (the live APIs are used if nil)
+	DoIncludeTimetables bool                  // whether the scheduled departures should be imported as well (which requires fetching the timetable of every stop of every route)
+}
+
+// importation holds the state of a single import.
+type importation struct {
+	*Importer
+	source     datasource.DataSource
+	statements map[string]*sql.Stmt
+	lineIDs    map[string]int64
+}
+
+const (
+	insertStopStatement          = "INSERT OR IGNORE INTO stops (code, name) VALUES (?, ?)"
+	updateStopNameStatement      = "UPDATE stops SET name_en = ? WHERE code = ?"
+	insertLineStatement          = "INSERT INTO lines (vehicle_type, line_number) VALUES (?, ?)"
+	insertOperationModeStatement = "INSERT INTO operation_modes (line_id, code, name) VALUES (?, ?, ?)"
+	insertRouteStatement         = "INSERT INTO routes (line_id, operation_mode_id, code, name) VALUES (?, ?, ?, ?)"
+	insertRouteStopStatement     = "INSERT INTO route_stops (route_id, stop_index, stop_code) VALUES (?, ?, ?)"
+	insertDepartureStatement     = "INSERT INTO departures (route_id, stop_code, time, minutes, markers) VALUES (?, ?, ?, ?, ?)"
+)
+
+var statementTexts = []string{
+	insertStopStatement,
+	updateStopNameStatement,
+	insertLineStatement,
+	insertOperationModeStatement,
+	insertRouteStatement,
+	insertRouteStopStatement,
+	insertDepartureStatement,
+}
+
+// ImportContext replaces the contents of db with all stops and routes and all lines of the schedule (along with their operation modes and routes and, if DoIncludeTimetables is true, their scheduled departures). The import is performed in a single transaction, so the previous contents are kept if it fails. Lines and timetables which cannot be obtained are skipped (and the errors are logged). The import is canceled when ctx is done.
+func (i *Importer) ImportContext(ctx context.Context, db *sql.DB) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	imp := &importation{Importer: i, source: i.Source, statements: map[string]*sql.Stmt{}, lineIDs: map[string]int64{}}
+	if imp.source == nil {
+		imp.source = datasource.NewLive(nil, nil)
+	}
+
+	for _, table := range tables {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+table)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, text := range statementTexts {
+		statement, err := tx.PrepareContext(ctx, text)
+		if err != nil {
+			return err
+		}
+		defer statement.Close()
+
+		imp.statements[text] = statement
+	}
+
+	err = imp.importStops(ctx)
+	if err != nil {
+		return
+	}
+
+	err = imp.importVirtualRoutes(ctx)
+	if err != nil {
+		return
+	}
+
+	lines, err := imp.source.GetLinesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	importLines := func(vehicleType string, lineNumbers []string) error {
+		for _, lineNumber := range lineNumbers {
+			err := imp.importScheduleLine(ctx, vehicleType, lineNumber)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, vehicleTypeLineNumbers := range lines.VehicleTypeLineNumbersList {
+		err = importLines(vehicleTypeLineNumbers.VehicleType, vehicleTypeLineNumbers.LineNumbers)
+		if err != nil {
+			return
+		}
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// Import replaces the contents of db with the information about the urban transit network (see ImportContext).
+func (i *Importer) Import(db *sql.DB) (err error) {
+	return i.ImportContext(context.Background(), db)
+}
+
+func (imp *importation) exec(ctx context.Context, statementText string, args ...interface{}) (result sql.Result, err error) {
+	return imp.statements[statementText].ExecContext(ctx, args...)
+}
+
+// importStops imports all stops with their names in Bulgarian and, if available, in English.
+func (imp *importation) importStops(ctx context.Context) (err error) {
+	stops, err := imp.source.GetStopsInLanguageContext(ctx, i18n.LanguageCodeBulgarian)
+	if err != nil {
+		return
+	}
+
+	for _, stop := range stops {
+		_, err = imp.exec(ctx, insertStopStatement, stop.Code, stop.Name)
+		if err != nil {
+			return
+		}
+	}
+
+	stopsInEnglish, fetchErr := imp.source.GetStopsInLanguageContext(ctx, i18n.LanguageCodeEnglish)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if fetchErr != nil {
+		log.Println(fetchErr.Error())
+		return
+	}
+
+	for _, stop := range stopsInEnglish {
+		_, err = imp.exec(ctx, updateStopNameStatement, stop.Name, stop.Code)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// getLineID returns the identifier of the line with the specified vehicleType (as used by the schedule) and lineNumber, which is inserted unless it has already been.
+func (imp *importation) getLineID(ctx context.Context, vehicleType string, lineNumber string) (lineID int64, err error) {
+	lineKey := vehicleType + "/" + lineNumber
+	lineID, ok := imp.lineIDs[lineKey]
+	if ok {
+		return
+	}
+
+	result, err := imp.exec(ctx, insertLineStatement, vehicleType, lineNumber)
+	if err != nil {
+		return
+	}
+
+	lineID, err = result.LastInsertId()
+	if err != nil {
+		return
+	}
+
+	imp.lineIDs[lineKey] = lineID
+	return
+}
+
+// importRoute inserts the route of the line with the specified lineID visiting the stops with the specified stopCodes in order. The operationModeID, code and name of routes from the virtual timetable API are NULL. Stops which have not been imported yet are inserted with the names from stopNames (or with empty names if stopNames is nil).
+func (imp *importation) importRoute(ctx context.Context, lineID int64, operationModeID sql.NullInt64, code sql.NullString, name sql.NullString, stopCodes []string, stopNames []string) (routeID int64, err error) {
+	result, err := imp.exec(ctx, insertRouteStatement, lineID, operationModeID, code, name)
+	if err != nil {
+		return
+	}
+
+	routeID, err = result.LastInsertId()
+	if err != nil {
+		return
+	}
+
+	for index, stopCode := range stopCodes {
+		stopName := ""
+		if stopNames != nil {
+			stopName = stopNames[index]
+		}
+		_, err = imp.exec(ctx, insertStopStatement, stopCode, stopName)
+		if err != nil {
+			return
+		}
+
+		_, err = imp.exec(ctx, insertRouteStopStatement, routeID, index, stopCode)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// importVirtualRoutes imports all routes from the virtual timetable API along with their lines.
+func (imp *importation) importVirtualRoutes(ctx context.Context) (err error) {
+	routes, err := imp.source.GetRoutesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, vehicleTypeRoutes := range routes {
+		vehicleType := virtual.GetScheduleVehicleType(vehicleTypeRoutes.VehicleType)
+		for _, lineRoutes := range vehicleTypeRoutes.LineNumberRouteListList {
+			lineID, err := imp.getLineID(ctx, vehicleType, lineRoutes.LineNumber)
+			if err != nil {
+				return err
+			}
+
+			for _, route := range lineRoutes.RouteList {
+				_, err = imp.importRoute(ctx, lineID, sql.NullInt64{}, sql.NullString{}, sql.NullString{}, route.StopCodes, nil)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return
+}
+
+// importScheduleLine imports the operation modes, routes and (if DoIncludeTimetables is true) scheduled departures of the line with the specified vehicleType and lineNumber. Errors caused by obtaining the line or its timetables are logged unless they are caused by ctx being done.
+func (imp *importation) importScheduleLine(ctx context.Context, vehicleType string, lineNumber string) (err error) {
+	line, fetchErr := imp.source.GetLineContext(ctx, vehicleType, lineNumber)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if fetchErr != nil {
+		log.Println(fetchErr.Error())
+		return
+	}
+
+	lineID, err := imp.getLineID(ctx, vehicleType, lineNumber)
+	if err != nil {
+		return
+	}
+
+	for _, operationModeRoutes := range line.OperationModeRoutesList {
+		result, err := imp.exec(ctx, insertOperationModeStatement, lineID, operationModeRoutes.Code, operationModeRoutes.Name)
+		if err != nil {
+			return err
+		}
+
+		operationModeID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		for _, route := range operationModeRoutes.RouteList {
+			stopCodes := make([]string, len(route.StopList))
+			stopNames := make([]string, len(route.StopList))
+			for index, stop := range route.StopList {
+				stopCodes[index] = stop.Code
+				stopNames[index] = stop.Name
+			}
+			routeID, err := imp.importRoute(ctx, lineID, sql.NullInt64{Int64: operationModeID, Valid: true}, sql.NullString{String: route.Code, Valid: true}, sql.NullString{String: route.Name, Valid: true}, stopCodes, stopNames)
+			if err != nil {
+				return err
+			}
+
+			if !imp.DoIncludeTimetables {
+				continue
+			}
+
+			for _, stopCode := range stopCodes {
+				err = imp.importDepartures(ctx, routeID, operationModeRoutes.Code, route.Code, stopCode)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return
+}
+
+// importDepartures imports the scheduled departures from the stop with the specified stopCode on the route with the specified routeID.
+func (imp *importation) importDepartures(ctx context.Context, routeID int64, operationModeCode string, routeCode string, stopCode string) (err error) {
+	timetable, fetchErr := imp.source.GetTimetableContext(ctx, operationModeCode, routeCode, stopCode)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if fetchErr != nil {
+		log.Println(fetchErr.Error())
+		return
+	}
+
+	for _, departure := range timetable {
+		_, err = imp.exec(ctx, insertDepartureStatement, routeID, stopCode, departure.FormatTime(), int64(departure.Time/time.Minute), departure.Markers)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
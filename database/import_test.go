@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// testSource is a DataSource serving bus line 94, which has a virtual route and a weekday schedule route from ЖК МЛАДОСТ 3 to ЦЕНТРАЛНА ГАРА.
+type testSource struct{}
+
+func (s *testSource) GetStopsInLanguageContext(ctx context.Context, language string) (virtual.StopList, error) {
+	if language == i18n.LanguageCodeEnglish {
+		return virtual.StopList{{Code: "0002", Name: "Central Railway Station"}}, nil
+	}
+	return virtual.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ЦЕНТРАЛНА ГАРА"}}, nil
+}
+
+func (s *testSource) GetRoutesContext(ctx context.Context) (virtual.VehicleTypeLineNumberRouteListListList, error) {
+	return virtual.VehicleTypeLineNumberRouteListListList{{
+		VehicleType:             virtual.VehicleTypeBus,
+		LineNumberRouteListList: virtual.LineNumberRouteListList{{LineNumber: "94", RouteList: virtual.RouteList{{StopCodes: []string{"0001", "0002"}}}}},
+	}}, nil
+}
+
+func (s *testSource) GetLinesContext(ctx context.Context) (*schedule.Lines, error) {
+	return &schedule.Lines{VehicleTypeLineNumbersList: []*schedule.VehicleTypeLineNumbers{{VehicleType: schedule.VehicleTypeBus, LineNumbers: []string{"94", "404"}}}}, nil
+}
+
+func (s *testSource) GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (*schedule.Line, error) {
+	if lineNumber != "94" {
+		return nil, &schedule.UnknownLineError{VehicleType: vehicleType, LineNumber: lineNumber}
+	}
+	return &schedule.Line{
+		VehicleType: vehicleType,
+		LineNumber:  lineNumber,
+		OperationModeRoutesList: schedule.OperationModeRoutesList{{
+			OperationMode: &schedule.OperationMode{Code: "1", Name: "делник"},
+			RouteList:     schedule.RouteList{{Code: "10", Name: "ЖК МЛАДОСТ 3 - ЦЕНТРАЛНА ГАРА", StopList: schedule.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ЦЕНТРАЛНА ГАРА"}}}},
+		}},
+	}, nil
+}
+
+func (s *testSource) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (schedule.Timetable, error) {
+	if stopCode != "0001" {
+		return nil, &schedule.UnknownStopError{OperationModeCode: operationModeCode, RouteCode: routeCode, StopCode: stopCode}
+	}
+	return schedule.Timetable{{Time: 23*time.Hour + 50*time.Minute}, {Time: 24*time.Hour + 20*time.Minute, Markers: "*"}}, nil
+}
+
+func TestImport(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "sofiatraffic.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer db.Close()
+
+	importer := &Importer{Source: &testSource{}, DoIncludeTimetables: true}
+	for i := 0; i < 2; i++ {
+		err = importer.Import(db)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		query        string
+		expectedRows string
+	}{
+		{name: "stops", query: "SELECT code, name, IFNULL(name_en, '-') FROM stops ORDER BY code", expectedRows: "[[0001 ЖК МЛАДОСТ 3 -] [0002 ЦЕНТРАЛНА ГАРА Central Railway Station]]"},
+		{name: "lines", query: "SELECT vehicle_type, line_number FROM lines", expectedRows: "[[autobus 94]]"},
+		{name: "operation modes", query: "SELECT code, name FROM operation_modes", expectedRows: "[[1 делник]]"},
+		{name: "routes", query: "SELECT operation_mode_id IS NULL, IFNULL(code, '-'), IFNULL(name, '-') FROM routes ORDER BY id", expectedRows: "[[1 - -] [0 10 ЖК МЛАДОСТ 3 - ЦЕНТРАЛНА ГАРА]]"},
+		{name: "route stops", query: "SELECT COUNT(*) FROM route_stops", expectedRows: "[[4]]"},
+		{name: "departures", query: "SELECT stop_code, time, minutes, markers FROM departures ORDER BY minutes", expectedRows: "[[0001 23:50 1430 ] [0001 00:20 1460 *]]"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := Query(db, testCase.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			rows := fmt.Sprint(result.Rows)
+			if rows != testCase.expectedRows {
+				t.Errorf("expected rows %s, got %s", testCase.expectedRows, rows)
+			}
+		})
+	}
+}
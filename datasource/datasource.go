@@ -0,0 +1,64 @@
+package datasource
+
+import (
+	"context"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// DataSource provides information about urban transit stops, routes, schedule lines and schedule timetables.
+type DataSource interface {
+	// GetStopsInLanguageContext returns the list of all urban transit stops with name in the specified language.
+	GetStopsInLanguageContext(ctx context.Context, language string) (stops virtual.StopList, err error)
+	// GetRoutesContext returns the VehicleTypeLineNumberRouteListListList of all urban transit routes.
+	GetRoutesContext(ctx context.Context) (routes virtual.VehicleTypeLineNumberRouteListListList, err error)
+	// GetLinesContext returns all urban transit lines from the schedule.
+	GetLinesContext(ctx context.Context) (lines *schedule.Lines, err error)
+	// GetLineContext returns the schedule of the urban transit line with the specified vehicleType and lineNumber.
+	GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (line *schedule.Line, err error)
+	// GetTimetableContext returns the schedule timetable matching the specified operationModeCode, routeCode and stopCode.
+	GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable schedule.Timetable, err error)
+}
+
+// Live is a DataSource which fetches the information from the Urban Mobility Centre Web APIs.
+type Live struct {
+	VirtualClient  *virtual.Client  // client for the virtual timetable API
+	ScheduleClient *schedule.Client // client for the schedule pages
+}
+
+// NewLive returns a Live data source which uses the specified clients (or virtual.DefaultClient and schedule.DefaultClient if they are nil).
+func NewLive(virtualClient *virtual.Client, scheduleClient *schedule.Client) *Live {
+	if virtualClient == nil {
+		virtualClient = virtual.DefaultClient
+	}
+	if scheduleClient == nil {
+		scheduleClient = schedule.DefaultClient
+	}
+	return &Live{VirtualClient: virtualClient, ScheduleClient: scheduleClient}
+}
+
+// GetStopsInLanguageContext fetches and returns the list of all urban transit stops with name in the specified language.
+func (l *Live) GetStopsInLanguageContext(ctx context.Context, language string) (stops virtual.StopList, err error) {
+	return l.VirtualClient.GetStopsInLanguageContext(ctx, language)
+}
+
+// GetRoutesContext fetches and returns the VehicleTypeLineNumberRouteListListList of all urban transit routes.
+func (l *Live) GetRoutesContext(ctx context.Context) (routes virtual.VehicleTypeLineNumberRouteListListList, err error) {
+	return l.VirtualClient.GetRoutesContext(ctx)
+}
+
+// GetLinesContext fetches and returns all urban transit lines from the schedule.
+func (l *Live) GetLinesContext(ctx context.Context) (lines *schedule.Lines, err error) {
+	return l.ScheduleClient.GetLinesContext(ctx)
+}
+
+// GetLineContext fetches and returns the schedule of the urban transit line with the specified vehicleType and lineNumber.
+func (l *Live) GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (line *schedule.Line, err error) {
+	return l.ScheduleClient.GetLineContext(ctx, vehicleType, lineNumber)
+}
+
+// GetTimetableContext fetches and returns the schedule timetable matching the specified operationModeCode, routeCode and stopCode.
+func (l *Live) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable schedule.Timetable, err error) {
+	return l.ScheduleClient.GetTimetableContext(ctx, operationModeCode, routeCode, stopCode)
+}
@@ -0,0 +1,4 @@
+/*
+Package datasource implements a common interface for obtaining stops, routes, schedule lines and schedule timetables either live from the Urban Mobility Centre Web APIs or offline from a snapshot produced by the `snapshot` package.
+*/
+package datasource
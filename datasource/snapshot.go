@@ -0,0 +1,151 @@
+package datasource
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/snapshot"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// ErrMissingResource indicates that a snapshot does not contain the requested resource.
+var ErrMissingResource = errors.New("resource missing from snapshot")
+
+// MissingResourceError represents a request for a resource which is not contained in a snapshot.
+type MissingResourceError struct {
+	Path string // path of the file which would contain the resource
+}
+
+// Snapshot is a DataSource which reads the information from a snapshot produced by the `snapshot` package.
+type Snapshot struct {
+	FS       fs.FS              // file system containing the snapshot
+	Manifest *snapshot.Manifest // manifest of the snapshot
+
+	closer io.Closer
+}
+
+func (e *MissingResourceError) Error() string {
+	return "resource missing from snapshot: " + e.Path
+}
+
+// Is reports whether target is ErrMissingResource.
+func (e *MissingResourceError) Is(target error) bool {
+	return target == ErrMissingResource
+}
+
+// NewSnapshot returns a Snapshot data source reading the snapshot contained in fsys. An error is returned if the manifest cannot be read or the snapshot has an unsupported format version.
+func NewSnapshot(fsys fs.FS) (source *Snapshot, err error) {
+	source = &Snapshot{FS: fsys}
+	manifest := &snapshot.Manifest{}
+	err = source.readJSON(snapshot.ManifestPath, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not read snapshot manifest: %w", err)
+	}
+
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > snapshot.FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version %d (the latest supported version is %d)", manifest.FormatVersion, snapshot.FormatVersion)
+	}
+
+	source.Manifest = manifest
+	return
+}
+
+// OpenSnapshot returns a Snapshot data source reading the snapshot stored at the specified path: from a ZIP archive if the path has a .zip extension and from a directory otherwise. The returned data source should be closed after use.
+func OpenSnapshot(snapshotPath string) (source *Snapshot, err error) {
+	if !strings.EqualFold(filepath.Ext(snapshotPath), ".zip") {
+		return NewSnapshot(os.DirFS(snapshotPath))
+	}
+
+	zipReader, err := zip.OpenReader(snapshotPath)
+	if err != nil {
+		return
+	}
+
+	source, err = NewSnapshot(zipReader)
+	if err != nil {
+		zipReader.Close()
+		return
+	}
+
+	source.closer = zipReader
+	return
+}
+
+// Close releases the resources associated with the data source.
+func (s *Snapshot) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+func (s *Snapshot) readJSON(filePath string, value interface{}) (err error) {
+	data, err := fs.ReadFile(s.FS, filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &MissingResourceError{Path: filePath}
+	}
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, value)
+	if err != nil {
+		err = fmt.Errorf("could not decode snapshot file %s: %w", filePath, err)
+		return
+	}
+
+	return
+}
+
+// GetStopsInLanguageContext returns the list of all urban transit stops with name in the specified language from the snapshot.
+func (s *Snapshot) GetStopsInLanguageContext(ctx context.Context, language string) (stops virtual.StopList, err error) {
+	if language != i18n.LanguageCodeBulgarian && language != i18n.LanguageCodeEnglish {
+		err = fmt.Errorf("unsupported language for stop names: %s", language)
+		return
+	}
+
+	err = s.readJSON(snapshot.GetStopsPath(language), &stops)
+	return
+}
+
+// GetRoutesContext returns the VehicleTypeLineNumberRouteListListList of all urban transit routes from the snapshot.
+func (s *Snapshot) GetRoutesContext(ctx context.Context) (routes virtual.VehicleTypeLineNumberRouteListListList, err error) {
+	err = s.readJSON(snapshot.RoutesPath, &routes)
+	return
+}
+
+// GetLinesContext returns all urban transit lines from the schedule contained in the snapshot.
+func (s *Snapshot) GetLinesContext(ctx context.Context) (lines *schedule.Lines, err error) {
+	lines = &schedule.Lines{}
+	err = s.readJSON(snapshot.LinesPath, lines)
+	return
+}
+
+// GetLineContext returns the schedule of the urban transit line with the specified vehicleType and lineNumber from the snapshot.
+func (s *Snapshot) GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (line *schedule.Line, err error) {
+	line = &schedule.Line{}
+	err = s.readJSON(snapshot.GetLinePath(vehicleType, lineNumber), line)
+	if errors.Is(err, ErrMissingResource) {
+		err = &schedule.UnknownLineError{VehicleType: vehicleType, LineNumber: lineNumber, Err: err}
+	}
+	return
+}
+
+// GetTimetableContext returns the schedule timetable matching the specified operationModeCode, routeCode and stopCode from the snapshot.
+func (s *Snapshot) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable schedule.Timetable, err error) {
+	err = s.readJSON(snapshot.GetTimetablePath(operationModeCode, routeCode, stopCode), &timetable)
+	if errors.Is(err, ErrMissingResource) {
+		err = &schedule.UnknownStopError{OperationModeCode: operationModeCode, RouteCode: routeCode, StopCode: stopCode, Err: err}
+	}
+	return
+}
@@ -0,0 +1,208 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/snapshot"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+var (
+	testStops  = virtual.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}}
+	testRoutes = virtual.VehicleTypeLineNumberRouteListListList{{VehicleType: virtual.VehicleTypeBus, LineNumberRouteListList: virtual.LineNumberRouteListList{{LineNumber: "94", RouteList: virtual.RouteList{{StopCodes: []string{"0001"}}}}}}}
+	testLines  = &schedule.Lines{VehicleTypeLineNumbersList: schedule.VehicleTypeLineNumbersList{{VehicleType: schedule.VehicleTypeBus, LineNumbers: []string{"94"}}}}
+	testLine   = &schedule.Line{
+		VehicleType: schedule.VehicleTypeBus,
+		LineNumber:  "94",
+		OperationModeRoutesList: schedule.OperationModeRoutesList{{
+			OperationMode: &schedule.OperationMode{Code: "1", Name: "делник"},
+			RouteList:     schedule.RouteList{{Code: "10", Name: "ЖК МЛАДОСТ 3 - ЦЕНТРАЛНА ГАРА", StopList: schedule.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}}}},
+		}},
+	}
+	testTimetable = schedule.Timetable{{Time: 5 * time.Hour, Raw: "05:00"}}
+)
+
+// writeTestSnapshot writes a snapshot of a network of a single bus line (94) with a single stop to the specified path.
+func writeTestSnapshot(t *testing.T, snapshotPath string) {
+	w, err := snapshot.Create(snapshotPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	files := []struct {
+		path  string
+		value interface{}
+	}{
+		{path: snapshot.GetStopsPath("bg"), value: testStops},
+		{path: snapshot.GetStopsPath("en"), value: testStops},
+		{path: snapshot.RoutesPath, value: testRoutes},
+		{path: snapshot.LinesPath, value: testLines},
+		{path: snapshot.GetLinePath(schedule.VehicleTypeBus, "94"), value: testLine},
+		{path: snapshot.GetTimetablePath("1", "10", "0001"), value: testTimetable},
+	}
+	for _, file := range files {
+		err = w.WriteJSON(&snapshot.File{Path: file.path}, file.value)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name         string
+		snapshotName string
+	}{
+		{name: "directory", snapshotName: "snapshot"},
+		{name: "ZIP archive", snapshotName: "snapshot.zip"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			snapshotPath := filepath.Join(t.TempDir(), testCase.snapshotName)
+			writeTestSnapshot(t, snapshotPath)
+			source, err := OpenSnapshot(snapshotPath)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			defer source.Close()
+
+			ctx := context.Background()
+			stops, err := source.GetStopsInLanguageContext(ctx, "en")
+			if err != nil || !reflect.DeepEqual(stops, testStops) {
+				t.Errorf("expected stops %v, got %v (%v)", testStops, stops, err)
+			}
+
+			routes, err := source.GetRoutesContext(ctx)
+			if err != nil || !reflect.DeepEqual(routes, testRoutes) {
+				t.Errorf("expected routes %v, got %v (%v)", testRoutes, routes, err)
+			}
+
+			lines, err := source.GetLinesContext(ctx)
+			if err != nil || !reflect.DeepEqual(lines, testLines) {
+				t.Errorf("expected lines %v, got %v (%v)", testLines, lines, err)
+			}
+
+			line, err := source.GetLineContext(ctx, schedule.VehicleTypeBus, "94")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			route := line.OperationModeRoutesMap["1"].RouteMap["10"]
+			if route == nil || route.StopMap["0001"] == nil || route.StopMap["0001"].Name != "ЖК МЛАДОСТ 3" {
+				t.Errorf("expected the maps of the line to be rebuilt, got %v", line.OperationModeRoutesMap)
+			}
+
+			timetable, err := source.GetTimetableContext(ctx, "1", "10", "0001")
+			if err != nil || len(timetable) != 1 || timetable[0].Time != testTimetable[0].Time {
+				t.Errorf("expected timetable %v, got %v (%v)", testTimetable, timetable, err)
+			}
+		})
+	}
+}
+
+func TestSnapshotMissingResources(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot")
+	writeTestSnapshot(t, snapshotPath)
+	source, err := OpenSnapshot(snapshotPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	ctx := context.Background()
+	_, err = source.GetLineContext(ctx, schedule.VehicleTypeTram, "5")
+	var unknownLineError *schedule.UnknownLineError
+	if !errors.As(err, &unknownLineError) || !errors.Is(err, ErrMissingResource) || !errors.Is(err, schedule.ErrUnknownLine) {
+		t.Errorf("expected an UnknownLineError wrapping a MissingResourceError, got %v", err)
+	}
+
+	_, err = source.GetTimetableContext(ctx, "1", "10", "9999")
+	var unknownStopError *schedule.UnknownStopError
+	if !errors.As(err, &unknownStopError) || !errors.Is(err, ErrMissingResource) {
+		t.Errorf("expected an UnknownStopError wrapping a MissingResourceError, got %v", err)
+	}
+
+	_, err = source.GetStopsInLanguageContext(ctx, "de")
+	if err == nil || errors.Is(err, ErrMissingResource) {
+		t.Errorf("expected an error for an unsupported language, got %v", err)
+	}
+}
+
+func TestNewSnapshotFormatVersion(t *testing.T) {
+	testCases := []struct {
+		name          string
+		manifest      string
+		isSupported   bool
+		isMissingFile bool
+	}{
+		{name: "current version", manifest: `{"format_version":` + strconv.Itoa(snapshot.FormatVersion) + `}`, isSupported: true},
+		{name: "first version", manifest: `{"format_version":1}`, isSupported: true},
+		{name: "newer version", manifest: `{"format_version":` + strconv.Itoa(snapshot.FormatVersion+1) + `}`},
+		{name: "missing version", manifest: `{}`},
+		{name: "malformed manifest", manifest: `{`},
+		{name: "missing manifest", isMissingFile: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			if !testCase.isMissingFile {
+				fsys[snapshot.ManifestPath] = &fstest.MapFile{Data: []byte(testCase.manifest)}
+			}
+
+			source, err := NewSnapshot(fsys)
+			if testCase.isSupported {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+				if source.Manifest == nil {
+					t.Error("expected the manifest to be read")
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrMissingResource) != testCase.isMissingFile {
+				t.Errorf("expected errors.Is(err, ErrMissingResource) to be %t for %v", testCase.isMissingFile, err)
+			}
+		})
+	}
+}
+
+func TestSnapshotGetLinesFirstVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		snapshot.ManifestPath: &fstest.MapFile{Data: []byte(`{"format_version":1}`)},
+		snapshot.LinesPath:    &fstest.MapFile{Data: []byte(`{"bus_line_numbers":["94"],"trolleybus_line_numbers":["2"],"tram_line_numbers":["5"]}`)},
+	}
+	source, err := NewSnapshot(fsys)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	lines, err := source.GetLinesContext(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expectedLines := schedule.VehicleTypeLineNumbersList{
+		{VehicleType: schedule.VehicleTypeBus, LineNumbers: []string{"94"}},
+		{VehicleType: schedule.VehicleTypeTrolleybus, LineNumbers: []string{"2"}},
+		{VehicleType: schedule.VehicleTypeTram, LineNumbers: []string{"5"}},
+	}
+	if !reflect.DeepEqual(lines.VehicleTypeLineNumbersList, expectedLines) {
+		t.Errorf("expected lines %v, got %v", expectedLines, lines.VehicleTypeLineNumbersList)
+	}
+}
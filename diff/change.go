@@ -0,0 +1,189 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/rgeorgiev583/sofiatraffic/diff/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/format"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+)
+
+// ChangeKind represents a kind of change of the urban transit network.
+type ChangeKind string
+
+const (
+	// ChangeKindLineAdded represents the addition of a line.
+	ChangeKindLineAdded ChangeKind = "line_added"
+	// ChangeKindLineRemoved represents the removal of a line.
+	ChangeKindLineRemoved ChangeKind = "line_removed"
+	// ChangeKindRouteAdded represents the addition of a route to a line.
+	ChangeKindRouteAdded ChangeKind = "route_added"
+	// ChangeKindRouteRemoved represents the removal of a route from a line.
+	ChangeKindRouteRemoved ChangeKind = "route_removed"
+	// ChangeKindStopInserted represents the insertion of a stop into a route.
+	ChangeKindStopInserted ChangeKind = "stop_inserted"
+	// ChangeKindStopDeleted represents the deletion of a stop from a route.
+	ChangeKindStopDeleted ChangeKind = "stop_deleted"
+	// ChangeKindStopReordered represents the move of a stop to another position in a route.
+	ChangeKindStopReordered ChangeKind = "stop_reordered"
+	// ChangeKindTerminalChanged represents the replacement of the first or the last stop of a route.
+	ChangeKindTerminalChanged ChangeKind = "terminal_changed"
+	// ChangeKindOperationModeAdded represents the addition of an operation mode to the schedule of a line.
+	ChangeKindOperationModeAdded ChangeKind = "operation_mode_added"
+	// ChangeKindOperationModeRemoved represents the removal of an operation mode from the schedule of a line.
+	ChangeKindOperationModeRemoved ChangeKind = "operation_mode_removed"
+	// ChangeKindStopAdded represents the addition of a stop to the list of stops.
+	ChangeKindStopAdded ChangeKind = "stop_added"
+	// ChangeKindStopRemoved represents the removal of a stop from the list of stops.
+	ChangeKindStopRemoved ChangeKind = "stop_removed"
+	// ChangeKindStopRenamed represents the change of the name of a stop.
+	ChangeKindStopRenamed ChangeKind = "stop_renamed"
+)
+
+// Terminal identifies one of the terminal stops of a route.
+type Terminal string
+
+const (
+	// TerminalFirst represents the first stop of a route.
+	TerminalFirst Terminal = "first"
+	// TerminalLast represents the last stop of a route.
+	TerminalLast Terminal = "last"
+)
+
+// Change represents a single difference between two versions of the urban transit network data.
+type Change struct {
+	Kind          ChangeKind     `json:"kind"`                     // kind of the change
+	VehicleType   string         `json:"vehicle_type,omitempty"`   // type of the vehicle of the changed line (as used by the schedule)
+	LineNumber    string         `json:"line_number,omitempty"`    // number of the changed line
+	OperationMode string         `json:"operation_mode,omitempty"` // name of the added, removed or changed operation mode
+	Route         string         `json:"route,omitempty"`          // name of the added, removed or changed route
+	Terminal      Terminal       `json:"terminal,omitempty"`       // changed terminal stop of the route
+	PreviousStop  *schedule.Stop `json:"previous_stop,omitempty"`  // stop before the change (for renamed stops and changed terminal stops)
+	Stop          *schedule.Stop `json:"stop,omitempty"`           // added, removed, inserted, deleted or reordered stop (or the stop after the change)
+}
+
+// ChangeList represents a list of changes.
+type ChangeList []*Change
+
+// getKindName returns the translated name of the kind of the change.
+func (c *Change) getKindName() string {
+	switch c.Kind {
+	case ChangeKindLineAdded:
+		return l10n.Translator[l10n.LineAdded]
+
+	case ChangeKindLineRemoved:
+		return l10n.Translator[l10n.LineRemoved]
+
+	case ChangeKindRouteAdded:
+		return l10n.Translator[l10n.RouteAdded]
+
+	case ChangeKindRouteRemoved:
+		return l10n.Translator[l10n.RouteRemoved]
+
+	case ChangeKindStopInserted:
+		return l10n.Translator[l10n.StopInserted]
+
+	case ChangeKindStopDeleted:
+		return l10n.Translator[l10n.StopDeleted]
+
+	case ChangeKindStopReordered:
+		return l10n.Translator[l10n.StopReordered]
+
+	case ChangeKindTerminalChanged:
+		if c.Terminal == TerminalFirst {
+			return l10n.Translator[l10n.FirstTerminalChanged]
+		}
+		return l10n.Translator[l10n.LastTerminalChanged]
+
+	case ChangeKindOperationModeAdded:
+		return l10n.Translator[l10n.OperationModeAdded]
+
+	case ChangeKindOperationModeRemoved:
+		return l10n.Translator[l10n.OperationModeRemoved]
+
+	case ChangeKindStopAdded:
+		return l10n.Translator[l10n.StopAdded]
+
+	case ChangeKindStopRemoved:
+		return l10n.Translator[l10n.StopRemoved]
+
+	case ChangeKindStopRenamed:
+		return l10n.Translator[l10n.StopRenamed]
+	}
+	return string(c.Kind)
+}
+
+func formatStop(stop *schedule.Stop) string {
+	if stop.Name == "" {
+		return stop.Code
+	}
+	return stop.Name + " (" + stop.Code + ")"
+}
+
+// getStopCells returns the code and the name of stop (or two empty strings if it is nil).
+func getStopCells(stop *schedule.Stop) []string {
+	if stop == nil {
+		return []string{"", ""}
+	}
+	return []string{stop.Code, stop.Name}
+}
+
+func (c *Change) String() (str string) {
+	if c.LineNumber != "" {
+		vehicleType, ok := l10n.Translator[c.VehicleType]
+		if !ok {
+			vehicleType = c.VehicleType
+		}
+		str += vehicleType + " " + c.LineNumber
+		if c.OperationMode != "" && c.Kind != ChangeKindOperationModeAdded && c.Kind != ChangeKindOperationModeRemoved {
+			str += " [" + c.OperationMode + "]"
+		}
+		if c.Route != "" {
+			str += " " + c.Route
+		}
+		str += ": "
+	}
+	str += c.getKindName()
+	switch c.Kind {
+	case ChangeKindOperationModeAdded, ChangeKindOperationModeRemoved:
+		str += ": " + c.OperationMode
+
+	case ChangeKindTerminalChanged:
+		str += ": " + formatStop(c.PreviousStop) + " → " + formatStop(c.Stop)
+
+	case ChangeKindStopRenamed:
+		str += ": " + c.PreviousStop.Name + " → " + formatStop(c.Stop)
+
+	case ChangeKindStopInserted, ChangeKindStopDeleted, ChangeKindStopReordered, ChangeKindStopAdded, ChangeKindStopRemoved:
+		str += ": " + formatStop(c.Stop)
+	}
+	return
+}
+
+func (cl ChangeList) String() string {
+	var builder strings.Builder
+	for _, change := range cl {
+		builder.WriteString(change.String() + "\n")
+	}
+	return builder.String()
+}
+
+// TableHeader lists the names of the columns of the tabular representation of changes.
+var TableHeader = []string{"kind", "vehicle_type", "line_number", "operation_mode", "route", "terminal", "previous_stop_code", "previous_stop_name", "stop_code", "stop_name"}
+
+// TableRow returns the cells of the row representing the change in the tabular representation of changes.
+func (c *Change) TableRow() (row []string) {
+	row = []string{string(c.Kind), c.VehicleType, c.LineNumber, c.OperationMode, c.Route, string(c.Terminal)}
+	row = append(row, getStopCells(c.PreviousStop)...)
+	row = append(row, getStopCells(c.Stop)...)
+	return
+}
+
+// Table returns the tabular representation of the list of changes.
+func (cl ChangeList) Table() *format.Table {
+	table := &format.Table{Header: TableHeader}
+	for _, change := range cl {
+		table.Rows = append(table.Rows, change.TableRow())
+	}
+	return table
+}
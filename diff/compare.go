@@ -0,0 +1,375 @@
+package diff
+
+import (
+	"sort"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// lineKey identifies an urban transit line by its vehicle type and number.
+type lineKey struct {
+	vehicleType string
+	lineNumber  string
+}
+
+// newChange returns a copy of template with the specified kind.
+func newChange(template *Change, kind ChangeKind) *Change {
+	change := *template
+	change.Kind = kind
+	return &change
+}
+
+// CompareStops returns the stops which were added to, removed from or renamed in newStops in comparison to oldStops.
+func CompareStops(oldStops virtual.StopList, newStops virtual.StopList) (changes ChangeList) {
+	changes = ChangeList{}
+	oldStopMap := oldStops.GetStopMap()
+	newStopMap := newStops.GetStopMap()
+	for _, stop := range newStops {
+		oldStop, ok := oldStopMap[stop.Code]
+		if !ok {
+			changes = append(changes, &Change{Kind: ChangeKindStopAdded, Stop: &schedule.Stop{Code: stop.Code, Name: stop.Name}})
+		} else if oldStop.Name != stop.Name {
+			changes = append(changes, &Change{Kind: ChangeKindStopRenamed, PreviousStop: &schedule.Stop{Code: oldStop.Code, Name: oldStop.Name}, Stop: &schedule.Stop{Code: stop.Code, Name: stop.Name}})
+		}
+	}
+	for _, stop := range oldStops {
+		if _, ok := newStopMap[stop.Code]; !ok {
+			changes = append(changes, &Change{Kind: ChangeKindStopRemoved, Stop: &schedule.Stop{Code: stop.Code, Name: stop.Name}})
+		}
+	}
+	return
+}
+
+// getLineRouteLists returns the keys of the lines in routes in their original order along with a map from each key to the routes of the line.
+func getLineRouteLists(routes virtual.VehicleTypeLineNumberRouteListListList) (keys []lineKey, lineRouteLists map[lineKey]virtual.RouteList) {
+	lineRouteLists = map[lineKey]virtual.RouteList{}
+	for _, vehicleTypeRoutes := range routes {
+		for _, lineRoutes := range vehicleTypeRoutes.LineNumberRouteListList {
+			key := lineKey{vehicleType: vehicleTypeRoutes.VehicleType, lineNumber: lineRoutes.LineNumber}
+			keys = append(keys, key)
+			lineRouteLists[key] = lineRoutes.RouteList
+		}
+	}
+	return
+}
+
+// getRouteStops returns the stops of route named as in stops (a stop missing from stops has an empty name).
+func getRouteStops(route *virtual.Route, stops virtual.StopMap) (stopList schedule.StopList) {
+	stopList = schedule.StopList{}
+	for _, code := range route.StopCodes {
+		stop := &schedule.Stop{Code: code}
+		if virtualStop, ok := stops[code]; ok {
+			stop.Name = virtualStop.Name
+		}
+		stopList = append(stopList, stop)
+	}
+	return
+}
+
+// getRouteName returns the name of route determined using stops or, if it cannot be determined, the codes of its terminal stops.
+func getRouteName(route *virtual.Route, stops virtual.StopMap) string {
+	name, err := route.GetName(stops)
+	if err == nil {
+		return name
+	}
+
+	if len(route.StopCodes) == 0 {
+		return ""
+	}
+	return route.StopCodes[0] + " - " + route.StopCodes[len(route.StopCodes)-1]
+}
+
+// CompareRoutes returns the lines which were added to or removed from newRoutes in comparison to oldRoutes and the changes of the routes of the rest of the lines. The routes of a line are matched by their stops (see matchRoutes), so reordering the routes of a line does not produce any changes. The stops argument is used to determine the names of the stops and the routes (it may be nil). The vehicle types of the changes are the ones used by the schedule.
+func CompareRoutes(oldRoutes virtual.VehicleTypeLineNumberRouteListListList, newRoutes virtual.VehicleTypeLineNumberRouteListListList, stops virtual.StopMap) (changes ChangeList) {
+	changes = ChangeList{}
+	oldKeys, oldLineRouteLists := getLineRouteLists(oldRoutes)
+	newKeys, newLineRouteLists := getLineRouteLists(newRoutes)
+	for _, key := range newKeys {
+		template := &Change{VehicleType: virtual.GetScheduleVehicleType(key.vehicleType), LineNumber: key.lineNumber}
+		oldRouteList, ok := oldLineRouteLists[key]
+		if !ok {
+			changes = append(changes, newChange(template, ChangeKindLineAdded))
+			continue
+		}
+
+		newRouteList := newLineRouteLists[key]
+		oldRouteStops, oldRouteNames := make([]schedule.StopList, len(oldRouteList)), make([]string, len(oldRouteList))
+		for i, route := range oldRouteList {
+			oldRouteStops[i], oldRouteNames[i] = getRouteStops(route, stops), getRouteName(route, stops)
+		}
+		newRouteStops, newRouteNames := make([]schedule.StopList, len(newRouteList)), make([]string, len(newRouteList))
+		for i, route := range newRouteList {
+			newRouteStops[i], newRouteNames[i] = getRouteStops(route, stops), getRouteName(route, stops)
+		}
+		changes = append(changes, compareRouteLists(template, oldRouteStops, oldRouteNames, newRouteStops, newRouteNames)...)
+	}
+	for _, key := range oldKeys {
+		if _, ok := newLineRouteLists[key]; !ok {
+			changes = append(changes, &Change{Kind: ChangeKindLineRemoved, VehicleType: virtual.GetScheduleVehicleType(key.vehicleType), LineNumber: key.lineNumber})
+		}
+	}
+	return
+}
+
+// CompareOperationModes returns the operation modes which were added to or removed from newLine in comparison to oldLine. The operation modes are matched by their names.
+func CompareOperationModes(oldLine *schedule.Line, newLine *schedule.Line) (changes ChangeList) {
+	changes = ChangeList{}
+	template := &Change{VehicleType: newLine.VehicleType, LineNumber: newLine.LineNumber}
+	oldOperationModeRoutes := getOperationModeRoutesByName(oldLine)
+	newOperationModeRoutes := getOperationModeRoutesByName(newLine)
+	for _, operationModeRoutes := range newLine.OperationModeRoutesList {
+		if _, ok := oldOperationModeRoutes[operationModeRoutes.Name]; !ok {
+			change := newChange(template, ChangeKindOperationModeAdded)
+			change.OperationMode = operationModeRoutes.Name
+			changes = append(changes, change)
+		}
+	}
+	for _, operationModeRoutes := range oldLine.OperationModeRoutesList {
+		if _, ok := newOperationModeRoutes[operationModeRoutes.Name]; !ok {
+			change := newChange(template, ChangeKindOperationModeRemoved)
+			change.OperationMode = operationModeRoutes.Name
+			changes = append(changes, change)
+		}
+	}
+	return
+}
+
+func getOperationModeRoutesByName(line *schedule.Line) (operationModeRoutesByName map[string]*schedule.OperationModeRoutes) {
+	operationModeRoutesByName = map[string]*schedule.OperationModeRoutes{}
+	for _, operationModeRoutes := range line.OperationModeRoutesList {
+		operationModeRoutesByName[operationModeRoutes.Name] = operationModeRoutes
+	}
+	return
+}
+
+// CompareLines returns the changes of the schedule of a line between oldLine and newLine: the added and removed operation modes (see CompareOperationModes) and the changes of the routes of the rest of the operation modes, which are matched by their stops (see matchRoutes). A nil oldLine or newLine represents a line which was added or removed, respectively.
+func CompareLines(oldLine *schedule.Line, newLine *schedule.Line) (changes ChangeList) {
+	switch {
+	case oldLine == nil && newLine == nil:
+		return ChangeList{}
+
+	case oldLine == nil:
+		return ChangeList{{Kind: ChangeKindLineAdded, VehicleType: newLine.VehicleType, LineNumber: newLine.LineNumber}}
+
+	case newLine == nil:
+		return ChangeList{{Kind: ChangeKindLineRemoved, VehicleType: oldLine.VehicleType, LineNumber: oldLine.LineNumber}}
+	}
+
+	changes = CompareOperationModes(oldLine, newLine)
+	oldOperationModeRoutes := getOperationModeRoutesByName(oldLine)
+	for _, operationModeRoutes := range newLine.OperationModeRoutesList {
+		oldOperationMode, ok := oldOperationModeRoutes[operationModeRoutes.Name]
+		if !ok {
+			continue
+		}
+
+		template := &Change{VehicleType: newLine.VehicleType, LineNumber: newLine.LineNumber, OperationMode: operationModeRoutes.Name}
+		oldRouteStops, oldRouteNames := make([]schedule.StopList, len(oldOperationMode.RouteList)), make([]string, len(oldOperationMode.RouteList))
+		for i, route := range oldOperationMode.RouteList {
+			oldRouteStops[i], oldRouteNames[i] = route.StopList, route.Name
+		}
+		newRouteStops, newRouteNames := make([]schedule.StopList, len(operationModeRoutes.RouteList)), make([]string, len(operationModeRoutes.RouteList))
+		for i, route := range operationModeRoutes.RouteList {
+			newRouteStops[i], newRouteNames[i] = route.StopList, route.Name
+		}
+		changes = append(changes, compareRouteLists(template, oldRouteStops, oldRouteNames, newRouteStops, newRouteNames)...)
+	}
+	return
+}
+
+// getLongestCommonSubsequence determines the longest common subsequence of the stops with the specified oldCodes and newCodes and reports which of the stops belong to it.
+func getLongestCommonSubsequence(oldCodes []string, newCodes []string) (isOldStopCommon []bool, isNewStopCommon []bool) {
+	lengths := make([][]int, len(oldCodes)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newCodes)+1)
+	}
+	for i := len(oldCodes) - 1; i >= 0; i-- {
+		for j := len(newCodes) - 1; j >= 0; j-- {
+			if oldCodes[i] == newCodes[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	isOldStopCommon = make([]bool, len(oldCodes))
+	isNewStopCommon = make([]bool, len(newCodes))
+	for i, j := 0, 0; i < len(oldCodes) && j < len(newCodes); {
+		switch {
+		case oldCodes[i] == newCodes[j]:
+			isOldStopCommon[i] = true
+			isNewStopCommon[j] = true
+			i++
+			j++
+
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+
+		default:
+			j++
+		}
+	}
+	return
+}
+
+// compareRouteLists returns the routes which were added to or removed from the new routes (whose stops are listed in newRouteStops and whose names are listed in newRouteNames) in comparison to the old routes (oldRouteStops and oldRouteNames) and the changes of the stops of the rest of the routes, which are matched by their stops (see matchRoutes). The changes are copies of template with the appropriate kind, route and stops.
+func compareRouteLists(template *Change, oldRouteStops []schedule.StopList, oldRouteNames []string, newRouteStops []schedule.StopList, newRouteNames []string) (changes ChangeList) {
+	changes = ChangeList{}
+	oldStopCodesList := make([][]string, len(oldRouteStops))
+	for i, stops := range oldRouteStops {
+		oldStopCodesList[i] = getStopCodes(stops)
+	}
+	newStopCodesList := make([][]string, len(newRouteStops))
+	for j, stops := range newRouteStops {
+		newStopCodesList[j] = getStopCodes(stops)
+	}
+
+	oldRouteIndices := matchRoutes(oldStopCodesList, newStopCodesList)
+	isOldRouteMatched := make([]bool, len(oldRouteStops))
+	for j, stops := range newRouteStops {
+		i, ok := oldRouteIndices[j]
+		if !ok {
+			change := newChange(template, ChangeKindRouteAdded)
+			change.Route = newRouteNames[j]
+			changes = append(changes, change)
+			continue
+		}
+
+		isOldRouteMatched[i] = true
+		routeTemplate := *template
+		routeTemplate.Route = newRouteNames[j]
+		changes = append(changes, compareStopSequences(&routeTemplate, oldRouteStops[i], stops)...)
+	}
+	for i, isMatched := range isOldRouteMatched {
+		if !isMatched {
+			change := newChange(template, ChangeKindRouteRemoved)
+			change.Route = oldRouteNames[i]
+			changes = append(changes, change)
+		}
+	}
+	return
+}
+
+// matchRoutes matches the old routes whose stops have the codes listed in oldStopCodesList to the new routes whose stops have the codes listed in newStopCodesList. Routes with the same terminal stops are matched first (in their original order); each of the remaining new routes is then matched to the remaining old route with which it has the longest common subsequence of stops (the longest subsequences are matched first), as long as the routes have at least one common stop. The result maps the index of each matched new route to the index of the old route matched to it.
+func matchRoutes(oldStopCodesList [][]string, newStopCodesList [][]string) (oldRouteIndices map[int]int) {
+	oldRouteIndices = map[int]int{}
+	isOldRouteMatched := make([]bool, len(oldStopCodesList))
+	haveSameTerminals := func(oldCodes []string, newCodes []string) bool {
+		return len(oldCodes) > 0 && len(newCodes) > 0 && oldCodes[0] == newCodes[0] && oldCodes[len(oldCodes)-1] == newCodes[len(newCodes)-1]
+	}
+	for j, newCodes := range newStopCodesList {
+		for i, oldCodes := range oldStopCodesList {
+			if !isOldRouteMatched[i] && haveSameTerminals(oldCodes, newCodes) {
+				oldRouteIndices[j] = i
+				isOldRouteMatched[i] = true
+				break
+			}
+		}
+	}
+
+	type candidate struct {
+		oldIndex     int
+		newIndex     int
+		commonLength int
+	}
+	var candidates []candidate
+	for j, newCodes := range newStopCodesList {
+		if _, ok := oldRouteIndices[j]; ok {
+			continue
+		}
+
+		for i, oldCodes := range oldStopCodesList {
+			if isOldRouteMatched[i] {
+				continue
+			}
+
+			_, isNewStopCommon := getLongestCommonSubsequence(oldCodes, newCodes)
+			commonLength := 0
+			for _, isCommon := range isNewStopCommon {
+				if isCommon {
+					commonLength++
+				}
+			}
+			if commonLength > 0 {
+				candidates = append(candidates, candidate{oldIndex: i, newIndex: j, commonLength: commonLength})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].commonLength > candidates[b].commonLength
+	})
+	for _, candidate := range candidates {
+		if _, ok := oldRouteIndices[candidate.newIndex]; ok || isOldRouteMatched[candidate.oldIndex] {
+			continue
+		}
+
+		oldRouteIndices[candidate.newIndex] = candidate.oldIndex
+		isOldRouteMatched[candidate.oldIndex] = true
+	}
+	return
+}
+
+func getStopCodes(stops schedule.StopList) (codes []string) {
+	codes = make([]string, len(stops))
+	for i, stop := range stops {
+		codes[i] = stop.Code
+	}
+	return
+}
+
+// compareStopSequences returns the stops which were inserted into, deleted from or reordered in newStops in comparison to oldStops and the changes of the terminal stops. The changes are copies of template with the appropriate kind and stops. Stops which are contained in both sequences but are not part of their longest common subsequence are considered reordered.
+func compareStopSequences(template *Change, oldStops schedule.StopList, newStops schedule.StopList) (changes ChangeList) {
+	changes = ChangeList{}
+	oldCodes := getStopCodes(oldStops)
+	newCodes := getStopCodes(newStops)
+	isOldStopCommon, isNewStopCommon := getLongestCommonSubsequence(oldCodes, newCodes)
+	isOldCode := map[string]bool{}
+	for _, code := range oldCodes {
+		isOldCode[code] = true
+	}
+	isNewCode := map[string]bool{}
+	for _, code := range newCodes {
+		isNewCode[code] = true
+	}
+
+	for i, stop := range oldStops {
+		if !isOldStopCommon[i] && !isNewCode[stop.Code] {
+			change := newChange(template, ChangeKindStopDeleted)
+			change.Stop = stop
+			changes = append(changes, change)
+		}
+	}
+	for i, stop := range newStops {
+		if isNewStopCommon[i] {
+			continue
+		}
+
+		change := newChange(template, ChangeKindStopReordered)
+		if !isOldCode[stop.Code] {
+			change.Kind = ChangeKindStopInserted
+		}
+		change.Stop = stop
+		changes = append(changes, change)
+	}
+
+	if len(oldStops) == 0 || len(newStops) == 0 {
+		return
+	}
+
+	compareTerminals := func(terminal Terminal, oldStop *schedule.Stop, newStop *schedule.Stop) {
+		if oldStop.Code != newStop.Code {
+			change := newChange(template, ChangeKindTerminalChanged)
+			change.Terminal = terminal
+			change.PreviousStop = oldStop
+			change.Stop = newStop
+			changes = append(changes, change)
+		}
+	}
+	compareTerminals(TerminalFirst, oldStops[0], newStops[0])
+	compareTerminals(TerminalLast, oldStops[len(oldStops)-1], newStops[len(newStops)-1])
+	return
+}
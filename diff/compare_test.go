@@ -0,0 +1,256 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+var testStops = virtual.StopList{
+	{Code: "0001", Name: "ЖК МЛАДОСТ 3"},
+	{Code: "0002", Name: "ОБЕЛЯ"},
+	{Code: "0003", Name: "ЦЕНТРАЛНА ГАРА"},
+	{Code: "0004", Name: "ЛЪВОВ МОСТ"},
+}
+
+// newTestRoutes returns the routes of a single line with the specified vehicleType (as used by the virtual timetable API) and lineNumber whose routes visit the stops with the specified codes.
+func newTestRoutes(vehicleType string, lineNumber string, stopCodesList ...[]string) virtual.VehicleTypeLineNumberRouteListListList {
+	lineRoutes := &virtual.LineNumberRouteList{LineNumber: lineNumber}
+	for _, stopCodes := range stopCodesList {
+		lineRoutes.RouteList = append(lineRoutes.RouteList, &virtual.Route{StopCodes: stopCodes})
+	}
+	return virtual.VehicleTypeLineNumberRouteListListList{{VehicleType: vehicleType, LineNumberRouteListList: virtual.LineNumberRouteListList{lineRoutes}}}
+}
+
+func getTestStop(code string) *schedule.Stop {
+	for _, stop := range testStops {
+		if stop.Code == code {
+			return &schedule.Stop{Code: stop.Code, Name: stop.Name}
+		}
+	}
+	return &schedule.Stop{Code: code}
+}
+
+func TestCompareStops(t *testing.T) {
+	oldStops := virtual.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ОБЕЛЯ 2"}, {Code: "0005", Name: "ДЕПО"}}
+	expectedChanges := ChangeList{
+		{Kind: ChangeKindStopRenamed, PreviousStop: &schedule.Stop{Code: "0002", Name: "ОБЕЛЯ 2"}, Stop: getTestStop("0002")},
+		{Kind: ChangeKindStopAdded, Stop: getTestStop("0003")},
+		{Kind: ChangeKindStopAdded, Stop: getTestStop("0004")},
+		{Kind: ChangeKindStopRemoved, Stop: &schedule.Stop{Code: "0005", Name: "ДЕПО"}},
+	}
+	changes := CompareStops(oldStops, testStops)
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("expected changes %v, got %v", expectedChanges, changes)
+	}
+}
+
+func TestCompareRoutes(t *testing.T) {
+	bus := virtual.VehicleTypeBus
+	testCases := []struct {
+		name            string
+		oldRoutes       virtual.VehicleTypeLineNumberRouteListListList
+		newRoutes       virtual.VehicleTypeLineNumberRouteListListList
+		expectedChanges ChangeList
+	}{
+		{
+			name:            "unchanged",
+			oldRoutes:       newTestRoutes(bus, "94", []string{"0001", "0002", "0003"}),
+			newRoutes:       newTestRoutes(bus, "94", []string{"0001", "0002", "0003"}),
+			expectedChanges: ChangeList{},
+		},
+		{
+			name:            "reordered routes",
+			oldRoutes:       newTestRoutes(bus, "94", []string{"0001", "0002", "0003"}, []string{"0003", "0002", "0001"}),
+			newRoutes:       newTestRoutes(bus, "94", []string{"0003", "0002", "0001"}, []string{"0001", "0002", "0003"}),
+			expectedChanges: ChangeList{},
+		},
+		{
+			name:      "stop inserted into reordered routes",
+			oldRoutes: newTestRoutes(bus, "94", []string{"0001", "0003"}, []string{"0003", "0001"}),
+			newRoutes: newTestRoutes(bus, "94", []string{"0003", "0002", "0001"}, []string{"0001", "0003"}),
+			expectedChanges: ChangeList{
+				{Kind: ChangeKindStopInserted, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", Route: "ЦЕНТРАЛНА ГАРА - ЖК МЛАДОСТ 3", Stop: getTestStop("0002")},
+			},
+		},
+		{
+			name:      "terminal changed",
+			oldRoutes: newTestRoutes(bus, "94", []string{"0003", "0002", "0001"}, []string{"0001", "0002", "0003"}),
+			newRoutes: newTestRoutes(bus, "94", []string{"0001", "0002", "0003", "0004"}, []string{"0003", "0002", "0001"}),
+			expectedChanges: ChangeList{
+				{Kind: ChangeKindStopInserted, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", Route: "ЖК МЛАДОСТ 3 - ЛЪВОВ МОСТ", Stop: getTestStop("0004")},
+				{Kind: ChangeKindTerminalChanged, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", Route: "ЖК МЛАДОСТ 3 - ЛЪВОВ МОСТ", Terminal: TerminalLast, PreviousStop: getTestStop("0003"), Stop: getTestStop("0004")},
+			},
+		},
+		{
+			name:      "route added and removed",
+			oldRoutes: newTestRoutes(bus, "94", []string{"0001", "0002"}, []string{"0002", "0001"}),
+			newRoutes: newTestRoutes(bus, "94", []string{"0003", "0004"}, []string{"0002", "0001"}),
+			expectedChanges: ChangeList{
+				{Kind: ChangeKindRouteAdded, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", Route: "ЦЕНТРАЛНА ГАРА - ЛЪВОВ МОСТ"},
+				{Kind: ChangeKindRouteRemoved, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", Route: "ЖК МЛАДОСТ 3 - ОБЕЛЯ"},
+			},
+		},
+		{
+			name:      "line replaced",
+			oldRoutes: newTestRoutes(virtual.VehicleTypeTrolleybus, "2", []string{"0001", "0002"}),
+			newRoutes: newTestRoutes(bus, "2", []string{"0001", "0002"}),
+			expectedChanges: ChangeList{
+				{Kind: ChangeKindLineAdded, VehicleType: schedule.VehicleTypeBus, LineNumber: "2"},
+				{Kind: ChangeKindLineRemoved, VehicleType: schedule.VehicleTypeTrolleybus, LineNumber: "2"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			changes := CompareRoutes(testCase.oldRoutes, testCase.newRoutes, testStops.GetStopMap())
+			if !reflect.DeepEqual(changes, testCase.expectedChanges) {
+				t.Errorf("expected changes %v, got %v", testCase.expectedChanges, changes)
+			}
+		})
+	}
+}
+
+func TestMatchRoutes(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		oldStopCodesList        [][]string
+		newStopCodesList        [][]string
+		expectedOldRouteIndices map[int]int
+	}{
+		{
+			name:                    "same terminals",
+			oldStopCodesList:        [][]string{{"1", "2", "3"}, {"3", "2", "1"}},
+			newStopCodesList:        [][]string{{"3", "1"}, {"1", "4", "3"}},
+			expectedOldRouteIndices: map[int]int{0: 1, 1: 0},
+		},
+		{
+			name:                    "longest common subsequence",
+			oldStopCodesList:        [][]string{{"1", "2", "3", "4"}, {"4", "3", "2", "1"}},
+			newStopCodesList:        [][]string{{"5", "3", "2", "1"}, {"1", "2", "3", "6"}},
+			expectedOldRouteIndices: map[int]int{0: 1, 1: 0},
+		},
+		{
+			name:                    "terminals take precedence",
+			oldStopCodesList:        [][]string{{"1", "2", "3", "4"}, {"1", "4"}},
+			newStopCodesList:        [][]string{{"1", "2", "3", "5"}, {"1", "2", "3", "4"}},
+			expectedOldRouteIndices: map[int]int{0: 1, 1: 0},
+		},
+		{
+			name:                    "no common stops",
+			oldStopCodesList:        [][]string{{"1", "2"}},
+			newStopCodesList:        [][]string{{"3", "4"}},
+			expectedOldRouteIndices: map[int]int{},
+		},
+		{
+			name:                    "more new routes",
+			oldStopCodesList:        [][]string{{"1", "2"}},
+			newStopCodesList:        [][]string{{"2", "3"}, {"1", "2", "3"}},
+			expectedOldRouteIndices: map[int]int{1: 0},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			oldRouteIndices := matchRoutes(testCase.oldStopCodesList, testCase.newStopCodesList)
+			if !reflect.DeepEqual(oldRouteIndices, testCase.expectedOldRouteIndices) {
+				t.Errorf("expected matches %v, got %v", testCase.expectedOldRouteIndices, oldRouteIndices)
+			}
+		})
+	}
+}
+
+func TestCompareLines(t *testing.T) {
+	newRoute := func(code string, name string, stopCodes ...string) *schedule.Route {
+		route := &schedule.Route{Code: code, Name: name}
+		for _, stopCode := range stopCodes {
+			route.StopList = append(route.StopList, getTestStop(stopCode))
+		}
+		return route
+	}
+	oldLine := &schedule.Line{
+		VehicleType: schedule.VehicleTypeBus,
+		LineNumber:  "94",
+		OperationModeRoutesList: schedule.OperationModeRoutesList{
+			{OperationMode: &schedule.OperationMode{Code: "1", Name: "делник"}, RouteList: schedule.RouteList{newRoute("10", "A", "0001", "0002", "0003"), newRoute("11", "B", "0003", "0002", "0001")}},
+			{OperationMode: &schedule.OperationMode{Code: "2", Name: "празник"}},
+		},
+	}
+	newLine := &schedule.Line{
+		VehicleType: schedule.VehicleTypeBus,
+		LineNumber:  "94",
+		OperationModeRoutesList: schedule.OperationModeRoutesList{
+			{OperationMode: &schedule.OperationMode{Code: "1", Name: "делник"}, RouteList: schedule.RouteList{newRoute("20", "B", "0003", "0001"), newRoute("21", "A", "0001", "0002", "0003")}},
+			{OperationMode: &schedule.OperationMode{Code: "3", Name: "предпразник"}},
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		oldLine         *schedule.Line
+		newLine         *schedule.Line
+		expectedChanges ChangeList
+	}{
+		{
+			name:    "changed line",
+			oldLine: oldLine,
+			newLine: newLine,
+			expectedChanges: ChangeList{
+				{Kind: ChangeKindOperationModeAdded, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", OperationMode: "предпразник"},
+				{Kind: ChangeKindOperationModeRemoved, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", OperationMode: "празник"},
+				{Kind: ChangeKindStopDeleted, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", OperationMode: "делник", Route: "B", Stop: getTestStop("0002")},
+			},
+		},
+		{name: "added line", newLine: newLine, expectedChanges: ChangeList{{Kind: ChangeKindLineAdded, VehicleType: schedule.VehicleTypeBus, LineNumber: "94"}}},
+		{name: "removed line", oldLine: oldLine, expectedChanges: ChangeList{{Kind: ChangeKindLineRemoved, VehicleType: schedule.VehicleTypeBus, LineNumber: "94"}}},
+		{name: "no line", expectedChanges: ChangeList{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			changes := CompareLines(testCase.oldLine, testCase.newLine)
+			if !reflect.DeepEqual(changes, testCase.expectedChanges) {
+				t.Errorf("expected changes %v, got %v", testCase.expectedChanges, changes)
+			}
+		})
+	}
+}
+
+func TestCompareStopSequences(t *testing.T) {
+	template := &Change{VehicleType: schedule.VehicleTypeBus, LineNumber: "94", Route: "A"}
+	newStops := func(codes ...string) (stops schedule.StopList) {
+		for _, code := range codes {
+			stops = append(stops, getTestStop(code))
+		}
+		return
+	}
+	newStopChange := func(kind ChangeKind, code string) *Change {
+		change := newChange(template, kind)
+		change.Stop = getTestStop(code)
+		return change
+	}
+
+	testCases := []struct {
+		name            string
+		oldStops        schedule.StopList
+		newStops        schedule.StopList
+		expectedChanges ChangeList
+	}{
+		{name: "unchanged", oldStops: newStops("0001", "0002", "0003"), newStops: newStops("0001", "0002", "0003"), expectedChanges: ChangeList{}},
+		{name: "stop deleted", oldStops: newStops("0001", "0002", "0003"), newStops: newStops("0001", "0003"), expectedChanges: ChangeList{newStopChange(ChangeKindStopDeleted, "0002")}},
+		{name: "stop inserted", oldStops: newStops("0001", "0003"), newStops: newStops("0001", "0004", "0003"), expectedChanges: ChangeList{newStopChange(ChangeKindStopInserted, "0004")}},
+		{name: "stop reordered", oldStops: newStops("0001", "0002", "0003", "0004"), newStops: newStops("0001", "0003", "0002", "0004"), expectedChanges: ChangeList{newStopChange(ChangeKindStopReordered, "0002")}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			changes := compareStopSequences(template, testCase.oldStops, testCase.newStops)
+			if !reflect.DeepEqual(changes, testCase.expectedChanges) {
+				t.Errorf("expected changes %v, got %v", testCase.expectedChanges, changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+/*
+Package diff implements structural comparison of the urban transit network data (i.e. stops, routes and schedule lines) which reports the differences as typed change records with human-readable and JSON representations.
+*/
+package diff
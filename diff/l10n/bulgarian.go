@@ -0,0 +1,32 @@
+package l10n
+
+// BulgarianTranslator maps names of terms in the reference language (i.e. English) to their translation in Bulgarian.
+var BulgarianTranslator = map[string]string{
+	VehicleTypeBus:        "автобус",
+	VehicleTypeTrolleybus: "тролейбус",
+	VehicleTypeTram:       "трамвай",
+	VehicleTypeMetro:      "метро",
+
+	LineAdded:            "добавена линия",
+	LineRemoved:          "премахната линия",
+	RouteAdded:           "добавен маршрут",
+	RouteRemoved:         "премахнат маршрут",
+	StopInserted:         "вмъкната спирка",
+	StopDeleted:          "изтрита спирка",
+	StopReordered:        "преместена спирка",
+	FirstTerminalChanged: "променена начална спирка",
+	LastTerminalChanged:  "променена крайна спирка",
+	OperationModeAdded:   "добавен режим",
+	OperationModeRemoved: "премахнат режим",
+	StopAdded:            "добавена спирка",
+	StopRemoved:          "премахната спирка",
+	StopRenamed:          "преименувана спирка",
+}
+
+// ReverseBulgarianTranslator maps translated terms in Bulgarian to their names in the reference language (i.e. English).
+var ReverseBulgarianTranslator = map[string]string{
+	"автобус":   VehicleTypeBus,
+	"тролейбус": VehicleTypeTrolleybus,
+	"трамвай":   VehicleTypeTram,
+	"метро":     VehicleTypeMetro,
+}
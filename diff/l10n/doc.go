@@ -0,0 +1,4 @@
+/*
+Package l10n provides localization for the `diff` package.
+*/
+package l10n
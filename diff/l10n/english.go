@@ -0,0 +1,32 @@
+package l10n
+
+// EnglishTranslator maps names of terms in the reference language (i.e. English) to their translation in English.
+var EnglishTranslator = map[string]string{
+	VehicleTypeBus:        "bus",
+	VehicleTypeTrolleybus: "trolleybus",
+	VehicleTypeTram:       "tram",
+	VehicleTypeMetro:      "metro",
+
+	LineAdded:            "line added",
+	LineRemoved:          "line removed",
+	RouteAdded:           "route added",
+	RouteRemoved:         "route removed",
+	StopInserted:         "stop inserted",
+	StopDeleted:          "stop deleted",
+	StopReordered:        "stop reordered",
+	FirstTerminalChanged: "first terminal stop changed",
+	LastTerminalChanged:  "last terminal stop changed",
+	OperationModeAdded:   "operation mode added",
+	OperationModeRemoved: "operation mode removed",
+	StopAdded:            "stop added",
+	StopRemoved:          "stop removed",
+	StopRenamed:          "stop renamed",
+}
+
+// ReverseEnglishTranslator maps translated terms in English to their names in the reference language (i.e. English).
+var ReverseEnglishTranslator = map[string]string{
+	"bus":        VehicleTypeBus,
+	"trolleybus": VehicleTypeTrolleybus,
+	"tram":       VehicleTypeTram,
+	"metro":      VehicleTypeMetro,
+}
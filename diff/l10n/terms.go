@@ -0,0 +1,23 @@
+package l10n
+
+const (
+	VehicleTypeBus        = "autobus"
+	VehicleTypeTrolleybus = "trolleybus"
+	VehicleTypeTram       = "tramway"
+	VehicleTypeMetro      = "metro"
+
+	LineAdded            = "line added"
+	LineRemoved          = "line removed"
+	RouteAdded           = "route added"
+	RouteRemoved         = "route removed"
+	StopInserted         = "stop inserted"
+	StopDeleted          = "stop deleted"
+	StopReordered        = "stop reordered"
+	FirstTerminalChanged = "first terminal stop changed"
+	LastTerminalChanged  = "last terminal stop changed"
+	OperationModeAdded   = "operation mode added"
+	OperationModeRemoved = "operation mode removed"
+	StopAdded            = "stop added"
+	StopRemoved          = "stop removed"
+	StopRenamed          = "stop renamed"
+)
@@ -0,0 +1,31 @@
+package format
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// CSVFormatter writes the tabular representation of values as comma-separated values with a header row.
+type CSVFormatter struct {
+	Comma rune // field delimiter (a comma is used if zero)
+}
+
+// Format writes the tabular representation of value to w as CSV.
+func (f *CSVFormatter) Format(w io.Writer, value interface{}) (err error) {
+	table, err := Tabulate(value)
+	if err != nil {
+		return
+	}
+
+	writer := csv.NewWriter(w)
+	if f.Comma != 0 {
+		writer.Comma = f.Comma
+	}
+	err = writer.Write(table.Header)
+	if err != nil {
+		return
+	}
+
+	err = writer.WriteAll(table.Rows)
+	return
+}
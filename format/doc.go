@@ -0,0 +1,4 @@
+/*
+Package format implements pluggable output formatters (text, Markdown, JSON, CSV, YAML and HTML) for the domain types of the `schedule` and `virtual` packages.
+*/
+package format
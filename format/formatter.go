@@ -0,0 +1,81 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formatter writes the representation of a value in a specific output format.
+type Formatter interface {
+	// Format writes the representation of value to w.
+	Format(w io.Writer, value interface{}) error
+}
+
+const (
+	// NameText is the name of the human-readable text format produced by the Render and String methods of the domain types.
+	NameText = "text"
+	// NameMarkdown is the name of the format rendering values as GitHub Flavored Markdown tables.
+	NameMarkdown = "markdown"
+	// NameJSON is the name of the JSON format.
+	NameJSON = "json"
+	// NameCSV is the name of the CSV format.
+	NameCSV = "csv"
+	// NameYAML is the name of the YAML format.
+	NameYAML = "yaml"
+	// NameHTML is the name of the format rendering values as HTML tables.
+	NameHTML = "html"
+	// NameSIRI is the name of the SIRI StopMonitoring XML format, which only supports real-time arrivals.
+	NameSIRI = "siri"
+)
+
+// Names lists the names of all supported formats.
+var Names = []string{NameText, NameMarkdown, NameJSON, NameCSV, NameYAML, NameHTML, NameSIRI}
+
+// ErrUnknownFormat indicates that the requested output format is not supported.
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// UnknownFormatError represents a request for an unsupported output format.
+type UnknownFormatError struct {
+	Name string // name of the requested format
+}
+
+func (e *UnknownFormatError) Error() string {
+	return fmt.Sprintf("unknown output format %s (supported formats: %s)", e.Name, strings.Join(Names, ", "))
+}
+
+// Is reports whether target is ErrUnknownFormat.
+func (e *UnknownFormatError) Is(target error) bool {
+	return target == ErrUnknownFormat
+}
+
+// New returns a Formatter for the format with the specified name configured with its default settings.
+func New(name string) (formatter Formatter, err error) {
+	switch strings.ToLower(name) {
+	case NameText:
+		formatter = &TextFormatter{}
+
+	case NameMarkdown, "md":
+		formatter = &MarkdownFormatter{}
+
+	case NameJSON:
+		formatter = &JSONFormatter{Indent: "  "}
+
+	case NameCSV:
+		formatter = &CSVFormatter{}
+
+	case NameYAML, "yml":
+		formatter = &YAMLFormatter{}
+
+	case NameHTML:
+		formatter = &HTMLFormatter{}
+
+	case NameSIRI:
+		formatter = &SIRIFormatter{}
+
+	default:
+		err = &UnknownFormatError{Name: name}
+	}
+	return
+}
@@ -0,0 +1,181 @@
+package format
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+	virtual_l10n "github.com/rgeorgiev583/sofiatraffic/virtual/l10n"
+)
+
+func TestMain(m *testing.M) {
+	virtual_l10n.Translator = virtual_l10n.EnglishTranslator
+	os.Exit(m.Run())
+}
+
+func newTestStopTimetable() *virtual.StopTimetable {
+	return &virtual.StopTimetable{
+		StopCode: "0001",
+		StopName: "STOP | 1",
+		LineVehicleArrivalListList: virtual.LineVehicleArrivalListList{
+			{
+				VehicleType: virtual.VehicleTypeBus,
+				LineNumber:  "94",
+				VehicleArrivalList: virtual.VehicleArrivalList{
+					{Time: "12:00:00", HasAirConditioning: true},
+					{Time: "12:10:00", IsWheelchairAccessible: true},
+				},
+			},
+		},
+		GenerationTime: "11:59:00",
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{
+			name:  NameJSON,
+			value: newTestStopTimetable(),
+			expected: `{
+  "code": "0001",
+  "name": "STOP | 1",
+  "lines": [
+    {
+      "vehicle_type": "bus",
+      "name": "94",
+      "arrivals": [
+        {
+          "time": "12:00:00",
+          "has_air_conditioning": true,
+          "is_wheelchair_accessible": false
+        },
+        {
+          "time": "12:10:00",
+          "has_air_conditioning": false,
+          "is_wheelchair_accessible": true
+        }
+      ]
+    }
+  ],
+  "timestamp_calculated": "11:59:00"
+}
+`,
+		},
+		{
+			name:  NameYAML,
+			value: newTestStopTimetable(),
+			expected: `code: "0001"
+name: STOP | 1
+lines:
+  - vehicle_type: bus
+    name: "94"
+    arrivals:
+      - time: 12:00:00
+        has_air_conditioning: true
+        is_wheelchair_accessible: false
+      - time: 12:10:00
+        has_air_conditioning: false
+        is_wheelchair_accessible: true
+timestamp_calculated: 11:59:00
+`,
+		},
+		{
+			name:  NameCSV,
+			value: newTestStopTimetable(),
+			expected: `stop_code,stop_name,generation_time,vehicle_type,line_number,time,has_air_conditioning,is_wheelchair_accessible
+0001,STOP | 1,11:59:00,bus,94,12:00:00,true,false
+0001,STOP | 1,11:59:00,bus,94,12:10:00,false,true
+`,
+		},
+		{
+			name:  NameMarkdown,
+			value: virtual.StopList{{Code: "0001", Name: "STOP | 1"}},
+			expected: `| stop_code | stop_name |
+| --- | --- |
+| 0001 | STOP \| 1 |
+`,
+		},
+		{
+			name:  NameHTML,
+			value: virtual.StopList{{Code: "0001", Name: "<STOP>"}},
+			expected: `<table>
+<thead>
+<tr><th>stop_code</th><th>stop_name</th></tr>
+</thead>
+<tbody>
+<tr><td>0001</td><td>&lt;STOP&gt;</td></tr>
+</tbody>
+</table>
+`,
+		},
+		{
+			name:  NameText,
+			value: newTestStopTimetable(),
+			expected: `STOP | 1 (0001)
+===============
+* bus 94: 12:00:00, 12:10:00
+`,
+		},
+		{
+			name:     NameCSV,
+			value:    schedule.Timetable{{Time: 25*60*60*1e9 + 5*60*1e9, Markers: "*"}},
+			expected: "time,is_after_midnight,markers\n01:05,true,*\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			formatter, err := New(testCase.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			var builder strings.Builder
+			err = formatter.Format(&builder, testCase.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if builder.String() != testCase.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", testCase.expected, builder.String())
+			}
+		})
+	}
+}
+
+func TestFormatChannel(t *testing.T) {
+	channel := make(chan *virtual.StopTimetableFetchResult, 3)
+	channel <- &virtual.StopTimetableFetchResult{StopTimetable: newTestStopTimetable()}
+	channel <- &virtual.StopTimetableFetchResult{StopTimetable: &virtual.StopTimetable{StopCode: "0002"}}
+	channel <- &virtual.StopTimetableFetchResult{Err: errors.New("failure")}
+	close(channel)
+
+	var builder strings.Builder
+	err := (&JSONFormatter{}).Format(&builder, virtual.StopTimetableChannel(channel))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if !strings.HasPrefix(builder.String(), `[{"code":"0001"`) || strings.Contains(builder.String(), "0002") {
+		t.Errorf("expected the timetables with arrivals to be collected into a list, got %s", builder.String())
+	}
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	_, err := New("xml")
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected the error to match ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestTabulateUnsupportedValue(t *testing.T) {
+	_, err := Tabulate(42)
+	if err == nil {
+		t.Error("expected an error for an unsupported value")
+	}
+}
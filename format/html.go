@@ -0,0 +1,43 @@
+package format
+
+import (
+	"html"
+	"io"
+	"strings"
+)
+
+// HTMLFormatter writes the tabular representation of values as HTML tables.
+type HTMLFormatter struct {
+	IsDocument bool // whether the table should be wrapped in a complete HTML document
+}
+
+// Format writes the tabular representation of value to w as an HTML table.
+func (f *HTMLFormatter) Format(w io.Writer, value interface{}) (err error) {
+	table, err := Tabulate(value)
+	if err != nil {
+		return
+	}
+
+	var builder strings.Builder
+	if f.IsDocument {
+		builder.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n</head>\n<body>\n")
+	}
+	builder.WriteString("<table>\n<thead>\n<tr>")
+	for _, cell := range table.Header {
+		builder.WriteString("<th>" + html.EscapeString(cell) + "</th>")
+	}
+	builder.WriteString("</tr>\n</thead>\n<tbody>\n")
+	for _, row := range table.Rows {
+		builder.WriteString("<tr>")
+		for _, cell := range row {
+			builder.WriteString("<td>" + html.EscapeString(cell) + "</td>")
+		}
+		builder.WriteString("</tr>\n")
+	}
+	builder.WriteString("</tbody>\n</table>\n")
+	if f.IsDocument {
+		builder.WriteString("</body>\n</html>\n")
+	}
+	_, err = io.WriteString(w, builder.String())
+	return
+}
@@ -0,0 +1,32 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// JSONFormatter writes the JSON representation of values.
+type JSONFormatter struct {
+	Indent string // string used for each level of indentation (the output is compact if empty)
+}
+
+// Format writes the JSON representation of value to w followed by a newline.
+func (f *JSONFormatter) Format(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", f.Indent)
+	return encoder.Encode(collect(value))
+}
+
+// collect returns the value which should be encoded instead of the specified one (i.e. channels are consumed and replaced with the list of received values).
+func collect(value interface{}) interface{} {
+	switch value := value.(type) {
+	case virtual.StopTimetableChannel:
+		return value.Collect()
+
+	default:
+		return value
+	}
+}
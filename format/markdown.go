@@ -0,0 +1,35 @@
+package format
+
+import (
+	"io"
+	"strings"
+)
+
+// MarkdownFormatter writes the tabular representation of values as GitHub Flavored Markdown tables.
+type MarkdownFormatter struct{}
+
+var markdownCellEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", "<br>")
+
+// Format writes the tabular representation of value to w as a Markdown table.
+func (f *MarkdownFormatter) Format(w io.Writer, value interface{}) (err error) {
+	table, err := Tabulate(value)
+	if err != nil {
+		return
+	}
+
+	var builder strings.Builder
+	writeRow := func(cells []string) {
+		builder.WriteString("|")
+		for _, cell := range cells {
+			builder.WriteString(" " + markdownCellEscaper.Replace(cell) + " |")
+		}
+		builder.WriteString("\n")
+	}
+	writeRow(table.Header)
+	builder.WriteString("|" + strings.Repeat(" --- |", len(table.Header)) + "\n")
+	for _, row := range table.Rows {
+		writeRow(row)
+	}
+	_, err = io.WriteString(w, builder.String())
+	return
+}
@@ -0,0 +1,45 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/siri"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// SIRIFormatter writes the expected arrivals from virtual timetables as SIRI StopMonitoring XML documents.
+type SIRIFormatter struct {
+	Now    time.Time                                      // time of the response, relative to which the arrival times are resolved (the current time is used if zero)
+	Routes virtual.VehicleTypeLineNumberRouteListListList // routes of the urban transit lines, which are used to determine the directions of the vehicle journeys (the directions are unknown if nil)
+}
+
+func (f *SIRIFormatter) getNow() time.Time {
+	if f.Now.IsZero() {
+		return time.Now()
+	}
+	return f.Now
+}
+
+// Format writes the SIRI StopMonitoring representation of value to w. Only stop timetables and lists of line vehicle arrivals (which are rendered as a delivery for an unspecified stop) are supported.
+func (f *SIRIFormatter) Format(w io.Writer, value interface{}) (err error) {
+	now := f.getNow()
+	var document *siri.Siri
+	switch value := collect(value).(type) {
+	case *virtual.StopTimetable:
+		document = siri.New([]*siri.StopMonitoringDelivery{siri.NewStopMonitoringDelivery(value, f.Routes, now)}, now)
+
+	case virtual.StopTimetableList:
+		document = siri.NewFromTimetables(value, f.Routes, now)
+
+	case virtual.LineVehicleArrivalListList:
+		delivery := &siri.StopMonitoringDelivery{Version: siri.Version, ResponseTimestamp: now.Truncate(time.Second), MonitoredStopVisits: siri.NewMonitoredStopVisits("", "", value, nil, now)}
+		document = siri.New([]*siri.StopMonitoringDelivery{delivery}, now)
+
+	default:
+		return fmt.Errorf("could not encode value of type %T as SIRI", value)
+	}
+
+	return siri.Encode(w, document)
+}
@@ -0,0 +1,337 @@
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// Table represents the tabular representation of a value, which is used by the Markdown, CSV and HTML formatters.
+type Table struct {
+	Header []string   // names of the columns
+	Rows   [][]string // values of the cells in each row
+}
+
+// Tabulator is implemented by values which can provide their own tabular representation.
+type Tabulator interface {
+	Table() *Table
+}
+
+const (
+	columnVehicleType            = "vehicle_type"
+	columnLineNumber             = "line_number"
+	columnOperationModeCode      = "operation_mode_code"
+	columnOperationModeName      = "operation_mode_name"
+	columnRouteCode              = "route_code"
+	columnRouteName              = "route_name"
+	columnRouteIndex             = "route_index"
+	columnStopIndex              = "stop_index"
+	columnStopCode               = "stop_code"
+	columnStopName               = "stop_name"
+	columnGenerationTime         = "generation_time"
+	columnTime                   = "time"
+	columnHasAirConditioning     = "has_air_conditioning"
+	columnIsWheelchairAccessible = "is_wheelchair_accessible"
+	columnIsAfterMidnight        = "is_after_midnight"
+	columnMarkers                = "markers"
+)
+
+var (
+	stopColumns                  = []string{columnStopCode, columnStopName}
+	lineColumns                  = []string{columnVehicleType, columnLineNumber}
+	operationModeColumns         = []string{columnOperationModeCode, columnOperationModeName}
+	vehicleArrivalColumns        = []string{columnTime, columnHasAirConditioning, columnIsWheelchairAccessible}
+	departureColumns             = []string{columnTime, columnIsAfterMidnight, columnMarkers}
+	virtualRouteColumns          = []string{columnRouteIndex, columnStopIndex, columnStopCode}
+	virtualNamedRouteColumns     = []string{columnRouteName, columnStopIndex, columnStopCode, columnStopName}
+	virtualStopTimetableColumns  = join([]string{columnStopCode, columnStopName, columnGenerationTime}, lineColumns, vehicleArrivalColumns)
+	scheduleRouteColumns         = []string{columnRouteCode, columnRouteName, columnStopIndex, columnStopCode, columnStopName}
+	scheduleOperationModeColumns = join(operationModeColumns, scheduleRouteColumns)
+	scheduleDetailedColumns      = join(lineColumns, operationModeColumns, []string{columnRouteCode, columnRouteName}, stopColumns, departureColumns)
+	scheduleUpcomingColumns      = join(lineColumns, operationModeColumns, []string{columnRouteCode, columnRouteName}, stopColumns, []string{columnTime, columnMarkers})
+)
+
+// join returns the concatenation of the specified lists.
+func join(lists ...[]string) (joined []string) {
+	for _, list := range lists {
+		joined = append(joined, list...)
+	}
+	return
+}
+
+// prefixRows returns the specified rows with the prefix cells prepended to each of them.
+func prefixRows(prefix []string, rows [][]string) (prefixedRows [][]string) {
+	prefixedRows = make([][]string, len(rows))
+	for i, row := range rows {
+		prefixedRows[i] = join(prefix, row)
+	}
+	return
+}
+
+// Tabulate returns the tabular representation of the specified value, which may be any of the domain types of the `schedule` and `virtual` packages or a Tabulator.
+func Tabulate(value interface{}) (table *Table, err error) {
+	switch value := value.(type) {
+	case Tabulator:
+		table = value.Table()
+
+	case *virtual.Stop:
+		table = &Table{Header: stopColumns, Rows: virtualStopRows(virtual.StopList{value})}
+
+	case virtual.StopList:
+		table = &Table{Header: stopColumns, Rows: virtualStopRows(value)}
+
+	case virtual.StopMap:
+		stops := virtual.StopList{}
+		for _, stop := range value {
+			stops = append(stops, stop)
+		}
+		sort.Sort(stops)
+		table = &Table{Header: stopColumns, Rows: virtualStopRows(stops)}
+
+	case *virtual.Line:
+		table = &Table{Header: lineColumns, Rows: [][]string{{value.VehicleType, value.LineNumber}}}
+
+	case *virtual.Route:
+		table = &Table{Header: virtualRouteColumns, Rows: virtualRouteRows(virtual.RouteList{value})}
+
+	case virtual.RouteList:
+		table = &Table{Header: virtualRouteColumns, Rows: virtualRouteRows(value)}
+
+	case *virtual.LineNumberRouteList:
+		table = &Table{Header: join([]string{columnLineNumber}, virtualRouteColumns), Rows: virtualLineNumberRouteRows(virtual.LineNumberRouteListList{value})}
+
+	case virtual.LineNumberRouteListList:
+		table = &Table{Header: join([]string{columnLineNumber}, virtualRouteColumns), Rows: virtualLineNumberRouteRows(value)}
+
+	case *virtual.VehicleTypeLineNumberRouteListList:
+		table = &Table{Header: join(lineColumns, virtualRouteColumns), Rows: virtualVehicleTypeRouteRows(virtual.VehicleTypeLineNumberRouteListListList{value})}
+
+	case virtual.VehicleTypeLineNumberRouteListListList:
+		table = &Table{Header: join(lineColumns, virtualRouteColumns), Rows: virtualVehicleTypeRouteRows(value)}
+
+	case *virtual.NamedRoute:
+		table = &Table{Header: virtualNamedRouteColumns, Rows: virtualNamedRouteRows(virtual.NamedRouteList{value})}
+
+	case virtual.NamedRouteList:
+		table = &Table{Header: virtualNamedRouteColumns, Rows: virtualNamedRouteRows(value)}
+
+	case *virtual.LineNamedRouteList:
+		table = &Table{Header: join(lineColumns, virtualNamedRouteColumns), Rows: virtualLineNamedRouteRows(virtual.LineNamedRouteListList{value})}
+
+	case virtual.LineNamedRouteListList:
+		table = &Table{Header: join(lineColumns, virtualNamedRouteColumns), Rows: virtualLineNamedRouteRows(value)}
+
+	case virtual.LineNamedRouteListMap:
+		lines := []virtual.Line{}
+		for line := range value {
+			lines = append(lines, line)
+		}
+		sort.Slice(lines, func(i, j int) bool {
+			if lines[i].VehicleType != lines[j].VehicleType {
+				return lines[i].VehicleType < lines[j].VehicleType
+			}
+			return lines[i].LineNumber < lines[j].LineNumber
+		})
+		lineNamedRoutes := virtual.LineNamedRouteListList{}
+		for i := range lines {
+			lineNamedRoutes = append(lineNamedRoutes, &virtual.LineNamedRouteList{Line: &lines[i], NamedRouteList: value[lines[i]]})
+		}
+		table = &Table{Header: join(lineColumns, virtualNamedRouteColumns), Rows: virtualLineNamedRouteRows(lineNamedRoutes)}
+
+	case *virtual.VehicleArrival:
+		table = &Table{Header: vehicleArrivalColumns, Rows: vehicleArrivalRows(virtual.VehicleArrivalList{value})}
+
+	case virtual.VehicleArrivalList:
+		table = &Table{Header: vehicleArrivalColumns, Rows: vehicleArrivalRows(value)}
+
+	case *virtual.LineVehicleArrivalList:
+		table = &Table{Header: join(lineColumns, vehicleArrivalColumns), Rows: lineVehicleArrivalRows(virtual.LineVehicleArrivalListList{value})}
+
+	case virtual.LineVehicleArrivalListList:
+		table = &Table{Header: join(lineColumns, vehicleArrivalColumns), Rows: lineVehicleArrivalRows(value)}
+
+	case *virtual.StopTimetable:
+		table = &Table{Header: virtualStopTimetableColumns, Rows: virtualStopTimetableRows(virtual.StopTimetableList{value})}
+
+	case virtual.StopTimetableList:
+		table = &Table{Header: virtualStopTimetableColumns, Rows: virtualStopTimetableRows(value)}
+
+	case virtual.StopTimetableChannel:
+		table = &Table{Header: virtualStopTimetableColumns, Rows: virtualStopTimetableRows(value.Collect())}
+
+	case *schedule.Lines:
+		table = &Table{Header: lineColumns, Rows: scheduleLinesRows(value)}
+
+	case *schedule.OperationMode:
+		table = &Table{Header: operationModeColumns, Rows: [][]string{{value.Code, value.Name}}}
+
+	case *schedule.Stop:
+		table = &Table{Header: stopColumns, Rows: [][]string{{value.Code, value.Name}}}
+
+	case schedule.StopList:
+		table = &Table{Header: join([]string{columnStopIndex}, stopColumns), Rows: scheduleStopRows(value)}
+
+	case *schedule.Route:
+		table = &Table{Header: scheduleRouteColumns, Rows: scheduleRouteRows(schedule.RouteList{value})}
+
+	case schedule.RouteList:
+		table = &Table{Header: scheduleRouteColumns, Rows: scheduleRouteRows(value)}
+
+	case *schedule.OperationModeRoutes:
+		table = &Table{Header: scheduleOperationModeColumns, Rows: scheduleOperationModeRoutesRows(schedule.OperationModeRoutesList{value})}
+
+	case schedule.OperationModeRoutesList:
+		table = &Table{Header: scheduleOperationModeColumns, Rows: scheduleOperationModeRoutesRows(value)}
+
+	case *schedule.Line:
+		table = &Table{Header: join(lineColumns, scheduleOperationModeColumns), Rows: prefixRows([]string{value.VehicleType, value.LineNumber}, scheduleOperationModeRoutesRows(value.OperationModeRoutesList))}
+
+	case schedule.LineList:
+		table = &Table{Header: join(lineColumns, scheduleOperationModeColumns)}
+		for _, line := range value {
+			table.Rows = append(table.Rows, prefixRows([]string{line.VehicleType, line.LineNumber}, scheduleOperationModeRoutesRows(line.OperationModeRoutesList))...)
+		}
+
+	case schedule.Timetable:
+		table = &Table{Header: departureColumns, Rows: scheduleTimetableRows(value)}
+
+	case *schedule.DetailedTimetable:
+		table = &Table{Header: scheduleDetailedColumns, Rows: scheduleDetailedTimetableRows(schedule.DetailedTimetableList{value})}
+
+	case schedule.DetailedTimetableList:
+		table = &Table{Header: scheduleDetailedColumns, Rows: scheduleDetailedTimetableRows(value)}
+
+	case *schedule.UpcomingDeparture:
+		table = &Table{Header: scheduleUpcomingColumns, Rows: scheduleUpcomingDepartureRows(schedule.UpcomingDepartureList{value})}
+
+	case schedule.UpcomingDepartureList:
+		table = &Table{Header: scheduleUpcomingColumns, Rows: scheduleUpcomingDepartureRows(value)}
+
+	default:
+		err = fmt.Errorf("could not tabulate value of type %T", value)
+	}
+	return
+}
+
+func virtualStopRows(stops virtual.StopList) (rows [][]string) {
+	for _, stop := range stops {
+		rows = append(rows, []string{stop.Code, stop.Name})
+	}
+	return
+}
+
+func virtualRouteRows(routes virtual.RouteList) (rows [][]string) {
+	for i, route := range routes {
+		for j, stopCode := range route.StopCodes {
+			rows = append(rows, []string{strconv.Itoa(i + 1), strconv.Itoa(j + 1), stopCode})
+		}
+	}
+	return
+}
+
+func virtualLineNumberRouteRows(lines virtual.LineNumberRouteListList) (rows [][]string) {
+	for _, line := range lines {
+		rows = append(rows, prefixRows([]string{line.LineNumber}, virtualRouteRows(line.RouteList))...)
+	}
+	return
+}
+
+func virtualVehicleTypeRouteRows(vehicleTypes virtual.VehicleTypeLineNumberRouteListListList) (rows [][]string) {
+	for _, vehicleType := range vehicleTypes {
+		rows = append(rows, prefixRows([]string{vehicleType.VehicleType}, virtualLineNumberRouteRows(vehicleType.LineNumberRouteListList))...)
+	}
+	return
+}
+
+func virtualNamedRouteRows(routes virtual.NamedRouteList) (rows [][]string) {
+	for _, route := range routes {
+		for i, stop := range route.StopList {
+			rows = append(rows, []string{route.Name, strconv.Itoa(i + 1), stop.Code, stop.Name})
+		}
+	}
+	return
+}
+
+func virtualLineNamedRouteRows(lines virtual.LineNamedRouteListList) (rows [][]string) {
+	for _, line := range lines {
+		rows = append(rows, prefixRows([]string{line.VehicleType, line.LineNumber}, virtualNamedRouteRows(line.NamedRouteList))...)
+	}
+	return
+}
+
+func vehicleArrivalRows(arrivals virtual.VehicleArrivalList) (rows [][]string) {
+	for _, arrival := range arrivals {
+		rows = append(rows, []string{arrival.Time, strconv.FormatBool(arrival.HasAirConditioning), strconv.FormatBool(arrival.IsWheelchairAccessible)})
+	}
+	return
+}
+
+func lineVehicleArrivalRows(lines virtual.LineVehicleArrivalListList) (rows [][]string) {
+	for _, line := range lines {
+		rows = append(rows, prefixRows([]string{line.VehicleType, line.LineNumber}, vehicleArrivalRows(line.VehicleArrivalList))...)
+	}
+	return
+}
+
+func virtualStopTimetableRows(timetables virtual.StopTimetableList) (rows [][]string) {
+	for _, timetable := range timetables {
+		rows = append(rows, prefixRows([]string{timetable.StopCode, timetable.StopName, timetable.GenerationTime}, lineVehicleArrivalRows(timetable.LineVehicleArrivalListList))...)
+	}
+	return
+}
+
+func scheduleLinesRows(lines *schedule.Lines) (rows [][]string) {
+	for _, vehicleTypeLineNumbers := range lines.VehicleTypeLineNumbersList {
+		for _, lineNumber := range vehicleTypeLineNumbers.LineNumbers {
+			rows = append(rows, []string{vehicleTypeLineNumbers.VehicleType, lineNumber})
+		}
+	}
+	return
+}
+
+func scheduleStopRows(stops schedule.StopList) (rows [][]string) {
+	for i, stop := range stops {
+		rows = append(rows, []string{strconv.Itoa(i + 1), stop.Code, stop.Name})
+	}
+	return
+}
+
+func scheduleRouteRows(routes schedule.RouteList) (rows [][]string) {
+	for _, route := range routes {
+		rows = append(rows, prefixRows([]string{route.Code, route.Name}, scheduleStopRows(route.StopList))...)
+	}
+	return
+}
+
+func scheduleOperationModeRoutesRows(operationModes schedule.OperationModeRoutesList) (rows [][]string) {
+	for _, operationMode := range operationModes {
+		rows = append(rows, prefixRows([]string{operationMode.Code, operationMode.Name}, scheduleRouteRows(operationMode.RouteList))...)
+	}
+	return
+}
+
+func scheduleTimetableRows(timetable schedule.Timetable) (rows [][]string) {
+	for _, departure := range timetable {
+		rows = append(rows, []string{departure.FormatTime(), strconv.FormatBool(departure.IsAfterMidnight()), departure.Markers})
+	}
+	return
+}
+
+func scheduleDetailedTimetableRows(timetables schedule.DetailedTimetableList) (rows [][]string) {
+	for _, timetable := range timetables {
+		prefix := []string{timetable.Line.VehicleType, timetable.Line.LineNumber, timetable.OperationMode.Code, timetable.OperationMode.Name, timetable.Route.Code, timetable.Route.Name, timetable.Stop.Code, timetable.Stop.Name}
+		rows = append(rows, prefixRows(prefix, scheduleTimetableRows(timetable.Timetable))...)
+	}
+	return
+}
+
+func scheduleUpcomingDepartureRows(departures schedule.UpcomingDepartureList) (rows [][]string) {
+	for _, departure := range departures {
+		rows = append(rows, []string{departure.Line.VehicleType, departure.Line.LineNumber, departure.OperationMode.Code, departure.OperationMode.Name, departure.Route.Code, departure.Route.Name, departure.Stop.Code, departure.Stop.Name, departure.Time.Format(time.RFC3339), departure.Departure.Markers})
+	}
+	return
+}
@@ -0,0 +1,46 @@
+package format
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// TextFormatter writes the human-readable display representation of values (as produced by their Render or String methods).
+type TextFormatter struct {
+	VirtualRenderOptions  *virtual.RenderOptions  // options for rendering values from the `virtual` package (virtual.DefaultRenderOptions is used if nil)
+	ScheduleRenderOptions *schedule.RenderOptions // options for rendering values from the `schedule` package (schedule.DefaultRenderOptions is used if nil)
+}
+
+type virtualRenderer interface {
+	Render(options *virtual.RenderOptions) string
+}
+
+type scheduleRenderer interface {
+	Render(options *schedule.RenderOptions) string
+}
+
+// Format writes the display representation of value to w.
+func (f *TextFormatter) Format(w io.Writer, value interface{}) (err error) {
+	switch value := value.(type) {
+	case virtualRenderer:
+		options := f.VirtualRenderOptions
+		if options == nil {
+			options = virtual.DefaultRenderOptions
+		}
+		_, err = io.WriteString(w, value.Render(options))
+
+	case scheduleRenderer:
+		options := f.ScheduleRenderOptions
+		if options == nil {
+			options = schedule.DefaultRenderOptions
+		}
+		_, err = io.WriteString(w, value.Render(options))
+
+	default:
+		_, err = fmt.Fprint(w, value)
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLFormatter writes the YAML representation of values. The keys and the order of the fields are the same as in the JSON representation.
+type YAMLFormatter struct {
+	Indent int // number of spaces used for each level of indentation (two spaces are used if zero)
+}
+
+// Format writes the YAML representation of value to w.
+func (f *YAMLFormatter) Format(w io.Writer, value interface{}) (err error) {
+	data, err := json.Marshal(collect(value))
+	if err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	node, err := decodeYAMLNode(decoder)
+	if err != nil {
+		return
+	}
+
+	indent := f.Indent
+	if indent == 0 {
+		indent = 2
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(indent)
+	err = encoder.Encode(node)
+	if err != nil {
+		return
+	}
+
+	return encoder.Close()
+}
+
+// decodeYAMLNode converts the next JSON value read from decoder into a YAML node (keeping the order of the object keys, unlike decoding into a map would).
+func decodeYAMLNode(decoder *json.Decoder) (node *yaml.Node, err error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				key, ok := keyToken.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid JSON object key: %v", keyToken)
+				}
+
+				valueNode, err := decodeYAMLNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, valueNode)
+			}
+
+		case '[':
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for decoder.More() {
+				elementNode, err := decodeYAMLNode(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				node.Content = append(node.Content, elementNode)
+			}
+
+		default:
+			return nil, fmt.Errorf("unexpected JSON delimiter: %v", token)
+		}
+
+		// consume the closing delimiter
+		_, err = decoder.Token()
+
+	case string:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}
+
+	case json.Number:
+		tag := "!!float"
+		if _, err := strconv.ParseInt(token.String(), 10, 64); err == nil {
+			tag = "!!int"
+		}
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: token.String()}
+
+	case bool:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(token)}
+
+	case nil:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	return
+}
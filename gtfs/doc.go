@@ -0,0 +1,4 @@
+/*
+Package gtfs implements generation of GTFS static feeds (https://gtfs.org/schedule/reference/) from the schedules of the urban transit lines, which makes it possible to use standard tooling (e.g. trip planners and feed validators) on top of the `schedule` package.
+*/
+package gtfs
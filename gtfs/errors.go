@@ -0,0 +1,28 @@
+package gtfs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMissingStopLocations indicates that the locations of some stops of a generated feed are unknown (which makes the feed invalid, since the GTFS specification requires the location of every stop).
+var ErrMissingStopLocations = errors.New("missing stop locations")
+
+// MissingStopLocationsError represents a generated feed containing stops whose locations are unknown.
+type MissingStopLocationsError struct {
+	StopCodes []string // codes of the stops whose locations are unknown
+}
+
+func (e *MissingStopLocationsError) Error() string {
+	const maxListedStopCodes = 10
+	if len(e.StopCodes) > maxListedStopCodes {
+		return fmt.Sprintf("the locations of %d stops are unknown (including %s)", len(e.StopCodes), strings.Join(e.StopCodes[:maxListedStopCodes], ", "))
+	}
+	return fmt.Sprintf("the locations of %d stops are unknown (%s)", len(e.StopCodes), strings.Join(e.StopCodes, ", "))
+}
+
+// Is reports whether target is ErrMissingStopLocations.
+func (e *MissingStopLocationsError) Is(target error) bool {
+	return target == ErrMissingStopLocations
+}
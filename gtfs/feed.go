@@ -0,0 +1,284 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// RouteType represents the type of transportation used on a GTFS route.
+type RouteType int
+
+const (
+	// RouteTypeTram represents a tram.
+	RouteTypeTram RouteType = 0
+	// RouteTypeSubway represents a subway (metro).
+	RouteTypeSubway RouteType = 1
+	// RouteTypeBus represents a bus.
+	RouteTypeBus RouteType = 3
+	// RouteTypeTrolleybus represents a trolleybus.
+	RouteTypeTrolleybus RouteType = 11
+)
+
+// ExceptionType represents the way a service is changed on a specific date.
+type ExceptionType int
+
+const (
+	// ExceptionTypeAdded represents a service which is added for the date.
+	ExceptionTypeAdded ExceptionType = 1
+	// ExceptionTypeRemoved represents a service which is removed for the date.
+	ExceptionTypeRemoved ExceptionType = 2
+)
+
+// Agency represents a transit agency (a record in agency.txt).
+type Agency struct {
+	ID       string // identifier of the agency
+	Name     string // full name of the agency
+	URL      string // URL of the website of the agency
+	Timezone string // IANA name of the timezone where the agency is located
+	Language string // language code of the primary language used by the agency
+}
+
+// StopLocation represents the geographic location of a stop.
+type StopLocation struct {
+	Latitude  float64 // WGS 84 latitude of the stop
+	Longitude float64 // WGS 84 longitude of the stop
+}
+
+// Stop represents a transit stop (a record in stops.txt).
+type Stop struct {
+	ID            string // identifier of the stop
+	Code          string // code of the stop displayed to riders
+	Name          string // name of the stop
+	*StopLocation        // location of the stop (may be nil if unknown)
+}
+
+// Route represents a transit route (a record in routes.txt), which corresponds to an urban transit line.
+type Route struct {
+	ID        string    // identifier of the route
+	AgencyID  string    // identifier of the agency operating the route
+	ShortName string    // short name of the route (i.e. the number of the line)
+	Type      RouteType // type of transportation used on the route
+}
+
+// Trip represents a single journey of a vehicle along a route (a record in trips.txt).
+type Trip struct {
+	RouteID     string // identifier of the route the trip belongs to
+	ServiceID   string // identifier of the service which determines the days when the trip is made
+	ID          string // identifier of the trip
+	Headsign    string // destination of the trip displayed to riders
+	DirectionID *int   // direction of travel of the trip (either 0 or 1, or nil if it is unknown)
+}
+
+// StopTime represents the time when a vehicle arrives at and departs from a stop on a trip (a record in stop_times.txt).
+type StopTime struct {
+	TripID        string        // identifier of the trip
+	ArrivalTime   time.Duration // arrival time measured from noon minus 12 hours of the service day (may exceed 24 hours)
+	DepartureTime time.Duration // departure time measured from noon minus 12 hours of the service day (may exceed 24 hours)
+	StopID        string        // identifier of the stop
+	StopSequence  int           // order of the stop on the trip
+	IsUntimed     bool          // whether the arrival and departure times are unspecified (which is allowed for intermediate stops, whose times are interpolated by consumers)
+}
+
+// Calendar represents the days of the week when a service is available within a date range (a record in calendar.txt).
+type Calendar struct {
+	ServiceID string    // identifier of the service
+	Weekdays  [7]bool   // whether the service is available on each day of the week (indexed by time.Weekday)
+	StartDate time.Time // first day when the service is available
+	EndDate   time.Time // last day when the service is available
+}
+
+// CalendarDate represents an exception to the regular availability of a service on a specific date (a record in calendar_dates.txt).
+type CalendarDate struct {
+	ServiceID     string        // identifier of the service
+	Date          time.Time     // date of the exception
+	ExceptionType ExceptionType // whether the service is added or removed for the date
+}
+
+// Translation represents the translation of a field of a feed record to a specific language (a record in translations.txt).
+type Translation struct {
+	TableName   string // name of the file containing the translated field (without the .txt extension)
+	FieldName   string // name of the translated field
+	Language    string // language code of the translation
+	Translation string // translated value
+	RecordID    string // identifier of the record containing the translated field (empty if FieldValue is set)
+	FieldValue  string // value of the field which is translated wherever it occurs (empty if RecordID is set)
+}
+
+// Feed represents a GTFS static feed.
+type Feed struct {
+	Agencies      []*Agency
+	Stops         []*Stop
+	Routes        []*Route
+	Trips         []*Trip
+	StopTimes     []*StopTime
+	Calendars     []*Calendar
+	CalendarDates []*CalendarDate
+	Translations  []*Translation
+}
+
+const dateLayout = "20060102"
+
+func formatDate(date time.Time) string {
+	return date.Format(dateLayout)
+}
+
+func formatTime(duration time.Duration) string {
+	seconds := int(duration / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+func formatBool(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+func formatOptionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatLocation(location *StopLocation) (latitude string, longitude string) {
+	if location == nil {
+		return
+	}
+
+	latitude = strconv.FormatFloat(location.Latitude, 'f', 6, 64)
+	longitude = strconv.FormatFloat(location.Longitude, 'f', 6, 64)
+	return
+}
+
+// writeFile writes a CSV file with the specified name, header and records to the archive.
+func writeFile(archive *zip.Writer, name string, header []string, records [][]string) (err error) {
+	fileWriter, err := archive.Create(name)
+	if err != nil {
+		return
+	}
+
+	csvWriter := csv.NewWriter(fileWriter)
+	csvWriter.UseCRLF = true
+	err = csvWriter.Write(header)
+	if err != nil {
+		return
+	}
+
+	err = csvWriter.WriteAll(records)
+	return
+}
+
+// WriteZip writes the feed to w as a ZIP archive containing the agency.txt, stops.txt, routes.txt, trips.txt, stop_times.txt, calendar.txt and calendar_dates.txt files (as well as translations.txt if the feed contains translations).
+func (f *Feed) WriteZip(w io.Writer) (err error) {
+	archive := zip.NewWriter(w)
+
+	agencyRecords := make([][]string, 0, len(f.Agencies))
+	for _, agency := range f.Agencies {
+		agencyRecords = append(agencyRecords, []string{agency.ID, agency.Name, agency.URL, agency.Timezone, agency.Language})
+	}
+	err = writeFile(archive, "agency.txt", []string{"agency_id", "agency_name", "agency_url", "agency_timezone", "agency_lang"}, agencyRecords)
+	if err != nil {
+		return
+	}
+
+	stopRecords := make([][]string, 0, len(f.Stops))
+	for _, stop := range f.Stops {
+		latitude, longitude := formatLocation(stop.StopLocation)
+		stopRecords = append(stopRecords, []string{stop.ID, stop.Code, stop.Name, latitude, longitude})
+	}
+	err = writeFile(archive, "stops.txt", []string{"stop_id", "stop_code", "stop_name", "stop_lat", "stop_lon"}, stopRecords)
+	if err != nil {
+		return
+	}
+
+	routeRecords := make([][]string, 0, len(f.Routes))
+	for _, route := range f.Routes {
+		routeRecords = append(routeRecords, []string{route.ID, route.AgencyID, route.ShortName, strconv.Itoa(int(route.Type))})
+	}
+	err = writeFile(archive, "routes.txt", []string{"route_id", "agency_id", "route_short_name", "route_type"}, routeRecords)
+	if err != nil {
+		return
+	}
+
+	tripRecords := make([][]string, 0, len(f.Trips))
+	for _, trip := range f.Trips {
+		tripRecords = append(tripRecords, []string{trip.RouteID, trip.ServiceID, trip.ID, trip.Headsign, formatOptionalInt(trip.DirectionID)})
+	}
+	err = writeFile(archive, "trips.txt", []string{"route_id", "service_id", "trip_id", "trip_headsign", "direction_id"}, tripRecords)
+	if err != nil {
+		return
+	}
+
+	stopTimeRecords := make([][]string, 0, len(f.StopTimes))
+	for _, stopTime := range f.StopTimes {
+		arrivalTime, departureTime := "", ""
+		if !stopTime.IsUntimed {
+			arrivalTime, departureTime = formatTime(stopTime.ArrivalTime), formatTime(stopTime.DepartureTime)
+		}
+		stopTimeRecords = append(stopTimeRecords, []string{stopTime.TripID, arrivalTime, departureTime, stopTime.StopID, strconv.Itoa(stopTime.StopSequence)})
+	}
+	err = writeFile(archive, "stop_times.txt", []string{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence"}, stopTimeRecords)
+	if err != nil {
+		return
+	}
+
+	calendarRecords := make([][]string, 0, len(f.Calendars))
+	for _, calendar := range f.Calendars {
+		record := []string{calendar.ServiceID}
+		for _, weekday := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+			record = append(record, formatBool(calendar.Weekdays[weekday]))
+		}
+		record = append(record, formatDate(calendar.StartDate), formatDate(calendar.EndDate))
+		calendarRecords = append(calendarRecords, record)
+	}
+	err = writeFile(archive, "calendar.txt", []string{"service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"}, calendarRecords)
+	if err != nil {
+		return
+	}
+
+	calendarDateRecords := make([][]string, 0, len(f.CalendarDates))
+	for _, calendarDate := range f.CalendarDates {
+		calendarDateRecords = append(calendarDateRecords, []string{calendarDate.ServiceID, formatDate(calendarDate.Date), strconv.Itoa(int(calendarDate.ExceptionType))})
+	}
+	err = writeFile(archive, "calendar_dates.txt", []string{"service_id", "date", "exception_type"}, calendarDateRecords)
+	if err != nil {
+		return
+	}
+
+	if len(f.Translations) > 0 {
+		translationRecords := make([][]string, 0, len(f.Translations))
+		for _, translation := range f.Translations {
+			translationRecords = append(translationRecords, []string{translation.TableName, translation.FieldName, translation.Language, translation.Translation, translation.RecordID, translation.FieldValue})
+		}
+		err = writeFile(archive, "translations.txt", []string{"table_name", "field_name", "language", "translation", "record_id", "field_value"}, translationRecords)
+		if err != nil {
+			return
+		}
+	}
+
+	err = archive.Close()
+	return
+}
+
+// Create writes the feed as a ZIP archive to the file at the specified path (which is created or truncated).
+func (f *Feed) Create(path string) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+
+	err = f.WriteZip(file)
+	if err != nil {
+		file.Close()
+		return
+	}
+
+	err = file.Close()
+	return
+}
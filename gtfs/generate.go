@@ -0,0 +1,244 @@
+package gtfs
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+)
+
+// DefaultAgency is the agency which operates the urban transit lines in Sofia.
+var DefaultAgency = &Agency{
+	ID:       "CGM",
+	Name:     "Център за градска мобилност",
+	URL:      "https://www.sofiatraffic.bg",
+	Timezone: "Europe/Sofia",
+	Language: "bg",
+}
+
+// routeTypes maps each vehicle type to the type of the GTFS routes served by vehicles of that type.
+var routeTypes = map[string]RouteType{
+	schedule.VehicleTypeBus:        RouteTypeBus,
+	schedule.VehicleTypeTrolleybus: RouteTypeTrolleybus,
+	schedule.VehicleTypeTram:       RouteTypeTram,
+	schedule.VehicleTypeMetro:      RouteTypeSubway,
+}
+
+// GetRouteID returns the identifier of the GTFS route corresponding to the urban transit line with the specified vehicleType (as used by the schedule) and lineNumber.
+func GetRouteID(vehicleType string, lineNumber string) string {
+	return vehicleType + "-" + lineNumber
+}
+
+// GetDirectionID returns the GTFS direction of travel along the specified route, which is derived from the names of its terminal stops so that the routes between the same terminals get opposite directions: 0 if the name of the first stop sorts before the name of the last one and 1 otherwise. Nil is returned if the terminal stops have the same name (e.g. for circular routes) or the route has less than two stops, since the direction cannot be determined then.
+func GetDirectionID(route *schedule.Route) *int {
+	if len(route.StopList) < 2 {
+		return nil
+	}
+
+	firstStopName, lastStopName := route.StopList[0].Name, route.StopList[len(route.StopList)-1].Name
+	if firstStopName == lastStopName {
+		return nil
+	}
+
+	directionID := 0
+	if firstStopName > lastStopName {
+		directionID = 1
+	}
+	return &directionID
+}
+
+// Generator generates GTFS static feeds from the schedules of the urban transit lines.
+type Generator struct {
+	Source        datasource.DataSource                 // source of the schedule lines and timetables (the live schedule is used if nil)
+	Agency        *Agency                               // agency operating the lines (DefaultAgency is used if nil)
+	StartDate     time.Time                             // first day when the feed is valid (the current day is used if zero)
+	EndDate       time.Time                             // last day when the feed is valid (the day before the same day in the year after StartDate is used if zero)
+	StopLocations map[string]*StopLocation              // maps the code of each stop to its location (the generation fails if a stop is missing from the map unless DoAllowMissingStopLocations is set)
+	GetDayType    func(date time.Time) calendar.DayType // determines the type of each day when the feed is valid (calendar.GetDayType, which takes the official holidays into account, is used if nil)
+
+	DoAllowMissingStopLocations bool // whether a feed should be generated even if the locations of some stops are unknown (their coordinates are left empty, which makes the feed incomplete since the GTFS specification requires them)
+}
+
+// generation holds the state of the generation of a single feed.
+type generation struct {
+	*Generator
+	source      datasource.DataSource
+	feed        *Feed
+	stopMap     map[string]*Stop
+	serviceList []*service
+	serviceMap  map[string]*service
+}
+
+func getDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// GenerateContext generates a GTFS static feed containing all lines of the schedule. The trips are inferred from the timetables of the stops of each route. Lines and timetables which cannot be obtained are skipped (and the errors are logged). Since the schedule does not contain the locations of the stops, a MissingStopLocationsError is returned if any stop is missing from StopLocations (unless DoAllowMissingStopLocations is set, in which case the error is only logged). The generation is canceled when ctx is done.
+func (g *Generator) GenerateContext(ctx context.Context) (feed *Feed, err error) {
+	agency := g.Agency
+	if agency == nil {
+		agency = DefaultAgency
+	}
+
+	gen := &generation{
+		Generator:  g,
+		source:     g.Source,
+		feed:       &Feed{Agencies: []*Agency{agency}},
+		stopMap:    map[string]*Stop{},
+		serviceMap: map[string]*service{},
+	}
+	if gen.source == nil {
+		gen.source = datasource.NewLive(nil, nil)
+	}
+
+	lines, err := gen.source.GetLinesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	addLines := func(vehicleType string, lineNumbers []string) error {
+		for _, lineNumber := range lineNumbers {
+			err := gen.addLine(ctx, vehicleType, lineNumber)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, vehicleTypeLineNumbers := range lines.VehicleTypeLineNumbersList {
+		err = addLines(vehicleTypeLineNumbers.VehicleType, vehicleTypeLineNumbers.LineNumbers)
+		if err != nil {
+			return
+		}
+	}
+
+	var missingStopCodes []string
+	for _, stop := range gen.feed.Stops {
+		if stop.StopLocation == nil {
+			missingStopCodes = append(missingStopCodes, stop.Code)
+		}
+	}
+	if len(missingStopCodes) > 0 {
+		missingStopLocationsErr := &MissingStopLocationsError{StopCodes: missingStopCodes}
+		if !g.DoAllowMissingStopLocations {
+			err = missingStopLocationsErr
+			return
+		}
+
+		log.Printf("%s; the generated feed is incomplete", missingStopLocationsErr.Error())
+	}
+
+	startDate := getDate(g.StartDate)
+	if g.StartDate.IsZero() {
+		startDate = getDate(time.Now())
+	}
+	endDate := getDate(g.EndDate)
+	if g.EndDate.IsZero() {
+		endDate = startDate.AddDate(1, 0, -1)
+	}
+	getDayType := g.GetDayType
+	if getDayType == nil {
+		getDayType = calendar.GetDayType
+	}
+	for _, service := range gen.serviceList {
+		gen.feed.Calendars = append(gen.feed.Calendars, service.getCalendar(startDate, endDate))
+		gen.feed.CalendarDates = append(gen.feed.CalendarDates, service.getCalendarDates(startDate, endDate, getDayType)...)
+	}
+
+	feed = gen.feed
+	return
+}
+
+// Generate generates a GTFS static feed containing all lines of the schedule (see GenerateContext).
+func (g *Generator) Generate() (feed *Feed, err error) {
+	return g.GenerateContext(context.Background())
+}
+
+// addLine adds the route, the stops and the trips of the line with the specified vehicleType and lineNumber to the feed. Only errors caused by ctx being done are returned; other errors are logged.
+func (gen *generation) addLine(ctx context.Context, vehicleType string, lineNumber string) (err error) {
+	line, fetchErr := gen.source.GetLineContext(ctx, vehicleType, lineNumber)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if fetchErr != nil {
+		log.Println(fetchErr.Error())
+		return
+	}
+
+	route := &Route{ID: GetRouteID(vehicleType, lineNumber), AgencyID: gen.feed.Agencies[0].ID, ShortName: lineNumber, Type: routeTypes[vehicleType]}
+	gen.feed.Routes = append(gen.feed.Routes, route)
+	for _, operationModeRoutes := range line.OperationModeRoutesList {
+		dayTypes := calendar.GetLineOperationModeDayTypes(line, operationModeRoutes)
+		if len(dayTypes) == 0 {
+			log.Printf("could not determine the days of operation mode %s (%s) of line %s", operationModeRoutes.Name, operationModeRoutes.Code, route.ID)
+			continue
+		}
+
+		service := newService(dayTypes)
+		if _, ok := gen.serviceMap[service.id]; !ok {
+			gen.serviceMap[service.id] = service
+			gen.serviceList = append(gen.serviceList, service)
+		}
+
+		for _, scheduleRoute := range operationModeRoutes.RouteList {
+			err = gen.addTrips(ctx, route, service, operationModeRoutes.OperationMode, scheduleRoute)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// addTrips adds the stops of scheduleRoute and the trips inferred from their timetables to the feed.
+func (gen *generation) addTrips(ctx context.Context, route *Route, service *service, operationMode *schedule.OperationMode, scheduleRoute *schedule.Route) (err error) {
+	departureTimesList := make([][]time.Duration, 0, len(scheduleRoute.StopList))
+	for _, scheduleStop := range scheduleRoute.StopList {
+		if _, ok := gen.stopMap[scheduleStop.Code]; !ok {
+			stop := &Stop{ID: scheduleStop.Code, Code: scheduleStop.Code, Name: scheduleStop.Name, StopLocation: gen.StopLocations[scheduleStop.Code]}
+			gen.stopMap[stop.ID] = stop
+			gen.feed.Stops = append(gen.feed.Stops, stop)
+		}
+
+		timetable, fetchErr := gen.source.GetTimetableContext(ctx, operationMode.Code, scheduleRoute.Code, scheduleStop.Code)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if fetchErr != nil {
+			log.Println(fetchErr.Error())
+			departureTimesList = append(departureTimesList, nil)
+			continue
+		}
+
+		departureTimesList = append(departureTimesList, GetDepartureTimes(timetable))
+	}
+
+	tripIDPrefix := route.ID + "-" + operationMode.Code + "-" + scheduleRoute.Code + "-"
+	directionID := GetDirectionID(scheduleRoute)
+	tripCount := 0
+	for _, tripStops := range InferTrips(departureTimesList) {
+		if len(tripStops) < 2 {
+			continue
+		}
+
+		tripCount++
+		lastStop := scheduleRoute.StopList[tripStops[len(tripStops)-1].StopIndex]
+		trip := &Trip{RouteID: route.ID, ServiceID: service.id, ID: tripIDPrefix + strconv.Itoa(tripCount), Headsign: lastStop.Name, DirectionID: directionID}
+		gen.feed.Trips = append(gen.feed.Trips, trip)
+		for _, tripStop := range tripStops {
+			gen.feed.StopTimes = append(gen.feed.StopTimes, &StopTime{
+				TripID:        trip.ID,
+				ArrivalTime:   tripStop.Time,
+				DepartureTime: tripStop.Time,
+				StopID:        scheduleRoute.StopList[tripStop.StopIndex].Code,
+				StopSequence:  tripStop.StopIndex + 1,
+			})
+		}
+	}
+	return
+}
@@ -0,0 +1,219 @@
+package gtfs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// testSource is a DataSource serving the schedule lines and timetables stored in its fields.
+type testSource struct {
+	lines      []*schedule.Line
+	timetables map[string]schedule.Timetable // maps the operation mode, route and stop codes (joined by slashes) to the timetable
+}
+
+func (s *testSource) GetStopsInLanguageContext(ctx context.Context, language string) (virtual.StopList, error) {
+	return nil, nil
+}
+
+func (s *testSource) GetRoutesContext(ctx context.Context) (virtual.VehicleTypeLineNumberRouteListListList, error) {
+	return nil, nil
+}
+
+func (s *testSource) GetLinesContext(ctx context.Context) (lines *schedule.Lines, err error) {
+	lines = &schedule.Lines{}
+	for _, line := range s.lines {
+		lines.VehicleTypeLineNumbersList = append(lines.VehicleTypeLineNumbersList, &schedule.VehicleTypeLineNumbers{VehicleType: line.VehicleType, LineNumbers: []string{line.LineNumber}})
+	}
+	return
+}
+
+func (s *testSource) GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (*schedule.Line, error) {
+	for _, line := range s.lines {
+		if line.VehicleType == vehicleType && line.LineNumber == lineNumber {
+			return line, nil
+		}
+	}
+	return nil, &schedule.UnknownLineError{VehicleType: vehicleType, LineNumber: lineNumber}
+}
+
+func (s *testSource) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (schedule.Timetable, error) {
+	timetable, ok := s.timetables[operationModeCode+"/"+routeCode+"/"+stopCode]
+	if !ok {
+		return nil, &schedule.UnknownStopError{OperationModeCode: operationModeCode, RouteCode: routeCode, StopCode: stopCode}
+	}
+	return timetable, nil
+}
+
+func newTestTimetable(departureTimes ...time.Duration) (timetable schedule.Timetable) {
+	for _, departureTime := range departureTimes {
+		timetable = append(timetable, &schedule.Departure{Time: departureTime})
+	}
+	return
+}
+
+// newTestSource returns a testSource serving bus line 94, which has a weekday route from ЖК МЛАДОСТ 3 to ЦЕНТРАЛНА ГАРА with two trips and a weekday route in the opposite direction with a single trip.
+func newTestSource() *testSource {
+	return &testSource{
+		lines: []*schedule.Line{{
+			VehicleType: schedule.VehicleTypeBus,
+			LineNumber:  "94",
+			OperationModeRoutesList: schedule.OperationModeRoutesList{{
+				OperationMode: &schedule.OperationMode{Code: "1", Name: "делник"},
+				RouteList: schedule.RouteList{
+					{Code: "10", StopList: schedule.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ЦЕНТРАЛНА ГАРА"}}},
+					{Code: "11", StopList: schedule.StopList{{Code: "0003", Name: "ЦЕНТРАЛНА ГАРА"}, {Code: "0004", Name: "ЖК МЛАДОСТ 3"}}},
+				},
+			}},
+		}},
+		timetables: map[string]schedule.Timetable{
+			"1/10/0001": newTestTimetable(6*time.Hour, 5*time.Hour),
+			"1/10/0002": newTestTimetable(5*time.Hour+20*time.Minute, 6*time.Hour+20*time.Minute),
+			"1/11/0003": newTestTimetable(7 * time.Hour),
+			"1/11/0004": newTestTimetable(7*time.Hour + 30*time.Minute),
+		},
+	}
+}
+
+var testStopLocations = map[string]*StopLocation{
+	"0001": {Latitude: 42.65, Longitude: 23.38},
+	"0002": {Latitude: 42.71, Longitude: 23.32},
+	"0003": {Latitude: 42.71, Longitude: 23.32},
+	"0004": {Latitude: 42.65, Longitude: 23.38},
+}
+
+func newTestGenerator(stopLocations map[string]*StopLocation) *Generator {
+	return &Generator{
+		Source:        newTestSource(),
+		StartDate:     time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC),
+		EndDate:       time.Date(2024, time.May, 12, 0, 0, 0, 0, time.UTC),
+		StopLocations: stopLocations,
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	feed, err := newTestGenerator(testStopLocations).Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(feed.Routes) != 1 || feed.Routes[0].ID != "autobus-94" || feed.Routes[0].Type != RouteTypeBus {
+		t.Errorf("expected a single bus route autobus-94, got %v", feed.Routes)
+	}
+	if len(feed.Stops) != 4 || feed.Stops[0].StopLocation != testStopLocations["0001"] {
+		t.Errorf("expected 4 stops with locations, got %v", feed.Stops)
+	}
+
+	serviceID := calendar.DayTypeWeekday.String()
+	zero, one := 0, 1
+	expectedTrips := []*Trip{
+		{RouteID: "autobus-94", ServiceID: serviceID, ID: "autobus-94-1-10-1", Headsign: "ЦЕНТРАЛНА ГАРА", DirectionID: &zero},
+		{RouteID: "autobus-94", ServiceID: serviceID, ID: "autobus-94-1-10-2", Headsign: "ЦЕНТРАЛНА ГАРА", DirectionID: &zero},
+		{RouteID: "autobus-94", ServiceID: serviceID, ID: "autobus-94-1-11-1", Headsign: "ЖК МЛАДОСТ 3", DirectionID: &one},
+	}
+	if !reflect.DeepEqual(feed.Trips, expectedTrips) {
+		t.Errorf("expected trips %v, got %v", expectedTrips, feed.Trips)
+	}
+
+	expectedStopTimes := []*StopTime{
+		{TripID: "autobus-94-1-10-1", ArrivalTime: 5 * time.Hour, DepartureTime: 5 * time.Hour, StopID: "0001", StopSequence: 1},
+		{TripID: "autobus-94-1-10-1", ArrivalTime: 5*time.Hour + 20*time.Minute, DepartureTime: 5*time.Hour + 20*time.Minute, StopID: "0002", StopSequence: 2},
+		{TripID: "autobus-94-1-10-2", ArrivalTime: 6 * time.Hour, DepartureTime: 6 * time.Hour, StopID: "0001", StopSequence: 1},
+		{TripID: "autobus-94-1-10-2", ArrivalTime: 6*time.Hour + 20*time.Minute, DepartureTime: 6*time.Hour + 20*time.Minute, StopID: "0002", StopSequence: 2},
+		{TripID: "autobus-94-1-11-1", ArrivalTime: 7 * time.Hour, DepartureTime: 7 * time.Hour, StopID: "0003", StopSequence: 1},
+		{TripID: "autobus-94-1-11-1", ArrivalTime: 7*time.Hour + 30*time.Minute, DepartureTime: 7*time.Hour + 30*time.Minute, StopID: "0004", StopSequence: 2},
+	}
+	if !reflect.DeepEqual(feed.StopTimes, expectedStopTimes) {
+		t.Errorf("expected stop times %v, got %v", expectedStopTimes, feed.StopTimes)
+	}
+
+	expectedCalendars := []*Calendar{{
+		ServiceID: serviceID,
+		Weekdays:  [7]bool{false, true, true, true, true, true, false},
+		StartDate: time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, time.May, 12, 0, 0, 0, 0, time.UTC),
+	}}
+	if !reflect.DeepEqual(feed.Calendars, expectedCalendars) {
+		t.Errorf("expected calendars %v, got %v", expectedCalendars, feed.Calendars)
+	}
+
+	expectedCalendarDates := []*CalendarDate{{ServiceID: serviceID, Date: time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC), ExceptionType: ExceptionTypeRemoved}}
+	if !reflect.DeepEqual(feed.CalendarDates, expectedCalendarDates) {
+		t.Errorf("expected the weekday service to be removed on St. George's Day, got %v", feed.CalendarDates)
+	}
+}
+
+func TestGenerateHolidayOperationModeOnPreHolidays(t *testing.T) {
+	generator := newTestGenerator(testStopLocations)
+	source := generator.Source.(*testSource)
+	line := source.lines[0]
+	line.OperationModeRoutesList = append(line.OperationModeRoutesList, &schedule.OperationModeRoutes{
+		OperationMode: &schedule.OperationMode{Code: "2", Name: "празник"},
+		RouteList:     schedule.RouteList{line.OperationModeRoutesList[0].RouteList[0]},
+	})
+	source.timetables["2/10/0001"] = newTestTimetable(8 * time.Hour)
+	source.timetables["2/10/0002"] = newTestTimetable(8*time.Hour + 20*time.Minute)
+	feed, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	serviceID := calendar.DayTypePreHoliday.String() + "+" + calendar.DayTypeHoliday.String()
+	if len(feed.Calendars) != 2 || feed.Calendars[1].ServiceID != serviceID || feed.Calendars[1].Weekdays != [7]bool{true, false, false, false, false, false, true} {
+		t.Errorf("expected the holiday operation mode to be in effect on Saturdays and Sundays, got %v", feed.Calendars)
+	}
+	if len(feed.Trips) != 4 || feed.Trips[3].ServiceID != serviceID {
+		t.Errorf("expected the holiday trip to belong to service %s, got %v", serviceID, feed.Trips)
+	}
+}
+
+func TestGenerateMissingStopLocations(t *testing.T) {
+	stopLocations := map[string]*StopLocation{"0001": testStopLocations["0001"], "0002": testStopLocations["0002"], "0003": testStopLocations["0003"]}
+	_, err := newTestGenerator(stopLocations).Generate()
+	var missingStopLocationsError *MissingStopLocationsError
+	if !errors.As(err, &missingStopLocationsError) || !reflect.DeepEqual(missingStopLocationsError.StopCodes, []string{"0004"}) {
+		t.Errorf("expected a MissingStopLocationsError for stop 0004, got %v", err)
+	}
+	if !errors.Is(err, ErrMissingStopLocations) {
+		t.Errorf("expected the error to match ErrMissingStopLocations, got %v", err)
+	}
+
+	generator := newTestGenerator(stopLocations)
+	generator.DoAllowMissingStopLocations = true
+	feed, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(feed.Stops) != 4 || feed.Stops[3].StopLocation != nil {
+		t.Errorf("expected the location of stop 0004 to be left empty, got %v", feed.Stops)
+	}
+}
+
+func TestGetDirectionID(t *testing.T) {
+	zero, one := 0, 1
+	testCases := []struct {
+		name                string
+		stops               schedule.StopList
+		expectedDirectionID *int
+	}{
+		{name: "outbound", stops: schedule.StopList{{Name: "ЖК МЛАДОСТ 3"}, {Name: "ОБЕЛЯ"}, {Name: "ЦЕНТРАЛНА ГАРА"}}, expectedDirectionID: &zero},
+		{name: "inbound", stops: schedule.StopList{{Name: "ЦЕНТРАЛНА ГАРА"}, {Name: "ОБЕЛЯ"}, {Name: "ЖК МЛАДОСТ 3"}}, expectedDirectionID: &one},
+		{name: "circular", stops: schedule.StopList{{Name: "ЦЕНТРАЛНА ГАРА"}, {Name: "ОБЕЛЯ"}, {Name: "ЦЕНТРАЛНА ГАРА"}}},
+		{name: "single stop", stops: schedule.StopList{{Name: "ЦЕНТРАЛНА ГАРА"}}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			directionID := GetDirectionID(&schedule.Route{StopList: testCase.stops})
+			if !reflect.DeepEqual(directionID, testCase.expectedDirectionID) {
+				t.Errorf("expected direction %v, got %v", testCase.expectedDirectionID, directionID)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// table represents the contents of a CSV file of a feed.
+type table struct {
+	name    string
+	columns map[string]int
+	records [][]string
+}
+
+// get returns the value of the specified column in record (or an empty string if the column is missing).
+func (t *table) get(record []string, column string) string {
+	index, ok := t.columns[column]
+	if !ok || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+// readTable reads the CSV file with the specified name from fsys. A nil table is returned if the file does not exist and isRequired is false.
+func readTable(fsys fs.FS, name string, isRequired bool) (t *table, err error) {
+	file, err := fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) && !isRequired {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	csvReader := csv.NewReader(file)
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return &table{name: name, columns: map[string]int{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the header of %s: %w", name, err)
+	}
+
+	t = &table{name: name, columns: map[string]int{}}
+	for index, column := range header {
+		if index == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		t.columns[strings.TrimSpace(column)] = index
+	}
+
+	t.records, err = csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", name, err)
+	}
+
+	return
+}
+
+// parseTime parses a time in the HH:MM:SS format (where HH may exceed 23) and returns it as a time measured from the start of the service day.
+func parseTime(value string) (duration time.Duration, err error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		err = fmt.Errorf("could not parse time %q: expected the HH:MM:SS format", value)
+		return
+	}
+
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		var number int
+		number, err = strconv.Atoi(parts[i])
+		if err != nil {
+			err = fmt.Errorf("could not parse time %q: %w", value, err)
+			return
+		}
+
+		duration += time.Duration(number) * unit
+	}
+	return
+}
+
+func parseDate(value string) (date time.Time, err error) {
+	date, err = time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		err = fmt.Errorf("could not parse date %q: %w", value, err)
+	}
+	return
+}
+
+// parseInt parses an optional integer field, which defaults to 0 if it is empty.
+func parseInt(value string) (number int, err error) {
+	if value == "" {
+		return
+	}
+	return strconv.Atoi(value)
+}
+
+// parseOptionalInt parses an optional integer field, which is nil if it is empty.
+func parseOptionalInt(value string) (number *int, err error) {
+	if value == "" {
+		return
+	}
+
+	parsedNumber, err := strconv.Atoi(value)
+	if err != nil {
+		return
+	}
+
+	number = &parsedNumber
+	return
+}
+
+// ReadFeed reads a GTFS static feed from the CSV files contained in fsys. The stops.txt, routes.txt, trips.txt and stop_times.txt files are required; the rest are optional. Stations and other locations which are not stops are skipped. Stop times without arrival and departure times are kept (with IsUntimed set), since they still determine the sequence of stops of their trips.
+func ReadFeed(fsys fs.FS) (feed *Feed, err error) {
+	feed = &Feed{}
+	agencyTable, err := readTable(fsys, "agency.txt", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if agencyTable != nil {
+		for _, record := range agencyTable.records {
+			feed.Agencies = append(feed.Agencies, &Agency{
+				ID:       agencyTable.get(record, "agency_id"),
+				Name:     agencyTable.get(record, "agency_name"),
+				URL:      agencyTable.get(record, "agency_url"),
+				Timezone: agencyTable.get(record, "agency_timezone"),
+				Language: agencyTable.get(record, "agency_lang"),
+			})
+		}
+	}
+
+	stopTable, err := readTable(fsys, "stops.txt", true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range stopTable.records {
+		locationType := stopTable.get(record, "location_type")
+		if locationType != "" && locationType != "0" {
+			continue
+		}
+
+		stop := &Stop{ID: stopTable.get(record, "stop_id"), Code: stopTable.get(record, "stop_code"), Name: stopTable.get(record, "stop_name")}
+		latitude, longitude := stopTable.get(record, "stop_lat"), stopTable.get(record, "stop_lon")
+		if latitude != "" && longitude != "" {
+			stop.StopLocation = &StopLocation{}
+			stop.Latitude, err = strconv.ParseFloat(latitude, 64)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the latitude of stop %s: %w", stop.ID, err)
+			}
+
+			stop.Longitude, err = strconv.ParseFloat(longitude, 64)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the longitude of stop %s: %w", stop.ID, err)
+			}
+		}
+		feed.Stops = append(feed.Stops, stop)
+	}
+
+	routeTable, err := readTable(fsys, "routes.txt", true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range routeTable.records {
+		route := &Route{ID: routeTable.get(record, "route_id"), AgencyID: routeTable.get(record, "agency_id"), ShortName: routeTable.get(record, "route_short_name")}
+		routeType, err := parseInt(routeTable.get(record, "route_type"))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the type of route %s: %w", route.ID, err)
+		}
+
+		route.Type = RouteType(routeType)
+		feed.Routes = append(feed.Routes, route)
+	}
+
+	tripTable, err := readTable(fsys, "trips.txt", true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range tripTable.records {
+		trip := &Trip{RouteID: tripTable.get(record, "route_id"), ServiceID: tripTable.get(record, "service_id"), ID: tripTable.get(record, "trip_id"), Headsign: tripTable.get(record, "trip_headsign")}
+		trip.DirectionID, err = parseOptionalInt(tripTable.get(record, "direction_id"))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the direction of trip %s: %w", trip.ID, err)
+		}
+
+		feed.Trips = append(feed.Trips, trip)
+	}
+
+	stopTimeTable, err := readTable(fsys, "stop_times.txt", true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range stopTimeTable.records {
+		stopTime := &StopTime{TripID: stopTimeTable.get(record, "trip_id"), StopID: stopTimeTable.get(record, "stop_id")}
+		arrivalTime, departureTime := stopTimeTable.get(record, "arrival_time"), stopTimeTable.get(record, "departure_time")
+		if arrivalTime == "" {
+			arrivalTime = departureTime
+		}
+		if departureTime == "" {
+			departureTime = arrivalTime
+		}
+
+		if arrivalTime == "" {
+			stopTime.IsUntimed = true
+		} else {
+			stopTime.ArrivalTime, err = parseTime(arrivalTime)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the arrival time of trip %s: %w", stopTime.TripID, err)
+			}
+
+			stopTime.DepartureTime, err = parseTime(departureTime)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the departure time of trip %s: %w", stopTime.TripID, err)
+			}
+		}
+
+		stopTime.StopSequence, err = parseInt(stopTimeTable.get(record, "stop_sequence"))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the stop sequence of trip %s: %w", stopTime.TripID, err)
+		}
+
+		feed.StopTimes = append(feed.StopTimes, stopTime)
+	}
+
+	calendarTable, err := readTable(fsys, "calendar.txt", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if calendarTable != nil {
+		weekdayColumns := map[time.Weekday]string{time.Monday: "monday", time.Tuesday: "tuesday", time.Wednesday: "wednesday", time.Thursday: "thursday", time.Friday: "friday", time.Saturday: "saturday", time.Sunday: "sunday"}
+		for _, record := range calendarTable.records {
+			calendar := &Calendar{ServiceID: calendarTable.get(record, "service_id")}
+			for weekday, column := range weekdayColumns {
+				calendar.Weekdays[weekday] = calendarTable.get(record, column) == "1"
+			}
+
+			calendar.StartDate, err = parseDate(calendarTable.get(record, "start_date"))
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the start date of service %s: %w", calendar.ServiceID, err)
+			}
+
+			calendar.EndDate, err = parseDate(calendarTable.get(record, "end_date"))
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the end date of service %s: %w", calendar.ServiceID, err)
+			}
+
+			feed.Calendars = append(feed.Calendars, calendar)
+		}
+	}
+
+	calendarDateTable, err := readTable(fsys, "calendar_dates.txt", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if calendarDateTable != nil {
+		for _, record := range calendarDateTable.records {
+			calendarDate := &CalendarDate{ServiceID: calendarDateTable.get(record, "service_id")}
+			calendarDate.Date, err = parseDate(calendarDateTable.get(record, "date"))
+			if err != nil {
+				return nil, fmt.Errorf("could not parse an exception date of service %s: %w", calendarDate.ServiceID, err)
+			}
+
+			exceptionType, err := parseInt(calendarDateTable.get(record, "exception_type"))
+			if err != nil {
+				return nil, fmt.Errorf("could not parse an exception type of service %s: %w", calendarDate.ServiceID, err)
+			}
+
+			calendarDate.ExceptionType = ExceptionType(exceptionType)
+			feed.CalendarDates = append(feed.CalendarDates, calendarDate)
+		}
+	}
+
+	translationTable, err := readTable(fsys, "translations.txt", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if translationTable != nil {
+		for _, record := range translationTable.records {
+			feed.Translations = append(feed.Translations, &Translation{
+				TableName:   translationTable.get(record, "table_name"),
+				FieldName:   translationTable.get(record, "field_name"),
+				Language:    translationTable.get(record, "language"),
+				Translation: translationTable.get(record, "translation"),
+				RecordID:    translationTable.get(record, "record_id"),
+				FieldValue:  translationTable.get(record, "field_value"),
+			})
+		}
+	}
+
+	return
+}
+
+// OpenFeed reads the GTFS static feed stored at the specified path: from a ZIP archive if the path has a .zip extension and from a directory otherwise (see ReadFeed).
+func OpenFeed(feedPath string) (feed *Feed, err error) {
+	if !strings.EqualFold(filepath.Ext(feedPath), ".zip") {
+		return ReadFeed(os.DirFS(feedPath))
+	}
+
+	zipReader, err := zip.OpenReader(feedPath)
+	if err != nil {
+		return
+	}
+	defer zipReader.Close()
+
+	return ReadFeed(zipReader)
+}
@@ -0,0 +1,129 @@
+package gtfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testFeedFiles contains a feed of bus line 94, whose trips skip the times of the intermediate stop and whose services are only defined in calendar_dates.txt.
+var testFeedFiles = fstest.MapFS{
+	"stops.txt": {Data: []byte("stop_id,stop_code,stop_name,stop_lat,stop_lon,location_type\n" +
+		"S1,0001,ЖК МЛАДОСТ 3,42.65,23.38,\n" +
+		"S2,0002,ОБЕЛЯ,,,0\n" +
+		"S3,0003,ЦЕНТРАЛНА ГАРА,42.71,23.32,\n" +
+		"ST,,ЦЕНТРАЛНА ГАРА,42.71,23.32,1\n")},
+	"routes.txt": {Data: []byte("route_id,route_short_name,route_type\nR94,94,3\n")},
+	"trips.txt": {Data: []byte("route_id,service_id,trip_id,direction_id\n" +
+		"R94,WD,T1,0\n" +
+		"R94,HD,T2,\n")},
+	"stop_times.txt": {Data: []byte("trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+		"T1,05:00:00,05:00:00,S1,1\n" +
+		"T1,,,S2,2\n" +
+		"T1,05:10:00,,S3,3\n" +
+		"T2,24:05:00,24:05:00,S1,1\n" +
+		"T2,,,S2,2\n" +
+		"T2,24:15:00,24:15:00,S3,3\n")},
+	"calendar_dates.txt": {Data: []byte("service_id,date,exception_type\n" +
+		"WD,20240502,1\n" +
+		"HD,20240505,1\n" +
+		"HD,20240506,1\n")},
+}
+
+func TestReadFeed(t *testing.T) {
+	feed, err := ReadFeed(testFeedFiles)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(feed.Stops) != 3 {
+		t.Fatalf("expected the station to be skipped, got %d stops", len(feed.Stops))
+	}
+	if feed.Stops[1].StopLocation != nil || feed.Stops[2].StopLocation == nil || feed.Stops[2].Latitude != 42.71 {
+		t.Errorf("expected only stops with coordinates to have locations, got %v, %v", feed.Stops[1].StopLocation, feed.Stops[2].StopLocation)
+	}
+
+	zero := 0
+	if !reflect.DeepEqual(feed.Trips[0].DirectionID, &zero) || feed.Trips[1].DirectionID != nil {
+		t.Errorf("expected the direction of the second trip to be unknown, got %v and %v", feed.Trips[0].DirectionID, feed.Trips[1].DirectionID)
+	}
+
+	expectedStopTimes := []*StopTime{
+		{TripID: "T1", ArrivalTime: 5 * time.Hour, DepartureTime: 5 * time.Hour, StopID: "S1", StopSequence: 1},
+		{TripID: "T1", StopID: "S2", StopSequence: 2, IsUntimed: true},
+		{TripID: "T1", ArrivalTime: 5*time.Hour + 10*time.Minute, DepartureTime: 5*time.Hour + 10*time.Minute, StopID: "S3", StopSequence: 3},
+		{TripID: "T2", ArrivalTime: 24*time.Hour + 5*time.Minute, DepartureTime: 24*time.Hour + 5*time.Minute, StopID: "S1", StopSequence: 1},
+		{TripID: "T2", StopID: "S2", StopSequence: 2, IsUntimed: true},
+		{TripID: "T2", ArrivalTime: 24*time.Hour + 15*time.Minute, DepartureTime: 24*time.Hour + 15*time.Minute, StopID: "S3", StopSequence: 3},
+	}
+	if !reflect.DeepEqual(feed.StopTimes, expectedStopTimes) {
+		t.Errorf("expected stop times %v, got %v", expectedStopTimes, feed.StopTimes)
+	}
+
+	if len(feed.CalendarDates) != 3 || feed.CalendarDates[0].ExceptionType != ExceptionTypeAdded || !feed.CalendarDates[0].Date.Equal(time.Date(2024, time.May, 2, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("unexpected calendar dates: %v", feed.CalendarDates)
+	}
+}
+
+func TestReadFeedErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileName string
+		data     string
+	}{
+		{name: "missing required file", fileName: "routes.txt"},
+		{name: "invalid time", fileName: "stop_times.txt", data: "trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,5 AM,,S1,1\n"},
+		{name: "invalid direction", fileName: "trips.txt", data: "route_id,service_id,trip_id,direction_id\nR94,WD,T1,north\n"},
+		{name: "invalid date", fileName: "calendar_dates.txt", data: "service_id,date,exception_type\nWD,2024-05-02,1\n"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			files := fstest.MapFS{}
+			for name, file := range testFeedFiles {
+				files[name] = file
+			}
+			if testCase.data == "" {
+				delete(files, testCase.fileName)
+			} else {
+				files[testCase.fileName] = &fstest.MapFile{Data: []byte(testCase.data)}
+			}
+
+			_, err := ReadFeed(files)
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestFeedRoundTrip(t *testing.T) {
+	feed, err := ReadFeed(testFeedFiles)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	feed.Agencies = []*Agency{DefaultAgency}
+	feed.Translations = []*Translation{{TableName: "stops", FieldName: "stop_name", Language: "en", Translation: "Central Railway Station", RecordID: "S3"}}
+	var buffer bytes.Buffer
+	err = feed.WriteZip(&buffer)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("could not read archive: %s", err.Error())
+	}
+
+	readFeed, err := ReadFeed(zipReader)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(readFeed, feed) {
+		t.Errorf("expected the feed to be read back unchanged, got %v instead of %v", readFeed, feed)
+	}
+}
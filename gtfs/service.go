@@ -0,0 +1,62 @@
+package gtfs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+)
+
+// service represents a set of days on which some trips are made.
+type service struct {
+	id       string
+	dayTypes []calendar.DayType
+}
+
+func newService(dayTypes []calendar.DayType) *service {
+	names := make([]string, 0, len(dayTypes))
+	for _, dayType := range dayTypes {
+		names = append(names, dayType.String())
+	}
+	return &service{id: strings.Join(names, "+"), dayTypes: dayTypes}
+}
+
+func (s *service) isAvailableOn(dayType calendar.DayType) bool {
+	for _, serviceDayType := range s.dayTypes {
+		if serviceDayType == dayType {
+			return true
+		}
+	}
+	return false
+}
+
+// getCalendar returns the calendar record of the service, which is available on the days of the week whose default type is among its day types.
+func (s *service) getCalendar(startDate time.Time, endDate time.Time) (serviceCalendar *Calendar) {
+	serviceCalendar = &Calendar{ServiceID: s.id, StartDate: startDate, EndDate: endDate}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		serviceCalendar.Weekdays[weekday] = s.isAvailableOn(calendar.GetWeekdayDayType(weekday))
+	}
+	return
+}
+
+// getCalendarDates returns the exceptions to the calendar of the service on the dates between startDate and endDate whose type (as determined by getDayType) differs from their default type.
+func (s *service) getCalendarDates(startDate time.Time, endDate time.Time, getDayType func(date time.Time) calendar.DayType) (calendarDates []*CalendarDate) {
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		defaultDayType := calendar.GetDefaultDayType(date)
+		dayType := getDayType(date)
+		if dayType == defaultDayType {
+			continue
+		}
+
+		isAvailableByDefault := s.isAvailableOn(defaultDayType)
+		isAvailable := s.isAvailableOn(dayType)
+		switch {
+		case isAvailable && !isAvailableByDefault:
+			calendarDates = append(calendarDates, &CalendarDate{ServiceID: s.id, Date: date, ExceptionType: ExceptionTypeAdded})
+
+		case !isAvailable && isAvailableByDefault:
+			calendarDates = append(calendarDates, &CalendarDate{ServiceID: s.id, Date: date, ExceptionType: ExceptionTypeRemoved})
+		}
+	}
+	return
+}
@@ -0,0 +1,361 @@
+package gtfs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// FeedSource is a datasource.DataSource which provides the information contained in a GTFS static feed. Every distinct sequence of stops visited by the trips of a GTFS route becomes a route of the corresponding line, and every service becomes an operation mode (named after the types of days when it is available, which are inferred from calendar_dates.txt for services without a record in calendar.txt). Stops without arrival and departure times are part of the routes but have no timetables.
+type FeedSource struct {
+	Feed *Feed // feed containing the information
+
+	stopIDMap       map[string]*Stop                               // maps the identifier of each stop to the stop
+	translatedNames map[string]map[string]string                   // maps each language to a map from the identifiers of stops to their names in that language
+	lines           *schedule.Lines                                // numbers of all lines grouped by vehicle type
+	lineMap         map[string]*schedule.Line                      // maps the vehicle type and the number of each line (separated by a slash) to the line
+	routes          virtual.VehicleTypeLineNumberRouteListListList // routes of all lines served by vehicles with a type supported by the virtual timetable API
+	timetableMap    map[string]schedule.Timetable                  // maps the operation mode code, route code and stop code of each timetable (separated by slashes) to the timetable
+}
+
+// getVehicleType returns the vehicle type used by the schedule for routes of the specified GTFS route type (including the extended route types). An empty string is returned for route types which do not correspond to urban transit vehicles.
+func getVehicleType(routeType RouteType) string {
+	switch {
+	case routeType == RouteTypeTram || routeType >= 900 && routeType < 1000:
+		return schedule.VehicleTypeTram
+
+	case routeType == RouteTypeSubway || routeType >= 400 && routeType < 500:
+		return schedule.VehicleTypeMetro
+
+	case routeType == RouteTypeBus || routeType >= 700 && routeType < 800:
+		return schedule.VehicleTypeBus
+
+	case routeType == RouteTypeTrolleybus || routeType == 800:
+		return schedule.VehicleTypeTrolleybus
+
+	default:
+		return ""
+	}
+}
+
+// getCalendarDayTypes returns the default types of the days of the week when the service with the specified calendar is available.
+func getCalendarDayTypes(serviceCalendar *Calendar) (dayTypes []calendar.DayType) {
+	for _, dayType := range calendar.DayTypes {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if serviceCalendar.Weekdays[weekday] && calendar.GetWeekdayDayType(weekday) == dayType {
+				dayTypes = append(dayTypes, dayType)
+				break
+			}
+		}
+	}
+	return
+}
+
+// getCalendarDatesDayTypes infers the types of days when a service which is only defined by the specified calendar dates (i.e. has no record in calendar.txt) is available. A day type is included if the service is available on most of the dates of that type (as determined by calendar.GetDayType) between the first and the last date when it is available, so that occasional exceptions (e.g. bridge days) do not affect the result.
+func getCalendarDatesDayTypes(calendarDates []*CalendarDate) (dayTypes []calendar.DayType) {
+	availableDates := map[time.Time]bool{}
+	var firstDate, lastDate time.Time
+	for _, calendarDate := range calendarDates {
+		if calendarDate.ExceptionType != ExceptionTypeAdded {
+			continue
+		}
+
+		date := getDate(calendarDate.Date)
+		availableDates[date] = true
+		if firstDate.IsZero() || date.Before(firstDate) {
+			firstDate = date
+		}
+		if lastDate.IsZero() || date.After(lastDate) {
+			lastDate = date
+		}
+	}
+	for _, calendarDate := range calendarDates {
+		if calendarDate.ExceptionType == ExceptionTypeRemoved {
+			delete(availableDates, getDate(calendarDate.Date))
+		}
+	}
+	if len(availableDates) == 0 {
+		return
+	}
+
+	dateCounts := map[calendar.DayType]int{}
+	availableDateCounts := map[calendar.DayType]int{}
+	for date := firstDate; !date.After(lastDate); date = date.AddDate(0, 0, 1) {
+		dayType := calendar.GetDayType(date)
+		dateCounts[dayType]++
+		if availableDates[date] {
+			availableDateCounts[dayType]++
+		}
+	}
+	for _, dayType := range calendar.DayTypes {
+		if availableDateCounts[dayType]*2 > dateCounts[dayType] {
+			dayTypes = append(dayTypes, dayType)
+		}
+	}
+	return
+}
+
+// getOperationModeName returns the name (in Bulgarian) of the operation mode in effect on days of the specified types.
+func getOperationModeName(dayTypes []calendar.DayType) string {
+	names := make([]string, 0, len(dayTypes))
+	for _, dayType := range dayTypes {
+		names = append(names, l10n.BulgarianTranslator[dayType.String()])
+	}
+	return strings.Join(names, ", ")
+}
+
+func getLineKey(vehicleType string, lineNumber string) string {
+	return vehicleType + "/" + lineNumber
+}
+
+func getTimetableKey(operationModeCode string, routeCode string, stopCode string) string {
+	return operationModeCode + "/" + routeCode + "/" + stopCode
+}
+
+func formatDepartureTime(departureTime time.Duration) string {
+	minutes := int(departureTime / time.Minute)
+	return fmt.Sprintf("%02d:%02d", minutes/60%24, minutes%60)
+}
+
+// NewFeedSource returns a FeedSource providing the information contained in feed.
+func NewFeedSource(feed *Feed) (source *FeedSource) {
+	source = &FeedSource{
+		Feed:            feed,
+		stopIDMap:       map[string]*Stop{},
+		translatedNames: map[string]map[string]string{},
+		lines:           &schedule.Lines{},
+		lineMap:         map[string]*schedule.Line{},
+		timetableMap:    map[string]schedule.Timetable{},
+	}
+	for _, stop := range feed.Stops {
+		source.stopIDMap[stop.ID] = stop
+	}
+	for _, translation := range feed.Translations {
+		if translation.TableName != "stops" || translation.FieldName != "stop_name" {
+			continue
+		}
+
+		names, ok := source.translatedNames[translation.Language]
+		if !ok {
+			names = map[string]string{}
+			source.translatedNames[translation.Language] = names
+		}
+
+		if translation.RecordID != "" {
+			names[translation.RecordID] = translation.Translation
+			continue
+		}
+
+		for _, stop := range feed.Stops {
+			if stop.Name == translation.FieldValue {
+				names[stop.ID] = translation.Translation
+			}
+		}
+	}
+
+	operationModeNames := map[string]string{}
+	for _, serviceCalendar := range feed.Calendars {
+		operationModeNames[serviceCalendar.ServiceID] = getOperationModeName(getCalendarDayTypes(serviceCalendar))
+	}
+	calendarDatesByServiceID := map[string][]*CalendarDate{}
+	for _, calendarDate := range feed.CalendarDates {
+		if _, ok := operationModeNames[calendarDate.ServiceID]; !ok {
+			calendarDatesByServiceID[calendarDate.ServiceID] = append(calendarDatesByServiceID[calendarDate.ServiceID], calendarDate)
+		}
+	}
+	for serviceID, calendarDates := range calendarDatesByServiceID {
+		operationModeNames[serviceID] = getOperationModeName(getCalendarDatesDayTypes(calendarDates))
+	}
+
+	stopTimesByTripID := map[string][]*StopTime{}
+	for _, stopTime := range feed.StopTimes {
+		if _, ok := source.stopIDMap[stopTime.StopID]; ok {
+			stopTimesByTripID[stopTime.TripID] = append(stopTimesByTripID[stopTime.TripID], stopTime)
+		}
+	}
+
+	routeMap := map[string]*Route{}
+	for _, route := range feed.Routes {
+		routeMap[route.ID] = route
+	}
+
+	scheduleRouteMap := map[string]*schedule.Route{} // maps the identifier of each GTFS route and the identifiers of the stops of each route (separated by slashes) to the route
+	virtualRouteListMap := map[string]*virtual.LineNumberRouteList{}
+	departureTimesMap := map[string][]time.Duration{}
+	for _, trip := range feed.Trips {
+		route, ok := routeMap[trip.RouteID]
+		if !ok {
+			continue
+		}
+
+		vehicleType := getVehicleType(route.Type)
+		if vehicleType == "" {
+			continue
+		}
+
+		stopTimes := stopTimesByTripID[trip.ID]
+		if len(stopTimes) == 0 {
+			continue
+		}
+
+		sort.SliceStable(stopTimes, func(i, j int) bool { return stopTimes[i].StopSequence < stopTimes[j].StopSequence })
+		lineNumber := route.ShortName
+		if lineNumber == "" {
+			lineNumber = route.ID
+		}
+		lineKey := getLineKey(vehicleType, lineNumber)
+		line, ok := source.lineMap[lineKey]
+		if !ok {
+			line = &schedule.Line{VehicleType: vehicleType, LineNumber: lineNumber, OperationModeRoutesMap: schedule.OperationModeRoutesMap{}}
+			source.lineMap[lineKey] = line
+			source.lines.AddLineNumber(vehicleType, lineNumber)
+		}
+
+		operationModeRoutes, ok := line.OperationModeRoutesMap[trip.ServiceID]
+		if !ok {
+			operationModeName, ok := operationModeNames[trip.ServiceID]
+			if !ok || operationModeName == "" {
+				operationModeName = trip.ServiceID
+			}
+			operationModeRoutes = &schedule.OperationModeRoutes{OperationMode: &schedule.OperationMode{Code: trip.ServiceID, Name: operationModeName}, RouteMap: schedule.RouteMap{}}
+			line.OperationModeRoutesList = append(line.OperationModeRoutesList, operationModeRoutes)
+			line.OperationModeRoutesMap[trip.ServiceID] = operationModeRoutes
+		}
+
+		stopIDs := make([]string, 0, len(stopTimes))
+		for _, stopTime := range stopTimes {
+			stopIDs = append(stopIDs, stopTime.StopID)
+		}
+		scheduleRouteKey := route.ID + "/" + strings.Join(stopIDs, "/")
+		scheduleRoute, ok := scheduleRouteMap[scheduleRouteKey]
+		if !ok {
+			scheduleRoute = &schedule.Route{Code: strconv.Itoa(len(scheduleRouteMap) + 1), StopMap: schedule.StopMap{}}
+			virtualRoute := &virtual.Route{}
+			for _, stopID := range stopIDs {
+				stop := source.getScheduleStop(stopID)
+				scheduleRoute.StopList = append(scheduleRoute.StopList, stop)
+				scheduleRoute.StopMap[stop.Code] = stop
+				virtualRoute.StopCodes = append(virtualRoute.StopCodes, stop.Code)
+			}
+			scheduleRoute.Name = scheduleRoute.StopList[0].Name + " - " + scheduleRoute.StopList[len(scheduleRoute.StopList)-1].Name
+			scheduleRouteMap[scheduleRouteKey] = scheduleRoute
+
+			if virtualVehicleType, ok := virtual.GetVehicleTypeFromSchedule(vehicleType); ok {
+				virtualRouteList, ok := virtualRouteListMap[lineKey]
+				if !ok {
+					virtualRouteList = &virtual.LineNumberRouteList{LineNumber: lineNumber}
+					virtualRouteListMap[lineKey] = virtualRouteList
+					source.addVirtualRouteList(virtualVehicleType, virtualRouteList)
+				}
+				virtualRouteList.RouteList = append(virtualRouteList.RouteList, virtualRoute)
+			}
+		}
+		if _, ok := operationModeRoutes.RouteMap[scheduleRoute.Code]; !ok {
+			operationModeRoutes.RouteList = append(operationModeRoutes.RouteList, scheduleRoute)
+			operationModeRoutes.RouteMap[scheduleRoute.Code] = scheduleRoute
+		}
+
+		for _, stopTime := range stopTimes {
+			if stopTime.IsUntimed {
+				continue
+			}
+
+			timetableKey := getTimetableKey(trip.ServiceID, scheduleRoute.Code, source.getStopCode(stopTime.StopID))
+			departureTimesMap[timetableKey] = append(departureTimesMap[timetableKey], stopTime.DepartureTime)
+		}
+	}
+
+	for timetableKey, departureTimes := range departureTimesMap {
+		sort.Slice(departureTimes, func(i, j int) bool { return departureTimes[i] < departureTimes[j] })
+		timetable := make(schedule.Timetable, 0, len(departureTimes))
+		for _, departureTime := range departureTimes {
+			timetable = append(timetable, &schedule.Departure{Time: departureTime, Raw: formatDepartureTime(departureTime)})
+		}
+		source.timetableMap[timetableKey] = timetable
+	}
+	return
+}
+
+// OpenFeedSource returns a FeedSource providing the information contained in the GTFS static feed stored at the specified path (see OpenFeed).
+func OpenFeedSource(feedPath string) (source *FeedSource, err error) {
+	feed, err := OpenFeed(feedPath)
+	if err != nil {
+		return
+	}
+
+	source = NewFeedSource(feed)
+	return
+}
+
+// getStopCode returns the code of the stop with the specified identifier, which is its stop_code if it has one and its identifier otherwise.
+func (s *FeedSource) getStopCode(stopID string) string {
+	stop := s.stopIDMap[stopID]
+	if stop.Code == "" {
+		return stop.ID
+	}
+	return stop.Code
+}
+
+func (s *FeedSource) getScheduleStop(stopID string) *schedule.Stop {
+	return &schedule.Stop{Code: s.getStopCode(stopID), Name: s.stopIDMap[stopID].Name}
+}
+
+func (s *FeedSource) addVirtualRouteList(virtualVehicleType string, virtualRouteList *virtual.LineNumberRouteList) {
+	for _, vehicleTypeRoutes := range s.routes {
+		if vehicleTypeRoutes.VehicleType == virtualVehicleType {
+			vehicleTypeRoutes.LineNumberRouteListList = append(vehicleTypeRoutes.LineNumberRouteListList, virtualRouteList)
+			return
+		}
+	}
+	s.routes = append(s.routes, &virtual.VehicleTypeLineNumberRouteListList{VehicleType: virtualVehicleType, LineNumberRouteListList: virtual.LineNumberRouteListList{virtualRouteList}})
+}
+
+// GetStopsInLanguageContext returns the list of all urban transit stops in the feed. The names of the stops are taken from translations.txt if it contains translations to the specified language and from stops.txt otherwise.
+func (s *FeedSource) GetStopsInLanguageContext(ctx context.Context, language string) (stops virtual.StopList, err error) {
+	translatedNames := s.translatedNames[language]
+	stops = make(virtual.StopList, 0, len(s.Feed.Stops))
+	for _, stop := range s.Feed.Stops {
+		name, ok := translatedNames[stop.ID]
+		if !ok {
+			name = stop.Name
+		}
+		stops = append(stops, &virtual.Stop{Code: s.getStopCode(stop.ID), Name: name})
+	}
+	return
+}
+
+// GetRoutesContext returns the VehicleTypeLineNumberRouteListListList of all urban transit routes in the feed.
+func (s *FeedSource) GetRoutesContext(ctx context.Context) (routes virtual.VehicleTypeLineNumberRouteListListList, err error) {
+	return s.routes, nil
+}
+
+// GetLinesContext returns all urban transit lines in the feed.
+func (s *FeedSource) GetLinesContext(ctx context.Context) (lines *schedule.Lines, err error) {
+	return s.lines, nil
+}
+
+// GetLineContext returns the schedule of the urban transit line with the specified vehicleType and lineNumber from the feed.
+func (s *FeedSource) GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (line *schedule.Line, err error) {
+	line, ok := s.lineMap[getLineKey(vehicleType, lineNumber)]
+	if !ok {
+		err = &schedule.UnknownLineError{VehicleType: vehicleType, LineNumber: lineNumber}
+	}
+	return
+}
+
+// GetTimetableContext returns the schedule timetable matching the specified operationModeCode, routeCode and stopCode from the feed.
+func (s *FeedSource) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable schedule.Timetable, err error) {
+	timetable, ok := s.timetableMap[getTimetableKey(operationModeCode, routeCode, stopCode)]
+	if !ok {
+		err = &schedule.UnknownStopError{OperationModeCode: operationModeCode, RouteCode: routeCode, StopCode: stopCode}
+	}
+	return
+}
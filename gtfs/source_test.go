@@ -0,0 +1,140 @@
+package gtfs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+)
+
+// getServiceCalendarDates returns the calendar dates adding the service with the specified serviceID on the days in May 2024 which have one of the specified dayTypes, except for the ones listed in exceptDays.
+func getServiceCalendarDates(serviceID string, dayTypes []calendar.DayType, exceptDays ...int) (calendarDates []*CalendarDate) {
+	for day := 1; day <= 31; day++ {
+		date := time.Date(2024, time.May, day, 0, 0, 0, 0, time.Local)
+		isExcepted := false
+		for _, exceptDay := range exceptDays {
+			isExcepted = isExcepted || exceptDay == day
+		}
+		for _, dayType := range dayTypes {
+			if !isExcepted && calendar.GetDayType(date) == dayType {
+				calendarDates = append(calendarDates, &CalendarDate{ServiceID: serviceID, Date: date, ExceptionType: ExceptionTypeAdded})
+			}
+		}
+	}
+	return
+}
+
+func TestGetCalendarDatesDayTypes(t *testing.T) {
+	testCases := []struct {
+		name             string
+		calendarDates    []*CalendarDate
+		expectedDayTypes []calendar.DayType
+	}{
+		{
+			name:             "weekdays",
+			calendarDates:    getServiceCalendarDates("WD", []calendar.DayType{calendar.DayTypeWeekday}),
+			expectedDayTypes: []calendar.DayType{calendar.DayTypeWeekday},
+		},
+		{
+			name:             "weekdays except bridge days",
+			calendarDates:    getServiceCalendarDates("WD", []calendar.DayType{calendar.DayTypeWeekday}, 2, 3),
+			expectedDayTypes: []calendar.DayType{calendar.DayTypeWeekday},
+		},
+		{
+			name:             "holidays",
+			calendarDates:    getServiceCalendarDates("HD", []calendar.DayType{calendar.DayTypeHoliday}),
+			expectedDayTypes: []calendar.DayType{calendar.DayTypeHoliday},
+		},
+		{
+			name:             "days off",
+			calendarDates:    getServiceCalendarDates("DO", []calendar.DayType{calendar.DayTypePreHoliday, calendar.DayTypeHoliday}),
+			expectedDayTypes: []calendar.DayType{calendar.DayTypePreHoliday, calendar.DayTypeHoliday},
+		},
+		{
+			name: "removed dates",
+			calendarDates: append(getServiceCalendarDates("WD", []calendar.DayType{calendar.DayTypeWeekday}),
+				&CalendarDate{ServiceID: "WD", Date: time.Date(2024, time.May, 13, 0, 0, 0, 0, time.Local), ExceptionType: ExceptionTypeRemoved}),
+			expectedDayTypes: []calendar.DayType{calendar.DayTypeWeekday},
+		},
+		{
+			name:          "only removed dates",
+			calendarDates: []*CalendarDate{{ServiceID: "WD", Date: time.Date(2024, time.May, 13, 0, 0, 0, 0, time.Local), ExceptionType: ExceptionTypeRemoved}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dayTypes := getCalendarDatesDayTypes(testCase.calendarDates)
+			if !reflect.DeepEqual(dayTypes, testCase.expectedDayTypes) {
+				t.Errorf("expected day types %v, got %v", testCase.expectedDayTypes, dayTypes)
+			}
+		})
+	}
+}
+
+func TestFeedSource(t *testing.T) {
+	feed, err := ReadFeed(testFeedFiles)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	feed.CalendarDates = append(getServiceCalendarDates("WD", []calendar.DayType{calendar.DayTypeWeekday}), getServiceCalendarDates("HD", []calendar.DayType{calendar.DayTypeHoliday})...)
+	feed.Calendars = []*Calendar{{ServiceID: "WE", Weekdays: [7]bool{time.Saturday: true, time.Sunday: true}}}
+	feed.Trips = append(feed.Trips, &Trip{RouteID: "R94", ServiceID: "WE", ID: "T3"})
+	feed.StopTimes = append(feed.StopTimes, &StopTime{TripID: "T3", ArrivalTime: 8 * time.Hour, DepartureTime: 8 * time.Hour, StopID: "S1", StopSequence: 1})
+	source := NewFeedSource(feed)
+	ctx := context.Background()
+
+	line, err := source.GetLineContext(ctx, schedule.VehicleTypeBus, "94")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var operationModeNames []string
+	for _, operationModeRoutes := range line.OperationModeRoutesList {
+		operationModeNames = append(operationModeNames, operationModeRoutes.Code+"="+operationModeRoutes.Name)
+	}
+	expectedOperationModeNames := []string{"WD=делник", "HD=празник", "WE=предпразник, празник"}
+	if !reflect.DeepEqual(operationModeNames, expectedOperationModeNames) {
+		t.Errorf("expected operation modes %v, got %v", expectedOperationModeNames, operationModeNames)
+	}
+
+	route := line.OperationModeRoutesMap["WD"].RouteList[0]
+	expectedStops := schedule.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ОБЕЛЯ"}, {Code: "0003", Name: "ЦЕНТРАЛНА ГАРА"}}
+	if !reflect.DeepEqual(route.StopList, expectedStops) {
+		t.Errorf("expected the untimed stop to be part of the route, got %v", route.StopList)
+	}
+	if line.OperationModeRoutesMap["HD"].RouteList[0] != route {
+		t.Error("expected the trips visiting the same stops to share a route")
+	}
+
+	timetable, err := source.GetTimetableContext(ctx, "HD", route.Code, "0003")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(timetable) != 1 || timetable[0].Time != 24*time.Hour+15*time.Minute || timetable[0].Raw != "00:15" {
+		t.Errorf("expected a single departure after midnight, got %v", timetable)
+	}
+
+	_, err = source.GetTimetableContext(ctx, "WD", route.Code, "0002")
+	if !errors.Is(err, schedule.ErrUnknownStop) {
+		t.Errorf("expected the untimed stop to have no timetable, got %v", err)
+	}
+
+	_, err = source.GetLineContext(ctx, schedule.VehicleTypeTram, "5")
+	if !errors.Is(err, schedule.ErrUnknownLine) {
+		t.Errorf("expected the error to match schedule.ErrUnknownLine, got %v", err)
+	}
+
+	routes, err := source.GetRoutesContext(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(routes) != 1 || len(routes[0].LineNumberRouteListList[0].RouteList) != 2 || len(routes[0].LineNumberRouteListList[0].RouteList[0].StopCodes) != 3 {
+		t.Errorf("unexpected virtual routes: %v", routes)
+	}
+}
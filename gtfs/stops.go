@@ -0,0 +1,82 @@
+package gtfs
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// ReadStopLocations reads the locations of stops from CSV data in the format of stops.txt (i.e. with a header row naming the stop_code or stop_id, stop_lat and stop_lon columns) and returns a map from the code of each stop to its location.
+func ReadStopLocations(r io.Reader) (locations map[string]*StopLocation, err error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if err != nil {
+		err = fmt.Errorf("could not read the header of the stop locations: %w", err)
+		return
+	}
+
+	codeColumn, latitudeColumn, longitudeColumn := -1, -1, -1
+	for column, name := range header {
+		switch name {
+		case "stop_code":
+			codeColumn = column
+
+		case "stop_id":
+			if codeColumn == -1 {
+				codeColumn = column
+			}
+
+		case "stop_lat":
+			latitudeColumn = column
+
+		case "stop_lon":
+			longitudeColumn = column
+		}
+	}
+	if codeColumn == -1 || latitudeColumn == -1 || longitudeColumn == -1 {
+		err = fmt.Errorf("could not find the stop_code (or stop_id), stop_lat and stop_lon columns in the header of the stop locations")
+		return
+	}
+
+	locations = map[string]*StopLocation{}
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read the stop locations: %w", err)
+		}
+
+		if codeColumn >= len(record) || latitudeColumn >= len(record) || longitudeColumn >= len(record) || record[latitudeColumn] == "" || record[longitudeColumn] == "" {
+			continue
+		}
+
+		latitude, err := strconv.ParseFloat(record[latitudeColumn], 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the latitude of stop %s: %w", record[codeColumn], err)
+		}
+
+		longitude, err := strconv.ParseFloat(record[longitudeColumn], 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the longitude of stop %s: %w", record[codeColumn], err)
+		}
+
+		locations[record[codeColumn]] = &StopLocation{Latitude: latitude, Longitude: longitude}
+	}
+	return
+}
+
+// ReadStopLocationsFile reads the locations of stops from the CSV file at the specified path (see ReadStopLocations).
+func ReadStopLocationsFile(path string) (locations map[string]*StopLocation, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	return ReadStopLocations(file)
+}
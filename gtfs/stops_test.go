@@ -0,0 +1,61 @@
+package gtfs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadStopLocations(t *testing.T) {
+	testCases := []struct {
+		name              string
+		data              string
+		expectedLocations map[string]*StopLocation
+		isError           bool
+	}{
+		{
+			name:              "stop codes",
+			data:              "stop_id,stop_code,stop_name,stop_lat,stop_lon\nA1,0001,ЖК МЛАДОСТ 3,42.65,23.38\n",
+			expectedLocations: map[string]*StopLocation{"0001": {Latitude: 42.65, Longitude: 23.38}},
+		},
+		{
+			name:              "stop identifiers",
+			data:              "stop_lon,stop_lat,stop_id\n23.38,42.65,0001\n",
+			expectedLocations: map[string]*StopLocation{"0001": {Latitude: 42.65, Longitude: 23.38}},
+		},
+		{
+			name:              "stop without a location",
+			data:              "stop_code,stop_lat,stop_lon\n0001,,\n0002,42.71,23.32\n",
+			expectedLocations: map[string]*StopLocation{"0002": {Latitude: 42.71, Longitude: 23.32}},
+		},
+		{
+			name:    "missing columns",
+			data:    "stop_code,stop_name\n0001,ЖК МЛАДОСТ 3\n",
+			isError: true,
+		},
+		{
+			name:    "invalid latitude",
+			data:    "stop_code,stop_lat,stop_lon\n0001,north,23.38\n",
+			isError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			locations, err := ReadStopLocations(strings.NewReader(testCase.data))
+			if testCase.isError {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(locations, testCase.expectedLocations) {
+				t.Errorf("expected locations %v, got %v", testCase.expectedLocations, locations)
+			}
+		})
+	}
+}
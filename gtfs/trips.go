@@ -0,0 +1,61 @@
+package gtfs
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+)
+
+const (
+	// maxTravelTimeBetweenStops is the longest time a vehicle is assumed to need to travel between two consecutive stops of a route.
+	maxTravelTimeBetweenStops = 30 * time.Minute
+)
+
+// TripStop represents the departure of a vehicle from a stop of a route on a specific trip.
+type TripStop struct {
+	StopIndex int           // index of the stop in the stop list of the route
+	Time      time.Duration // departure time measured from the start of the service day
+}
+
+// GetDepartureTimes returns the departure times from a schedule timetable in chronological order.
+func GetDepartureTimes(timetable schedule.Timetable) (departureTimes []time.Duration) {
+	departureTimes = make([]time.Duration, 0, len(timetable))
+	for _, departure := range timetable {
+		departureTimes = append(departureTimes, departure.Time)
+	}
+	sort.Slice(departureTimes, func(i, j int) bool { return departureTimes[i] < departureTimes[j] })
+	return
+}
+
+// InferTrips reconstructs the trips made along a route from the chronologically ordered departure times from each of its stops (in the order of the stops on the route). Each vehicle is matched with the earliest unmatched departure from the next stop which is not earlier than its departure from the previous stop (and is at most maxTravelTimeBetweenStops later); unmatched departures start new trips.
+func InferTrips(departureTimesList [][]time.Duration) (trips [][]*TripStop) {
+	var activeTripIndices []int // indices of the trips which may continue to the next stop (in chronological order of their last departure)
+	for stopIndex, departureTimes := range departureTimesList {
+		var nextActiveTripIndices []int
+		startTrip := func(departureTime time.Duration) {
+			trips = append(trips, []*TripStop{{StopIndex: stopIndex, Time: departureTime}})
+			nextActiveTripIndices = append(nextActiveTripIndices, len(trips)-1)
+		}
+
+		departureIndex := 0
+		for _, tripIndex := range activeTripIndices {
+			lastDepartureTime := trips[tripIndex][len(trips[tripIndex])-1].Time
+			for ; departureIndex < len(departureTimes) && departureTimes[departureIndex] < lastDepartureTime; departureIndex++ {
+				startTrip(departureTimes[departureIndex])
+			}
+			if departureIndex == len(departureTimes) || departureTimes[departureIndex]-lastDepartureTime > maxTravelTimeBetweenStops {
+				continue
+			}
+
+			trips[tripIndex] = append(trips[tripIndex], &TripStop{StopIndex: stopIndex, Time: departureTimes[departureIndex]})
+			nextActiveTripIndices = append(nextActiveTripIndices, tripIndex)
+			departureIndex++
+		}
+		for ; departureIndex < len(departureTimes); departureIndex++ {
+			startTrip(departureTimes[departureIndex])
+		}
+		activeTripIndices = nextActiveTripIndices
+	}
+	return
+}
@@ -0,0 +1,71 @@
+package gtfs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInferTrips(t *testing.T) {
+	testCases := []struct {
+		name               string
+		departureTimesList [][]time.Duration
+		expectedTrips      [][]*TripStop
+	}{
+		{
+			name:               "single trip",
+			departureTimesList: [][]time.Duration{{5 * time.Hour}, {5*time.Hour + 5*time.Minute}, {5*time.Hour + 9*time.Minute}},
+			expectedTrips:      [][]*TripStop{{{0, 5 * time.Hour}, {1, 5*time.Hour + 5*time.Minute}, {2, 5*time.Hour + 9*time.Minute}}},
+		},
+		{
+			name:               "consecutive trips",
+			departureTimesList: [][]time.Duration{{5 * time.Hour, 6 * time.Hour}, {5*time.Hour + 5*time.Minute, 6*time.Hour + 5*time.Minute}},
+			expectedTrips:      [][]*TripStop{{{0, 5 * time.Hour}, {1, 5*time.Hour + 5*time.Minute}}, {{0, 6 * time.Hour}, {1, 6*time.Hour + 5*time.Minute}}},
+		},
+		{
+			name:               "trip starting at an intermediate stop",
+			departureTimesList: [][]time.Duration{{6 * time.Hour}, {5 * time.Hour, 6*time.Hour + 5*time.Minute}},
+			expectedTrips:      [][]*TripStop{{{0, 6 * time.Hour}, {1, 6*time.Hour + 5*time.Minute}}, {{1, 5 * time.Hour}}},
+		},
+		{
+			name:               "trip ending at an intermediate stop",
+			departureTimesList: [][]time.Duration{{5 * time.Hour, 6 * time.Hour}, {5*time.Hour + 5*time.Minute}},
+			expectedTrips:      [][]*TripStop{{{0, 5 * time.Hour}, {1, 5*time.Hour + 5*time.Minute}}, {{0, 6 * time.Hour}}},
+		},
+		{
+			name:               "departure too late to continue a trip",
+			departureTimesList: [][]time.Duration{{5 * time.Hour}, {5*time.Hour + 31*time.Minute}},
+			expectedTrips:      [][]*TripStop{{{0, 5 * time.Hour}}, {{1, 5*time.Hour + 31*time.Minute}}},
+		},
+		{
+			name:               "stop without a timetable",
+			departureTimesList: [][]time.Duration{{5 * time.Hour}, nil, {5*time.Hour + 9*time.Minute}},
+			expectedTrips:      [][]*TripStop{{{0, 5 * time.Hour}}, {{2, 5*time.Hour + 9*time.Minute}}},
+		},
+		{
+			name:               "after midnight",
+			departureTimesList: [][]time.Duration{{23*time.Hour + 55*time.Minute}, {24*time.Hour + 2*time.Minute}},
+			expectedTrips:      [][]*TripStop{{{0, 23*time.Hour + 55*time.Minute}, {1, 24*time.Hour + 2*time.Minute}}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			trips := InferTrips(testCase.departureTimesList)
+			if !reflect.DeepEqual(trips, testCase.expectedTrips) {
+				t.Errorf("expected trips %v, got %v", formatTrips(testCase.expectedTrips), formatTrips(trips))
+			}
+		})
+	}
+}
+
+func formatTrips(trips [][]*TripStop) (formattedTrips [][]TripStop) {
+	for _, trip := range trips {
+		var formattedTrip []TripStop
+		for _, tripStop := range trip {
+			formattedTrip = append(formattedTrip, *tripStop)
+		}
+		formattedTrips = append(formattedTrips, formattedTrip)
+	}
+	return
+}
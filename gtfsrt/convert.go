@@ -0,0 +1,44 @@
+package gtfsrt
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/gtfs"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// NewFeedMessage converts the expected arrivals from the specified urban transit stop timetables into a full-dataset feed message created at now. Each vehicle arrival becomes a trip update with a single stop time update for the stop code. Since the virtual timetable API does not report which scheduled trip an arrival belongs to (nor its direction and start time), the trip is only identified by the route corresponding to its line (as identified in static feeds generated by the `gtfs` package) and the service date of now (which starts at schedule.ServiceDayStart), and it is marked as ADDED, as a SCHEDULED trip without a trip ID would also require its direction and start time; consumers can thus match the arrivals to routes and stops but not to individual trips. Arrivals whose time cannot be parsed are skipped (and the errors are logged).
+func NewFeedMessage(timetables virtual.StopTimetableList, now time.Time) (message *FeedMessage) {
+	message = &FeedMessage{Header: &FeedHeader{Version: Version, Incrementality: IncrementalityFullDataset, Timestamp: uint64(now.Unix())}}
+	// arrivals after midnight belong to trips which started on the previous service day
+	startDate := now.Add(-schedule.ServiceDayStart).Format("20060102")
+	for _, timetable := range timetables {
+		for _, lineArrivals := range timetable.LineVehicleArrivalListList {
+			routeID := gtfs.GetRouteID(virtual.GetScheduleVehicleType(lineArrivals.VehicleType), lineArrivals.LineNumber)
+			for index, arrival := range lineArrivals.VehicleArrivalList {
+				arrivalTime, err := arrival.GetTime(now)
+				if err != nil {
+					log.Printf("could not parse arrival time (%s): %s", arrival.Time, err)
+					continue
+				}
+
+				wheelchairAccessibility := WheelchairAccessibilityInaccessible
+				if arrival.IsWheelchairAccessible {
+					wheelchairAccessibility = WheelchairAccessibilityAccessible
+				}
+				message.Entities = append(message.Entities, &FeedEntity{
+					ID: timetable.StopCode + "-" + routeID + "-" + strconv.Itoa(index+1),
+					TripUpdate: &TripUpdate{
+						Trip:            &TripDescriptor{RouteID: routeID, StartDate: startDate, ScheduleRelationship: ScheduleRelationshipAdded},
+						Vehicle:         &VehicleDescriptor{WheelchairAccessibility: wheelchairAccessibility},
+						StopTimeUpdates: []*StopTimeUpdate{{StopID: timetable.StopCode, Arrival: &StopTimeEvent{Time: arrivalTime.Unix()}}},
+					},
+				})
+			}
+		}
+	}
+	return
+}
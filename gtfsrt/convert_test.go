@@ -0,0 +1,98 @@
+package gtfsrt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+func TestNewFeedMessage(t *testing.T) {
+	now := time.Date(2024, time.May, 6, 23, 50, 0, 0, time.UTC)
+	timetables := virtual.StopTimetableList{
+		{
+			StopCode: "0001",
+			LineVehicleArrivalListList: virtual.LineVehicleArrivalListList{
+				{VehicleType: virtual.VehicleTypeBus, LineNumber: "94", VehicleArrivalList: virtual.VehicleArrivalList{{Time: "23:55:00", IsWheelchairAccessible: true}, {Time: "00:05:00"}}},
+				{VehicleType: virtual.VehicleTypeTram, LineNumber: "5", VehicleArrivalList: virtual.VehicleArrivalList{{Time: "soon"}}},
+			},
+		},
+		{
+			StopCode: "0002",
+			LineVehicleArrivalListList: virtual.LineVehicleArrivalListList{
+				{VehicleType: "metro", LineNumber: "M1", VehicleArrivalList: virtual.VehicleArrivalList{{Time: "23:51:30"}}},
+			},
+		},
+	}
+
+	message := NewFeedMessage(timetables, now)
+	expectedHeader := &FeedHeader{Version: Version, Incrementality: IncrementalityFullDataset, Timestamp: uint64(now.Unix())}
+	if !reflect.DeepEqual(message.Header, expectedHeader) {
+		t.Errorf("expected header %v, got %v", expectedHeader, message.Header)
+	}
+
+	expectedEntities := []*FeedEntity{
+		{
+			ID: "0001-autobus-94-1",
+			TripUpdate: &TripUpdate{
+				Trip:            &TripDescriptor{RouteID: "autobus-94", StartDate: "20240506", ScheduleRelationship: ScheduleRelationshipAdded},
+				Vehicle:         &VehicleDescriptor{WheelchairAccessibility: WheelchairAccessibilityAccessible},
+				StopTimeUpdates: []*StopTimeUpdate{{StopID: "0001", Arrival: &StopTimeEvent{Time: now.Add(5 * time.Minute).Unix()}}},
+			},
+		},
+		{
+			ID: "0001-autobus-94-2",
+			TripUpdate: &TripUpdate{
+				Trip:            &TripDescriptor{RouteID: "autobus-94", StartDate: "20240506", ScheduleRelationship: ScheduleRelationshipAdded},
+				Vehicle:         &VehicleDescriptor{WheelchairAccessibility: WheelchairAccessibilityInaccessible},
+				StopTimeUpdates: []*StopTimeUpdate{{StopID: "0001", Arrival: &StopTimeEvent{Time: now.Add(15 * time.Minute).Unix()}}},
+			},
+		},
+		{
+			ID: "0002-metro-M1-1",
+			TripUpdate: &TripUpdate{
+				Trip:            &TripDescriptor{RouteID: "metro-M1", StartDate: "20240506", ScheduleRelationship: ScheduleRelationshipAdded},
+				Vehicle:         &VehicleDescriptor{WheelchairAccessibility: WheelchairAccessibilityInaccessible},
+				StopTimeUpdates: []*StopTimeUpdate{{StopID: "0002", Arrival: &StopTimeEvent{Time: now.Add(90 * time.Second).Unix()}}},
+			},
+		},
+	}
+	if len(message.Entities) != len(expectedEntities) {
+		t.Fatalf("expected %d entities, got %d", len(expectedEntities), len(message.Entities))
+	}
+	for index, entity := range message.Entities {
+		if !reflect.DeepEqual(entity, expectedEntities[index]) {
+			t.Errorf("expected entity %d to be %v, got %v", index, expectedEntities[index].TripUpdate, entity.TripUpdate)
+		}
+	}
+}
+
+func TestNewFeedMessageStartDate(t *testing.T) {
+	tests := []struct {
+		name              string
+		now               time.Time
+		expectedStartDate string
+	}{
+		{"evening", time.Date(2024, time.May, 6, 23, 50, 0, 0, time.UTC), "20240506"},
+		{"after midnight", time.Date(2024, time.May, 7, 2, 59, 0, 0, time.UTC), "20240506"},
+		{"start of the service day", time.Date(2024, time.May, 7, 3, 0, 0, 0, time.UTC), "20240507"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timetables := virtual.StopTimetableList{{
+				StopCode: "0001",
+				LineVehicleArrivalListList: virtual.LineVehicleArrivalListList{
+					{VehicleType: virtual.VehicleTypeBus, LineNumber: "94", VehicleArrivalList: virtual.VehicleArrivalList{{Time: test.now.Add(time.Minute).Format("15:04:05")}}},
+				},
+			}}
+			message := NewFeedMessage(timetables, test.now)
+			if len(message.Entities) != 1 {
+				t.Fatalf("expected 1 entity, got %d", len(message.Entities))
+			}
+			if trip := message.Entities[0].TripUpdate.Trip; trip.StartDate != test.expectedStartDate {
+				t.Errorf("expected start date %s, got %s", test.expectedStartDate, trip.StartDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+/*
+Package gtfsrt implements generation of GTFS-Realtime feeds (https://gtfs.org/realtime/reference/) containing trip updates for the expected arrivals of vehicles from the virtual timetables, which can be written to files or served over HTTP.
+*/
+package gtfsrt
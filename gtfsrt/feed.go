@@ -0,0 +1,250 @@
+package gtfsrt
+
+import (
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Version is the version of the GTFS-Realtime specification implemented by the feeds.
+const Version = "2.0"
+
+// Incrementality determines whether a feed contains the full dataset or only changes to it.
+type Incrementality int
+
+const (
+	// IncrementalityFullDataset represents a feed which contains the full dataset.
+	IncrementalityFullDataset Incrementality = 0
+	// IncrementalityDifferential represents a feed which only contains changes to the dataset.
+	IncrementalityDifferential Incrementality = 1
+)
+
+// ScheduleRelationship represents the relation between a trip and the static schedule.
+type ScheduleRelationship int
+
+const (
+	// ScheduleRelationshipScheduled represents a trip which is running in accordance with its static schedule.
+	ScheduleRelationshipScheduled ScheduleRelationship = 0
+	// ScheduleRelationshipAdded represents a trip which was added in addition to the static schedule.
+	ScheduleRelationshipAdded ScheduleRelationship = 1
+	// ScheduleRelationshipUnscheduled represents a trip which is running with no static schedule associated to it.
+	ScheduleRelationshipUnscheduled ScheduleRelationship = 2
+	// ScheduleRelationshipCanceled represents a trip which existed in the static schedule but was removed.
+	ScheduleRelationshipCanceled ScheduleRelationship = 3
+)
+
+// WheelchairAccessibility represents whether a vehicle is wheelchair-accessible.
+type WheelchairAccessibility int
+
+const (
+	// WheelchairAccessibilityNoValue represents a vehicle without information about its accessibility.
+	WheelchairAccessibilityNoValue WheelchairAccessibility = 0
+	// WheelchairAccessibilityUnknown represents a vehicle whose accessibility is unknown.
+	WheelchairAccessibilityUnknown WheelchairAccessibility = 1
+	// WheelchairAccessibilityAccessible represents a wheelchair-accessible vehicle.
+	WheelchairAccessibilityAccessible WheelchairAccessibility = 2
+	// WheelchairAccessibilityInaccessible represents a vehicle which is not wheelchair-accessible.
+	WheelchairAccessibilityInaccessible WheelchairAccessibility = 3
+)
+
+// FeedHeader represents the metadata of a feed.
+type FeedHeader struct {
+	Version        string         // version of the feed specification
+	Incrementality Incrementality // whether the feed contains the full dataset or only changes to it
+	Timestamp      uint64         // POSIX time when the content of the feed was created
+}
+
+// TripDescriptor identifies a trip.
+type TripDescriptor struct {
+	TripID               string               // identifier of the trip from the static feed (may be empty if the trip cannot be matched to a trip from the static feed)
+	RouteID              string               // identifier of the route from the static feed
+	StartDate            string               // service date on which the trip started in the YYYYMMDD format (may be empty)
+	ScheduleRelationship ScheduleRelationship // relation between the trip and the static schedule
+}
+
+// VehicleDescriptor represents the vehicle making a trip.
+type VehicleDescriptor struct {
+	ID                      string                  // internal identifier of the vehicle (may be empty)
+	WheelchairAccessibility WheelchairAccessibility // whether the vehicle is wheelchair-accessible
+}
+
+// StopTimeEvent represents the timing of an arrival at or a departure from a stop.
+type StopTimeEvent struct {
+	Time int64 // POSIX time of the event
+}
+
+// StopTimeUpdate represents a realtime update of the arrival at and the departure from a stop on a trip.
+type StopTimeUpdate struct {
+	StopID  string         // identifier of the stop from the static feed
+	Arrival *StopTimeEvent // expected arrival at the stop (may be nil)
+}
+
+// TripUpdate represents a realtime update of the progress of a vehicle along a trip.
+type TripUpdate struct {
+	Trip            *TripDescriptor    // trip to which the update applies
+	Vehicle         *VehicleDescriptor // vehicle making the trip (may be nil)
+	StopTimeUpdates []*StopTimeUpdate  // updates of the stop times of the trip
+}
+
+// FeedEntity represents a single entity of a feed.
+type FeedEntity struct {
+	ID         string      // identifier of the entity, which is unique within the feed
+	TripUpdate *TripUpdate // realtime update of a trip
+}
+
+// FeedMessage represents the contents of a feed.
+type FeedMessage struct {
+	Header   *FeedHeader
+	Entities []*FeedEntity
+}
+
+// field numbers from gtfs-realtime.proto
+const (
+	feedMessageHeaderField        protowire.Number = 1
+	feedMessageEntityField        protowire.Number = 2
+	feedHeaderVersionField        protowire.Number = 1
+	feedHeaderIncrementalityField protowire.Number = 2
+	feedHeaderTimestampField      protowire.Number = 3
+
+	feedEntityIDField         protowire.Number = 1
+	feedEntityTripUpdateField protowire.Number = 3
+
+	tripUpdateTripField           protowire.Number = 1
+	tripUpdateStopTimeUpdateField protowire.Number = 2
+	tripUpdateVehicleField        protowire.Number = 3
+
+	tripDescriptorTripIDField               protowire.Number = 1
+	tripDescriptorStartDateField            protowire.Number = 3
+	tripDescriptorScheduleRelationshipField protowire.Number = 4
+	tripDescriptorRouteIDField              protowire.Number = 5
+
+	vehicleDescriptorIDField                   protowire.Number = 1
+	vehicleDescriptorWheelchairAccessibleField protowire.Number = 4
+
+	stopTimeUpdateArrivalField protowire.Number = 2
+	stopTimeUpdateStopIDField  protowire.Number = 4
+
+	stopTimeEventTimeField protowire.Number = 2
+)
+
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendVarint(b []byte, number protowire.Number, value uint64) []byte {
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func appendMessage(b []byte, number protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func (h *FeedHeader) marshal() (b []byte) {
+	b = appendString(b, feedHeaderVersionField, h.Version)
+	b = appendVarint(b, feedHeaderIncrementalityField, uint64(h.Incrementality))
+	if h.Timestamp != 0 {
+		b = appendVarint(b, feedHeaderTimestampField, h.Timestamp)
+	}
+	return
+}
+
+func (td *TripDescriptor) marshal() (b []byte) {
+	b = appendString(b, tripDescriptorTripIDField, td.TripID)
+	b = appendString(b, tripDescriptorStartDateField, td.StartDate)
+	if td.ScheduleRelationship != ScheduleRelationshipScheduled {
+		b = appendVarint(b, tripDescriptorScheduleRelationshipField, uint64(td.ScheduleRelationship))
+	}
+	b = appendString(b, tripDescriptorRouteIDField, td.RouteID)
+	return
+}
+
+func (vd *VehicleDescriptor) marshal() (b []byte) {
+	b = appendString(b, vehicleDescriptorIDField, vd.ID)
+	if vd.WheelchairAccessibility != WheelchairAccessibilityNoValue {
+		b = appendVarint(b, vehicleDescriptorWheelchairAccessibleField, uint64(vd.WheelchairAccessibility))
+	}
+	return
+}
+
+func (ste *StopTimeEvent) marshal() (b []byte) {
+	return appendVarint(b, stopTimeEventTimeField, uint64(ste.Time))
+}
+
+func (stu *StopTimeUpdate) marshal() (b []byte) {
+	if stu.Arrival != nil {
+		b = appendMessage(b, stopTimeUpdateArrivalField, stu.Arrival.marshal())
+	}
+	b = appendString(b, stopTimeUpdateStopIDField, stu.StopID)
+	return
+}
+
+func (tu *TripUpdate) marshal() (b []byte) {
+	trip := tu.Trip
+	if trip == nil {
+		trip = &TripDescriptor{}
+	}
+	b = appendMessage(b, tripUpdateTripField, trip.marshal())
+	for _, stopTimeUpdate := range tu.StopTimeUpdates {
+		b = appendMessage(b, tripUpdateStopTimeUpdateField, stopTimeUpdate.marshal())
+	}
+	if tu.Vehicle != nil {
+		b = appendMessage(b, tripUpdateVehicleField, tu.Vehicle.marshal())
+	}
+	return
+}
+
+func (fe *FeedEntity) marshal() (b []byte) {
+	b = appendString(b, feedEntityIDField, fe.ID)
+	if fe.TripUpdate != nil {
+		b = appendMessage(b, feedEntityTripUpdateField, fe.TripUpdate.marshal())
+	}
+	return
+}
+
+// Marshal returns the protocol buffer encoding of the feed message.
+func (m *FeedMessage) Marshal() (b []byte) {
+	header := m.Header
+	if header == nil {
+		header = &FeedHeader{Version: Version}
+	}
+	b = appendMessage(b, feedMessageHeaderField, header.marshal())
+	for _, entity := range m.Entities {
+		b = appendMessage(b, feedMessageEntityField, entity.marshal())
+	}
+	return
+}
+
+// WriteFile writes the protocol buffer encoding of message to the file at the specified path. The file is replaced atomically, so that consumers polling it never read a partially written feed.
+func WriteFile(path string, message *FeedMessage) (err error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return
+	}
+
+	err = file.Chmod(0644)
+	if err == nil {
+		_, err = file.Write(message.Marshal())
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return
+}
@@ -0,0 +1,136 @@
+package gtfsrt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeFields decodes the fields of a protocol buffer message into a map from each field number to the values of the field (uint64 for varints and []byte for length-delimited fields).
+func decodeFields(t *testing.T, b []byte) (fields map[protowire.Number][]interface{}) {
+	fields = map[protowire.Number][]interface{}{}
+	for len(b) > 0 {
+		number, wireType, length := protowire.ConsumeTag(b)
+		if length < 0 {
+			t.Fatalf("could not decode tag: %s", protowire.ParseError(length))
+		}
+		b = b[length:]
+
+		switch wireType {
+		case protowire.VarintType:
+			value, length := protowire.ConsumeVarint(b)
+			if length < 0 {
+				t.Fatalf("could not decode varint: %s", protowire.ParseError(length))
+			}
+			fields[number] = append(fields[number], value)
+			b = b[length:]
+
+		case protowire.BytesType:
+			value, length := protowire.ConsumeBytes(b)
+			if length < 0 {
+				t.Fatalf("could not decode bytes: %s", protowire.ParseError(length))
+			}
+			fields[number] = append(fields[number], value)
+			b = b[length:]
+
+		default:
+			t.Fatalf("unexpected wire type %d of field %d", wireType, number)
+		}
+	}
+	return
+}
+
+// getMessageField decodes the single embedded message stored in the field with the specified number.
+func getMessageField(t *testing.T, fields map[protowire.Number][]interface{}, number protowire.Number) map[protowire.Number][]interface{} {
+	if len(fields[number]) != 1 {
+		t.Fatalf("expected a single field %d, got %v", number, fields[number])
+	}
+	return decodeFields(t, fields[number][0].([]byte))
+}
+
+func TestFeedMessageMarshal(t *testing.T) {
+	message := &FeedMessage{
+		Header: &FeedHeader{Version: Version, Incrementality: IncrementalityFullDataset, Timestamp: 1715000000},
+		Entities: []*FeedEntity{{
+			ID: "0001-autobus-94-1",
+			TripUpdate: &TripUpdate{
+				Trip:            &TripDescriptor{RouteID: "autobus-94", StartDate: "20240506", ScheduleRelationship: ScheduleRelationshipAdded},
+				Vehicle:         &VehicleDescriptor{WheelchairAccessibility: WheelchairAccessibilityAccessible},
+				StopTimeUpdates: []*StopTimeUpdate{{StopID: "0001", Arrival: &StopTimeEvent{Time: 1715000300}}},
+			},
+		}},
+	}
+	fields := decodeFields(t, message.Marshal())
+
+	header := getMessageField(t, fields, feedMessageHeaderField)
+	expectedHeader := map[protowire.Number][]interface{}{
+		feedHeaderVersionField:        {[]byte(Version)},
+		feedHeaderIncrementalityField: {uint64(IncrementalityFullDataset)},
+		feedHeaderTimestampField:      {uint64(1715000000)},
+	}
+	if !reflect.DeepEqual(header, expectedHeader) {
+		t.Errorf("expected header %v, got %v", expectedHeader, header)
+	}
+
+	entity := getMessageField(t, fields, feedMessageEntityField)
+	if !reflect.DeepEqual(entity[feedEntityIDField], []interface{}{[]byte("0001-autobus-94-1")}) {
+		t.Errorf("unexpected entity ID: %v", entity[feedEntityIDField])
+	}
+
+	tripUpdate := getMessageField(t, entity, feedEntityTripUpdateField)
+	trip := getMessageField(t, tripUpdate, tripUpdateTripField)
+	expectedTrip := map[protowire.Number][]interface{}{
+		tripDescriptorStartDateField:            {[]byte("20240506")},
+		tripDescriptorScheduleRelationshipField: {uint64(ScheduleRelationshipAdded)},
+		tripDescriptorRouteIDField:              {[]byte("autobus-94")},
+	}
+	if !reflect.DeepEqual(trip, expectedTrip) {
+		t.Errorf("expected trip descriptor %v, got %v", expectedTrip, trip)
+	}
+
+	vehicle := getMessageField(t, tripUpdate, tripUpdateVehicleField)
+	if !reflect.DeepEqual(vehicle[vehicleDescriptorWheelchairAccessibleField], []interface{}{uint64(WheelchairAccessibilityAccessible)}) {
+		t.Errorf("unexpected wheelchair accessibility: %v", vehicle)
+	}
+
+	stopTimeUpdate := getMessageField(t, tripUpdate, tripUpdateStopTimeUpdateField)
+	arrival := getMessageField(t, stopTimeUpdate, stopTimeUpdateArrivalField)
+	if !reflect.DeepEqual(stopTimeUpdate[stopTimeUpdateStopIDField], []interface{}{[]byte("0001")}) || !reflect.DeepEqual(arrival[stopTimeEventTimeField], []interface{}{uint64(1715000300)}) {
+		t.Errorf("unexpected stop time update: %v (arrival %v)", stopTimeUpdate, arrival)
+	}
+}
+
+func TestTripDescriptorScheduleRelationship(t *testing.T) {
+	fields := decodeFields(t, (&TripDescriptor{TripID: "T1", ScheduleRelationship: ScheduleRelationshipCanceled}).marshal())
+	if !reflect.DeepEqual(fields[tripDescriptorScheduleRelationshipField], []interface{}{uint64(ScheduleRelationshipCanceled)}) {
+		t.Errorf("expected a non-default schedule relationship to be encoded, got %v", fields)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "feed.pb")
+	message := &FeedMessage{Header: &FeedHeader{Version: Version}}
+	for i := 0; i < 2; i++ {
+		err := WriteFile(path, message)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if !reflect.DeepEqual(data, message.Marshal()) {
+		t.Errorf("expected the file to contain the encoded message, got %v", data)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left behind, got %v (%v)", entries, err)
+	}
+}
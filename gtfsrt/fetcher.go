@@ -0,0 +1,113 @@
+package gtfsrt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+const (
+	// ContentType is the media type of the protocol buffer encoding of feed messages.
+	ContentType = "application/x-protobuf"
+	// DefaultRefreshInterval is the time for which a Fetcher serves the same feed message over HTTP by default.
+	DefaultRefreshInterval = 30 * time.Second
+)
+
+// ErrNoStopCodes indicates that a feed containing the arrivals at all stops was requested over HTTP (which is not supported since it requires a request for each stop).
+var ErrNoStopCodes = errors.New("the stops whose arrivals are served are not specified")
+
+// now returns the current time (it is replaced in tests).
+var now = time.Now
+
+// Fetcher fetches the expected arrivals at a set of urban transit stops and converts them into feed messages. It is also an http.Handler serving a feed message with the expected arrivals, which are fetched at most once per refresh interval regardless of the number of requests.
+type Fetcher struct {
+	Client          *virtual.Client       // client for the virtual timetable API (virtual.DefaultClient is used if nil)
+	StopCodes       []string              // codes of the stops whose arrivals are included in the feed (all stops are included if empty, which requires a request for each stop and is therefore not supported by ServeHTTP)
+	AsyncOptions    *virtual.AsyncOptions // options for fetching the timetables of the stops (virtual.DefaultAsyncOptions are used if nil)
+	RefreshInterval time.Duration         // time for which ServeHTTP serves the same feed message before fetching the arrivals again (DefaultRefreshInterval is used if zero)
+
+	mutex         sync.Mutex
+	servedMessage []byte    // encoding of the feed message served by ServeHTTP (nil if none has been fetched yet)
+	servedAt      time.Time // time at which the served feed message was fetched
+}
+
+func (f *Fetcher) getClient() *virtual.Client {
+	if f.Client == nil {
+		return virtual.DefaultClient
+	}
+	return f.Client
+}
+
+// FetchContext fetches the timetables of the stops and returns the feed message containing their expected arrivals (see NewFeedMessage). Timetables which cannot be fetched are skipped (and the errors are logged). Fetching is canceled when ctx is done.
+func (f *Fetcher) FetchContext(ctx context.Context) (message *FeedMessage, err error) {
+	client := f.getClient()
+	var stops virtual.StopList
+	if len(f.StopCodes) == 0 {
+		stops, err = client.GetStopsInLanguageContext(ctx, i18n.LanguageCodeBulgarian)
+		if err != nil {
+			return
+		}
+	} else {
+		stops = make(virtual.StopList, 0, len(f.StopCodes))
+		for _, stopCode := range f.StopCodes {
+			stops = append(stops, &virtual.Stop{Code: stopCode})
+		}
+	}
+
+	timetables := client.GetTimetablesByStopNameAndLineAsyncWithOptions(ctx, stops, "", "", "", false, f.AsyncOptions).Collect()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	message = NewFeedMessage(timetables, now())
+	return
+}
+
+// Fetch fetches the timetables of the stops and returns the feed message containing their expected arrivals (see FetchContext).
+func (f *Fetcher) Fetch() (message *FeedMessage, err error) {
+	return f.FetchContext(context.Background())
+}
+
+// ServeHTTP responds with the protocol buffer encoding of the feed message containing the expected arrivals at the stops. The feed message is fetched anew only if the one served previously is older than the refresh interval; concurrent requests wait for a single fetch. StopCodes must not be empty.
+func (f *Fetcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(f.StopCodes) == 0 {
+		http.Error(w, ErrNoStopCodes.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := f.getServedMessage(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(data)
+}
+
+// getServedMessage returns the encoding of the feed message served by ServeHTTP, fetching the arrivals anew if the message has not been fetched within the refresh interval.
+func (f *Fetcher) getServedMessage(ctx context.Context) (data []byte, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	refreshInterval := f.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+	if f.servedMessage != nil && now().Sub(f.servedAt) < refreshInterval {
+		return f.servedMessage, nil
+	}
+
+	message, err := f.FetchContext(ctx)
+	if err != nil {
+		return
+	}
+
+	f.servedMessage, f.servedAt = message.Marshal(), now()
+	return f.servedMessage, nil
+}
@@ -0,0 +1,139 @@
+package gtfsrt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// newArrivalsServer returns a server which responds to each request for the arrivals at a stop with a single bus arrival, and a function returning the number of requests it has received.
+func newArrivalsServer(t *testing.T) (server *httptest.Server, getRequestCount func() int) {
+	var mutex sync.Mutex
+	requestCount := 0
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requestCount++
+		mutex.Unlock()
+
+		stopCode := strings.Trim(strings.TrimPrefix(r.URL.Path, "/arrivals"), "/")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"code":"` + stopCode + `","name":"Stop","lines":[{"vehicle_type":"bus","name":"94","arrivals":[{"time":"12:05:00"}]}]}`))
+	}))
+	t.Cleanup(server.Close)
+	getRequestCount = func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return requestCount
+	}
+	return
+}
+
+// installFakeNow replaces the clock of the package with one returning the time stored in the result for the duration of the test.
+func installFakeNow(t *testing.T) *time.Time {
+	currentTime := time.Date(2024, time.May, 6, 12, 0, 0, 0, time.Local)
+	previousNow := now
+	now = func() time.Time { return currentTime }
+	t.Cleanup(func() {
+		now = previousNow
+	})
+	return &currentTime
+}
+
+func newTestFetcher(server *httptest.Server, stopCodes ...string) *Fetcher {
+	return &Fetcher{
+		Client:    &virtual.Client{HTTPClient: server.Client(), ResourcesBaseURL: server.URL, ArrivalsBaseURL: server.URL, Header: http.Header{}},
+		StopCodes: stopCodes,
+	}
+}
+
+func serve(t *testing.T, fetcher *Fetcher) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	fetcher.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	return recorder
+}
+
+func TestFetcherFetch(t *testing.T) {
+	currentTime := installFakeNow(t)
+	server, getRequestCount := newArrivalsServer(t)
+	message, err := newTestFetcher(server, "0001", "0002").Fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if getRequestCount() != 2 {
+		t.Errorf("expected a request for each stop, got %d", getRequestCount())
+	}
+	if message.Header.Timestamp != uint64(currentTime.Unix()) || len(message.Entities) != 2 {
+		t.Errorf("expected a message with an entity for each stop created at %s, got %v", currentTime, message)
+	}
+}
+
+func TestFetcherServeHTTPCachesMessage(t *testing.T) {
+	currentTime := installFakeNow(t)
+	server, getRequestCount := newArrivalsServer(t)
+	fetcher := newTestFetcher(server, "0001")
+	fetcher.RefreshInterval = time.Minute
+
+	steps := []struct {
+		name             string
+		advance          time.Duration
+		expectedRequests int
+	}{
+		{name: "first request fetches the arrivals", expectedRequests: 1},
+		{name: "request within the refresh interval is served from the cache", advance: 59 * time.Second, expectedRequests: 1},
+		{name: "request after the refresh interval fetches the arrivals", advance: time.Second, expectedRequests: 2},
+		{name: "next request is served from the cache", expectedRequests: 2},
+	}
+	var previousBody string
+	for _, step := range steps {
+		*currentTime = currentTime.Add(step.advance)
+		recorder := serve(t, fetcher)
+		if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != ContentType {
+			t.Fatalf("%s: unexpected response %d with content type %q", step.name, recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+		if getRequestCount() != step.expectedRequests {
+			t.Errorf("%s: expected %d requests, got %d", step.name, step.expectedRequests, getRequestCount())
+		}
+		if step.advance == 0 && previousBody != "" && recorder.Body.String() != previousBody {
+			t.Errorf("%s: expected the cached message to be served", step.name)
+		}
+		previousBody = recorder.Body.String()
+	}
+}
+
+func TestFetcherServeHTTPConcurrentRequests(t *testing.T) {
+	installFakeNow(t)
+	server, getRequestCount := newArrivalsServer(t)
+	fetcher := newTestFetcher(server, "0001")
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			serve(t, fetcher)
+		}()
+	}
+	waitGroup.Wait()
+
+	if getRequestCount() != 1 {
+		t.Errorf("expected concurrent requests to share a single fetch, got %d requests", getRequestCount())
+	}
+}
+
+func TestFetcherServeHTTPWithoutStopCodes(t *testing.T) {
+	server, getRequestCount := newArrivalsServer(t)
+	recorder := serve(t, newTestFetcher(server))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+	if getRequestCount() != 0 {
+		t.Errorf("expected no arrivals to be fetched, got %d requests", getRequestCount())
+	}
+}
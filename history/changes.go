@@ -0,0 +1,182 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/diff"
+	"github.com/rgeorgiev583/sofiatraffic/format"
+	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// Change represents a change of the urban transit network observed between two consecutive records.
+type Change struct {
+	Time         time.Time `json:"time"` // time of the record in which the change was first observed
+	*diff.Change           // the change itself
+}
+
+// ChangeList represents a list of changes.
+type ChangeList []*Change
+
+// recordState holds the information from a record which is compared with the information from the next record.
+type recordState struct {
+	stops  virtual.StopList
+	routes virtual.VehicleTypeLineNumberRouteListListList
+	lines  schedule.LineList
+}
+
+// GetChangesContext compares every pair of consecutive records in the store and returns the observed changes of the line with the specified vehicleType (as used by the schedule) and lineNumber (or, alternatively, of all lines matching the other criterion if one of them is empty; or of all lines if both are empty) ordered from the oldest to the newest. The changes of the lines and their routes are determined from the routes of the virtual timetable API (see diff.CompareRoutes), while the changes of the operation modes are determined from the schedule lines (see diff.CompareOperationModes), which are only compared if they are contained in both records. The changes of the list of stops are only included if both criteria are empty. The comparison is canceled when ctx is done.
+func (s *Store) GetChangesContext(ctx context.Context, vehicleType string, lineNumber string) (changes ChangeList, err error) {
+	records, err := s.GetRecords()
+	if err != nil {
+		return
+	}
+
+	changes = ChangeList{}
+	doCompareStops := vehicleType == "" && lineNumber == ""
+	var previousState *recordState
+	for _, record := range records {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		state, err := readRecordState(ctx, record, vehicleType, lineNumber)
+		if err != nil {
+			return nil, fmt.Errorf("could not read history record %s: %w", record.Path, err)
+		}
+
+		if previousState != nil {
+			for _, change := range compareRecordStates(previousState, state, doCompareStops) {
+				changes = append(changes, &Change{Time: record.Time, Change: change})
+			}
+		}
+		previousState = state
+	}
+	return
+}
+
+// GetChanges returns the observed changes of the lines matching the specified criteria (see GetChangesContext).
+func (s *Store) GetChanges(vehicleType string, lineNumber string) (changes ChangeList, err error) {
+	return s.GetChangesContext(context.Background(), vehicleType, lineNumber)
+}
+
+func isMatchingLine(lineVehicleType string, lineNumber string, vehicleType string, number string) bool {
+	return (vehicleType == "" || lineVehicleType == vehicleType) && (number == "" || lineNumber == number)
+}
+
+// readRecordState reads the stops and the routes and schedules of the lines matching the specified criteria from record. Schedule lines which are missing from the record (because they could not be fetched when the record was made) are skipped.
+func readRecordState(ctx context.Context, record *Record, vehicleType string, lineNumber string) (state *recordState, err error) {
+	source, err := record.Open()
+	if err != nil {
+		return
+	}
+	defer source.Close()
+
+	stops, err := source.GetStopsInLanguageContext(ctx, i18n.LanguageCodeBulgarian)
+	if err != nil {
+		return
+	}
+
+	routes, err := source.GetRoutesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	lines, err := source.GetLinesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	state = &recordState{stops: stops, routes: virtual.VehicleTypeLineNumberRouteListListList{}, lines: schedule.LineList{}}
+	for _, vehicleTypeRoutes := range routes {
+		routesVehicleType := virtual.GetScheduleVehicleType(vehicleTypeRoutes.VehicleType)
+		matchingRoutes := &virtual.VehicleTypeLineNumberRouteListList{VehicleType: vehicleTypeRoutes.VehicleType, LineNumberRouteListList: virtual.LineNumberRouteListList{}}
+		for _, lineRoutes := range vehicleTypeRoutes.LineNumberRouteListList {
+			if isMatchingLine(routesVehicleType, lineRoutes.LineNumber, vehicleType, lineNumber) {
+				matchingRoutes.LineNumberRouteListList = append(matchingRoutes.LineNumberRouteListList, lineRoutes)
+			}
+		}
+		state.routes = append(state.routes, matchingRoutes)
+	}
+
+	readLines := func(lineVehicleType string, lineNumbers []string) error {
+		for _, number := range lineNumbers {
+			if !isMatchingLine(lineVehicleType, number, vehicleType, lineNumber) {
+				continue
+			}
+
+			line, err := source.GetLineContext(ctx, lineVehicleType, number)
+			if errors.Is(err, datasource.ErrMissingResource) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			state.lines = append(state.lines, line)
+		}
+		return nil
+	}
+	for _, vehicleTypeLineNumbers := range lines.VehicleTypeLineNumbersList {
+		err = readLines(vehicleTypeLineNumbers.VehicleType, vehicleTypeLineNumbers.LineNumbers)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// compareRecordStates returns the changes observed between previousState and state. The changes of the list of stops are only included if doCompareStops is true.
+func compareRecordStates(previousState *recordState, state *recordState, doCompareStops bool) (changes diff.ChangeList) {
+	changes = diff.ChangeList{}
+	if doCompareStops {
+		changes = append(changes, diff.CompareStops(previousState.stops, state.stops)...)
+	}
+
+	// stops which have been removed are still named after their previous names
+	stops := previousState.stops.GetStopMap()
+	for code, stop := range state.stops.GetStopMap() {
+		stops[code] = stop
+	}
+	changes = append(changes, diff.CompareRoutes(previousState.routes, state.routes, stops)...)
+
+	previousLines := map[string]*schedule.Line{}
+	for _, line := range previousState.lines {
+		previousLines[line.VehicleType+"/"+line.LineNumber] = line
+	}
+	for _, line := range state.lines {
+		previousLine, ok := previousLines[line.VehicleType+"/"+line.LineNumber]
+		if ok {
+			changes = append(changes, diff.CompareOperationModes(previousLine, line)...)
+		}
+	}
+	return
+}
+
+func (c *Change) String() string {
+	return c.Time.Local().Format("2006-01-02 15:04") + "  " + c.Change.String()
+}
+
+func (cl ChangeList) String() string {
+	var builder strings.Builder
+	for _, change := range cl {
+		builder.WriteString(change.String() + "\n")
+	}
+	return builder.String()
+}
+
+// Table returns the tabular representation of the list of changes.
+func (cl ChangeList) Table() *format.Table {
+	table := &format.Table{Header: append([]string{"time"}, diff.TableHeader...)}
+	for _, change := range cl {
+		table.Rows = append(table.Rows, append([]string{change.Time.Format(time.RFC3339)}, change.TableRow()...))
+	}
+	return table
+}
@@ -0,0 +1,143 @@
+package history
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/diff"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/snapshot"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// testRecord describes the contents of a record of a network of bus lines.
+type testRecord struct {
+	time   time.Time
+	stops  virtual.StopList
+	routes map[string][]string // maps the number of each bus line to the codes of the stops of its only route (the lines are ordered by number)
+	lines  []*schedule.Line    // schedule lines
+}
+
+func writeTestRecord(t *testing.T, store *Store, record *testRecord) {
+	w, err := snapshot.NewDirWriter(filepath.Join(store.Dir, record.time.Format(RecordNameLayout)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	busRoutes := &virtual.VehicleTypeLineNumberRouteListList{VehicleType: virtual.VehicleTypeBus}
+	lineNumbers := make([]string, 0, len(record.routes))
+	for lineNumber := range record.routes {
+		lineNumbers = append(lineNumbers, lineNumber)
+	}
+	sort.Strings(lineNumbers)
+	for _, lineNumber := range lineNumbers {
+		busRoutes.LineNumberRouteListList = append(busRoutes.LineNumberRouteListList, &virtual.LineNumberRouteList{LineNumber: lineNumber, RouteList: virtual.RouteList{{StopCodes: record.routes[lineNumber]}}})
+	}
+	lines := &schedule.Lines{}
+	for _, line := range record.lines {
+		lines.VehicleTypeLineNumbersList = append(lines.VehicleTypeLineNumbersList, &schedule.VehicleTypeLineNumbers{VehicleType: line.VehicleType, LineNumbers: []string{line.LineNumber}})
+	}
+	files := []struct {
+		path  string
+		value interface{}
+	}{
+		{path: snapshot.GetStopsPath("bg"), value: record.stops},
+		{path: snapshot.RoutesPath, value: virtual.VehicleTypeLineNumberRouteListListList{busRoutes}},
+		{path: snapshot.LinesPath, value: lines},
+	}
+	for _, line := range record.lines {
+		files = append(files, struct {
+			path  string
+			value interface{}
+		}{path: snapshot.GetLinePath(line.VehicleType, line.LineNumber), value: line})
+	}
+	for _, file := range files {
+		err = w.WriteJSON(&snapshot.File{Path: file.path}, file.value)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+}
+
+func newTestLine(lineNumber string, operationModeNames ...string) (line *schedule.Line) {
+	line = &schedule.Line{VehicleType: schedule.VehicleTypeBus, LineNumber: lineNumber}
+	for _, name := range operationModeNames {
+		line.OperationModeRoutesList = append(line.OperationModeRoutesList, &schedule.OperationModeRoutes{OperationMode: &schedule.OperationMode{Code: name, Name: name}})
+	}
+	return
+}
+
+func TestGetChanges(t *testing.T) {
+	store := NewStore(t.TempDir())
+	firstTime := time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC)
+	secondTime := firstTime.Add(24 * time.Hour)
+	records := []*testRecord{
+		{
+			time:   firstTime,
+			stops:  virtual.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ЦЕНТРАЛНА ГАРА"}},
+			routes: map[string][]string{"94": {"0001", "0002"}},
+			lines:  []*schedule.Line{newTestLine("94", "делник")},
+		},
+		{
+			time:   secondTime,
+			stops:  virtual.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ЦЕНТРАЛНА ГАРА"}, {Code: "0003", Name: "ОБЕЛЯ"}},
+			routes: map[string][]string{"94": {"0001", "0003", "0002"}, "280": {"0003", "0002"}},
+			lines:  []*schedule.Line{newTestLine("94", "делник", "празник")},
+		},
+	}
+	for _, record := range records {
+		writeTestRecord(t, store, record)
+	}
+
+	testCases := []struct {
+		name            string
+		vehicleType     string
+		lineNumber      string
+		expectedChanges ChangeList
+	}{
+		{
+			name: "all lines",
+			expectedChanges: ChangeList{
+				{Time: secondTime, Change: &diff.Change{Kind: diff.ChangeKindStopAdded, Stop: &schedule.Stop{Code: "0003", Name: "ОБЕЛЯ"}}},
+				{Time: secondTime, Change: &diff.Change{Kind: diff.ChangeKindLineAdded, VehicleType: schedule.VehicleTypeBus, LineNumber: "280"}},
+				{Time: secondTime, Change: &diff.Change{Kind: diff.ChangeKindStopInserted, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", Route: "ЖК МЛАДОСТ 3 - ЦЕНТРАЛНА ГАРА", Stop: &schedule.Stop{Code: "0003", Name: "ОБЕЛЯ"}}},
+				{Time: secondTime, Change: &diff.Change{Kind: diff.ChangeKindOperationModeAdded, VehicleType: schedule.VehicleTypeBus, LineNumber: "94", OperationMode: "празник"}},
+			},
+		},
+		{
+			name:        "line matched by the vehicle type used by the schedule",
+			vehicleType: schedule.VehicleTypeBus,
+			lineNumber:  "280",
+			expectedChanges: ChangeList{
+				{Time: secondTime, Change: &diff.Change{Kind: diff.ChangeKindLineAdded, VehicleType: schedule.VehicleTypeBus, LineNumber: "280"}},
+			},
+		},
+		{name: "line of another vehicle type", vehicleType: schedule.VehicleTypeTram, lineNumber: "94", expectedChanges: ChangeList{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			changes, err := store.GetChanges(testCase.vehicleType, testCase.lineNumber)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(changes) != len(testCase.expectedChanges) {
+				t.Fatalf("expected changes %v, got %v", testCase.expectedChanges, changes)
+			}
+			for i, change := range changes {
+				if !reflect.DeepEqual(change, testCase.expectedChanges[i]) {
+					t.Errorf("expected change %d to be %v, got %v", i, testCase.expectedChanges[i], change)
+				}
+			}
+		})
+	}
+}
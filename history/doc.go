@@ -0,0 +1,4 @@
+/*
+Package history implements keeping of timestamped records of the urban transit stops, routes and schedule lines (as snapshots produced by the `snapshot` package) and detection of the changes of the urban transit network between them.
+*/
+package history
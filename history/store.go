@@ -0,0 +1,109 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/snapshot"
+)
+
+// RecordNameLayout is the layout of the names of the record directories, which are the times at which the records were made (in UTC).
+const RecordNameLayout = "20060102T150405Z"
+
+const defaultHistoryDirName = "sofiatraffic/history"
+
+// Store keeps timestamped records of the stops, routes and schedule lines in a directory.
+type Store struct {
+	Dir string // directory containing a snapshot directory for each record
+}
+
+// Record represents a record kept in a Store.
+type Record struct {
+	Time time.Time // time at which the record was made
+	Path string    // path of the snapshot directory of the record
+}
+
+// GetDefaultDir returns the directory in which the history is kept by default, which is located in the user-specific configuration directory.
+func GetDefaultDir() (dir string, err error) {
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return
+	}
+
+	dir = filepath.Join(userConfigDir, filepath.FromSlash(defaultHistoryDirName))
+	return
+}
+
+// NewStore returns a Store which keeps the records in the specified directory (which is created when the first record is made).
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// GetRecords returns all records in the store ordered from the oldest to the newest. Entries of the directory whose names do not follow RecordNameLayout are ignored.
+func (s *Store) GetRecords() (records []*Record, err error) {
+	dirEntries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []*Record{}, nil
+	}
+	if err != nil {
+		return
+	}
+
+	records = []*Record{}
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+
+		recordTime, err := time.Parse(RecordNameLayout, dirEntry.Name())
+		if err != nil {
+			continue
+		}
+
+		records = append(records, &Record{Time: recordTime, Path: filepath.Join(s.Dir, dirEntry.Name())})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return
+}
+
+// AddRecordContext makes a new record of the current stops, routes and schedule lines using exporter (schedule timetables are never included) and returns it. A partially written record is removed if the export fails. The export is canceled when ctx is done.
+func (s *Store) AddRecordContext(ctx context.Context, exporter *snapshot.Exporter) (record *Record, err error) {
+	recordTime := time.Now().UTC().Truncate(time.Second)
+	record = &Record{Time: recordTime, Path: filepath.Join(s.Dir, recordTime.Format(RecordNameLayout))}
+	writer, err := snapshot.NewDirWriter(record.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	recordExporter := *exporter
+	recordExporter.DoIncludeTimetables = false
+	err = recordExporter.ExportContext(ctx, writer)
+	closeErr := writer.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.RemoveAll(record.Path)
+		return nil, err
+	}
+
+	return
+}
+
+// AddRecord makes a new record of the current stops, routes and schedule lines (see AddRecordContext).
+func (s *Store) AddRecord(exporter *snapshot.Exporter) (record *Record, err error) {
+	return s.AddRecordContext(context.Background(), exporter)
+}
+
+// Open returns a data source reading the snapshot of the record. The returned data source should be closed after use.
+func (r *Record) Open() (source *datasource.Snapshot, err error) {
+	return datasource.OpenSnapshot(r.Path)
+}
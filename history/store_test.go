@@ -0,0 +1,57 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetRecords(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20240506T120000Z", "20240101T000000Z", "not-a-record", "20240301T080000Z"} {
+		err := os.Mkdir(filepath.Join(dir, name), 0755)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	err := os.WriteFile(filepath.Join(dir, "20240401T000000Z"), nil, 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	testCases := []struct {
+		name          string
+		dir           string
+		expectedTimes []time.Time
+	}{
+		{
+			name: "records ordered by time",
+			dir:  dir,
+			expectedTimes: []time.Time{
+				time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC),
+				time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{name: "missing directory", dir: filepath.Join(dir, "missing")},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			records, err := NewStore(testCase.dir).GetRecords()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if len(records) != len(testCase.expectedTimes) {
+				t.Fatalf("expected %d records, got %d", len(testCase.expectedTimes), len(records))
+			}
+			for i, record := range records {
+				if !record.Time.Equal(testCase.expectedTimes[i]) || record.Path != filepath.Join(testCase.dir, testCase.expectedTimes[i].Format(RecordNameLayout)) {
+					t.Errorf("unexpected record %d: %v", i, record)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,4 @@
+/*
+Package netex implements generation of NeTEx (Network Timetable Exchange) publications conforming to the European Passenger Information Profile (EPIP) from the stops, routes and schedules of the urban transit lines. The trips described by the publications are inferred from the schedule timetables by the `gtfs` package.
+*/
+package netex
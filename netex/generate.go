@@ -0,0 +1,404 @@
+package netex
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/gtfs"
+	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+const dateLayout = "2006-01-02"
+
+// transportModes maps each vehicle type (as used by the schedule) to the NeTEx mode of transport of the vehicles of that type.
+var transportModes = map[string]string{
+	schedule.VehicleTypeBus:        "bus",
+	schedule.VehicleTypeTrolleybus: "trolleyBus",
+	schedule.VehicleTypeTram:       "tram",
+	schedule.VehicleTypeMetro:      "metro",
+}
+
+// daysOfWeek maps each day type to the days of the week which have that type by default.
+var daysOfWeek = map[calendar.DayType]string{
+	calendar.DayTypeWeekday:    "Monday Tuesday Wednesday Thursday Friday",
+	calendar.DayTypePreHoliday: "Saturday",
+	calendar.DayTypeHoliday:    "Sunday",
+}
+
+// Generator generates NeTEx publications from the stops, routes and schedules of the urban transit lines.
+type Generator struct {
+	Source     datasource.DataSource                 // source of the stops, routes, schedule lines and schedule timetables (the live APIs are used if nil)
+	Agency     *gtfs.Agency                          // agency operating the lines, whose identifier is used as the codespace of the publication (gtfs.DefaultAgency is used if nil)
+	StartDate  time.Time                             // first day when the publication is valid (the current day is used if zero)
+	EndDate    time.Time                             // last day when the publication is valid (the day before the same day in the year after StartDate is used if zero)
+	GetDayType func(date time.Time) calendar.DayType // determines the type of each day when the publication is valid (calendar.GetDayType, which takes the official holidays into account, is used if nil)
+}
+
+// generation holds the state of the generation of a single publication.
+type generation struct {
+	*Generator
+	source            datasource.DataSource
+	codespace         string
+	operatorRef       *Ref
+	serviceFrame      *ServiceFrame
+	timetableFrame    *TimetableFrame
+	stopPointMap      map[string]*ScheduledStopPoint
+	lineMap           map[string]*Line
+	routeMap          map[string]*Route
+	routeCounts       map[string]int
+	journeyPatternMap map[string]*ServiceJourneyPattern
+}
+
+func getDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// formatTime formats a time measured from the start of the service day as a time of day in the HH:MM:SS format and the number of days after the start of the service day.
+func formatTime(duration time.Duration) (timeOfDay string, dayOffset int) {
+	dayOffset = int(duration / (24 * time.Hour))
+	duration -= time.Duration(dayOffset) * 24 * time.Hour
+	timeOfDay = fmt.Sprintf("%02d:%02d:%02d", int(duration/time.Hour), int(duration/time.Minute%60), int(duration/time.Second%60))
+	return
+}
+
+// getID returns the identifier of the object of the specified type with the specified identifier local to the codespace of the publication.
+func (gen *generation) getID(objectType string, localID string) string {
+	return gen.codespace + ":" + objectType + ":" + localID
+}
+
+func (gen *generation) getRef(objectType string, localID string) *Ref {
+	return &Ref{Ref: gen.getID(objectType, localID), Version: ObjectVersion}
+}
+
+// GenerateContext generates a NeTEx publication containing all stops and routes and all lines of the schedule. The service journeys are inferred from the timetables of the stops of each route of the schedule. Lines and timetables which cannot be obtained are skipped (and the errors are logged). The generation is canceled when ctx is done.
+func (g *Generator) GenerateContext(ctx context.Context) (publication *PublicationDelivery, err error) {
+	agency := g.Agency
+	if agency == nil {
+		agency = gtfs.DefaultAgency
+	}
+
+	gen := &generation{
+		Generator:         g,
+		source:            g.Source,
+		codespace:         agency.ID,
+		stopPointMap:      map[string]*ScheduledStopPoint{},
+		lineMap:           map[string]*Line{},
+		routeMap:          map[string]*Route{},
+		routeCounts:       map[string]int{},
+		journeyPatternMap: map[string]*ServiceJourneyPattern{},
+	}
+	if gen.source == nil {
+		gen.source = datasource.NewLive(nil, nil)
+	}
+	gen.operatorRef = gen.getRef("Operator", agency.ID)
+	gen.serviceFrame = &ServiceFrame{ID: gen.getID("ServiceFrame", "1"), Version: ObjectVersion}
+	gen.timetableFrame = &TimetableFrame{ID: gen.getID("TimetableFrame", "1"), Version: ObjectVersion}
+
+	stops, err := gen.source.GetStopsInLanguageContext(ctx, i18n.LanguageCodeBulgarian)
+	if err != nil {
+		return
+	}
+
+	for _, stop := range stops {
+		gen.addStopPoint(stop.Code, stop.Name)
+	}
+
+	routes, err := gen.source.GetRoutesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, vehicleTypeRoutes := range routes {
+		vehicleType := virtual.GetScheduleVehicleType(vehicleTypeRoutes.VehicleType)
+		for _, lineRoutes := range vehicleTypeRoutes.LineNumberRouteListList {
+			line := gen.addLine(vehicleType, lineRoutes.LineNumber)
+			for _, route := range lineRoutes.RouteList {
+				gen.addRoute(line, route.StopCodes, "")
+			}
+		}
+	}
+
+	lines, err := gen.source.GetLinesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	addLines := func(vehicleType string, lineNumbers []string) error {
+		for _, lineNumber := range lineNumbers {
+			err := gen.addScheduleLine(ctx, vehicleType, lineNumber)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, vehicleTypeLineNumbers := range lines.VehicleTypeLineNumbersList {
+		err = addLines(vehicleTypeLineNumbers.VehicleType, vehicleTypeLineNumbers.LineNumbers)
+		if err != nil {
+			return
+		}
+	}
+
+	startDate := getDate(g.StartDate)
+	if g.StartDate.IsZero() {
+		startDate = getDate(time.Now())
+	}
+	endDate := getDate(g.EndDate)
+	if g.EndDate.IsZero() {
+		endDate = startDate.AddDate(1, 0, -1)
+	}
+
+	publication = &PublicationDelivery{
+		Version:              Version,
+		PublicationTimestamp: time.Now().Truncate(time.Second),
+		ParticipantRef:       agency.ID,
+		CompositeFrame: &CompositeFrame{
+			ID:             gen.getID("CompositeFrame", "1"),
+			Version:        ObjectVersion,
+			ValidBetween:   &ValidBetween{FromDate: startDate, ToDate: endDate.AddDate(0, 0, 1).Add(-time.Second)},
+			TypeOfFrameRef: &TypeOfFrameRef{Ref: ProfileFrameRef, VersionRef: ProfileVersion},
+			Codespaces:     []*Codespace{{ID: strings.ToLower(agency.ID), Xmlns: agency.ID, XmlnsURL: agency.URL}},
+			FrameDefaults:  &FrameDefaults{DefaultLocale: &DefaultLocale{TimeZone: agency.Timezone, DefaultLanguage: agency.Language}},
+			ResourceFrame: &ResourceFrame{
+				ID:        gen.getID("ResourceFrame", "1"),
+				Version:   ObjectVersion,
+				Operators: []*Operator{{ID: gen.operatorRef.Ref, Version: ObjectVersion, Name: agency.Name, URL: agency.URL}},
+			},
+			ServiceCalendarFrame: gen.getServiceCalendarFrame(startDate, endDate),
+			ServiceFrame:         gen.serviceFrame,
+			TimetableFrame:       gen.timetableFrame,
+		},
+	}
+	return
+}
+
+// Generate generates a NeTEx publication containing all stops and routes and all lines of the schedule (see GenerateContext).
+func (g *Generator) Generate() (publication *PublicationDelivery, err error) {
+	return g.GenerateContext(context.Background())
+}
+
+// getServiceCalendarFrame returns the frame containing all day types along with assignments of day types to the dates between startDate and endDate whose type (as determined by GetDayType) differs from their default type.
+func (gen *generation) getServiceCalendarFrame(startDate time.Time, endDate time.Time) (frame *ServiceCalendarFrame) {
+	frame = &ServiceCalendarFrame{ID: gen.getID("ServiceCalendarFrame", "1"), Version: ObjectVersion}
+	for _, dayType := range calendar.DayTypes {
+		frame.DayTypes = append(frame.DayTypes, &DayType{
+			ID:         gen.getID("DayType", dayType.String()),
+			Version:    ObjectVersion,
+			Name:       l10n.BulgarianTranslator[dayType.String()],
+			Properties: []*PropertyOfDay{{DaysOfWeek: daysOfWeek[dayType]}},
+		})
+	}
+
+	getDayType := gen.GetDayType
+	if getDayType == nil {
+		getDayType = calendar.GetDayType
+	}
+	var assignments []*DayTypeAssignment
+	addAssignment := func(date time.Time, dayType calendar.DayType, isAvailable bool) {
+		order := len(assignments) + 1
+		assignments = append(assignments, &DayTypeAssignment{
+			ID:          gen.getID("DayTypeAssignment", strconv.Itoa(order)),
+			Version:     ObjectVersion,
+			Order:       order,
+			Date:        date.Format(dateLayout),
+			DayTypeRef:  gen.getRef("DayType", dayType.String()),
+			IsAvailable: isAvailable,
+		})
+	}
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		defaultDayType := calendar.GetDefaultDayType(date)
+		dayType := getDayType(date)
+		if dayType == defaultDayType {
+			continue
+		}
+
+		addAssignment(date, dayType, true)
+		addAssignment(date, defaultDayType, false)
+	}
+	if len(assignments) > 0 {
+		frame.DayTypeAssignments = &DayTypeAssignments{DayTypeAssignments: assignments}
+	}
+	return
+}
+
+// addStopPoint adds the scheduled stop point with the specified code and name (along with the route point projected onto it) to the publication unless it has already been added.
+func (gen *generation) addStopPoint(code string, name string) {
+	if _, ok := gen.stopPointMap[code]; ok {
+		return
+	}
+
+	stopPoint := &ScheduledStopPoint{ID: gen.getID("ScheduledStopPoint", code), Version: ObjectVersion, Name: name, PublicCode: code}
+	gen.stopPointMap[code] = stopPoint
+	gen.serviceFrame.ScheduledStopPoints = append(gen.serviceFrame.ScheduledStopPoints, stopPoint)
+	gen.serviceFrame.RoutePoints = append(gen.serviceFrame.RoutePoints, &RoutePoint{
+		ID:          gen.getID("RoutePoint", code),
+		Version:     ObjectVersion,
+		Projections: []*PointProjection{{ID: gen.getID("PointProjection", code), Version: ObjectVersion, ProjectToPointRef: gen.getRef("ScheduledStopPoint", code)}},
+	})
+}
+
+// addLine adds the line with the specified vehicleType (as used by the schedule) and lineNumber to the publication unless it has already been added, and returns it.
+func (gen *generation) addLine(vehicleType string, lineNumber string) (line *Line) {
+	lineID := gen.getID("Line", gtfs.GetRouteID(vehicleType, lineNumber))
+	line, ok := gen.lineMap[lineID]
+	if ok {
+		return
+	}
+
+	line = &Line{ID: lineID, Version: ObjectVersion, Name: lineNumber, TransportMode: transportModes[vehicleType], PublicCode: lineNumber, OperatorRef: gen.operatorRef}
+	gen.lineMap[lineID] = line
+	gen.serviceFrame.Lines = append(gen.serviceFrame.Lines, line)
+	return
+}
+
+// getLocalID returns the identifier of the object with the specified identifier local to the codespace of the publication.
+func (gen *generation) getLocalID(id string) string {
+	return id[strings.LastIndex(id, ":")+1:]
+}
+
+// addRoute adds the route of line visiting the stops with the specified stopCodes to the publication unless a route of line visiting the same stops has already been added, and returns it. The name of the route is set to name unless the latter is empty; the names of the first and the last stop are used for routes without a name.
+func (gen *generation) addRoute(line *Line, stopCodes []string, name string) (route *Route) {
+	routeKey := line.ID + "/" + strings.Join(stopCodes, ",")
+	route, ok := gen.routeMap[routeKey]
+	if ok {
+		if name != "" {
+			route.Name = name
+		}
+		return
+	}
+
+	lineLocalID := gen.getLocalID(line.ID)
+	gen.routeCounts[lineLocalID]++
+	routeLocalID := lineLocalID + "-" + strconv.Itoa(gen.routeCounts[lineLocalID])
+	route = &Route{ID: gen.getID("Route", routeLocalID), Version: ObjectVersion, Name: name, LineRef: &Ref{Ref: line.ID, Version: ObjectVersion}}
+	for index, stopCode := range stopCodes {
+		route.PointsInSequence = append(route.PointsInSequence, &PointOnRoute{
+			ID:            gen.getID("PointOnRoute", routeLocalID+"-"+strconv.Itoa(index+1)),
+			Version:       ObjectVersion,
+			Order:         index + 1,
+			RoutePointRef: gen.getRef("RoutePoint", stopCode),
+		})
+	}
+	if route.Name == "" && len(stopCodes) > 0 {
+		firstStopPoint, lastStopPoint := gen.stopPointMap[stopCodes[0]], gen.stopPointMap[stopCodes[len(stopCodes)-1]]
+		if firstStopPoint != nil && lastStopPoint != nil {
+			route.Name = firstStopPoint.Name + " - " + lastStopPoint.Name
+		}
+	}
+	gen.routeMap[routeKey] = route
+	gen.serviceFrame.Routes = append(gen.serviceFrame.Routes, route)
+	return
+}
+
+// addScheduleLine adds the routes, journey patterns and service journeys of the line with the specified vehicleType and lineNumber to the publication. Only errors caused by ctx being done are returned; other errors are logged.
+func (gen *generation) addScheduleLine(ctx context.Context, vehicleType string, lineNumber string) (err error) {
+	scheduleLine, fetchErr := gen.source.GetLineContext(ctx, vehicleType, lineNumber)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if fetchErr != nil {
+		log.Println(fetchErr.Error())
+		return
+	}
+
+	line := gen.addLine(vehicleType, lineNumber)
+	for _, operationModeRoutes := range scheduleLine.OperationModeRoutesList {
+		operationModeDayTypes := calendar.GetLineOperationModeDayTypes(scheduleLine, operationModeRoutes)
+		if len(operationModeDayTypes) == 0 {
+			log.Printf("could not determine the days of operation mode %s (%s) of line %s", operationModeRoutes.Name, operationModeRoutes.Code, line.ID)
+			continue
+		}
+
+		dayTypeRefs := make([]*Ref, 0, len(operationModeDayTypes))
+		for _, dayType := range operationModeDayTypes {
+			dayTypeRefs = append(dayTypeRefs, gen.getRef("DayType", dayType.String()))
+		}
+		for _, scheduleRoute := range operationModeRoutes.RouteList {
+			err = gen.addServiceJourneys(ctx, line, operationModeRoutes.OperationMode, dayTypeRefs, scheduleRoute)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// addServiceJourneys adds the route and the journey pattern corresponding to scheduleRoute and the service journeys inferred from the timetables of its stops to the publication.
+func (gen *generation) addServiceJourneys(ctx context.Context, line *Line, operationMode *schedule.OperationMode, dayTypeRefs []*Ref, scheduleRoute *schedule.Route) (err error) {
+	stopCodes := make([]string, 0, len(scheduleRoute.StopList))
+	for _, scheduleStop := range scheduleRoute.StopList {
+		gen.addStopPoint(scheduleStop.Code, scheduleStop.Name)
+		stopCodes = append(stopCodes, scheduleStop.Code)
+	}
+	route := gen.addRoute(line, stopCodes, scheduleRoute.Name)
+
+	lineLocalID := gen.getLocalID(line.ID)
+	journeyPatternLocalID := lineLocalID + "-" + scheduleRoute.Code
+	journeyPatternID := gen.getID("ServiceJourneyPattern", journeyPatternLocalID)
+	if _, ok := gen.journeyPatternMap[journeyPatternID]; !ok {
+		journeyPattern := &ServiceJourneyPattern{ID: journeyPatternID, Version: ObjectVersion, Name: scheduleRoute.Name, RouteRef: &Ref{Ref: route.ID, Version: ObjectVersion}}
+		for index, stopCode := range stopCodes {
+			journeyPattern.PointsInSequence = append(journeyPattern.PointsInSequence, &StopPointInJourneyPattern{
+				ID:                    gen.getID("StopPointInJourneyPattern", journeyPatternLocalID+"-"+strconv.Itoa(index+1)),
+				Version:               ObjectVersion,
+				Order:                 index + 1,
+				ScheduledStopPointRef: gen.getRef("ScheduledStopPoint", stopCode),
+			})
+		}
+		gen.journeyPatternMap[journeyPatternID] = journeyPattern
+		gen.serviceFrame.JourneyPatterns = append(gen.serviceFrame.JourneyPatterns, journeyPattern)
+	}
+
+	departureTimesList := make([][]time.Duration, 0, len(stopCodes))
+	for _, stopCode := range stopCodes {
+		timetable, fetchErr := gen.source.GetTimetableContext(ctx, operationMode.Code, scheduleRoute.Code, stopCode)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if fetchErr != nil {
+			log.Println(fetchErr.Error())
+			departureTimesList = append(departureTimesList, nil)
+			continue
+		}
+
+		departureTimesList = append(departureTimesList, gtfs.GetDepartureTimes(timetable))
+	}
+
+	journeyIDPrefix := lineLocalID + "-" + operationMode.Code + "-" + scheduleRoute.Code + "-"
+	journeyCount := 0
+	for _, tripStops := range gtfs.InferTrips(departureTimesList) {
+		if len(tripStops) < 2 {
+			continue
+		}
+
+		journeyCount++
+		journey := &ServiceJourney{
+			ID:                       gen.getID("ServiceJourney", journeyIDPrefix+strconv.Itoa(journeyCount)),
+			Version:                  ObjectVersion,
+			DayTypes:                 dayTypeRefs,
+			ServiceJourneyPatternRef: &Ref{Ref: journeyPatternID, Version: ObjectVersion},
+			LineRef:                  &Ref{Ref: line.ID, Version: ObjectVersion},
+		}
+		for _, tripStop := range tripStops {
+			departureTime, dayOffset := formatTime(tripStop.Time)
+			journey.PassingTimes = append(journey.PassingTimes, &TimetabledPassingTime{
+				Version:                      ObjectVersion,
+				StopPointInJourneyPatternRef: gen.getRef("StopPointInJourneyPattern", journeyPatternLocalID+"-"+strconv.Itoa(tripStop.StopIndex+1)),
+				DepartureTime:                departureTime,
+				DepartureDayOffset:           dayOffset,
+			})
+		}
+		gen.timetableFrame.ServiceJourneys = append(gen.timetableFrame.ServiceJourneys, journey)
+	}
+	return
+}
@@ -0,0 +1,238 @@
+package netex
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// testSource is a DataSource serving the stops, routes, schedule lines and timetables stored in its fields.
+type testSource struct {
+	stops      virtual.StopList
+	routes     virtual.VehicleTypeLineNumberRouteListListList
+	lines      []*schedule.Line
+	timetables map[string]schedule.Timetable // maps the operation mode, route and stop codes (joined by slashes) to the timetable
+}
+
+func (s *testSource) GetStopsInLanguageContext(ctx context.Context, language string) (virtual.StopList, error) {
+	return s.stops, nil
+}
+
+func (s *testSource) GetRoutesContext(ctx context.Context) (virtual.VehicleTypeLineNumberRouteListListList, error) {
+	return s.routes, nil
+}
+
+func (s *testSource) GetLinesContext(ctx context.Context) (lines *schedule.Lines, err error) {
+	lines = &schedule.Lines{}
+	for _, line := range s.lines {
+		lines.VehicleTypeLineNumbersList = append(lines.VehicleTypeLineNumbersList, &schedule.VehicleTypeLineNumbers{VehicleType: line.VehicleType, LineNumbers: []string{line.LineNumber}})
+	}
+	return
+}
+
+func (s *testSource) GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (*schedule.Line, error) {
+	for _, line := range s.lines {
+		if line.VehicleType == vehicleType && line.LineNumber == lineNumber {
+			return line, nil
+		}
+	}
+	return nil, &schedule.UnknownLineError{VehicleType: vehicleType, LineNumber: lineNumber}
+}
+
+func (s *testSource) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (schedule.Timetable, error) {
+	timetable, ok := s.timetables[operationModeCode+"/"+routeCode+"/"+stopCode]
+	if !ok {
+		return nil, &schedule.UnknownStopError{OperationModeCode: operationModeCode, RouteCode: routeCode, StopCode: stopCode}
+	}
+	return timetable, nil
+}
+
+func newTestTimetable(departureTimes ...time.Duration) (timetable schedule.Timetable) {
+	for _, departureTime := range departureTimes {
+		timetable = append(timetable, &schedule.Departure{Time: departureTime})
+	}
+	return
+}
+
+// newTestSource returns a testSource serving bus line 94, which has a weekday route from ЖК МЛАДОСТ 3 to ЦЕНТРАЛНА ГАРА with a trip in the evening and a trip after midnight. The virtual routes of the line contain the same route and a route in the opposite direction.
+func newTestSource() *testSource {
+	return &testSource{
+		stops: virtual.StopList{
+			{Code: "0001", Name: "ЖК МЛАДОСТ 3"},
+			{Code: "0002", Name: "ЦЕНТРАЛНА ГАРА"},
+		},
+		routes: virtual.VehicleTypeLineNumberRouteListListList{{
+			VehicleType: virtual.VehicleTypeBus,
+			LineNumberRouteListList: virtual.LineNumberRouteListList{{
+				LineNumber: "94",
+				RouteList:  virtual.RouteList{{StopCodes: []string{"0001", "0002"}}, {StopCodes: []string{"0002", "0001"}}},
+			}},
+		}},
+		lines: []*schedule.Line{{
+			VehicleType: schedule.VehicleTypeBus,
+			LineNumber:  "94",
+			OperationModeRoutesList: schedule.OperationModeRoutesList{{
+				OperationMode: &schedule.OperationMode{Code: "1", Name: "делник"},
+				RouteList: schedule.RouteList{
+					{Code: "10", Name: "ЖК МЛАДОСТ 3 - ЦЕНТРАЛНА ГАРА", StopList: schedule.StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ЦЕНТРАЛНА ГАРА"}}},
+				},
+			}},
+		}},
+		timetables: map[string]schedule.Timetable{
+			"1/10/0001": newTestTimetable(23*time.Hour+50*time.Minute, 24*time.Hour+20*time.Minute),
+			"1/10/0002": newTestTimetable(24*time.Hour+10*time.Minute, 24*time.Hour+40*time.Minute),
+		},
+	}
+}
+
+func newTestGenerator(source *testSource) *Generator {
+	return &Generator{
+		Source:    source,
+		StartDate: time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, time.May, 12, 0, 0, 0, 0, time.UTC),
+		GetDayType: func(date time.Time) calendar.DayType {
+			if date.Day() == 6 {
+				return calendar.DayTypeHoliday
+			}
+			return calendar.GetDefaultDayType(date)
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	publication, err := newTestGenerator(newTestSource()).Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	frame := publication.CompositeFrame
+	if frame.Codespaces[0].Xmlns != "CGM" || !frame.ValidBetween.ToDate.Equal(time.Date(2024, time.May, 12, 23, 59, 59, 0, time.UTC)) {
+		t.Errorf("unexpected codespace %v or validity %v", frame.Codespaces[0], frame.ValidBetween)
+	}
+
+	serviceFrame := frame.ServiceFrame
+	if len(serviceFrame.Lines) != 1 || serviceFrame.Lines[0].ID != "CGM:Line:autobus-94" || serviceFrame.Lines[0].TransportMode != "bus" {
+		t.Fatalf("expected the virtual and the schedule routes to belong to the bus line autobus-94, got %v", serviceFrame.Lines)
+	}
+	if len(serviceFrame.ScheduledStopPoints) != 2 || len(serviceFrame.RoutePoints) != 2 {
+		t.Errorf("expected a stop point and a route point for each stop, got %v and %v", serviceFrame.ScheduledStopPoints, serviceFrame.RoutePoints)
+	}
+
+	var routeNames []string
+	for _, route := range serviceFrame.Routes {
+		routeNames = append(routeNames, route.ID+"="+route.Name)
+	}
+	expectedRouteNames := []string{"CGM:Route:autobus-94-1=ЖК МЛАДОСТ 3 - ЦЕНТРАЛНА ГАРА", "CGM:Route:autobus-94-2=ЦЕНТРАЛНА ГАРА - ЖК МЛАДОСТ 3"}
+	if !reflect.DeepEqual(routeNames, expectedRouteNames) {
+		t.Errorf("expected the schedule route to be merged with the virtual one into routes %v, got %v", expectedRouteNames, routeNames)
+	}
+
+	if len(serviceFrame.JourneyPatterns) != 1 || serviceFrame.JourneyPatterns[0].RouteRef.Ref != "CGM:Route:autobus-94-1" {
+		t.Errorf("expected a single journey pattern along the first route, got %v", serviceFrame.JourneyPatterns)
+	}
+
+	journeys := frame.TimetableFrame.ServiceJourneys
+	if len(journeys) != 2 {
+		t.Fatalf("expected 2 service journeys, got %d", len(journeys))
+	}
+	expectedDayTypes := []*Ref{{Ref: "CGM:DayType:weekday", Version: ObjectVersion}}
+	expectedPassingTimes := [][]*TimetabledPassingTime{
+		{
+			{Version: ObjectVersion, StopPointInJourneyPatternRef: &Ref{Ref: "CGM:StopPointInJourneyPattern:autobus-94-10-1", Version: ObjectVersion}, DepartureTime: "23:50:00"},
+			{Version: ObjectVersion, StopPointInJourneyPatternRef: &Ref{Ref: "CGM:StopPointInJourneyPattern:autobus-94-10-2", Version: ObjectVersion}, DepartureTime: "00:10:00", DepartureDayOffset: 1},
+		},
+		{
+			{Version: ObjectVersion, StopPointInJourneyPatternRef: &Ref{Ref: "CGM:StopPointInJourneyPattern:autobus-94-10-1", Version: ObjectVersion}, DepartureTime: "00:20:00", DepartureDayOffset: 1},
+			{Version: ObjectVersion, StopPointInJourneyPatternRef: &Ref{Ref: "CGM:StopPointInJourneyPattern:autobus-94-10-2", Version: ObjectVersion}, DepartureTime: "00:40:00", DepartureDayOffset: 1},
+		},
+	}
+	for index, journey := range journeys {
+		if !reflect.DeepEqual(journey.DayTypes, expectedDayTypes) {
+			t.Errorf("expected journey %d to be made on weekdays, got %v", index, journey.DayTypes)
+		}
+		if !reflect.DeepEqual(journey.PassingTimes, expectedPassingTimes[index]) {
+			t.Errorf("expected the passing times of journey %d to be %v, got %v", index, expectedPassingTimes[index], journey.PassingTimes)
+		}
+	}
+
+	assignments := frame.ServiceCalendarFrame.DayTypeAssignments.DayTypeAssignments
+	if len(assignments) != 2 || assignments[0].Date != "2024-05-06" || assignments[0].DayTypeRef.Ref != "CGM:DayType:holiday" || !assignments[0].IsAvailable || assignments[1].DayTypeRef.Ref != "CGM:DayType:weekday" || assignments[1].IsAvailable {
+		t.Errorf("expected the holiday on a Monday to replace the weekday, got %v", assignments)
+	}
+}
+
+func TestGenerateHolidayOperationModeOnPreHolidays(t *testing.T) {
+	source := newTestSource()
+	source.lines[0].OperationModeRoutesList[0].OperationMode = &schedule.OperationMode{Code: "1", Name: "празник"}
+	publication, err := newTestGenerator(source).Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	journeys := publication.CompositeFrame.TimetableFrame.ServiceJourneys
+	if len(journeys) != 2 {
+		t.Fatalf("expected 2 service journeys, got %d", len(journeys))
+	}
+	expectedDayTypes := []*Ref{{Ref: "CGM:DayType:pre-holiday", Version: ObjectVersion}, {Ref: "CGM:DayType:holiday", Version: ObjectVersion}}
+	for index, journey := range journeys {
+		if !reflect.DeepEqual(journey.DayTypes, expectedDayTypes) {
+			t.Errorf("expected journey %d to be made on pre-holidays and holidays, got %v", index, journey.DayTypes)
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	testCases := []struct {
+		name              string
+		duration          time.Duration
+		expectedTimeOfDay string
+		expectedDayOffset int
+	}{
+		{name: "start of the day", expectedTimeOfDay: "00:00:00"},
+		{name: "during the day", duration: 13*time.Hour + 5*time.Minute + 7*time.Second, expectedTimeOfDay: "13:05:07"},
+		{name: "after midnight", duration: 24*time.Hour + 30*time.Minute, expectedTimeOfDay: "00:30:00", expectedDayOffset: 1},
+		{name: "two days later", duration: 49 * time.Hour, expectedTimeOfDay: "01:00:00", expectedDayOffset: 2},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			timeOfDay, dayOffset := formatTime(testCase.duration)
+			if timeOfDay != testCase.expectedTimeOfDay || dayOffset != testCase.expectedDayOffset {
+				t.Errorf("expected %s (+%d days), got %s (+%d days)", testCase.expectedTimeOfDay, testCase.expectedDayOffset, timeOfDay, dayOffset)
+			}
+		})
+	}
+}
+
+func TestPublicationDeliveryEncode(t *testing.T) {
+	publication, err := newTestGenerator(newTestSource()).Generate()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var buffer bytes.Buffer
+	err = publication.Encode(&buffer)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if !strings.HasPrefix(buffer.String(), xml.Header+`<PublicationDelivery xmlns="http://www.netex.org.uk/netex" version="`+Version+`">`) {
+		t.Errorf("unexpected start of the document: %q", buffer.String()[:200])
+	}
+
+	var decodedPublication PublicationDelivery
+	err = xml.Unmarshal(buffer.Bytes(), &decodedPublication)
+	if err != nil {
+		t.Fatalf("could not decode document: %s", err.Error())
+	}
+	if len(decodedPublication.CompositeFrame.TimetableFrame.ServiceJourneys) != 2 || len(decodedPublication.CompositeFrame.ServiceFrame.Routes) != 2 {
+		t.Errorf("expected the document to contain the service journeys and routes, got %v", decodedPublication.CompositeFrame)
+	}
+}
@@ -0,0 +1,268 @@
+package netex
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// Namespace is the XML namespace of NeTEx documents.
+	Namespace = "http://www.netex.org.uk/netex"
+	// Version is the version of the NeTEx schema which the publications conform to.
+	Version = "1.1"
+	// ProfileFrameRef identifies the type of frame defined by the European Passenger Information Profile for line offers.
+	ProfileFrameRef = "epip:EU_PI_LINE_OFFER"
+	// ProfileVersion is the version of the European Passenger Information Profile which the publications conform to.
+	ProfileVersion = "1.0"
+	// ObjectVersion is the version of all objects in the publications.
+	ObjectVersion = "1"
+)
+
+// Ref represents a reference to another object.
+type Ref struct {
+	Ref     string `xml:"ref,attr"`               // identifier of the referenced object
+	Version string `xml:"version,attr,omitempty"` // version of the referenced object
+}
+
+// TypeOfFrameRef represents a reference to the type of a frame defined by a profile.
+type TypeOfFrameRef struct {
+	Ref        string `xml:"ref,attr"`        // identifier of the type of frame
+	VersionRef string `xml:"versionRef,attr"` // version of the profile
+}
+
+// ValidBetween represents the period during which the contents of a frame are valid.
+type ValidBetween struct {
+	FromDate time.Time // start of the period
+	ToDate   time.Time // end of the period
+}
+
+// Codespace represents the namespace of the identifiers of the objects.
+type Codespace struct {
+	ID       string `xml:"id,attr"`
+	Xmlns    string // prefix of the identifiers
+	XmlnsURL string `xml:"XmlnsUrl"` // URL uniquely identifying the namespace
+}
+
+// DefaultLocale represents the locale in which the times and names are expressed.
+type DefaultLocale struct {
+	TimeZone        string // name of the time zone in the IANA Time Zone Database
+	DefaultLanguage string // code of the language of the names
+}
+
+// FrameDefaults represents the default values applying to all objects of a frame.
+type FrameDefaults struct {
+	DefaultLocale *DefaultLocale
+}
+
+// Operator represents a company operating urban transit lines.
+type Operator struct {
+	ID      string `xml:"id,attr"`
+	Version string `xml:"version,attr"`
+	Name    string // name of the operator
+	URL     string `xml:"ContactDetails>Url,omitempty"` // URL of the website of the operator
+}
+
+// ResourceFrame contains the organisations referenced by the rest of the frames.
+type ResourceFrame struct {
+	ID        string      `xml:"id,attr"`
+	Version   string      `xml:"version,attr"`
+	Operators []*Operator `xml:"organisations>Operator"`
+}
+
+// PropertyOfDay represents a property which days of some type have.
+type PropertyOfDay struct {
+	DaysOfWeek string // space-separated days of the week
+}
+
+// DayType represents a type of day which determines the operation mode of the urban transit lines in effect.
+type DayType struct {
+	ID         string           `xml:"id,attr"`
+	Version    string           `xml:"version,attr"`
+	Name       string           // name of the operation mode
+	Properties []*PropertyOfDay `xml:"properties>PropertyOfDay"` // properties of the days of the type
+}
+
+// DayTypeAssignment represents the assignment of a day type to a specific date (or its removal from the date).
+type DayTypeAssignment struct {
+	ID          string `xml:"id,attr"`
+	Version     string `xml:"version,attr"`
+	Order       int    `xml:"order,attr"`
+	Date        string // date in the YYYY-MM-DD format
+	DayTypeRef  *Ref   // reference to the day type
+	IsAvailable bool   `xml:"isAvailable"` // whether the day type applies on the date
+}
+
+// DayTypeAssignments represents a non-empty collection of assignments of day types to dates.
+type DayTypeAssignments struct {
+	DayTypeAssignments []*DayTypeAssignment `xml:"DayTypeAssignment"`
+}
+
+// ServiceCalendarFrame contains the day types and their assignments to specific dates.
+type ServiceCalendarFrame struct {
+	ID                 string              `xml:"id,attr"`
+	Version            string              `xml:"version,attr"`
+	DayTypes           []*DayType          `xml:"dayTypes>DayType"`
+	DayTypeAssignments *DayTypeAssignments `xml:"dayTypeAssignments"` // assignments of day types to specific dates (nil if there are none)
+}
+
+// PointProjection represents the projection of a point onto another point.
+type PointProjection struct {
+	ID                string `xml:"id,attr"`
+	Version           string `xml:"version,attr"`
+	ProjectToPointRef *Ref   // reference to the point onto which the point is projected
+}
+
+// RoutePoint represents a point of a route.
+type RoutePoint struct {
+	ID          string             `xml:"id,attr"`
+	Version     string             `xml:"version,attr"`
+	Projections []*PointProjection `xml:"projections>PointProjection"` // projections of the point onto scheduled stop points
+}
+
+// PointOnRoute represents a route point in the sequence of points of a route.
+type PointOnRoute struct {
+	ID            string `xml:"id,attr"`
+	Version       string `xml:"version,attr"`
+	Order         int    `xml:"order,attr"` // position of the point in the sequence (starting from 1)
+	RoutePointRef *Ref   // reference to the route point
+}
+
+// Route represents an ordered sequence of points visited by the vehicles of a line.
+type Route struct {
+	ID               string          `xml:"id,attr"`
+	Version          string          `xml:"version,attr"`
+	Name             string          // name of the route
+	LineRef          *Ref            // reference to the line
+	PointsInSequence []*PointOnRoute `xml:"pointsInSequence>PointOnRoute"`
+}
+
+// Line represents an urban transit line.
+type Line struct {
+	ID            string `xml:"id,attr"`
+	Version       string `xml:"version,attr"`
+	Name          string // name of the line
+	TransportMode string // mode of transport of the vehicles serving the line
+	PublicCode    string // number of the line as shown to passengers
+	OperatorRef   *Ref   // reference to the operator of the line
+}
+
+// ScheduledStopPoint represents an urban transit stop.
+type ScheduledStopPoint struct {
+	ID         string `xml:"id,attr"`
+	Version    string `xml:"version,attr"`
+	Name       string // name of the stop
+	PublicCode string // numerical code of the stop
+}
+
+// StopPointInJourneyPattern represents a scheduled stop point in the sequence of stops of a journey pattern.
+type StopPointInJourneyPattern struct {
+	ID                    string `xml:"id,attr"`
+	Version               string `xml:"version,attr"`
+	Order                 int    `xml:"order,attr"` // position of the stop in the sequence (starting from 1)
+	ScheduledStopPointRef *Ref   // reference to the scheduled stop point
+}
+
+// ServiceJourneyPattern represents the ordered sequence of stops served by the vehicles along a route.
+type ServiceJourneyPattern struct {
+	ID               string                       `xml:"id,attr"`
+	Version          string                       `xml:"version,attr"`
+	Name             string                       // name of the journey pattern
+	RouteRef         *Ref                         // reference to the route
+	PointsInSequence []*StopPointInJourneyPattern `xml:"pointsInSequence>StopPointInJourneyPattern"`
+}
+
+// ServiceFrame contains the network description, i.e. the stops, lines, routes and journey patterns.
+type ServiceFrame struct {
+	ID                  string                   `xml:"id,attr"`
+	Version             string                   `xml:"version,attr"`
+	RoutePoints         []*RoutePoint            `xml:"routePoints>RoutePoint"`
+	Routes              []*Route                 `xml:"routes>Route"`
+	Lines               []*Line                  `xml:"lines>Line"`
+	ScheduledStopPoints []*ScheduledStopPoint    `xml:"scheduledStopPoints>ScheduledStopPoint"`
+	JourneyPatterns     []*ServiceJourneyPattern `xml:"journeyPatterns>ServiceJourneyPattern"`
+}
+
+// TimetabledPassingTime represents the departure of a vehicle from a stop on a service journey.
+type TimetabledPassingTime struct {
+	Version                      string `xml:"version,attr"`
+	StopPointInJourneyPatternRef *Ref   // reference to the stop in the journey pattern
+	DepartureTime                string // departure time of day in the HH:MM:SS format
+	DepartureDayOffset           int    `xml:",omitempty"` // number of days after the start of the service day on which the departure is made
+}
+
+// ServiceJourney represents a trip made by a vehicle along a journey pattern.
+type ServiceJourney struct {
+	ID                       string                   `xml:"id,attr"`
+	Version                  string                   `xml:"version,attr"`
+	DayTypes                 []*Ref                   `xml:"dayTypes>DayTypeRef"` // references to the types of the days on which the journey is made
+	ServiceJourneyPatternRef *Ref                     // reference to the journey pattern
+	LineRef                  *Ref                     // reference to the line
+	PassingTimes             []*TimetabledPassingTime `xml:"passingTimes>TimetabledPassingTime"`
+}
+
+// TimetableFrame contains the service journeys.
+type TimetableFrame struct {
+	ID              string            `xml:"id,attr"`
+	Version         string            `xml:"version,attr"`
+	ServiceJourneys []*ServiceJourney `xml:"vehicleJourneys>ServiceJourney"`
+}
+
+// CompositeFrame groups the frames of a publication.
+type CompositeFrame struct {
+	ID                   string                `xml:"id,attr"`
+	Version              string                `xml:"version,attr"`
+	ValidBetween         *ValidBetween         // period during which the publication is valid
+	TypeOfFrameRef       *TypeOfFrameRef       // type of the frame defined by the profile
+	Codespaces           []*Codespace          `xml:"codespaces>Codespace"`
+	FrameDefaults        *FrameDefaults        // default values applying to all frames
+	ResourceFrame        *ResourceFrame        `xml:"frames>ResourceFrame"`
+	ServiceCalendarFrame *ServiceCalendarFrame `xml:"frames>ServiceCalendarFrame"`
+	ServiceFrame         *ServiceFrame         `xml:"frames>ServiceFrame"`
+	TimetableFrame       *TimetableFrame       `xml:"frames>TimetableFrame"`
+}
+
+// PublicationDelivery represents the root element of a NeTEx document.
+type PublicationDelivery struct {
+	XMLName              xml.Name        `xml:"http://www.netex.org.uk/netex PublicationDelivery"`
+	Version              string          `xml:"version,attr"`
+	PublicationTimestamp time.Time       // time when the publication was created
+	ParticipantRef       string          // identifier of the producer of the publication
+	CompositeFrame       *CompositeFrame `xml:"dataObjects>CompositeFrame"`
+}
+
+// Encode writes the XML representation of the publication (preceded by an XML declaration) to w.
+func (d *PublicationDelivery) Encode(w io.Writer) (err error) {
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(d)
+	if err != nil {
+		return
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return
+}
+
+// Create writes the XML representation of the publication to the file at the specified path (which is created or truncated).
+func (d *PublicationDelivery) Create(path string) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return
+	}
+
+	err = d.Encode(file)
+	if err != nil {
+		file.Close()
+		return
+	}
+
+	err = file.Close()
+	return
+}
@@ -1,8 +1,99 @@
 package schedule
 
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+)
+
+// RequestDecorator modifies an HTTP request to a schedule-related page before it is issued (e.g. by setting headers).
+type RequestDecorator func(request *http.Request)
+
+// Client represents a client for the schedule pages on the Urban Mobility Centre website.
+type Client struct {
+	HTTPClient        *http.Client             // HTTP client used to issue requests (http.DefaultClient is used if nil)
+	BaseURL           string                   // base URL of the schedule-related pages
+	RequestDecorators []RequestDecorator       // functions applied in order to each request before it is issued
+	RetryPolicy       *upstream.RetryPolicy    // policy for retrying failed requests (requests are not retried if nil)
+	CircuitBreaker    *upstream.CircuitBreaker // circuit breaker guarding the schedule host (not used if nil)
+	RateLimiter       *upstream.RateLimiter    // rate limiter for the requests to the schedule host (requests are not limited if nil)
+	Cache             upstream.Cache           // cache for the schedule pages (pages are not cached if nil)
+	CachePolicy       *upstream.CachePolicy    // policy for using cached schedule pages (upstream.DefaultCachePolicy is used if nil)
+}
+
 const (
 	// Scheme represents the URL scheme for schedule-related pages.
+	//
+	// Deprecated: use the BaseURL field of Client instead.
 	Scheme = "https"
 	// Hostname represents the URL hostname for schedule-related pages.
+	//
+	// Deprecated: use the BaseURL field of Client instead.
 	Hostname = "schedules.sofiatraffic.bg"
+
+	// DefaultBaseURL represents the base URL of the schedule-related pages on the Urban Mobility Centre website.
+	DefaultBaseURL = Scheme + "://" + Hostname
 )
+
+// DefaultClient is the Client used by the package-level functions and by the methods of the Line type.
+var DefaultClient = NewClient(nil)
+
+// NewClient returns a Client which issues requests to DefaultBaseURL through the specified httpClient (or through http.DefaultClient if httpClient is nil). The client uses upstream.DefaultRetryPolicy, upstream.DefaultCircuitBreaker, upstream.DefaultRateLimiter and upstream.DefaultCache.
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		HTTPClient:     httpClient,
+		BaseURL:        DefaultBaseURL,
+		RetryPolicy:    upstream.DefaultRetryPolicy,
+		CircuitBreaker: upstream.DefaultCircuitBreaker,
+		RateLimiter:    upstream.DefaultRateLimiter,
+		Cache:          upstream.DefaultCache,
+	}
+}
+
+func (c *Client) getFetcher() *upstream.Fetcher {
+	return &upstream.Fetcher{
+		HTTPClient:     c.HTTPClient,
+		RetryPolicy:    c.RetryPolicy,
+		CircuitBreaker: c.CircuitBreaker,
+		RateLimiter:    c.RateLimiter,
+		Cache:          c.Cache,
+		CachePolicy:    c.CachePolicy,
+	}
+}
+
+func (c *Client) getPageURL(pagePath string) (pageURL string, err error) {
+	parsedPageURL, err := url.Parse(c.BaseURL)
+	if err != nil {
+		err = &upstream.RequestError{URL: c.BaseURL + pagePath, Err: err}
+		return
+	}
+
+	parsedPageURL.Path = strings.TrimSuffix(parsedPageURL.Path, "/") + pagePath
+	pageURL = parsedPageURL.String()
+	return
+}
+
+func (c *Client) getPage(ctx context.Context, pagePath string, kind upstream.ResourceKind) (pageURL string, body []byte, err error) {
+	pageURL, err = c.getPageURL(pagePath)
+	if err != nil {
+		return
+	}
+
+	request := &upstream.Request{
+		URL:        pageURL,
+		Kind:       kind,
+		MediaTypes: upstream.HTMLMediaTypes,
+		Decorate:   c.decorateRequest,
+	}
+	body, err = c.getFetcher().Fetch(ctx, request)
+	return
+}
+
+func (c *Client) decorateRequest(request *http.Request) {
+	for _, decorate := range c.RequestDecorators {
+		decorate(request)
+	}
+}
@@ -0,0 +1,264 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+)
+
+func TestMain(m *testing.M) {
+	l10n.Translator = l10n.EnglishTranslator
+	l10n.ReverseTranslator = l10n.ReverseEnglishTranslator
+	os.Exit(m.Run())
+}
+
+// newTestServer returns a server which serves the fixtures from the testdata directory at the paths specified in pages (and responds with 404 Not Found to any other request), recording the paths of the requests in requestPaths.
+func newTestServer(t *testing.T, pages map[string]string, requestPaths *[]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestPaths != nil {
+			*requestPaths = append(*requestPaths, r.URL.Path)
+		}
+		fixture, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, err := os.ReadFile(filepath.Join("testdata", fixture))
+		if err != nil {
+			t.Errorf("could not read fixture %s: %s", fixture, err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestClient returns a Client whose base URL points to basePath on server and which neither retries requests nor uses a circuit breaker or a rate limiter.
+func newTestClient(server *httptest.Server, basePath string) *Client {
+	return &Client{HTTPClient: server.Client(), BaseURL: server.URL + basePath}
+}
+
+func TestClientBaseURL(t *testing.T) {
+	testCases := []struct {
+		name         string
+		basePath     string
+		expectedPath string
+	}{
+		{name: "root", basePath: "", expectedPath: "/autobus/94"},
+		{name: "root with trailing slash", basePath: "/", expectedPath: "/autobus/94"},
+		{name: "prefix", basePath: "/mirror", expectedPath: "/mirror/autobus/94"},
+		{name: "prefix with trailing slash", basePath: "/mirror/", expectedPath: "/mirror/autobus/94"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var requestPaths []string
+			server := newTestServer(t, map[string]string{testCase.expectedPath: "line.html"}, &requestPaths)
+			_, err := newTestClient(server, testCase.basePath).GetLine(VehicleTypeBus, "94")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(requestPaths, []string{testCase.expectedPath}) {
+				t.Errorf("expected requests to %v, got %v", []string{testCase.expectedPath}, requestPaths)
+			}
+		})
+	}
+}
+
+func TestNewClientCachesPages(t *testing.T) {
+	var requestPaths []string
+	server := newTestServer(t, map[string]string{"/autobus/94": "line.html"}, &requestPaths)
+	client := NewClient(server.Client())
+	client.BaseURL = server.URL
+	for i := 0; i < 2; i++ {
+		_, err := client.GetLine(VehicleTypeBus, "94")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	if len(requestPaths) != 1 {
+		t.Errorf("expected the line page to be served from the default cache, got requests to %v", requestPaths)
+	}
+}
+
+func TestClientRequestDecorators(t *testing.T) {
+	var userAgent, referer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, referer = r.Header.Get("User-Agent"), r.Header.Get("Referer")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/autobus/94">94</a>`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server, "")
+	client.RequestDecorators = []RequestDecorator{
+		func(request *http.Request) { request.Header.Set("User-Agent", "first") },
+		func(request *http.Request) { request.Header.Set("Referer", "https://example.com/") },
+		func(request *http.Request) { request.Header.Set("User-Agent", "second") },
+	}
+	_, err := client.GetLines()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if userAgent != "second" {
+		t.Errorf("expected the decorators to be applied in order, got User-Agent %q", userAgent)
+	}
+	if referer != "https://example.com/" {
+		t.Errorf("expected Referer %q, got %q", "https://example.com/", referer)
+	}
+}
+
+func TestClientGetLines(t *testing.T) {
+	server := newTestServer(t, map[string]string{"/": "lines.html"}, nil)
+	lines, err := newTestClient(server, "").GetLines()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expectedLines := VehicleTypeLineNumbersList{
+		{VehicleType: VehicleTypeBus, LineNumbers: []string{"94", "404"}},
+		{VehicleType: VehicleTypeTrolleybus, LineNumbers: []string{"2"}},
+		{VehicleType: VehicleTypeTram, LineNumbers: []string{"5"}},
+		{VehicleType: VehicleTypeMetro, LineNumbers: []string{"M1", "M4"}},
+	}
+	if !reflect.DeepEqual(lines.VehicleTypeLineNumbersList, expectedLines) {
+		t.Errorf("expected lines %v, got %v", expectedLines, lines.VehicleTypeLineNumbersList)
+	}
+}
+
+func TestClientGetLine(t *testing.T) {
+	server := newTestServer(t, map[string]string{"/autobus/94": "line.html"}, nil)
+	line, err := newTestClient(server, "").GetLine(VehicleTypeBus, "94")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(line.OperationModeRoutesList) != 2 {
+		t.Fatalf("expected 2 operation modes, got %d", len(line.OperationModeRoutesList))
+	}
+	weekday := line.OperationModeRoutesMap["101"]
+	if weekday == nil || weekday.OperationMode.Name != "делник" {
+		t.Fatalf("expected operation mode 101 named делник, got %v", weekday)
+	}
+	route := weekday.RouteMap["201"]
+	if route == nil || route.Name != "ЖК МЛАДОСТ 3 - ЦЕНТРАЛНА ГАРА" {
+		t.Fatalf("expected route 201, got %v", route)
+	}
+	expectedStops := StopList{{Code: "0001", Name: "ЖК МЛАДОСТ 3"}, {Code: "0002", Name: "ЦЕНТРАЛНА ГАРА"}}
+	if !reflect.DeepEqual(route.StopList, expectedStops) {
+		t.Errorf("expected stops %v, got %v", expectedStops, route.StopList)
+	}
+	if len(line.OperationModeRoutesMap["102"].RouteMap["202"].StopList) != 1 {
+		t.Errorf("expected 1 stop on route 202, got %v", line.OperationModeRoutesMap["102"].RouteMap["202"].StopList)
+	}
+}
+
+func TestClientGetLineMetro(t *testing.T) {
+	server := newTestServer(t, map[string]string{"/metro/M1": "metro_line.html"}, nil)
+	line, err := newTestClient(server, "").GetLine(VehicleTypeMetro, "M1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if line.VehicleType != VehicleTypeMetro || line.LineNumber != "M1" || len(line.OperationModeRoutesList) != 2 {
+		t.Fatalf("expected metro line M1 with 2 operation modes, got %v", line)
+	}
+	expectedStops := map[string]StopList{
+		"301/401": {{Code: "3001", Name: "М.СЛИВНИЦА"}, {Code: "3002", Name: "М.СЕРДИКА"}, {Code: "3003", Name: "М.БИЗНЕС ПАРК"}},
+		"302/402": {{Code: "3003", Name: "М.БИЗНЕС ПАРК"}, {Code: "3002", Name: "М.СЕРДИКА"}, {Code: "3001", Name: "М.СЛИВНИЦА"}},
+	}
+	for key, stops := range expectedStops {
+		codes := strings.Split(key, "/")
+		operationModeRoutes := line.OperationModeRoutesMap[codes[0]]
+		if operationModeRoutes == nil || operationModeRoutes.RouteMap[codes[1]] == nil {
+			t.Fatalf("expected route %s of operation mode %s, got %v", codes[1], codes[0], operationModeRoutes)
+		}
+		if !reflect.DeepEqual(operationModeRoutes.RouteMap[codes[1]].StopList, stops) {
+			t.Errorf("expected stops %v on route %s, got %v", stops, codes[1], operationModeRoutes.RouteMap[codes[1]].StopList)
+		}
+	}
+	if line.OperationModeRoutesMap["302"].OperationMode.Name != "предпразник, празник" {
+		t.Errorf("unexpected name of operation mode 302: %s", line.OperationModeRoutesMap["302"].OperationMode.Name)
+	}
+}
+
+func TestClientGetLineUnknown(t *testing.T) {
+	server := newTestServer(t, map[string]string{}, nil)
+	_, err := newTestClient(server, "").GetLine(VehicleTypeBus, "999")
+	var unknownLineError *UnknownLineError
+	if !errors.As(err, &unknownLineError) || unknownLineError.LineNumber != "999" {
+		t.Errorf("expected UnknownLineError for line 999, got %v", err)
+	}
+	if !errors.Is(err, ErrUnknownLine) {
+		t.Errorf("expected the error to match ErrUnknownLine, got %v", err)
+	}
+}
+
+func TestClientGetLinesWithoutLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>Maintenance</p></body></html>`))
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server, "").GetLines()
+	if !errors.Is(err, ErrMarkupChanged) {
+		t.Errorf("expected the error to match ErrMarkupChanged, got %v", err)
+	}
+}
+
+func TestLinesUnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          string
+		expectedLines VehicleTypeLineNumbersList
+	}{
+		{
+			name: "current representation",
+			data: `{"vehicle_types":[{"vehicle_type":"autobus","line_numbers":["94"]},{"vehicle_type":"metro","line_numbers":["M1","M2"]}]}`,
+			expectedLines: VehicleTypeLineNumbersList{
+				{VehicleType: VehicleTypeBus, LineNumbers: []string{"94"}},
+				{VehicleType: VehicleTypeMetro, LineNumbers: []string{"M1", "M2"}},
+			},
+		},
+		{
+			name: "representation of earlier versions",
+			data: `{"bus_line_numbers":["94","404"],"trolleybus_line_numbers":[],"tram_line_numbers":["5"]}`,
+			expectedLines: VehicleTypeLineNumbersList{
+				{VehicleType: VehicleTypeBus, LineNumbers: []string{"94", "404"}},
+				{VehicleType: VehicleTypeTram, LineNumbers: []string{"5"}},
+			},
+		},
+		{
+			name:          "no lines",
+			data:          `{}`,
+			expectedLines: VehicleTypeLineNumbersList{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			lines := &Lines{}
+			err := json.Unmarshal([]byte(testCase.data), lines)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(lines.VehicleTypeLineNumbersList, testCase.expectedLines) {
+				t.Errorf("expected lines %v, got %v", testCase.expectedLines, lines.VehicleTypeLineNumbersList)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ServiceDayStart is the time of the day before which departures are considered to belong to the previous service day (i.e. to be made after midnight).
+const ServiceDayStart = 3 * time.Hour
+
+// Departure represents a scheduled departure of an urban transit vehicle from a stop.
+type Departure struct {
+	Time    time.Duration // departure time measured from the start of the service day (departures made after midnight are at least 24 hours after its start)
+	Markers string        // markers following the departure time in the timetable (e.g. denoting departures which do not serve the whole route), if any
+	Raw     string        // text of the departure as it appears in the timetable
+}
+
+// ParseDeparture parses a departure from a schedule timetable (in the HH:MM format, optionally followed by markers). Departures made before ServiceDayStart are considered to belong to the previous service day. Hours past 23 are only accepted for departures made after midnight (i.e. before ServiceDayStart on the following day).
+func ParseDeparture(value string) (departure *Departure, err error) {
+	text := strings.TrimSpace(value)
+	hoursString, rest, isFound := strings.Cut(text, ":")
+	if !isFound {
+		err = fmt.Errorf("could not parse departure time %q: missing colon", value)
+		return
+	}
+
+	minutesLength := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) })
+	if minutesLength == -1 {
+		minutesLength = len(rest)
+	}
+	minutesString := rest[:minutesLength]
+
+	hours, err := strconv.Atoi(hoursString)
+	if err != nil {
+		err = fmt.Errorf("could not parse hours of departure time %q: %w", value, err)
+		return
+	}
+
+	if hours < 0 || time.Duration(hours)*time.Hour >= 24*time.Hour+ServiceDayStart {
+		err = fmt.Errorf("could not parse hours of departure time %q: hours out of range", value)
+		return
+	}
+
+	minutes, err := strconv.Atoi(minutesString)
+	if err != nil {
+		err = fmt.Errorf("could not parse minutes of departure time %q: %w", value, err)
+		return
+	}
+	if minutes >= 60 {
+		err = fmt.Errorf("could not parse minutes of departure time %q: more than 59 minutes", value)
+		return
+	}
+
+	departure = &Departure{
+		Time:    time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute,
+		Markers: strings.TrimSpace(rest[minutesLength:]),
+		Raw:     value,
+	}
+	if departure.Time < ServiceDayStart {
+		departure.Time += 24 * time.Hour
+	}
+	return
+}
+
+// IsAfterMidnight reports whether the departure is made after midnight, i.e. on the calendar day following its service day.
+func (d *Departure) IsAfterMidnight() bool {
+	return d.Time >= 24*time.Hour
+}
+
+// GetTimeOfDay returns the time of the calendar day on which the departure is made.
+func (d *Departure) GetTimeOfDay() time.Duration {
+	return d.Time % (24 * time.Hour)
+}
+
+// GetTime returns the time at which the departure is made on the service day starting on the date of serviceDate (in its location).
+func (d *Departure) GetTime(serviceDate time.Time) time.Time {
+	year, month, day := serviceDate.Date()
+	timeOfDay := d.GetTimeOfDay()
+	if d.IsAfterMidnight() {
+		day++
+	}
+	return time.Date(year, month, day, int(timeOfDay/time.Hour), int(timeOfDay%time.Hour/time.Minute), 0, 0, serviceDate.Location())
+}
+
+// FormatTime returns the time of the day at which the departure is made in the HH:MM format.
+func (d *Departure) FormatTime() string {
+	timeOfDay := d.GetTimeOfDay()
+	return fmt.Sprintf("%02d:%02d", timeOfDay/time.Hour, timeOfDay%time.Hour/time.Minute)
+}
+
+func (d *Departure) String() string {
+	if d.Raw != "" {
+		return strings.TrimSpace(d.Raw)
+	}
+	return d.FormatTime() + d.Markers
+}
+
+// MarshalJSON encodes the departure as a JSON string containing its text as it appears in the timetable (so that timetables keep the representation they had before departures were parsed).
+func (d *Departure) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes the departure from the JSON string produced by MarshalJSON.
+func (d *Departure) UnmarshalJSON(data []byte) (err error) {
+	var value string
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return
+	}
+
+	departure, err := ParseDeparture(value)
+	if err != nil {
+		return
+	}
+
+	*d = *departure
+	return
+}
+
+func (t Timetable) Len() int {
+	return len(t)
+}
+
+func (t Timetable) Less(i, j int) bool {
+	return t[i].Time < t[j].Time
+}
+
+func (t Timetable) Swap(i, j int) {
+	temp := t[i]
+	t[i] = t[j]
+	t[j] = temp
+}
+
+// Filter returns the departures from the timetable for which predicate returns true (keeping their order).
+func (t Timetable) Filter(predicate func(departure *Departure) bool) (filtered Timetable) {
+	filtered = Timetable{}
+	for _, departure := range t {
+		if predicate(departure) {
+			filtered = append(filtered, departure)
+		}
+	}
+	return
+}
+
+// GetDeparturesAfter returns the departures from the timetable which are made at or after the specified time measured from the start of the service day (keeping their order).
+func (t Timetable) GetDeparturesAfter(departureTime time.Duration) Timetable {
+	return t.Filter(func(departure *Departure) bool {
+		return departure.Time >= departureTime
+	})
+}
@@ -0,0 +1,89 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeparture(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		value                   string
+		expectedTime            time.Duration
+		expectedMarkers         string
+		expectedIsAfterMidnight bool
+		expectedFormattedTime   string
+		isErrorExpected         bool
+	}{
+		{name: "morning", value: "05:10", expectedTime: 5*time.Hour + 10*time.Minute, expectedFormattedTime: "05:10"},
+		{name: "surrounding whitespace", value: " 5:07\n", expectedTime: 5*time.Hour + 7*time.Minute, expectedFormattedTime: "05:07"},
+		{name: "markers", value: "06:15 к*", expectedTime: 6*time.Hour + 15*time.Minute, expectedMarkers: "к*", expectedFormattedTime: "06:15"},
+		{name: "late evening", value: "23:59", expectedTime: 23*time.Hour + 59*time.Minute, expectedFormattedTime: "23:59"},
+		{name: "after midnight", value: "00:25", expectedTime: 24*time.Hour + 25*time.Minute, expectedIsAfterMidnight: true, expectedFormattedTime: "00:25"},
+		{name: "just before the start of the service day", value: "02:59", expectedTime: 26*time.Hour + 59*time.Minute, expectedIsAfterMidnight: true, expectedFormattedTime: "02:59"},
+		{name: "start of the service day", value: "03:00", expectedTime: 3 * time.Hour, expectedFormattedTime: "03:00"},
+		{name: "after midnight past 23 hours", value: "24:25", expectedTime: 24*time.Hour + 25*time.Minute, expectedIsAfterMidnight: true, expectedFormattedTime: "00:25"},
+		{name: "last hour before the end of the service day", value: "26:59", expectedTime: 26*time.Hour + 59*time.Minute, expectedIsAfterMidnight: true, expectedFormattedTime: "02:59"},
+		{name: "missing colon", value: "0510", isErrorExpected: true},
+		{name: "missing minutes", value: "05:", isErrorExpected: true},
+		{name: "invalid hours", value: "ab:10", isErrorExpected: true},
+		{name: "negative hours", value: "-1:10", isErrorExpected: true},
+		{name: "hours after the end of the service day", value: "27:00", isErrorExpected: true},
+		{name: "hours of the day after next", value: "48:00", isErrorExpected: true},
+		{name: "too many hours", value: "99:10", isErrorExpected: true},
+		{name: "too many minutes", value: "05:60", isErrorExpected: true},
+		{name: "marker only", value: "*", isErrorExpected: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			departure, err := ParseDeparture(testCase.value)
+			if testCase.isErrorExpected {
+				if err == nil {
+					t.Errorf("expected an error, got %v", departure)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if departure.Time != testCase.expectedTime || departure.Markers != testCase.expectedMarkers || departure.Raw != testCase.value {
+				t.Errorf("expected departure at %s with markers %q, got %v", testCase.expectedTime, testCase.expectedMarkers, departure)
+			}
+			if departure.IsAfterMidnight() != testCase.expectedIsAfterMidnight {
+				t.Errorf("expected IsAfterMidnight to be %t", testCase.expectedIsAfterMidnight)
+			}
+			if departure.FormatTime() != testCase.expectedFormattedTime {
+				t.Errorf("expected formatted time %s, got %s", testCase.expectedFormattedTime, departure.FormatTime())
+			}
+		})
+	}
+}
+
+func TestDepartureGetTime(t *testing.T) {
+	location := time.FixedZone("EET", 2*60*60)
+	serviceDate := time.Date(2024, time.December, 31, 0, 0, 0, 0, location)
+	testCases := []struct {
+		name         string
+		value        string
+		expectedTime time.Time
+	}{
+		{name: "same day", value: "23:55", expectedTime: time.Date(2024, time.December, 31, 23, 55, 0, 0, location)},
+		{name: "after midnight", value: "00:25", expectedTime: time.Date(2025, time.January, 1, 0, 25, 0, 0, location)},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			departure, err := ParseDeparture(testCase.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			departureTime := departure.GetTime(serviceDate)
+			if !departureTime.Equal(testCase.expectedTime) {
+				t.Errorf("expected %s, got %s", testCase.expectedTime, departureTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+)
+
+var (
+	// ErrUnknownLine indicates that an urban transit line with the requested vehicle type and number does not exist.
+	ErrUnknownLine = errors.New("unknown line")
+	// ErrUnknownOperationMode indicates that an operation mode with the requested code does not exist for the requested urban transit line.
+	ErrUnknownOperationMode = errors.New("unknown operation mode")
+	// ErrUnknownStop indicates that an urban transit stop with the requested code does not exist for the requested operation mode and route.
+	ErrUnknownStop = errors.New("unknown stop")
+	// ErrMarkupChanged indicates that a schedule page does not have the expected structure (which most likely means that the markup of the website has changed).
+	ErrMarkupChanged = errors.New("unexpected schedule page markup")
+	// ErrUpstreamUnavailable indicates that the schedule pages could not be reached or the server failed to handle the request.
+	ErrUpstreamUnavailable = upstream.ErrUnavailable
+	// ErrMalformedResponse indicates that the server returned a response which could not be processed.
+	ErrMalformedResponse = upstream.ErrMalformedResponse
+)
+
+// UnknownLineError represents a reference to an urban transit line which does not exist.
+type UnknownLineError struct {
+	VehicleType, LineNumber string
+	Err                     error // underlying error (nil if the line is missing from local data)
+}
+
+// UnknownOperationModeError represents a reference to an operation mode which does not exist for the specified urban transit line.
+type UnknownOperationModeError struct {
+	VehicleType, LineNumber, OperationModeCode string
+}
+
+// UnknownStopError represents a reference to an urban transit stop which does not exist for the specified operation mode and route.
+type UnknownStopError struct {
+	OperationModeCode, RouteCode, StopCode string
+	Err                                    error // underlying error (nil if the stop is missing from local data)
+}
+
+// MarkupError represents a schedule page which does not have the expected structure.
+type MarkupError struct {
+	URL    string // URL of the page
+	Detail string // description of the discrepancy
+}
+
+func (e *UnknownLineError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("unknown line with vehicle type %s and number %s: %s", e.VehicleType, e.LineNumber, e.Err.Error())
+	}
+	return fmt.Sprintf("unknown line with vehicle type %s and number %s", e.VehicleType, e.LineNumber)
+}
+
+func (e *UnknownLineError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrUnknownLine.
+func (e *UnknownLineError) Is(target error) bool {
+	return target == ErrUnknownLine
+}
+
+func (e *UnknownOperationModeError) Error() string {
+	return fmt.Sprintf("unknown operation mode with code %s for line with vehicle type %s and number %s", e.OperationModeCode, e.VehicleType, e.LineNumber)
+}
+
+// Is reports whether target is ErrUnknownOperationMode.
+func (e *UnknownOperationModeError) Is(target error) bool {
+	return target == ErrUnknownOperationMode
+}
+
+func (e *UnknownStopError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("unknown stop with code %s for route %s of operation mode %s: %s", e.StopCode, e.RouteCode, e.OperationModeCode, e.Err.Error())
+	}
+	return fmt.Sprintf("unknown stop with code %s for route %s of operation mode %s", e.StopCode, e.RouteCode, e.OperationModeCode)
+}
+
+func (e *UnknownStopError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrUnknownStop.
+func (e *UnknownStopError) Is(target error) bool {
+	return target == ErrUnknownStop
+}
+
+func (e *MarkupError) Error() string {
+	return fmt.Sprintf("unexpected markup of schedule page %s: %s", e.URL, e.Detail)
+}
+
+// Is reports whether target is either ErrMarkupChanged or ErrMalformedResponse.
+func (e *MarkupError) Is(target error) bool {
+	return target == ErrMarkupChanged || target == ErrMalformedResponse
+}
@@ -10,6 +10,7 @@ var BulgarianTranslator = map[string]string{
 	BusLines:        "автобусни линии",
 	TrolleybusLines: "тролейбусни линии",
 	TramLines:       "трамвайни линии",
+	MetroLines:      "линии на метрото",
 
 	OperationMode:           "режим",
 	OperationModeWeekday:    "делник",
@@ -10,6 +10,7 @@ var EnglishTranslator = map[string]string{
 	BusLines:        "bus lines",
 	TrolleybusLines: "trolleybus lines",
 	TramLines:       "tram lines",
+	MetroLines:      "metro lines",
 
 	OperationMode:           "operation mode",
 	OperationModeWeekday:    "weekday",
@@ -9,6 +9,7 @@ const (
 	BusLines        = "bus lines"
 	TrolleybusLines = "trolleybus lines"
 	TramLines       = "tram lines"
+	MetroLines      = "metro lines"
 
 	OperationMode           = "operation mode"
 	OperationModeWeekday    = "weekday"
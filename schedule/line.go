@@ -1,15 +1,17 @@
 package schedule
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 	"unicode/utf8"
 
-	"github.com/rgeorgiev583/sofiatraffic/i18n"
 	"github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
@@ -17,12 +19,14 @@ import (
 
 // OperationMode represents a type of urban transit line schedule classified by the frequency of vehicle arrivals (which is determined by the current day being a workday or a holiday).
 type OperationMode struct {
-	Code, Name string
+	Code string `json:"code"` // code of the operation mode
+	Name string `json:"name"` // name of the operation mode
 }
 
 // Stop represents an urban transit stop.
 type Stop struct {
-	Code, Name string
+	Code string `json:"code"` // numerical code of the stop
+	Name string `json:"name"` // name of the stop
 }
 
 // StopList represents a list of urban transit stops.
@@ -33,9 +37,10 @@ type StopMap map[string]*Stop
 
 // Route represents the sequence of stops where an urban transit line stops when traveling in a specific direction.
 type Route struct {
-	Code, Name string
-	StopList
-	StopMap
+	Code     string `json:"code"` // code of the route
+	Name     string `json:"name"` // name of the route
+	StopList `json:"stops"`
+	StopMap  `json:"-"`
 }
 
 // RouteList represents a list of routes.
@@ -46,9 +51,9 @@ type RouteMap map[string]*Route
 
 // OperationModeRoutes represents the routes for a specific urban transit line operation mode.
 type OperationModeRoutes struct {
-	*OperationMode
-	RouteList
-	RouteMap
+	*OperationMode `json:"operation_mode"`
+	RouteList      `json:"routes"`
+	RouteMap       `json:"-"`
 }
 
 // OperationModeRoutesList represents a list of OperationModeRoutes objects.
@@ -57,11 +62,15 @@ type OperationModeRoutesList []*OperationModeRoutes
 // OperationModeRoutesMap represents a map from the code of each urban transit line operation mode to its corresponding OperationModeRoutes object.
 type OperationModeRoutesMap map[string]*OperationModeRoutes
 
+// LineList represents a list of urban transit lines.
+type LineList []*Line
+
 // Line represents an urban transit line.
 type Line struct {
-	VehicleType, LineNumber string
-	OperationModeRoutesList
-	OperationModeRoutesMap
+	VehicleType             string `json:"vehicle_type"` // type of the vehicle
+	LineNumber              string `json:"line_number"`  // number of the line
+	OperationModeRoutesList `json:"operation_modes"`
+	OperationModeRoutesMap  `json:"-"`
 }
 
 type lineScannerState int
@@ -84,12 +93,6 @@ const (
 	lineScannerInsideStopAnchor
 )
 
-// DoTranslateStopNames determines whether stop names should be translated from Bulgarian to the local language.
-var DoTranslateStopNames bool
-
-// StopNameTranslator maps names of stops in Bulgarian to their translation in the local language.
-var StopNameTranslator map[string]string
-
 func translateOperationModeName(name string) string {
 	name = strings.ReplaceAll(name, l10n.BulgarianTranslator[l10n.OperationModeWeekday], l10n.Translator[l10n.OperationModeWeekday])
 	name = strings.ReplaceAll(name, l10n.BulgarianTranslator[l10n.OperationModePreHoliday], l10n.Translator[l10n.OperationModePreHoliday])
@@ -97,19 +100,26 @@ func translateOperationModeName(name string) string {
 	return name
 }
 
-// GetLine returns the urban transit line with the specified vehicleType and lineNumber.
-func GetLine(vehicleType string, lineNumber string) (line *Line, err error) {
-	linePageURL := &url.URL{
-		Scheme: Scheme,
-		Host:   Hostname,
-		Path:   "/" + vehicleType + "/" + lineNumber,
+func getLinePagePath(vehicleType string, lineNumber string) string {
+	return "/" + vehicleType + "/" + lineNumber
+}
+
+// GetLineURL returns the URL of the page of the urban transit line with the specified vehicleType and lineNumber.
+func (c *Client) GetLineURL(vehicleType string, lineNumber string) (pageURL string, err error) {
+	return c.getPageURL(getLinePagePath(vehicleType, lineNumber))
+}
+
+// GetLineContext returns the urban transit line with the specified vehicleType and lineNumber. The request is canceled when ctx is done.
+func (c *Client) GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (line *Line, err error) {
+	pageURL, body, err := c.getPage(ctx, getLinePagePath(vehicleType, lineNumber), upstream.ResourceKindScheduleLine)
+	if errors.Is(err, upstream.ErrNotFound) {
+		err = &UnknownLineError{VehicleType: vehicleType, LineNumber: lineNumber, Err: err}
+		return
 	}
-	response, err := http.Get(linePageURL.String())
 	if err != nil {
-		err = fmt.Errorf("could not initiate HTTP GET request to the schedule line page: %s", err.Error())
+		err = fmt.Errorf("could not fetch the schedule line page: %w", err)
 		return
 	}
-	defer response.Body.Close()
 
 	line = &Line{
 		VehicleType:            vehicleType,
@@ -120,7 +130,7 @@ func GetLine(vehicleType string, lineNumber string) (line *Line, err error) {
 	var route *Route
 	var stop *Stop
 	var state lineScannerState
-	for tokenizer := html.NewTokenizer(response.Body); tokenizer.Next() != html.ErrorToken; {
+	for tokenizer := html.NewTokenizer(bytes.NewReader(body)); tokenizer.Next() != html.ErrorToken; {
 		token := tokenizer.Token()
 		switch token.Type {
 		case html.StartTagToken:
@@ -148,7 +158,7 @@ func GetLine(vehicleType string, lineNumber string) (line *Line, err error) {
 					state = lineScannerInsideOperationAnchor
 					idComponents := strings.Split(id, "_")
 					if len(idComponents) != 3 || idComponents[0] != "schedule" || idComponents[2] != "button" {
-						err = fmt.Errorf("the value of the `id` attribute of the `a` element is not valid: %s", id)
+						err = &MarkupError{URL: pageURL, Detail: "the value of the `id` attribute of the `a` element is not valid: " + id}
 						return line, err
 					}
 
@@ -164,14 +174,14 @@ func GetLine(vehicleType string, lineNumber string) (line *Line, err error) {
 					state = lineScannerInsideDirectionAnchor
 					idComponents := strings.Split(id, "_")
 					if len(idComponents) != 5 || idComponents[0] != "schedule" || idComponents[1] != "direction" || idComponents[4] != "button" {
-						err = fmt.Errorf("the value of the `id` attribute of the `a` element is not valid: %s", id)
+						err = &MarkupError{URL: pageURL, Detail: "the value of the `id` attribute of the `a` element is not valid: " + id}
 						return line, err
 					}
 
 					operationModeCode := idComponents[2]
 					schedule, ok := line.OperationModeRoutesMap[operationModeCode]
 					if !ok {
-						err = fmt.Errorf("invalid operation mode code: %s", operationModeCode)
+						err = &MarkupError{URL: pageURL, Detail: "invalid operation mode code: " + operationModeCode}
 						return line, err
 					}
 
@@ -187,21 +197,21 @@ func GetLine(vehicleType string, lineNumber string) (line *Line, err error) {
 					state = lineScannerInsideStopAnchor
 					idComponents := strings.Split(id, "_")
 					if len(idComponents) != 6 || idComponents[0] != "schedule" || idComponents[2] != "direction" || idComponents[4] != "sign" {
-						err = fmt.Errorf("the value of the `id` attribute of the `a` element is not valid: %s", id)
+						err = &MarkupError{URL: pageURL, Detail: "the value of the `id` attribute of the `a` element is not valid: " + id}
 						return line, err
 					}
 
 					operationModeCode := idComponents[1]
 					schedule, ok := line.OperationModeRoutesMap[operationModeCode]
 					if !ok {
-						err = fmt.Errorf("invalid operation mode code: %s", operationModeCode)
+						err = &MarkupError{URL: pageURL, Detail: "invalid operation mode code: " + operationModeCode}
 						return line, err
 					}
 
 					routeCode := idComponents[3]
 					route, ok := schedule.RouteMap[routeCode]
 					if !ok {
-						err = fmt.Errorf("invalid route code: %s", operationModeCode)
+						err = &MarkupError{URL: pageURL, Detail: "invalid route code: " + routeCode}
 						return line, err
 					}
 
@@ -240,6 +250,49 @@ func GetLine(vehicleType string, lineNumber string) (line *Line, err error) {
 			}
 		}
 	}
+	if len(line.OperationModeRoutesList) == 0 {
+		err = &MarkupError{URL: pageURL, Detail: "no operation modes found"}
+	}
+	return
+}
+
+// GetLine returns the urban transit line with the specified vehicleType and lineNumber.
+func (c *Client) GetLine(vehicleType string, lineNumber string) (line *Line, err error) {
+	return c.GetLineContext(context.Background(), vehicleType, lineNumber)
+}
+
+// GetLine returns the urban transit line with the specified vehicleType and lineNumber using the DefaultClient.
+func GetLine(vehicleType string, lineNumber string) (line *Line, err error) {
+	return DefaultClient.GetLine(vehicleType, lineNumber)
+}
+
+// GetLineContext is the context-aware version of GetLine.
+func GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (line *Line, err error) {
+	return DefaultClient.GetLineContext(ctx, vehicleType, lineNumber)
+}
+
+// UnmarshalJSON decodes the line from its JSON representation and rebuilds the maps from codes to operation modes, routes and stops.
+func (l *Line) UnmarshalJSON(data []byte) (err error) {
+	type plainLine Line
+	err = json.Unmarshal(data, (*plainLine)(l))
+	if err != nil {
+		return
+	}
+
+	l.OperationModeRoutesMap = OperationModeRoutesMap{}
+	for _, operationModeRoutes := range l.OperationModeRoutesList {
+		operationModeRoutes.RouteMap = RouteMap{}
+		for _, route := range operationModeRoutes.RouteList {
+			route.StopMap = StopMap{}
+			for _, stop := range route.StopList {
+				route.StopMap[stop.Code] = stop
+			}
+			operationModeRoutes.RouteMap[route.Code] = route
+		}
+		if operationModeRoutes.OperationMode != nil {
+			l.OperationModeRoutesMap[operationModeRoutes.OperationMode.Code] = operationModeRoutes
+		}
+	}
 	return
 }
 
@@ -345,50 +398,96 @@ func (om *OperationMode) String() string {
 	return l10n.Translator[l10n.OperationMode] + ": " + translateOperationModeName(om.Name) + " (" + om.Code + ")"
 }
 
-func (s *Stop) String() string {
-	var translatedStopName string
-	if DoTranslateStopNames && i18n.Language == i18n.LanguageCodeEnglish {
-		translatedStopName = StopNameTranslator[s.Name]
-	} else {
+// Render returns the display representation of the stop as determined by the specified options.
+func (s *Stop) Render(options *RenderOptions) string {
+	translatedStopName, ok := options.StopNameTranslator[s.Name]
+	if !ok {
 		translatedStopName = s.Name
 	}
 	return translatedStopName + " (" + s.Code + ")"
 }
 
-func (sl StopList) String() string {
+func (s *Stop) String() string {
+	return s.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the list of stops as determined by the specified options.
+func (sl StopList) Render(options *RenderOptions) string {
 	var builder strings.Builder
 	for i, stop := range sl {
-		builder.WriteString(strconv.Itoa(i+1) + ". " + stop.String() + "\n")
+		builder.WriteString(strconv.Itoa(i+1) + ". " + stop.Render(options) + "\n")
 	}
 	return builder.String()
 }
 
+func (sl StopList) String() string {
+	return sl.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the route as determined by the specified options.
+func (r *Route) Render(options *RenderOptions) string {
+	return "### " + r.Name + " (" + r.Code + ")\n" + r.StopList.Render(options)
+}
+
 func (r *Route) String() string {
-	return "### " + r.Name + " (" + r.Code + ")\n" + r.StopList.String()
+	return r.Render(DefaultRenderOptions)
 }
 
-func (nrl RouteList) String() string {
+// Render returns the display representation of the list of routes as determined by the specified options.
+func (nrl RouteList) Render(options *RenderOptions) string {
 	var builder strings.Builder
 	for _, route := range nrl {
-		builder.WriteString(route.String() + "\n")
+		builder.WriteString(route.Render(options) + "\n")
 	}
 	return builder.String()
 }
 
-func (omr *OperationModeRoutes) String() string {
+func (nrl RouteList) String() string {
+	return nrl.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the routes for the operation mode as determined by the specified options.
+func (omr *OperationModeRoutes) Render(options *RenderOptions) string {
 	operationModeTitle := omr.OperationMode.String()
-	return operationModeTitle + "\n" + strings.Repeat("-", utf8.RuneCountInString(operationModeTitle)) + "\n" + omr.RouteList.String()
+	return operationModeTitle + "\n" + strings.Repeat("-", utf8.RuneCountInString(operationModeTitle)) + "\n" + omr.RouteList.Render(options)
 }
 
-func (omrl OperationModeRoutesList) String() string {
+func (omr *OperationModeRoutes) String() string {
+	return omr.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the list of routes for each operation mode as determined by the specified options.
+func (omrl OperationModeRoutesList) Render(options *RenderOptions) string {
 	var builder strings.Builder
 	for _, operationModeRoutes := range omrl {
-		builder.WriteString(operationModeRoutes.String() + "\n")
+		builder.WriteString(operationModeRoutes.Render(options) + "\n")
 	}
 	return builder.String()
 }
 
-func (l *Line) String() string {
+func (omrl OperationModeRoutesList) String() string {
+	return omrl.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the line as determined by the specified options.
+func (l *Line) Render(options *RenderOptions) string {
 	lineTitle := l10n.Translator[l.VehicleType] + " " + l.LineNumber
-	return lineTitle + "\n" + strings.Repeat("=", utf8.RuneCountInString(lineTitle)) + "\n\n" + l.OperationModeRoutesList.String()
+	return lineTitle + "\n" + strings.Repeat("=", utf8.RuneCountInString(lineTitle)) + "\n\n" + l.OperationModeRoutesList.Render(options)
+}
+
+func (l *Line) String() string {
+	return l.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the list of lines as determined by the specified options.
+func (ll LineList) Render(options *RenderOptions) string {
+	var builder strings.Builder
+	for _, line := range ll {
+		builder.WriteString(line.Render(options))
+	}
+	return builder.String()
+}
+
+func (ll LineList) String() string {
+	return ll.Render(DefaultRenderOptions)
 }
@@ -1,74 +1,201 @@
 package schedule
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
 	"path"
 	"strings"
 
 	"github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
 
 	"golang.org/x/net/html/atom"
 
 	"golang.org/x/net/html"
 )
 
-// Lines represents the numbers of all urban transit lines.
+// VehicleTypeLineNumbers represents the numbers of the urban transit lines served by vehicles of the specified type.
+type VehicleTypeLineNumbers struct {
+	VehicleType string   `json:"vehicle_type"` // type of the vehicle
+	LineNumbers []string `json:"line_numbers"` // numbers of the lines
+}
+
+// VehicleTypeLineNumbersList represents a list of VehicleTypeLineNumbers objects.
+type VehicleTypeLineNumbersList []*VehicleTypeLineNumbers
+
+// Lines represents the numbers of all urban transit lines grouped by vehicle type.
 type Lines struct {
-	BusLineNumbers, TrolleybusLineNumbers, TramLineNumbers []string
+	VehicleTypeLineNumbersList `json:"vehicle_types"` // should have an element for each vehicle type with at least one line
+}
+
+// linesJSON is the JSON representation of Lines, which also accepts the fields used by earlier versions (which only supported buses, trolleybuses and trams) so that older snapshots can still be read.
+type linesJSON struct {
+	VehicleTypeLineNumbersList `json:"vehicle_types"`
+	BusLineNumbers             []string `json:"bus_line_numbers"`        // numbers of the bus lines (earlier versions only)
+	TrolleybusLineNumbers      []string `json:"trolleybus_line_numbers"` // numbers of the trolleybus lines (earlier versions only)
+	TramLineNumbers            []string `json:"tram_line_numbers"`       // numbers of the tram lines (earlier versions only)
+}
+
+// VehicleTypes lists the types of the vehicles whose lines are listed by the schedule in the order in which they are listed.
+var VehicleTypes = []string{VehicleTypeBus, VehicleTypeTrolleybus, VehicleTypeTram, VehicleTypeMetro}
+
+// vehicleTypeLinesTerms maps each vehicle type to the term for the lines served by vehicles of that type.
+var vehicleTypeLinesTerms = map[string]string{
+	VehicleTypeBus:        l10n.BusLines,
+	VehicleTypeTrolleybus: l10n.TrolleybusLines,
+	VehicleTypeTram:       l10n.TramLines,
+	VehicleTypeMetro:      l10n.MetroLines,
 }
 
 const (
 	linesPagePath = "/"
 )
 
-// GetLines fetches and returns all urban transit lines.
-func GetLines() (lines *Lines, err error) {
-	linesPageURL := &url.URL{
-		Scheme: Scheme,
-		Host:   Hostname,
-		Path:   linesPagePath,
+// getVehicleTypeOrder returns the position of vehicleType in VehicleTypes (or the length of VehicleTypes if it is not listed there).
+func getVehicleTypeOrder(vehicleType string) int {
+	for i, knownVehicleType := range VehicleTypes {
+		if knownVehicleType == vehicleType {
+			return i
+		}
 	}
-	response, err := http.Get(linesPageURL.String())
-	if err != nil {
-		err = fmt.Errorf("could not initiate HTTP GET request to the schedule line list page: %s", err.Error())
+	return len(VehicleTypes)
+}
+
+// parseLineLink returns the vehicle type and the number of the line whose page is the target of the link with the specified href (or empty strings if the link does not point to the page of a line).
+func parseLineLink(href string) (vehicleType string, lineNumber string, err error) {
+	linkPath := strings.TrimPrefix(href, "/")
+	for _, knownVehicleType := range VehicleTypes {
+		if !strings.HasPrefix(linkPath, knownVehicleType+"/") {
+			continue
+		}
+
+		lineNumber, err = url.PathUnescape(path.Base(linkPath))
+		if err != nil {
+			return
+		}
+
+		vehicleType = knownVehicleType
 		return
 	}
-	defer response.Body.Close()
+	return
+}
+
+// GetLinesURL returns the URL of the page listing all urban transit lines.
+func (c *Client) GetLinesURL() (pageURL string, err error) {
+	return c.getPageURL(linesPagePath)
+}
 
-	lines = &Lines{
-		BusLineNumbers:        []string{},
-		TrolleybusLineNumbers: []string{},
-		TramLineNumbers:       []string{},
+// GetLinesContext fetches and returns all urban transit lines. The request is canceled when ctx is done.
+func (c *Client) GetLinesContext(ctx context.Context) (lines *Lines, err error) {
+	pageURL, body, err := c.getPage(ctx, linesPagePath, upstream.ResourceKindScheduleLines)
+	if err != nil {
+		err = fmt.Errorf("could not fetch the schedule line list page: %w", err)
+		return
 	}
-	for tokenizer := html.NewTokenizer(response.Body); tokenizer.Next() != html.ErrorToken; {
+
+	lines = &Lines{VehicleTypeLineNumbersList: VehicleTypeLineNumbersList{}}
+	for tokenizer := html.NewTokenizer(bytes.NewReader(body)); tokenizer.Next() != html.ErrorToken; {
 		token := tokenizer.Token()
 		if token.Type == html.StartTagToken && token.DataAtom == atom.A {
 			for _, attr := range token.Attr {
 				if atom.Lookup([]byte(attr.Key)) == atom.Href {
-					lineNumber, err := url.PathUnescape(path.Base(attr.Val))
+					vehicleType, lineNumber, err := parseLineLink(attr.Val)
 					if err != nil {
-						return lines, err
+						return lines, &MarkupError{URL: pageURL, Detail: "invalid link to line page: " + attr.Val}
 					}
 
-					if strings.HasPrefix(attr.Val, VehicleTypeTram) {
-						lines.TramLineNumbers = append(lines.TramLineNumbers, lineNumber)
-					} else if strings.HasPrefix(attr.Val, VehicleTypeTrolleybus) {
-						lines.TrolleybusLineNumbers = append(lines.TrolleybusLineNumbers, lineNumber)
-					} else if strings.HasPrefix(attr.Val, VehicleTypeBus) {
-						lines.BusLineNumbers = append(lines.BusLineNumbers, lineNumber)
+					if vehicleType != "" {
+						lines.AddLineNumber(vehicleType, lineNumber)
 					}
 				}
 			}
 		}
 	}
+	if len(lines.VehicleTypeLineNumbersList) == 0 {
+		err = &MarkupError{URL: pageURL, Detail: "no links to line pages found"}
+	}
+	return
+}
+
+// GetLines fetches and returns all urban transit lines.
+func (c *Client) GetLines() (lines *Lines, err error) {
+	return c.GetLinesContext(context.Background())
+}
+
+// GetLines fetches and returns all urban transit lines using the DefaultClient.
+func GetLines() (lines *Lines, err error) {
+	return DefaultClient.GetLines()
+}
+
+// GetLinesContext is the context-aware version of GetLines.
+func GetLinesContext(ctx context.Context) (lines *Lines, err error) {
+	return DefaultClient.GetLinesContext(ctx)
+}
+
+// GetLineNumbers returns the numbers of the lines served by vehicles of the specified vehicleType (or nil if there are none).
+func (ls *Lines) GetLineNumbers(vehicleType string) []string {
+	for _, vehicleTypeLineNumbers := range ls.VehicleTypeLineNumbersList {
+		if vehicleTypeLineNumbers.VehicleType == vehicleType {
+			return vehicleTypeLineNumbers.LineNumbers
+		}
+	}
+	return nil
+}
+
+// AddLineNumber adds the number of a line served by vehicles of the specified vehicleType. Vehicle types are listed in the order of VehicleTypes, followed by any other vehicle types in the order in which their first lines are added.
+func (ls *Lines) AddLineNumber(vehicleType string, lineNumber string) {
+	index := len(ls.VehicleTypeLineNumbersList)
+	for i, vehicleTypeLineNumbers := range ls.VehicleTypeLineNumbersList {
+		if vehicleTypeLineNumbers.VehicleType == vehicleType {
+			vehicleTypeLineNumbers.LineNumbers = append(vehicleTypeLineNumbers.LineNumbers, lineNumber)
+			return
+		}
+
+		if index == len(ls.VehicleTypeLineNumbersList) && getVehicleTypeOrder(vehicleTypeLineNumbers.VehicleType) > getVehicleTypeOrder(vehicleType) {
+			index = i
+		}
+	}
+
+	ls.VehicleTypeLineNumbersList = append(ls.VehicleTypeLineNumbersList, nil)
+	copy(ls.VehicleTypeLineNumbersList[index+1:], ls.VehicleTypeLineNumbersList[index:])
+	ls.VehicleTypeLineNumbersList[index] = &VehicleTypeLineNumbers{VehicleType: vehicleType, LineNumbers: []string{lineNumber}}
+}
+
+// UnmarshalJSON decodes the lines from JSON, including the representation used by earlier versions.
+func (ls *Lines) UnmarshalJSON(data []byte) (err error) {
+	value := &linesJSON{}
+	err = json.Unmarshal(data, value)
+	if err != nil {
+		return
+	}
+
+	ls.VehicleTypeLineNumbersList = value.VehicleTypeLineNumbersList
+	if ls.VehicleTypeLineNumbersList == nil {
+		ls.VehicleTypeLineNumbersList = VehicleTypeLineNumbersList{}
+	}
+	legacyLineNumbersList := VehicleTypeLineNumbersList{
+		{VehicleType: VehicleTypeBus, LineNumbers: value.BusLineNumbers},
+		{VehicleType: VehicleTypeTrolleybus, LineNumbers: value.TrolleybusLineNumbers},
+		{VehicleType: VehicleTypeTram, LineNumbers: value.TramLineNumbers},
+	}
+	for _, vehicleTypeLineNumbers := range legacyLineNumbersList {
+		if len(vehicleTypeLineNumbers.LineNumbers) > 0 {
+			ls.VehicleTypeLineNumbersList = append(ls.VehicleTypeLineNumbersList, vehicleTypeLineNumbers)
+		}
+	}
 	return
 }
 
 func (ls *Lines) String() (str string) {
-	str += "* " + l10n.Translator[l10n.BusLines] + ": " + strings.Join(ls.BusLineNumbers, ", ") + "\n"
-	str += "* " + l10n.Translator[l10n.TrolleybusLines] + ": " + strings.Join(ls.TrolleybusLineNumbers, ", ") + "\n"
-	str += "* " + l10n.Translator[l10n.TramLines] + ": " + strings.Join(ls.TramLineNumbers, ", ") + "\n"
+	for _, vehicleTypeLineNumbers := range ls.VehicleTypeLineNumbersList {
+		label, ok := l10n.Translator[vehicleTypeLinesTerms[vehicleTypeLineNumbers.VehicleType]]
+		if !ok {
+			label = vehicleTypeLineNumbers.VehicleType
+		}
+		str += "* " + label + ": " + strings.Join(vehicleTypeLineNumbers.LineNumbers, ", ") + "\n"
+	}
 	return
 }
@@ -0,0 +1,11 @@
+package schedule
+
+// RenderOptions determines how schedule information is rendered in its display representation.
+type RenderOptions struct {
+	DoShowOperationMode bool              // whether info about the urban transit operation mode should be displayed for DetailedTimetable objects
+	DoShowRoute         bool              // whether info about the urban transit line route should be displayed for DetailedTimetable objects
+	StopNameTranslator  map[string]string // maps names of stops in Bulgarian to their translation in the local language (stop names are not translated if nil)
+}
+
+// DefaultRenderOptions are the options used by the String methods (i.e. only the essential information is displayed and stop names are not translated).
+var DefaultRenderOptions = &RenderOptions{}
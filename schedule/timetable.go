@@ -1,20 +1,46 @@
 package schedule
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
 	"strings"
 	"unicode/utf8"
 
 	"github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
 	"golang.org/x/net/html/atom"
 
 	"golang.org/x/net/html"
 )
 
-// Timetable represents a list of urban transit vehicle arrival times.
-type Timetable []string
+// Timetable represents a list of scheduled departures of urban transit vehicles from a stop.
+type Timetable []*Departure
+
+// DetailedTimetable represents an urban transit stop timetable annotated with the line, operation mode, route and stop it belongs to.
+type DetailedTimetable struct {
+	Line          *Line
+	OperationMode *OperationMode
+	Route         *Route
+	Stop          *Stop
+	Timetable
+}
+
+// DetailedTimetableList represents a list of DetailedTimetable objects.
+type DetailedTimetableList []*DetailedTimetable
+
+// detailedTimetableJSON is the JSON representation of a DetailedTimetable, which only identifies the line and the route instead of embedding them whole.
+type detailedTimetableJSON struct {
+	VehicleType   string         `json:"vehicle_type"`
+	LineNumber    string         `json:"line_number"`
+	OperationMode *OperationMode `json:"operation_mode"`
+	RouteCode     string         `json:"route_code"`
+	RouteName     string         `json:"route_name"`
+	Stop          *Stop          `json:"stop"`
+	Timetable     Timetable      `json:"timetable"`
+}
 
 type timetableScannerState int
 
@@ -24,30 +50,33 @@ const (
 	timetableScannerNotInsideRelevantElement timetableScannerState = iota
 	timetableScannerInsideHoursCellDiv
 	timetableScannerInsideHoursCellAnchor
+	timetableScannerInsideHoursCellMarker
 )
 
-// DoShowOperationMode determines whether info about the urban transit operation mode should be displayed for DetailedTimetable objects.
-var DoShowOperationMode bool
+func getTimetablePagePath(operationModeCode string, routeCode string, stopCode string) string {
+	return timetablePagePath + "/" + operationModeCode + "/" + routeCode + "/" + stopCode
+}
 
-// DoShowRoute determines whether info about the urban transit line route should be displayed for DetailedTimetable objects.
-var DoShowRoute bool
+// GetTimetableURL returns the URL of the page containing the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode.
+func (c *Client) GetTimetableURL(operationModeCode string, routeCode string, stopCode string) (pageURL string, err error) {
+	return c.getPageURL(getTimetablePagePath(operationModeCode, routeCode, stopCode))
+}
 
-// GetTimetable fetches and returns the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode.
-func GetTimetable(operationModeCode string, routeCode string, stopCode string) (timetable Timetable, err error) {
-	timetablePageURL := &url.URL{
-		Scheme: Scheme,
-		Host:   Hostname,
-		Path:   timetablePagePath + "/" + operationModeCode + "/" + routeCode + "/" + stopCode,
+// GetTimetableContext fetches and returns the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode. The request is canceled when ctx is done.
+func (c *Client) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable Timetable, err error) {
+	pageURL, body, err := c.getPage(ctx, getTimetablePagePath(operationModeCode, routeCode, stopCode), upstream.ResourceKindScheduleTimetable)
+	if errors.Is(err, upstream.ErrNotFound) {
+		err = &UnknownStopError{OperationModeCode: operationModeCode, RouteCode: routeCode, StopCode: stopCode, Err: err}
+		return
 	}
-	response, err := http.Get(timetablePageURL.String())
 	if err != nil {
-		err = fmt.Errorf("could not initiate HTTP GET request to the schedule timetable page: %s", err.Error())
+		err = fmt.Errorf("could not fetch the schedule timetable page: %w", err)
 		return
 	}
-	defer response.Body.Close()
 
 	var state timetableScannerState
-	for tokenizer := html.NewTokenizer(response.Body); tokenizer.Next() != html.ErrorToken; {
+	isHoursCellFound := false
+	for tokenizer := html.NewTokenizer(bytes.NewReader(body)); tokenizer.Next() != html.ErrorToken; {
 		token := tokenizer.Token()
 		switch token.Type {
 		case html.StartTagToken:
@@ -62,54 +91,165 @@ func GetTimetable(operationModeCode string, routeCode string, stopCode string) (
 				}
 				if strings.Contains(class, "hours_cell") {
 					state = timetableScannerInsideHoursCellDiv
+					isHoursCellFound = true
 				}
 
 			case atom.A:
 				if state == timetableScannerInsideHoursCellDiv {
 					state = timetableScannerInsideHoursCellAnchor
 				}
+
+			case atom.Sup, atom.Span:
+				if state == timetableScannerInsideHoursCellAnchor {
+					state = timetableScannerInsideHoursCellMarker
+				}
 			}
 
 		case html.EndTagToken:
 			switch token.DataAtom {
 			case atom.A:
-				if state == timetableScannerInsideHoursCellAnchor {
+				if state == timetableScannerInsideHoursCellAnchor || state == timetableScannerInsideHoursCellMarker {
 					state = timetableScannerInsideHoursCellDiv
 				}
 
+			case atom.Sup, atom.Span:
+				if state == timetableScannerInsideHoursCellMarker {
+					state = timetableScannerInsideHoursCellAnchor
+				}
+
 			case atom.Div:
 				state = timetableScannerNotInsideRelevantElement
 			}
 
 		case html.TextToken:
-			if state == timetableScannerInsideHoursCellAnchor {
-				timetable = append(timetable, token.Data)
+			if state != timetableScannerInsideHoursCellAnchor && state != timetableScannerInsideHoursCellMarker {
+				break
+			}
+
+			text := strings.TrimSpace(token.Data)
+			if text == "" {
+				break
+			}
+
+			if state == timetableScannerInsideHoursCellMarker || !strings.Contains(text, ":") {
+				if len(timetable) == 0 {
+					err = &MarkupError{URL: pageURL, Detail: fmt.Sprintf("marker %q precedes all departure times", text)}
+					return nil, err
+				}
+
+				previousDeparture := timetable[len(timetable)-1]
+				previousDeparture.Markers += text
+				previousDeparture.Raw = strings.TrimSpace(previousDeparture.Raw) + text
+				break
 			}
+
+			departure, parseErr := ParseDeparture(text)
+			if parseErr != nil {
+				err = &MarkupError{URL: pageURL, Detail: parseErr.Error()}
+				return nil, err
+			}
+
+			timetable = append(timetable, departure)
 		}
 	}
+	if !isHoursCellFound {
+		err = &MarkupError{URL: pageURL, Detail: "no hours cells found"}
+	}
 	return
 }
 
+// GetTimetable fetches and returns the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode.
+func (c *Client) GetTimetable(operationModeCode string, routeCode string, stopCode string) (timetable Timetable, err error) {
+	return c.GetTimetableContext(context.Background(), operationModeCode, routeCode, stopCode)
+}
+
+// GetTimetable fetches and returns the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode using the DefaultClient.
+func GetTimetable(operationModeCode string, routeCode string, stopCode string) (timetable Timetable, err error) {
+	return DefaultClient.GetTimetable(operationModeCode, routeCode, stopCode)
+}
+
+// GetTimetableContext is the context-aware version of GetTimetable.
+func GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable Timetable, err error) {
+	return DefaultClient.GetTimetableContext(ctx, operationModeCode, routeCode, stopCode)
+}
+
 func (t Timetable) String() string {
-	return strings.Join(t, ", ")
+	departures := make([]string, len(t))
+	for index, departure := range t {
+		departures[index] = departure.String()
+	}
+	return strings.Join(departures, ", ")
 }
 
-func (line *Line) getTimetableStringDetails(operationModeRoutes *OperationModeRoutes, route *Route, stop *Stop) (timetableDetailsString string) {
-	stopTitle := stop.String()
-	timetableDetailsString += stopTitle + "\n" + strings.Repeat("=", utf8.RuneCountInString(stopTitle)) + "\n"
-	if DoShowOperationMode {
-		timetableDetailsString += "(" + operationModeRoutes.OperationMode.String() + ")\n"
+// Render returns the display representation of the detailed timetable as determined by the specified options.
+func (dt *DetailedTimetable) Render(options *RenderOptions) (str string) {
+	stopTitle := dt.Stop.Render(options)
+	str += stopTitle + "\n" + strings.Repeat("=", utf8.RuneCountInString(stopTitle)) + "\n"
+	if options.DoShowOperationMode {
+		str += "(" + dt.OperationMode.String() + ")\n"
 	}
-	timetableDetailsString += "* " + l10n.Translator[line.VehicleType] + " " + line.LineNumber
-	if DoShowRoute {
-		timetableDetailsString += " - " + l10n.Translator[l10n.OnRoute] + " " + route.Name + "(" + route.Code + ")"
+	str += "* " + l10n.Translator[dt.Line.VehicleType] + " " + dt.Line.LineNumber
+	if options.DoShowRoute {
+		str += " - " + l10n.Translator[l10n.OnRoute] + " " + dt.Route.Name + " (" + dt.Route.Code + ")"
 	}
-	timetableDetailsString += ": "
+	str += ": " + dt.Timetable.String() + "\n"
 	return
 }
 
-// GetDetailedTimetableString fetches and returns a detailed string representation of the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode (annotated with information obtained from the Line object).
-func (line *Line) GetDetailedTimetableString(operationModeCode string, routeCode string, stopCode string) (detailedTimetableString string, err error) {
+func (dt *DetailedTimetable) String() string {
+	return dt.Render(DefaultRenderOptions)
+}
+
+// MarshalJSON encodes the detailed timetable as a JSON object which identifies the line and the route by their codes and names.
+func (dt *DetailedTimetable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&detailedTimetableJSON{
+		VehicleType:   dt.Line.VehicleType,
+		LineNumber:    dt.Line.LineNumber,
+		OperationMode: dt.OperationMode,
+		RouteCode:     dt.Route.Code,
+		RouteName:     dt.Route.Name,
+		Stop:          dt.Stop,
+		Timetable:     dt.Timetable,
+	})
+}
+
+// UnmarshalJSON decodes the detailed timetable from the JSON representation produced by MarshalJSON. The decoded Line and Route only contain their identifying fields.
+func (dt *DetailedTimetable) UnmarshalJSON(data []byte) (err error) {
+	var value detailedTimetableJSON
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return
+	}
+
+	dt.Line = &Line{VehicleType: value.VehicleType, LineNumber: value.LineNumber}
+	dt.OperationMode = value.OperationMode
+	dt.Route = &Route{Code: value.RouteCode, Name: value.RouteName}
+	dt.Stop = value.Stop
+	dt.Timetable = value.Timetable
+	return
+}
+
+// Render returns the display representation of the list of detailed timetables as determined by the specified options.
+func (dtl DetailedTimetableList) Render(options *RenderOptions) string {
+	var builder strings.Builder
+	for _, detailedTimetable := range dtl {
+		builder.WriteString(detailedTimetable.Render(options))
+	}
+	return builder.String()
+}
+
+func (dtl DetailedTimetableList) String() string {
+	return dtl.Render(DefaultRenderOptions)
+}
+
+// TimetableSource provides urban transit stop timetables. It is implemented by Client.
+type TimetableSource interface {
+	// GetTimetableContext returns the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode. The request is canceled when ctx is done.
+	GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable Timetable, err error)
+}
+
+// GetDetailedTimetableFromSourceContext obtains the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode from source and returns it annotated with information obtained from the specified line. The request is canceled when ctx is done.
+func GetDetailedTimetableFromSourceContext(ctx context.Context, source TimetableSource, line *Line, operationModeCode string, routeCode string, stopCode string) (detailedTimetable *DetailedTimetable, err error) {
 	operationModeRoutes, ok := line.OperationModeRoutesMap[operationModeCode]
 	if !ok {
 		err = fmt.Errorf("could not find operation mode with code %s in info for line %s of type `%s`", operationModeCode, line.LineNumber, line.VehicleType)
@@ -124,36 +264,47 @@ func (line *Line) GetDetailedTimetableString(operationModeCode string, routeCode
 
 	stop, ok := route.StopMap[stopCode]
 	if !ok {
-		err = fmt.Errorf("could not find stop with code %s for route with code %s for operation mode %s of line %s of type `%s`", stopCode, routeCode, operationModeCode, line.LineNumber, line.VehicleType)
+		err = &UnknownStopError{OperationModeCode: operationModeCode, RouteCode: routeCode, StopCode: stopCode}
 		return
 	}
 
-	timetable, err := GetTimetable(operationModeCode, routeCode, stopCode)
+	timetable, err := source.GetTimetableContext(ctx, operationModeCode, routeCode, stopCode)
 	if err != nil {
 		return
 	}
 
-	detailedTimetableString += line.getTimetableStringDetails(operationModeRoutes, route, stop)
-	detailedTimetableString += timetable.String() + "\n"
+	detailedTimetable = &DetailedTimetable{
+		Line:          line,
+		OperationMode: operationModeRoutes.OperationMode,
+		Route:         route,
+		Stop:          stop,
+		Timetable:     timetable,
+	}
 	return
 }
 
-// GetDetailedTimetableStrings fetches and returns a detailed string representation of the urban transit stop timetables for the specified line matching the specified operationModeCode, routeCode and stopCode.
-func (line *Line) GetDetailedTimetableStrings(operationModeCode string, routeCode string, stopCode string) (detailedTimetableStrings []string, err error) {
-	detailedTimetableStrings = []string{}
+// GetDetailedTimetablesFromSourceContext obtains the urban transit stop timetables for the specified line matching the specified operationModeCode, routeCode and stopCode (each of which matches everything if empty) from source. The requests are canceled when ctx is done.
+func GetDetailedTimetablesFromSourceContext(ctx context.Context, source TimetableSource, line *Line, operationModeCode string, routeCode string, stopCode string) (detailedTimetables DetailedTimetableList, err error) {
+	detailedTimetables = DetailedTimetableList{}
 	for _, operationModeRoutes := range line.OperationModeRoutesList {
 		if operationModeCode == "" || operationModeRoutes.Code == operationModeCode {
 			for _, route := range operationModeRoutes.RouteList {
-				if routeCode != "" || route.Code == routeCode {
+				if routeCode == "" || route.Code == routeCode {
 					for _, stop := range route.StopList {
-						if stopCode != "" || stop.Code == stopCode {
-							timetable, err := GetTimetable(operationModeCode, routeCode, stopCode)
+						if stopCode == "" || stop.Code == stopCode {
+							timetable, err := source.GetTimetableContext(ctx, operationModeRoutes.Code, route.Code, stop.Code)
 							if err != nil {
-								return detailedTimetableStrings, err
+								return detailedTimetables, err
 							}
 
-							detailedTimetableString := line.getTimetableStringDetails(operationModeRoutes, route, stop) + timetable.String() + "\n"
-							detailedTimetableStrings = append(detailedTimetableStrings, detailedTimetableString)
+							detailedTimetable := &DetailedTimetable{
+								Line:          line,
+								OperationMode: operationModeRoutes.OperationMode,
+								Route:         route,
+								Stop:          stop,
+								Timetable:     timetable,
+							}
+							detailedTimetables = append(detailedTimetables, detailedTimetable)
 						}
 					}
 				}
@@ -162,3 +313,68 @@ func (line *Line) GetDetailedTimetableStrings(operationModeCode string, routeCod
 	}
 	return
 }
+
+// GetDetailedTimetableContext fetches and returns the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode annotated with information obtained from the specified line. The request is canceled when ctx is done.
+func (c *Client) GetDetailedTimetableContext(ctx context.Context, line *Line, operationModeCode string, routeCode string, stopCode string) (detailedTimetable *DetailedTimetable, err error) {
+	return GetDetailedTimetableFromSourceContext(ctx, c, line, operationModeCode, routeCode, stopCode)
+}
+
+// GetDetailedTimetablesContext fetches and returns the urban transit stop timetables for the specified line matching the specified operationModeCode, routeCode and stopCode (each of which matches everything if empty). The requests are canceled when ctx is done.
+func (c *Client) GetDetailedTimetablesContext(ctx context.Context, line *Line, operationModeCode string, routeCode string, stopCode string) (detailedTimetables DetailedTimetableList, err error) {
+	return GetDetailedTimetablesFromSourceContext(ctx, c, line, operationModeCode, routeCode, stopCode)
+}
+
+// GetDetailedTimetable fetches and returns the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode annotated with information obtained from the specified line.
+func (c *Client) GetDetailedTimetable(line *Line, operationModeCode string, routeCode string, stopCode string) (detailedTimetable *DetailedTimetable, err error) {
+	return c.GetDetailedTimetableContext(context.Background(), line, operationModeCode, routeCode, stopCode)
+}
+
+// GetDetailedTimetables fetches and returns the urban transit stop timetables for the specified line matching the specified operationModeCode, routeCode and stopCode (each of which matches everything if empty).
+func (c *Client) GetDetailedTimetables(line *Line, operationModeCode string, routeCode string, stopCode string) (detailedTimetables DetailedTimetableList, err error) {
+	return c.GetDetailedTimetablesContext(context.Background(), line, operationModeCode, routeCode, stopCode)
+}
+
+// GetDetailedTimetable fetches and returns the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode annotated with information obtained from the Line object using the DefaultClient.
+func (line *Line) GetDetailedTimetable(operationModeCode string, routeCode string, stopCode string) (detailedTimetable *DetailedTimetable, err error) {
+	return DefaultClient.GetDetailedTimetable(line, operationModeCode, routeCode, stopCode)
+}
+
+// GetDetailedTimetables fetches and returns the urban transit stop timetables for the line matching the specified operationModeCode, routeCode and stopCode (each of which matches everything if empty) using the DefaultClient.
+func (line *Line) GetDetailedTimetables(operationModeCode string, routeCode string, stopCode string) (detailedTimetables DetailedTimetableList, err error) {
+	return DefaultClient.GetDetailedTimetables(line, operationModeCode, routeCode, stopCode)
+}
+
+// GetDetailedTimetableContext is the context-aware version of GetDetailedTimetable.
+func (line *Line) GetDetailedTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (detailedTimetable *DetailedTimetable, err error) {
+	return DefaultClient.GetDetailedTimetableContext(ctx, line, operationModeCode, routeCode, stopCode)
+}
+
+// GetDetailedTimetablesContext is the context-aware version of GetDetailedTimetables.
+func (line *Line) GetDetailedTimetablesContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (detailedTimetables DetailedTimetableList, err error) {
+	return DefaultClient.GetDetailedTimetablesContext(ctx, line, operationModeCode, routeCode, stopCode)
+}
+
+// GetDetailedTimetableString fetches and returns a detailed string representation of the urban transit stop timetable matching the specified operationModeCode, routeCode and stopCode (annotated with information obtained from the Line object).
+//
+// Deprecated: use GetDetailedTimetable and render the result using its Render method instead.
+func (line *Line) GetDetailedTimetableString(operationModeCode string, routeCode string, stopCode string) (detailedTimetableString string, err error) {
+	detailedTimetable, err := line.GetDetailedTimetable(operationModeCode, routeCode, stopCode)
+	if err != nil {
+		return
+	}
+
+	detailedTimetableString = detailedTimetable.Render(DefaultRenderOptions)
+	return
+}
+
+// GetDetailedTimetableStrings fetches and returns a detailed string representation of the urban transit stop timetables for the specified line matching the specified operationModeCode, routeCode and stopCode.
+//
+// Deprecated: use GetDetailedTimetables and render the results using their Render methods instead.
+func (line *Line) GetDetailedTimetableStrings(operationModeCode string, routeCode string, stopCode string) (detailedTimetableStrings []string, err error) {
+	detailedTimetables, err := line.GetDetailedTimetables(operationModeCode, routeCode, stopCode)
+	detailedTimetableStrings = make([]string, len(detailedTimetables))
+	for i, detailedTimetable := range detailedTimetables {
+		detailedTimetableStrings[i] = detailedTimetable.Render(DefaultRenderOptions)
+	}
+	return
+}
@@ -0,0 +1,212 @@
+package schedule
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeTimetableSource is a TimetableSource which returns a timetable with a single departure for every stop and records the requested codes.
+type fakeTimetableSource struct {
+	requests []string
+}
+
+func (s *fakeTimetableSource) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable Timetable, err error) {
+	s.requests = append(s.requests, operationModeCode+"/"+routeCode+"/"+stopCode)
+	departure, err := ParseDeparture("12:00")
+	timetable = Timetable{departure}
+	return
+}
+
+// newTestLine returns a line with two operation modes, each with two routes of two stops.
+func newTestLine() *Line {
+	line := &Line{VehicleType: VehicleTypeBus, LineNumber: "94"}
+	for _, operationModeCode := range []string{"101", "102"} {
+		operationModeRoutes := &OperationModeRoutes{OperationMode: &OperationMode{Code: operationModeCode, Name: "mode " + operationModeCode}}
+		for _, routeCode := range []string{"201", "202"} {
+			route := &Route{Code: routeCode, Name: "route " + routeCode}
+			for _, stopCode := range []string{"0001", "0002"} {
+				route.StopList = append(route.StopList, &Stop{Code: stopCode, Name: "STOP " + stopCode})
+			}
+			operationModeRoutes.RouteList = append(operationModeRoutes.RouteList, route)
+		}
+		line.OperationModeRoutesList = append(line.OperationModeRoutesList, operationModeRoutes)
+	}
+	buildLineMaps(line)
+	return line
+}
+
+// buildLineMaps builds the maps from codes to operation modes, routes and stops of line from its lists.
+func buildLineMaps(l *Line) {
+	l.OperationModeRoutesMap = OperationModeRoutesMap{}
+	for _, operationModeRoutes := range l.OperationModeRoutesList {
+		operationModeRoutes.RouteMap = RouteMap{}
+		for _, route := range operationModeRoutes.RouteList {
+			route.StopMap = StopMap{}
+			for _, stop := range route.StopList {
+				route.StopMap[stop.Code] = stop
+			}
+			operationModeRoutes.RouteMap[route.Code] = route
+		}
+		l.OperationModeRoutesMap[operationModeRoutes.Code] = operationModeRoutes
+	}
+}
+
+func TestGetDetailedTimetablesFromSource(t *testing.T) {
+	testCases := []struct {
+		name              string
+		operationModeCode string
+		routeCode         string
+		stopCode          string
+		expectedRequests  []string
+	}{
+		{name: "all", expectedRequests: []string{"101/201/0001", "101/201/0002", "101/202/0001", "101/202/0002", "102/201/0001", "102/201/0002", "102/202/0001", "102/202/0002"}},
+		{name: "operation mode", operationModeCode: "102", expectedRequests: []string{"102/201/0001", "102/201/0002", "102/202/0001", "102/202/0002"}},
+		{name: "route", routeCode: "202", expectedRequests: []string{"101/202/0001", "101/202/0002", "102/202/0001", "102/202/0002"}},
+		{name: "stop", stopCode: "0002", expectedRequests: []string{"101/201/0002", "101/202/0002", "102/201/0002", "102/202/0002"}},
+		{name: "all codes", operationModeCode: "101", routeCode: "202", stopCode: "0001", expectedRequests: []string{"101/202/0001"}},
+		{name: "no match", routeCode: "999"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			source := &fakeTimetableSource{}
+			detailedTimetables, err := GetDetailedTimetablesFromSourceContext(context.Background(), source, newTestLine(), testCase.operationModeCode, testCase.routeCode, testCase.stopCode)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if !reflect.DeepEqual(source.requests, testCase.expectedRequests) {
+				t.Errorf("expected requests %v, got %v", testCase.expectedRequests, source.requests)
+			}
+			if len(detailedTimetables) != len(testCase.expectedRequests) {
+				t.Fatalf("expected %d timetables, got %d", len(testCase.expectedRequests), len(detailedTimetables))
+			}
+			for i, detailedTimetable := range detailedTimetables {
+				codes := detailedTimetable.OperationMode.Code + "/" + detailedTimetable.Route.Code + "/" + detailedTimetable.Stop.Code
+				if codes != testCase.expectedRequests[i] {
+					t.Errorf("expected timetable %d to be annotated with %s, got %s", i, testCase.expectedRequests[i], codes)
+				}
+			}
+		})
+	}
+}
+
+func TestGetDetailedTimetableFromSourceUnknownStop(t *testing.T) {
+	_, err := GetDetailedTimetableFromSourceContext(context.Background(), &fakeTimetableSource{}, newTestLine(), "101", "201", "9999")
+	if err == nil || !strings.Contains(err.Error(), "9999") {
+		t.Errorf("expected an error for the unknown stop, got %v", err)
+	}
+}
+
+func TestDetailedTimetableRender(t *testing.T) {
+	detailedTimetable, err := GetDetailedTimetableFromSourceContext(context.Background(), &fakeTimetableSource{}, newTestLine(), "101", "202", "0002")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	testCases := []struct {
+		name     string
+		options  *RenderOptions
+		expected string
+	}{
+		{
+			name:     "default",
+			options:  DefaultRenderOptions,
+			expected: "STOP 0002 (0002)\n================\n* bus 94: 12:00\n",
+		},
+		{
+			name:     "operation mode and route",
+			options:  &RenderOptions{DoShowOperationMode: true, DoShowRoute: true},
+			expected: "STOP 0002 (0002)\n================\n(operation mode: mode 101 (101))\n* bus 94 - on route route 202 (202): 12:00\n",
+		},
+		{
+			name:     "translated stop name",
+			options:  &RenderOptions{StopNameTranslator: map[string]string{"STOP 0002": "Stop 2"}},
+			expected: "Stop 2 (0002)\n=============\n* bus 94: 12:00\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rendered := detailedTimetable.Render(testCase.options)
+			if rendered != testCase.expected {
+				t.Errorf("expected %q, got %q", testCase.expected, rendered)
+			}
+		})
+	}
+}
+
+func TestGetDetailedTimetableStrings(t *testing.T) {
+	server := newTestServer(t, map[string]string{
+		"/server/html/schedule_load/101/201/0001": "timetable.html",
+		"/server/html/schedule_load/101/201/0002": "timetable.html",
+	}, nil)
+	previousDefaultClient := DefaultClient
+	DefaultClient = newTestClient(server, "")
+	defer func() {
+		DefaultClient = previousDefaultClient
+	}()
+
+	line := newTestLine()
+	detailedTimetableString, err := line.GetDetailedTimetableString("101", "201", "0001")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	expected := "STOP 0001 (0001)\n================\n* bus 94: 05:10, 05:40, 23:55, 00:25\n"
+	if detailedTimetableString != expected {
+		t.Errorf("expected %q, got %q", expected, detailedTimetableString)
+	}
+
+	detailedTimetableStrings, err := line.GetDetailedTimetableStrings("101", "201", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(detailedTimetableStrings) != 2 || detailedTimetableStrings[0] != expected || !strings.HasPrefix(detailedTimetableStrings[1], "STOP 0002 (0002)\n") {
+		t.Errorf("unexpected detailed timetable strings: %q", detailedTimetableStrings)
+	}
+}
+
+func TestClientGetTimetable(t *testing.T) {
+	testCases := []struct {
+		name            string
+		fixture         string
+		expectedStrings []string
+		expectedMarkers []string
+	}{
+		{
+			name:            "plain departures",
+			fixture:         "timetable.html",
+			expectedStrings: []string{"05:10", "05:40", "23:55", "00:25"},
+			expectedMarkers: []string{"", "", "", ""},
+		},
+		{
+			name:            "whitespace and markers",
+			fixture:         "timetable_markers.html",
+			expectedStrings: []string{"05:10", "05:40*", "06:15к", "23:55", "00:25"},
+			expectedMarkers: []string{"", "*", "к", "", ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := newTestServer(t, map[string]string{"/server/html/schedule_load/101/201/0001": testCase.fixture}, nil)
+			timetable, err := newTestClient(server, "").GetTimetable("101", "201", "0001")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			var departureStrings, markers []string
+			for _, departure := range timetable {
+				departureStrings = append(departureStrings, departure.String())
+				markers = append(markers, departure.Markers)
+			}
+			if !reflect.DeepEqual(departureStrings, testCase.expectedStrings) || !reflect.DeepEqual(markers, testCase.expectedMarkers) {
+				t.Errorf("expected departures %q with markers %q, got %q with markers %q", testCase.expectedStrings, testCase.expectedMarkers, departureStrings, markers)
+			}
+			if !timetable[len(timetable)-1].IsAfterMidnight() || timetable[len(timetable)-2].IsAfterMidnight() {
+				t.Error("expected only the last departure to be made after midnight")
+			}
+		})
+	}
+}
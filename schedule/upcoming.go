@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+)
+
+// UpcomingDeparture represents a scheduled departure of an urban transit vehicle from a stop on a specific date, annotated with the line, operation mode and route it belongs to.
+type UpcomingDeparture struct {
+	Line          *Line
+	OperationMode *OperationMode
+	Route         *Route
+	Stop          *Stop
+	Departure     *Departure
+	Time          time.Time // time at which the departure is made
+}
+
+// UpcomingDepartureList represents a list of UpcomingDeparture objects.
+type UpcomingDepartureList []*UpcomingDeparture
+
+// upcomingDepartureJSON is the JSON representation of an UpcomingDeparture, which only identifies the line and the route instead of embedding them whole.
+type upcomingDepartureJSON struct {
+	VehicleType   string         `json:"vehicle_type"`
+	LineNumber    string         `json:"line_number"`
+	OperationMode *OperationMode `json:"operation_mode"`
+	RouteCode     string         `json:"route_code"`
+	RouteName     string         `json:"route_name"`
+	Stop          *Stop          `json:"stop"`
+	Departure     *Departure     `json:"departure"`
+	Time          time.Time      `json:"time"`
+}
+
+// GetUpcomingDeparturesFromSourceContext obtains the timetables of the stop with the specified stopCode for every route of the operation mode of line with the specified operationModeCode which serves the stop from source and returns the departures made at or after the specified time on the service day starting on the date of serviceDate (in chronological order). An UnknownOperationModeError is returned if line has no such operation mode. The requests are canceled when ctx is done.
+func GetUpcomingDeparturesFromSourceContext(ctx context.Context, source TimetableSource, line *Line, operationModeCode string, stopCode string, serviceDate time.Time, after time.Time) (departures UpcomingDepartureList, err error) {
+	departures = UpcomingDepartureList{}
+	operationModeRoutes, ok := line.OperationModeRoutesMap[operationModeCode]
+	if !ok {
+		err = &UnknownOperationModeError{VehicleType: line.VehicleType, LineNumber: line.LineNumber, OperationModeCode: operationModeCode}
+		return
+	}
+
+	for _, route := range operationModeRoutes.RouteList {
+		stop, ok := route.StopMap[stopCode]
+		if !ok {
+			continue
+		}
+
+		timetable, err := source.GetTimetableContext(ctx, operationModeCode, route.Code, stopCode)
+		if err != nil {
+			return departures, err
+		}
+
+		for _, departure := range timetable {
+			departureTime := departure.GetTime(serviceDate)
+			if departureTime.Before(after) {
+				continue
+			}
+
+			departures = append(departures, &UpcomingDeparture{
+				Line:          line,
+				OperationMode: operationModeRoutes.OperationMode,
+				Route:         route,
+				Stop:          stop,
+				Departure:     departure,
+				Time:          departureTime,
+			})
+		}
+	}
+	departures.Sort()
+	return
+}
+
+// Render returns the display representation of the upcoming departure as determined by the specified options.
+func (ud *UpcomingDeparture) Render(options *RenderOptions) (str string) {
+	str = ud.Time.Format("15:04")
+	if ud.Departure.Markers != "" {
+		str += " " + ud.Departure.Markers
+	}
+	str += " - " + l10n.Translator[ud.Line.VehicleType] + " " + ud.Line.LineNumber
+	if options.DoShowRoute {
+		str += " - " + l10n.Translator[l10n.OnRoute] + " " + ud.Route.Name + " (" + ud.Route.Code + ")"
+	}
+	if options.DoShowOperationMode {
+		str += " (" + ud.OperationMode.String() + ")"
+	}
+	return
+}
+
+func (ud *UpcomingDeparture) String() string {
+	return ud.Render(DefaultRenderOptions)
+}
+
+// MarshalJSON encodes the upcoming departure as a JSON object which identifies the line and the route by their codes and names.
+func (ud *UpcomingDeparture) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&upcomingDepartureJSON{
+		VehicleType:   ud.Line.VehicleType,
+		LineNumber:    ud.Line.LineNumber,
+		OperationMode: ud.OperationMode,
+		RouteCode:     ud.Route.Code,
+		RouteName:     ud.Route.Name,
+		Stop:          ud.Stop,
+		Departure:     ud.Departure,
+		Time:          ud.Time,
+	})
+}
+
+// Sort sorts the list of upcoming departures in chronological order (keeping the order of simultaneous departures).
+func (udl UpcomingDepartureList) Sort() {
+	sort.SliceStable(udl, func(i, j int) bool {
+		return udl[i].Time.Before(udl[j].Time)
+	})
+}
+
+// Render returns the display representation of the list of upcoming departures as determined by the specified options.
+func (udl UpcomingDepartureList) Render(options *RenderOptions) string {
+	var builder strings.Builder
+	for _, departure := range udl {
+		builder.WriteString(departure.Render(options) + "\n")
+	}
+	return builder.String()
+}
+
+func (udl UpcomingDepartureList) String() string {
+	return udl.Render(DefaultRenderOptions)
+}
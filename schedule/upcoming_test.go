@@ -0,0 +1,130 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// mapTimetableSource is a TimetableSource serving the departure times stored in it, which maps the operation mode, route and stop codes (joined by slashes) to the raw departure times.
+type mapTimetableSource map[string][]string
+
+func (s mapTimetableSource) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable Timetable, err error) {
+	rawDepartures, ok := s[operationModeCode+"/"+routeCode+"/"+stopCode]
+	if !ok {
+		return nil, &UnknownStopError{OperationModeCode: operationModeCode, RouteCode: routeCode, StopCode: stopCode}
+	}
+
+	for _, rawDeparture := range rawDepartures {
+		departure, err := ParseDeparture(rawDeparture)
+		if err != nil {
+			return nil, err
+		}
+
+		timetable = append(timetable, departure)
+	}
+	return
+}
+
+// newUpcomingTestLine returns a line with a single operation mode (101) whose routes 201 and 202 serve stop 0002 and whose route 203 does not.
+func newUpcomingTestLine() *Line {
+	line := &Line{VehicleType: VehicleTypeBus, LineNumber: "94"}
+	operationModeRoutes := &OperationModeRoutes{OperationMode: &OperationMode{Code: "101", Name: "делник"}}
+	for index, stopCodes := range [][]string{{"0001", "0002"}, {"0002", "0003"}, {"0003", "0004"}} {
+		route := &Route{Code: strconv.Itoa(201 + index), Name: "route " + strconv.Itoa(201+index)}
+		for _, stopCode := range stopCodes {
+			route.StopList = append(route.StopList, &Stop{Code: stopCode, Name: "STOP " + stopCode})
+		}
+		operationModeRoutes.RouteList = append(operationModeRoutes.RouteList, route)
+	}
+	line.OperationModeRoutesList = OperationModeRoutesList{operationModeRoutes}
+	buildLineMaps(line)
+	return line
+}
+
+var upcomingTestSource = mapTimetableSource{
+	"101/201/0002": {"05:10", "12:00", "23:50", "00:20"},
+	"101/202/0002": {"12:00", "18:30", "01:10"},
+	"101/203/0003": {"06:00"},
+}
+
+func TestGetUpcomingDeparturesFromSource(t *testing.T) {
+	testCases := []struct {
+		name               string
+		serviceDate        time.Time
+		after              time.Time
+		expectedDepartures []string
+	}{
+		{
+			name:               "whole service day",
+			serviceDate:        time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC),
+			after:              time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC),
+			expectedDepartures: []string{"2024-05-06 05:10 201", "2024-05-06 12:00 201", "2024-05-06 12:00 202", "2024-05-06 18:30 202", "2024-05-06 23:50 201", "2024-05-07 00:20 201", "2024-05-07 01:10 202"},
+		},
+		{
+			name:               "departures at the cutoff are included",
+			serviceDate:        time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC),
+			after:              time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC),
+			expectedDepartures: []string{"2024-05-06 12:00 201", "2024-05-06 12:00 202", "2024-05-06 18:30 202", "2024-05-06 23:50 201", "2024-05-07 00:20 201", "2024-05-07 01:10 202"},
+		},
+		{
+			name:               "after midnight",
+			serviceDate:        time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC),
+			after:              time.Date(2024, time.May, 7, 0, 0, 0, 0, time.UTC),
+			expectedDepartures: []string{"2024-05-07 00:20 201", "2024-05-07 01:10 202"},
+		},
+		{
+			name:               "previous service day before the start of the current one",
+			serviceDate:        time.Date(2024, time.May, 5, 0, 0, 0, 0, time.UTC),
+			after:              time.Date(2024, time.May, 6, 0, 30, 0, 0, time.UTC),
+			expectedDepartures: []string{"2024-05-06 01:10 202"},
+		},
+		{
+			name:               "after the end of the service day",
+			serviceDate:        time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC),
+			after:              time.Date(2024, time.May, 7, 2, 0, 0, 0, time.UTC),
+			expectedDepartures: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			line := newUpcomingTestLine()
+			departures, err := GetUpcomingDeparturesFromSourceContext(context.Background(), upcomingTestSource, line, "101", "0002", testCase.serviceDate, testCase.after)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			departureStrings := []string{}
+			for _, departure := range departures {
+				departureStrings = append(departureStrings, departure.Time.Format("2006-01-02 15:04")+" "+departure.Route.Code)
+				if departure.Line != line || departure.OperationMode.Code != "101" || departure.Stop.Code != "0002" {
+					t.Errorf("unexpected line, operation mode or stop of departure %s", departure)
+				}
+			}
+			if !reflect.DeepEqual(departureStrings, testCase.expectedDepartures) {
+				t.Errorf("expected departures %v, got %v", testCase.expectedDepartures, departureStrings)
+			}
+		})
+	}
+}
+
+func TestGetUpcomingDeparturesFromSourceErrors(t *testing.T) {
+	serviceDate := time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC)
+	_, err := GetUpcomingDeparturesFromSourceContext(context.Background(), upcomingTestSource, newUpcomingTestLine(), "102", "0002", serviceDate, serviceDate)
+	var unknownOperationModeError *UnknownOperationModeError
+	if !errors.As(err, &unknownOperationModeError) || unknownOperationModeError.OperationModeCode != "102" || unknownOperationModeError.LineNumber != "94" {
+		t.Errorf("expected UnknownOperationModeError for operation mode 102, got %v", err)
+	}
+	if !errors.Is(err, ErrUnknownOperationMode) {
+		t.Errorf("expected the error to match ErrUnknownOperationMode, got %v", err)
+	}
+
+	_, err = GetUpcomingDeparturesFromSourceContext(context.Background(), mapTimetableSource{}, newUpcomingTestLine(), "101", "0002", serviceDate, serviceDate)
+	if !errors.Is(err, ErrUnknownStop) {
+		t.Errorf("expected the error of the timetable source to be returned, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package siri
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+const (
+	// Namespace is the XML namespace of SIRI documents.
+	Namespace = "http://www.siri.org.uk/siri"
+	// Version is the version of the SIRI specification implemented by the deliveries.
+	Version = "2.0"
+	// ProducerRef identifies the producer of the deliveries.
+	ProducerRef = "sofiatraffic"
+)
+
+const (
+	// VehicleModeBus is the SIRI vehicle mode of buses and trolleybuses (SIRI does not define a separate mode for the latter).
+	VehicleModeBus = "bus"
+	// VehicleModeTram is the SIRI vehicle mode of trams.
+	VehicleModeTram = "tram"
+)
+
+const (
+	// VehicleFeatureAirConditioning is the vehicle feature code of vehicles with air conditioning.
+	VehicleFeatureAirConditioning = "airConditioning"
+	// VehicleFeatureWheelchairAccess is the vehicle feature code of wheelchair-accessible vehicles.
+	VehicleFeatureWheelchairAccess = "wheelchairAccess"
+)
+
+// DirectionRefUnknown is the direction reference of vehicle journeys whose direction cannot be determined.
+const DirectionRefUnknown = "unknown"
+
+// vehicleModes maps the vehicle types used by the virtual timetable API to SIRI vehicle modes.
+var vehicleModes = map[string]string{
+	virtual.VehicleTypeBus:        VehicleModeBus,
+	virtual.VehicleTypeTrolleybus: VehicleModeBus,
+	virtual.VehicleTypeTram:       VehicleModeTram,
+}
+
+// MonitoredCall represents the call of a vehicle journey at the monitored stop.
+type MonitoredCall struct {
+	StopPointRef        string    // code of the stop
+	StopPointName       string    `xml:",omitempty"` // name of the stop
+	ExpectedArrivalTime time.Time // expected time of arrival of the vehicle at the stop
+}
+
+// MonitoredVehicleJourney represents a vehicle journey which calls at the monitored stop.
+type MonitoredVehicleJourney struct {
+	LineRef            string         // identifier of the line, which is unique across vehicle modes
+	DirectionRef       string         // identifier of the direction of the vehicle journey (DirectionRefUnknown if it cannot be determined)
+	VehicleMode        string         `xml:",omitempty"` // mode of transport of the vehicle
+	PublishedLineName  string         // number of the line as shown to passengers
+	VehicleFeatureRefs []string       `xml:"VehicleFeatureRef"` // codes of the facilities in the vehicle
+	MonitoredCall      *MonitoredCall // call of the vehicle journey at the monitored stop
+}
+
+// MonitoredStopVisit represents the expected visit of a vehicle at the monitored stop.
+type MonitoredStopVisit struct {
+	RecordedAtTime          time.Time                // time when the information was recorded
+	ItemIdentifier          string                   `xml:",omitempty"` // identifier of the visit, which is unique within the delivery
+	MonitoringRef           string                   // code of the monitored stop
+	MonitoredVehicleJourney *MonitoredVehicleJourney // vehicle journey calling at the stop
+}
+
+// StopMonitoringDelivery represents the expected visits of vehicles at a monitored stop.
+type StopMonitoringDelivery struct {
+	Version             string                `xml:"version,attr"`
+	ResponseTimestamp   time.Time             // time when the delivery was created
+	MonitoredStopVisits []*MonitoredStopVisit `xml:"MonitoredStopVisit"`
+}
+
+// ServiceDelivery represents a response containing stop monitoring deliveries.
+type ServiceDelivery struct {
+	ResponseTimestamp        time.Time                 // time when the response was created
+	ProducerRef              string                    `xml:",omitempty"` // identifier of the producer of the response
+	StopMonitoringDeliveries []*StopMonitoringDelivery `xml:"StopMonitoringDelivery"`
+}
+
+// Siri represents the root element of a SIRI document.
+type Siri struct {
+	XMLName         xml.Name         `xml:"http://www.siri.org.uk/siri Siri"`
+	Version         string           `xml:"version,attr"`
+	ServiceDelivery *ServiceDelivery // response contained in the document
+}
+
+// GetLineRef returns the SIRI line reference of the urban transit line with the specified vehicleType (as used by the virtual timetable API) and lineNumber.
+func GetLineRef(vehicleType string, lineNumber string) string {
+	return vehicleType + "-" + lineNumber
+}
+
+// GetDirectionRef returns the SIRI direction reference of the vehicle journeys of the urban transit line with the specified vehicleType (as used by the virtual timetable API) and lineNumber which call at the stop with the specified stopCode. The direction is identified by the code of the final stop of the only route of the line (as listed in routes) which passes through the stop; DirectionRefUnknown is returned if there is no such route or if several routes pass through the stop.
+func GetDirectionRef(routes virtual.VehicleTypeLineNumberRouteListListList, vehicleType string, lineNumber string, stopCode string) (directionRef string) {
+	directionRef = DirectionRefUnknown
+	for _, vehicleTypeRoutes := range routes {
+		if vehicleTypeRoutes.VehicleType != vehicleType {
+			continue
+		}
+
+		for _, lineNumberRoutes := range vehicleTypeRoutes.LineNumberRouteListList {
+			if lineNumberRoutes.LineNumber != lineNumber {
+				continue
+			}
+
+			var stopRoutes virtual.RouteList
+			for _, route := range lineNumberRoutes.RouteList {
+				for _, routeStopCode := range route.StopCodes {
+					if routeStopCode == stopCode {
+						stopRoutes = append(stopRoutes, route)
+						break
+					}
+				}
+			}
+			if len(stopRoutes) == 1 {
+				directionRef = stopRoutes[0].StopCodes[len(stopRoutes[0].StopCodes)-1]
+			}
+			return
+		}
+	}
+	return
+}
+
+// NewMonitoredStopVisits converts the expected arrivals of vehicles from the specified lines at the stop with the specified stopCode and stopName into monitored stop visits recorded at now. The directions of the vehicle journeys are determined from routes (and are unknown if it is nil). Arrivals whose time cannot be parsed are skipped (and the errors are logged).
+func NewMonitoredStopVisits(stopCode string, stopName string, arrivals virtual.LineVehicleArrivalListList, routes virtual.VehicleTypeLineNumberRouteListListList, now time.Time) (visits []*MonitoredStopVisit) {
+	now = now.Truncate(time.Second)
+	for _, lineArrivals := range arrivals {
+		lineRef := GetLineRef(lineArrivals.VehicleType, lineArrivals.LineNumber)
+		directionRef := GetDirectionRef(routes, lineArrivals.VehicleType, lineArrivals.LineNumber, stopCode)
+		for index, arrival := range lineArrivals.VehicleArrivalList {
+			arrivalTime, err := arrival.GetTime(now)
+			if err != nil {
+				log.Printf("could not parse arrival time (%s): %s", arrival.Time, err)
+				continue
+			}
+
+			var vehicleFeatureRefs []string
+			if arrival.HasAirConditioning {
+				vehicleFeatureRefs = append(vehicleFeatureRefs, VehicleFeatureAirConditioning)
+			}
+			if arrival.IsWheelchairAccessible {
+				vehicleFeatureRefs = append(vehicleFeatureRefs, VehicleFeatureWheelchairAccess)
+			}
+			visits = append(visits, &MonitoredStopVisit{
+				RecordedAtTime: now,
+				ItemIdentifier: stopCode + "-" + lineRef + "-" + strconv.Itoa(index+1),
+				MonitoringRef:  stopCode,
+				MonitoredVehicleJourney: &MonitoredVehicleJourney{
+					LineRef:            lineRef,
+					DirectionRef:       directionRef,
+					VehicleMode:        vehicleModes[lineArrivals.VehicleType],
+					PublishedLineName:  lineArrivals.LineNumber,
+					VehicleFeatureRefs: vehicleFeatureRefs,
+					MonitoredCall:      &MonitoredCall{StopPointRef: stopCode, StopPointName: stopName, ExpectedArrivalTime: arrivalTime},
+				},
+			})
+		}
+	}
+	return
+}
+
+// NewStopMonitoringDelivery converts the expected arrivals from the specified urban transit stop timetable into a stop monitoring delivery created at now. The directions of the vehicle journeys are determined from routes (and are unknown if it is nil).
+func NewStopMonitoringDelivery(timetable *virtual.StopTimetable, routes virtual.VehicleTypeLineNumberRouteListListList, now time.Time) *StopMonitoringDelivery {
+	return &StopMonitoringDelivery{
+		Version:             Version,
+		ResponseTimestamp:   now.Truncate(time.Second),
+		MonitoredStopVisits: NewMonitoredStopVisits(timetable.StopCode, timetable.StopName, timetable.LineVehicleArrivalListList, routes, now),
+	}
+}
+
+// New returns a SIRI document created at now which contains a stop monitoring delivery for each of the specified deliveries.
+func New(deliveries []*StopMonitoringDelivery, now time.Time) *Siri {
+	return &Siri{
+		Version:         Version,
+		ServiceDelivery: &ServiceDelivery{ResponseTimestamp: now.Truncate(time.Second), ProducerRef: ProducerRef, StopMonitoringDeliveries: deliveries},
+	}
+}
+
+// NewFromTimetables returns a SIRI document created at now which contains a stop monitoring delivery for each of the specified urban transit stop timetables. The directions of the vehicle journeys are determined from routes (and are unknown if it is nil).
+func NewFromTimetables(timetables virtual.StopTimetableList, routes virtual.VehicleTypeLineNumberRouteListListList, now time.Time) *Siri {
+	deliveries := make([]*StopMonitoringDelivery, len(timetables))
+	for i, timetable := range timetables {
+		deliveries[i] = NewStopMonitoringDelivery(timetable, routes, now)
+	}
+	return New(deliveries, now)
+}
+
+// Encode writes the XML representation of document (preceded by an XML declaration) to w.
+func Encode(w io.Writer, document *Siri) (err error) {
+	_, err = io.WriteString(w, xml.Header)
+	if err != nil {
+		return
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(document)
+	if err != nil {
+		return
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return
+}
@@ -0,0 +1,131 @@
+package siri
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+var testRoutes = virtual.VehicleTypeLineNumberRouteListListList{
+	{
+		VehicleType: virtual.VehicleTypeBus,
+		LineNumberRouteListList: virtual.LineNumberRouteListList{
+			{LineNumber: "94", RouteList: virtual.RouteList{{StopCodes: []string{"0001", "0002", "0003"}}, {StopCodes: []string{"0004", "0005", "0006"}}}},
+			{LineNumber: "280", RouteList: virtual.RouteList{{StopCodes: []string{"0001", "0007"}}, {StopCodes: []string{"0007", "0001"}}}},
+		},
+	},
+}
+
+func TestGetDirectionRef(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		routes               virtual.VehicleTypeLineNumberRouteListListList
+		vehicleType          string
+		lineNumber           string
+		stopCode             string
+		expectedDirectionRef string
+	}{
+		{name: "single route through the stop", routes: testRoutes, vehicleType: virtual.VehicleTypeBus, lineNumber: "94", stopCode: "0002", expectedDirectionRef: "0003"},
+		{name: "other direction", routes: testRoutes, vehicleType: virtual.VehicleTypeBus, lineNumber: "94", stopCode: "0004", expectedDirectionRef: "0006"},
+		{name: "several routes through the stop", routes: testRoutes, vehicleType: virtual.VehicleTypeBus, lineNumber: "280", stopCode: "0001", expectedDirectionRef: DirectionRefUnknown},
+		{name: "stop not on the line", routes: testRoutes, vehicleType: virtual.VehicleTypeBus, lineNumber: "94", stopCode: "0007", expectedDirectionRef: DirectionRefUnknown},
+		{name: "unknown line", routes: testRoutes, vehicleType: virtual.VehicleTypeTram, lineNumber: "94", stopCode: "0002", expectedDirectionRef: DirectionRefUnknown},
+		{name: "no routes", vehicleType: virtual.VehicleTypeBus, lineNumber: "94", stopCode: "0002", expectedDirectionRef: DirectionRefUnknown},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			directionRef := GetDirectionRef(testCase.routes, testCase.vehicleType, testCase.lineNumber, testCase.stopCode)
+			if directionRef != testCase.expectedDirectionRef {
+				t.Errorf("expected direction %q, got %q", testCase.expectedDirectionRef, directionRef)
+			}
+		})
+	}
+}
+
+func TestNewMonitoredStopVisits(t *testing.T) {
+	now := time.Date(2024, time.May, 6, 23, 50, 0, 500, time.UTC)
+	arrivals := virtual.LineVehicleArrivalListList{
+		{VehicleType: virtual.VehicleTypeBus, LineNumber: "94", VehicleArrivalList: virtual.VehicleArrivalList{{Time: "23:55:00", HasAirConditioning: true, IsWheelchairAccessible: true}, {Time: "soon"}, {Time: "00:05:00"}}},
+		{VehicleType: virtual.VehicleTypeTrolleybus, LineNumber: "2", VehicleArrivalList: virtual.VehicleArrivalList{{Time: "23:51:00"}}},
+	}
+
+	visits := NewMonitoredStopVisits("0002", "ОБЕЛЯ", arrivals, testRoutes, now)
+	if len(visits) != 3 {
+		t.Fatalf("expected the arrival with an invalid time to be skipped, got %d visits", len(visits))
+	}
+
+	testCases := []struct {
+		itemIdentifier      string
+		lineRef             string
+		directionRef        string
+		vehicleMode         string
+		vehicleFeatureRefs  []string
+		expectedArrivalTime time.Time
+	}{
+		{itemIdentifier: "0002-bus-94-1", lineRef: "bus-94", directionRef: "0003", vehicleMode: VehicleModeBus, vehicleFeatureRefs: []string{VehicleFeatureAirConditioning, VehicleFeatureWheelchairAccess}, expectedArrivalTime: time.Date(2024, time.May, 6, 23, 55, 0, 0, time.UTC)},
+		{itemIdentifier: "0002-bus-94-3", lineRef: "bus-94", directionRef: "0003", vehicleMode: VehicleModeBus, expectedArrivalTime: time.Date(2024, time.May, 7, 0, 5, 0, 0, time.UTC)},
+		{itemIdentifier: "0002-trolley-2-1", lineRef: "trolley-2", directionRef: DirectionRefUnknown, vehicleMode: VehicleModeBus, expectedArrivalTime: time.Date(2024, time.May, 6, 23, 51, 0, 0, time.UTC)},
+	}
+	for index, testCase := range testCases {
+		visit := visits[index]
+		journey := visit.MonitoredVehicleJourney
+		if visit.ItemIdentifier != testCase.itemIdentifier || !visit.RecordedAtTime.Equal(now.Truncate(time.Second)) || visit.MonitoringRef != "0002" {
+			t.Errorf("unexpected visit %d: %v", index, visit)
+		}
+		if journey.LineRef != testCase.lineRef || journey.DirectionRef != testCase.directionRef || journey.VehicleMode != testCase.vehicleMode || strings.Join(journey.VehicleFeatureRefs, ",") != strings.Join(testCase.vehicleFeatureRefs, ",") {
+			t.Errorf("unexpected vehicle journey of visit %d: %v", index, journey)
+		}
+		if journey.MonitoredCall.StopPointRef != "0002" || journey.MonitoredCall.StopPointName != "ОБЕЛЯ" || !journey.MonitoredCall.ExpectedArrivalTime.Equal(testCase.expectedArrivalTime) {
+			t.Errorf("expected the vehicle to arrive at %s, got %v", testCase.expectedArrivalTime, journey.MonitoredCall)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	now := time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC)
+	timetables := virtual.StopTimetableList{
+		{StopCode: "0002", StopName: "ОБЕЛЯ", LineVehicleArrivalListList: virtual.LineVehicleArrivalListList{{VehicleType: virtual.VehicleTypeBus, LineNumber: "94", VehicleArrivalList: virtual.VehicleArrivalList{{Time: "12:05:00"}}}}},
+	}
+	var buffer bytes.Buffer
+	err := Encode(&buffer, NewFromTimetables(timetables, nil, now))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	document := buffer.String()
+	if !strings.HasPrefix(document, xml.Header) {
+		t.Errorf("expected the document to start with an XML declaration, got %q", document)
+	}
+
+	expectedElements := []string{
+		`<Siri xmlns="http://www.siri.org.uk/siri" version="2.0">`,
+		`<ProducerRef>sofiatraffic</ProducerRef>`,
+		`<StopMonitoringDelivery version="2.0">`,
+		`<LineRef>bus-94</LineRef>`,
+		`<DirectionRef>unknown</DirectionRef>`,
+		`<VehicleMode>bus</VehicleMode>`,
+		`<ExpectedArrivalTime>2024-05-06T12:05:00Z</ExpectedArrivalTime>`,
+	}
+	previousIndex := -1
+	for _, element := range expectedElements {
+		index := strings.Index(document, element)
+		if index <= previousIndex {
+			t.Errorf("expected the document to contain %q after the previous elements, got %s", element, document)
+		}
+		previousIndex = index
+	}
+
+	var decodedDocument Siri
+	err = xml.Unmarshal(buffer.Bytes(), &decodedDocument)
+	if err != nil {
+		t.Fatalf("could not decode document: %s", err.Error())
+	}
+	if len(decodedDocument.ServiceDelivery.StopMonitoringDeliveries) != 1 || len(decodedDocument.ServiceDelivery.StopMonitoringDeliveries[0].MonitoredStopVisits) != 1 {
+		t.Errorf("expected a single delivery with a single visit, got %v", decodedDocument.ServiceDelivery)
+	}
+}
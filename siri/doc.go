@@ -0,0 +1,4 @@
+/*
+Package siri implements rendering of the expected arrivals of vehicles from the virtual timetables as SIRI (Service Interface for Real Time Information) StopMonitoring deliveries, which are consumed by many passenger information displays.
+*/
+package siri
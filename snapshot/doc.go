@@ -0,0 +1,4 @@
+/*
+Package snapshot implements exporting of all information available from the Urban Mobility Centre Web APIs into self-describing snapshots consisting of JSON files and a manifest (either in a directory or in a ZIP archive).
+*/
+package snapshot
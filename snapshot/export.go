@@ -0,0 +1,165 @@
+package snapshot
+
+import (
+	"context"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// Exporter crawls all information available through the virtual timetable and schedule clients into a snapshot.
+type Exporter struct {
+	VirtualClient       *virtual.Client  // client for the virtual timetable API (virtual.DefaultClient is used if nil)
+	ScheduleClient      *schedule.Client // client for the schedule pages (schedule.DefaultClient is used if nil)
+	DoIncludeTimetables bool             // whether the schedule timetable of every stop of every route of every line should be exported as well
+}
+
+func (e *Exporter) getVirtualClient() *virtual.Client {
+	if e.VirtualClient == nil {
+		return virtual.DefaultClient
+	}
+	return e.VirtualClient
+}
+
+func (e *Exporter) getScheduleClient() *schedule.Client {
+	if e.ScheduleClient == nil {
+		return schedule.DefaultClient
+	}
+	return e.ScheduleClient
+}
+
+// ExportContext writes the stops (in Bulgarian and English), the routes, the list of schedule lines, every schedule line and (if DoIncludeTimetables is set) every schedule timetable to w. The export is aborted if the stops, the routes or the list of lines cannot be fetched; failures to fetch individual lines and timetables are recorded in the manifest instead. The snapshot is not finalized (i.e. w should be closed by the caller). The export is canceled when ctx is done.
+func (e *Exporter) ExportContext(ctx context.Context, w *Writer) (err error) {
+	virtualClient := e.getVirtualClient()
+	scheduleClient := e.getScheduleClient()
+
+	for _, language := range []string{i18n.LanguageCodeBulgarian, i18n.LanguageCodeEnglish} {
+		sourceURL, err := virtualClient.GetStopsInLanguageURL(language)
+		if err != nil {
+			return err
+		}
+
+		stops, err := virtualClient.GetStopsInLanguageContext(ctx, language)
+		if err != nil {
+			return err
+		}
+
+		err = w.WriteJSON(&File{Path: GetStopsPath(language), Kind: upstream.ResourceKindStops, SourceURL: sourceURL, FetchedAt: time.Now()}, stops)
+		if err != nil {
+			return err
+		}
+	}
+
+	sourceURL, err := virtualClient.GetRoutesURL()
+	if err != nil {
+		return
+	}
+
+	routes, err := virtualClient.GetRoutesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	err = w.WriteJSON(&File{Path: RoutesPath, Kind: upstream.ResourceKindRoutes, SourceURL: sourceURL, FetchedAt: time.Now()}, routes)
+	if err != nil {
+		return
+	}
+
+	sourceURL, err = scheduleClient.GetLinesURL()
+	if err != nil {
+		return
+	}
+
+	lines, err := scheduleClient.GetLinesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	err = w.WriteJSON(&File{Path: LinesPath, Kind: upstream.ResourceKindScheduleLines, SourceURL: sourceURL, FetchedAt: time.Now()}, lines)
+	if err != nil {
+		return
+	}
+
+	exportedTimetablePaths := map[string]bool{}
+	exportLines := func(vehicleType string, lineNumbers []string) error {
+		for _, lineNumber := range lineNumbers {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			err := e.exportLine(ctx, w, vehicleType, lineNumber, exportedTimetablePaths)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, vehicleTypeLineNumbers := range lines.VehicleTypeLineNumbersList {
+		err = exportLines(vehicleTypeLineNumbers.VehicleType, vehicleTypeLineNumbers.LineNumbers)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Export writes all information available through the clients to w (see ExportContext).
+func (e *Exporter) Export(w *Writer) error {
+	return e.ExportContext(context.Background(), w)
+}
+
+// exportLine writes the schedule line with the specified vehicleType and lineNumber (and its timetables if necessary) to w. Only errors from w are returned; fetch failures are recorded in the manifest.
+func (e *Exporter) exportLine(ctx context.Context, w *Writer, vehicleType string, lineNumber string, exportedTimetablePaths map[string]bool) (err error) {
+	scheduleClient := e.getScheduleClient()
+	sourceURL, err := scheduleClient.GetLineURL(vehicleType, lineNumber)
+	if err != nil {
+		return
+	}
+
+	line, fetchErr := scheduleClient.GetLineContext(ctx, vehicleType, lineNumber)
+	if fetchErr != nil {
+		w.AddFailure(upstream.ResourceKindScheduleLine, sourceURL, fetchErr)
+		return
+	}
+
+	err = w.WriteJSON(&File{Path: GetLinePath(vehicleType, lineNumber), Kind: upstream.ResourceKindScheduleLine, SourceURL: sourceURL, FetchedAt: time.Now()}, line)
+	if err != nil || !e.DoIncludeTimetables {
+		return
+	}
+
+	for _, operationModeRoutes := range line.OperationModeRoutesList {
+		for _, route := range operationModeRoutes.RouteList {
+			for _, stop := range route.StopList {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				timetablePath := GetTimetablePath(operationModeRoutes.Code, route.Code, stop.Code)
+				if exportedTimetablePaths[timetablePath] {
+					continue
+				}
+
+				exportedTimetablePaths[timetablePath] = true
+				sourceURL, err := scheduleClient.GetTimetableURL(operationModeRoutes.Code, route.Code, stop.Code)
+				if err != nil {
+					return err
+				}
+
+				timetable, fetchErr := scheduleClient.GetTimetableContext(ctx, operationModeRoutes.Code, route.Code, stop.Code)
+				if fetchErr != nil {
+					w.AddFailure(upstream.ResourceKindScheduleTimetable, sourceURL, fetchErr)
+					continue
+				}
+
+				err = w.WriteJSON(&File{Path: timetablePath, Kind: upstream.ResourceKindScheduleTimetable, SourceURL: sourceURL, FetchedAt: time.Now()}, timetable)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,234 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// newExportServer returns a server which serves a network of a single bus line (94) with a single stop, lists a second bus line (404) whose page does not exist and responds with 500 Internal Server Error to the requests for the lists of stops if isStopsFailing is set.
+func newExportServer(t *testing.T, isStopsFailing bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/stops-bg.json", r.URL.Path == "/stops-en.json":
+			if isStopsFailing {
+				http.Error(w, "failure", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"c":"0001","n":"ЖК МЛАДОСТ 3"}]`))
+
+		case r.URL.Path == "/routes.json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"type":"bus","lines":[{"name":"94","routes":[{"codes":["0001"]}]}]}]`))
+
+		case r.URL.Path == "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/autobus/94">94</a><a href="/autobus/404">404</a>`))
+
+		case r.URL.Path == "/autobus/94":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a id="schedule_1_button" class="schedule_active_list_tab"><span>делник</span></a><a id="schedule_direction_1_10_button" class="schedule_view_direction_tab"><span>ЖК МЛАДОСТ 3 - ЦЕНТРАЛНА ГАРА</span></a><a id="schedule_1_direction_10_sign_0001" class="stop_change">ЖК МЛАДОСТ 3</a>`))
+
+		case strings.HasPrefix(r.URL.Path, "/server/html/schedule_load/"):
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<div class="hours_cell"><a>05:00</a><a>06:00</a></div>`))
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestExporter returns an Exporter whose clients issue requests to server and neither retry requests nor use a circuit breaker or a rate limiter.
+func newTestExporter(server *httptest.Server) *Exporter {
+	return &Exporter{
+		VirtualClient:       &virtual.Client{HTTPClient: server.Client(), ResourcesBaseURL: server.URL, ArrivalsBaseURL: server.URL, Header: http.Header{}},
+		ScheduleClient:      &schedule.Client{HTTPClient: server.Client(), BaseURL: server.URL},
+		DoIncludeTimetables: true,
+	}
+}
+
+// checkSnapshot verifies that fsys contains a complete snapshot of the network served by newExportServer.
+func checkSnapshot(t *testing.T, fsys fs.FS) {
+	data, err := fs.ReadFile(fsys, ManifestPath)
+	if err != nil {
+		t.Fatalf("could not read manifest: %s", err.Error())
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		t.Fatalf("could not decode manifest: %s", err.Error())
+	}
+
+	if manifest.FormatVersion != FormatVersion {
+		t.Errorf("expected format version %d, got %d", FormatVersion, manifest.FormatVersion)
+	}
+	if manifest.CompletedAt.Before(manifest.CreatedAt) {
+		t.Errorf("expected the export to be completed after it was created, got %s and %s", manifest.CreatedAt, manifest.CompletedAt)
+	}
+
+	var paths []string
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+
+		data, err := fs.ReadFile(fsys, file.Path)
+		if err != nil {
+			t.Errorf("could not read %s: %s", file.Path, err.Error())
+			continue
+		}
+
+		checksum := sha256.Sum256(data)
+		if file.Size != len(data) || file.SHA256 != hex.EncodeToString(checksum[:]) {
+			t.Errorf("expected the size and checksum of %s to match its contents", file.Path)
+		}
+		if file.SourceURL == "" || file.FetchedAt.IsZero() {
+			t.Errorf("expected the source of %s to be recorded, got %v", file.Path, file)
+		}
+	}
+	sort.Strings(paths)
+	expectedPaths := []string{
+		LinesPath,
+		GetLinePath(schedule.VehicleTypeBus, "94"),
+		GetTimetablePath("1", "10", "0001"),
+		RoutesPath,
+		GetStopsPath("bg"),
+		GetStopsPath("en"),
+	}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("expected files %v, got %v", expectedPaths, paths)
+	}
+
+	if len(manifest.Failures) != 1 || manifest.Failures[0].Kind != upstream.ResourceKindScheduleLine || !strings.HasSuffix(manifest.Failures[0].SourceURL, "/autobus/404") {
+		t.Errorf("expected a failure for line 404, got %v", manifest.Failures)
+	}
+
+	file := manifest.GetFile(GetTimetablePath("1", "10", "0001"))
+	if file == nil || file.Kind != upstream.ResourceKindScheduleTimetable {
+		t.Errorf("expected the timetable to be described as a schedule timetable, got %v", file)
+	}
+}
+
+func TestExportDir(t *testing.T) {
+	server := newExportServer(t, false)
+	dir := t.TempDir()
+	w, err := NewDirWriter(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	err = newTestExporter(server).Export(w)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	checkSnapshot(t, os.DirFS(dir))
+}
+
+func TestExportZip(t *testing.T) {
+	server := newExportServer(t, false)
+	var buffer bytes.Buffer
+	w := NewZipWriter(&buffer)
+	err := newTestExporter(server).Export(w)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("could not read archive: %s", err.Error())
+	}
+
+	checkSnapshot(t, zipReader)
+}
+
+func TestExportWithoutTimetables(t *testing.T) {
+	server := newExportServer(t, false)
+	dir := t.TempDir()
+	w, err := NewDirWriter(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	exporter := newTestExporter(server)
+	exporter.DoIncludeTimetables = false
+	err = exporter.Export(w)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if w.manifest.GetFile(GetLinePath(schedule.VehicleTypeBus, "94")) == nil {
+		t.Error("expected the line to be exported")
+	}
+	if w.manifest.GetFile(GetTimetablePath("1", "10", "0001")) != nil {
+		t.Error("expected the timetable not to be exported")
+	}
+}
+
+func TestExportAborted(t *testing.T) {
+	testCases := []struct {
+		name           string
+		isStopsFailing bool
+		isCanceled     bool
+	}{
+		{name: "stops unavailable", isStopsFailing: true},
+		{name: "canceled", isCanceled: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := newExportServer(t, testCase.isStopsFailing)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if testCase.isCanceled {
+				cancel()
+			}
+
+			w, err := NewDirWriter(t.TempDir())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			err = newTestExporter(server).ExportContext(ctx, w)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if testCase.isStopsFailing && !errors.Is(err, upstream.ErrUnavailable) {
+				t.Errorf("expected the error to match upstream.ErrUnavailable, got %v", err)
+			}
+			if testCase.isCanceled && !errors.Is(err, context.Canceled) {
+				t.Errorf("expected the error to match context.Canceled, got %v", err)
+			}
+			if len(w.manifest.Files) != 0 {
+				t.Errorf("expected no files to be exported, got %v", w.manifest.Files)
+			}
+		})
+	}
+}
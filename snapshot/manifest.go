@@ -0,0 +1,72 @@
+package snapshot
+
+import (
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+)
+
+// FormatVersion is the version of the snapshot layout produced by this package. It is incremented whenever the layout changes incompatibly. Version 2 lists the schedule lines grouped by vehicle type instead of in separate bus, trolleybus and tram lists.
+const FormatVersion = 2
+
+const (
+	// ManifestPath is the path of the manifest within a snapshot.
+	ManifestPath = "manifest.json"
+	// RoutesPath is the path of the list of routes from the virtual timetable API within a snapshot.
+	RoutesPath = "virtual/routes.json"
+	// LinesPath is the path of the list of schedule lines within a snapshot.
+	LinesPath = "schedule/lines.json"
+)
+
+// Manifest describes the contents of a snapshot.
+type Manifest struct {
+	FormatVersion int        `json:"format_version"` // version of the snapshot layout
+	CreatedAt     time.Time  `json:"created_at"`     // time at which the export was started
+	CompletedAt   time.Time  `json:"completed_at"`   // time at which the export was finished
+	Files         []*File    `json:"files"`          // files contained in the snapshot
+	Failures      []*Failure `json:"failures"`       // resources which could not be exported
+}
+
+// File describes a file contained in a snapshot.
+type File struct {
+	Path      string                `json:"path"`       // slash-separated path of the file within the snapshot
+	Kind      upstream.ResourceKind `json:"kind"`       // kind of the resource stored in the file
+	SourceURL string                `json:"source_url"` // URL from which the resource was fetched
+	FetchedAt time.Time             `json:"fetched_at"` // time at which the resource was fetched
+	Size      int                   `json:"size"`       // size of the file in bytes
+	SHA256    string                `json:"sha256"`     // hex-encoded SHA-256 checksum of the file
+}
+
+// Failure describes a resource which could not be exported.
+type Failure struct {
+	Kind      upstream.ResourceKind `json:"kind"`       // kind of the resource
+	SourceURL string                `json:"source_url"` // URL of the resource
+	Error     string                `json:"error"`      // description of the error
+}
+
+// GetStopsPath returns the path of the list of stops with names in the specified language within a snapshot.
+func GetStopsPath(language string) string {
+	return "virtual/stops-" + language + ".json"
+}
+
+// GetLinePath returns the path of the schedule line with the specified vehicleType and lineNumber within a snapshot.
+func GetLinePath(vehicleType string, lineNumber string) string {
+	return path.Join("schedule", "lines", url.PathEscape(vehicleType), url.PathEscape(lineNumber)+".json")
+}
+
+// GetTimetablePath returns the path of the schedule timetable matching the specified operationModeCode, routeCode and stopCode within a snapshot.
+func GetTimetablePath(operationModeCode string, routeCode string, stopCode string) string {
+	return path.Join("schedule", "timetables", url.PathEscape(operationModeCode), url.PathEscape(routeCode), url.PathEscape(stopCode)+".json")
+}
+
+// GetFile returns the description of the file with the specified path (or nil if the snapshot does not contain such a file).
+func (m *Manifest) GetFile(filePath string) *File {
+	for _, file := range m.Files {
+		if file.Path == filePath {
+			return file
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+)
+
+// Writer writes the files of a snapshot and records them in its manifest. It is safe for concurrent use.
+type Writer struct {
+	mutex    sync.Mutex
+	sink     sink
+	manifest *Manifest
+}
+
+// sink stores the files of a snapshot.
+type sink interface {
+	writeFile(filePath string, data []byte) error
+	close() error
+}
+
+type dirSink struct {
+	dir string
+}
+
+type zipSink struct {
+	writer *zip.Writer
+	closer io.Closer // closed after the archive is finalized (may be nil)
+}
+
+const zipExtension = ".zip"
+
+func newWriter(sink sink) *Writer {
+	return &Writer{
+		sink: sink,
+		manifest: &Manifest{
+			FormatVersion: FormatVersion,
+			CreatedAt:     time.Now(),
+			Files:         []*File{},
+			Failures:      []*Failure{},
+		},
+	}
+}
+
+// NewDirWriter returns a Writer which stores the snapshot in the specified directory (which is created if necessary).
+func NewDirWriter(dir string) (w *Writer, err error) {
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return
+	}
+
+	w = newWriter(&dirSink{dir: dir})
+	return
+}
+
+// NewZipWriter returns a Writer which stores the snapshot as a ZIP archive written to the specified writer.
+func NewZipWriter(writer io.Writer) *Writer {
+	return newWriter(&zipSink{writer: zip.NewWriter(writer)})
+}
+
+// Create returns a Writer which stores the snapshot at the specified path: as a ZIP archive if the path has a .zip extension and in a directory otherwise.
+func Create(snapshotPath string) (w *Writer, err error) {
+	if !strings.EqualFold(filepath.Ext(snapshotPath), zipExtension) {
+		return NewDirWriter(snapshotPath)
+	}
+
+	file, err := os.Create(snapshotPath)
+	if err != nil {
+		return
+	}
+
+	w = newWriter(&zipSink{writer: zip.NewWriter(file), closer: file})
+	return
+}
+
+// WriteJSON stores the JSON representation of value in the file described by file and records the file in the manifest. The Size and SHA256 fields of file are filled in.
+func (w *Writer) WriteJSON(file *File, value interface{}) (err error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return
+	}
+
+	checksum := sha256.Sum256(data)
+	file.Size = len(data)
+	file.SHA256 = hex.EncodeToString(checksum[:])
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	err = w.sink.writeFile(file.Path, data)
+	if err != nil {
+		return
+	}
+
+	w.manifest.Files = append(w.manifest.Files, file)
+	return
+}
+
+// AddFailure records in the manifest that the resource of the specified kind at sourceURL could not be exported because of err.
+func (w *Writer) AddFailure(kind upstream.ResourceKind, sourceURL string, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.manifest.Failures = append(w.manifest.Failures, &Failure{Kind: kind, SourceURL: sourceURL, Error: err.Error()})
+}
+
+// Close writes the manifest and finalizes the snapshot.
+func (w *Writer) Close() (err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.manifest.CompletedAt = time.Now()
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return
+	}
+
+	err = w.sink.writeFile(ManifestPath, data)
+	closeErr := w.sink.close()
+	if err == nil {
+		err = closeErr
+	}
+	return
+}
+
+func (s *dirSink) writeFile(filePath string, data []byte) (err error) {
+	fullPath := filepath.Join(s.dir, filepath.FromSlash(filePath))
+	err = os.MkdirAll(filepath.Dir(fullPath), 0755)
+	if err != nil {
+		return
+	}
+
+	return os.WriteFile(fullPath, data, 0644)
+}
+
+func (s *dirSink) close() error {
+	return nil
+}
+
+func (s *zipSink) writeFile(filePath string, data []byte) (err error) {
+	fileWriter, err := s.writer.CreateHeader(&zip.FileHeader{
+		Name:     path.Clean(filePath),
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return
+	}
+
+	_, err = fileWriter.Write(data)
+	return
+}
+
+func (s *zipSink) close() (err error) {
+	err = s.writer.Close()
+	if s.closer != nil {
+		closeErr := s.closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	return
+}
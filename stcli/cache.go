@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/format"
+	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/stcli/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// cachedResourceKinds lists the kinds of resources which are kept in the local cache.
+var cachedResourceKinds = []upstream.ResourceKind{
+	upstream.ResourceKindStops,
+	upstream.ResourceKindRoutes,
+	upstream.ResourceKindScheduleLines,
+	upstream.ResourceKindScheduleLine,
+}
+
+// cacheEntryStatus describes a resource kept in the local cache.
+type cacheEntryStatus struct {
+	Kind     upstream.ResourceKind `json:"kind"`      // kind of the resource
+	URL      string                `json:"url"`       // URL of the resource
+	StoredAt time.Time             `json:"stored_at"` // time at which the resource was fetched or last revalidated
+	Age      string                `json:"age"`       // time elapsed since the resource was fetched or last revalidated
+	Size     int                   `json:"size"`      // size of the resource in bytes
+}
+
+// cacheStatus describes all resources kept in the local cache.
+type cacheStatus []*cacheEntryStatus
+
+func getCacheStatus(diskCache *upstream.DiskCache) (status cacheStatus, err error) {
+	entries, err := diskCache.GetEntries()
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	status = cacheStatus{}
+	for _, entry := range entries {
+		status = append(status, &cacheEntryStatus{
+			Kind:     entry.Kind,
+			URL:      entry.URL,
+			StoredAt: entry.StoredAt,
+			Age:      now.Sub(entry.StoredAt).Round(time.Second).String(),
+			Size:     len(entry.Body),
+		})
+	}
+	return
+}
+
+func (cs cacheStatus) String() string {
+	var builder strings.Builder
+	writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, l10n.Translator[l10n.ResourceKind]+"\t"+l10n.Translator[l10n.Age]+"\t"+l10n.Translator[l10n.Size]+"\t"+l10n.Translator[l10n.URL])
+	for _, entry := range cs {
+		fmt.Fprintln(writer, string(entry.Kind)+"\t"+entry.Age+"\t"+strconv.Itoa(entry.Size)+"\t"+entry.URL)
+	}
+	writer.Flush()
+	return builder.String()
+}
+
+func (cs cacheStatus) Table() *format.Table {
+	table := &format.Table{Header: []string{"kind", "url", "stored_at", "age", "size"}}
+	for _, entry := range cs {
+		table.Rows = append(table.Rows, []string{string(entry.Kind), entry.URL, entry.StoredAt.Format(time.RFC3339), entry.Age, strconv.Itoa(entry.Size)})
+	}
+	return table
+}
+
+// refreshCache fetches all cacheable resources through the specified clients (which should use a cache with upstream.AlwaysStaleCachePolicy). Failures to fetch individual schedule lines are logged and do not stop the refresh.
+func refreshCache(virtualClient *virtual.Client, scheduleClient *schedule.Client) (err error) {
+	for _, language := range []string{i18n.LanguageCodeBulgarian, i18n.LanguageCodeEnglish} {
+		_, err = virtualClient.GetStopsInLanguage(language)
+		if err != nil {
+			return
+		}
+	}
+
+	_, err = virtualClient.GetRoutes()
+	if err != nil {
+		return
+	}
+
+	lines, err := scheduleClient.GetLines()
+	if err != nil {
+		return
+	}
+
+	refreshLines := func(vehicleType string, lineNumbers []string) {
+		for _, lineNumber := range lineNumbers {
+			_, err := scheduleClient.GetLine(vehicleType, lineNumber)
+			if err != nil {
+				log.Println(err.Error())
+			}
+		}
+	}
+	for _, vehicleTypeLineNumbers := range lines.VehicleTypeLineNumbersList {
+		refreshLines(vehicleTypeLineNumbers.VehicleType, vehicleTypeLineNumbers.LineNumbers)
+	}
+	return
+}
+
+func (context *commandContext) runCacheAction(diskCache *upstream.DiskCache, virtualClient *virtual.Client, scheduleClient *schedule.Client, output func(value interface{})) {
+	if diskCache == nil {
+		log.Fatalln(l10n.Translator[l10n.CacheIsNotAvailable])
+	}
+
+	if len(context.positionalArgs) != 1 {
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	switch context.positionalArgs[0] {
+	case l10n.Translator[l10n.CacheRefreshActionName]:
+		virtualClient.CachePolicy = upstream.AlwaysStaleCachePolicy
+		scheduleClient.CachePolicy = upstream.AlwaysStaleCachePolicy
+		err := refreshCache(virtualClient, scheduleClient)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+	case l10n.Translator[l10n.CacheStatusActionName]:
+		status, err := getCacheStatus(diskCache)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		output(status)
+
+	case l10n.Translator[l10n.CacheClearActionName]:
+		err := diskCache.Clear()
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, l10n.Translator[l10n.InvalidCacheActionName])
+		context.command.Usage()
+		os.Exit(1)
+	}
+}
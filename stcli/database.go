@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rgeorgiev583/sofiatraffic/database"
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/stcli/l10n"
+)
+
+const defaultDatabasePath = "sofiatraffic.db"
+
+func (context *commandContext) runDBAction(ctx context.Context, dataSource datasource.DataSource) {
+	if len(context.positionalArgs) != 1 {
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	switch context.positionalArgs[0] {
+	case l10n.Translator[l10n.DBImportActionName]:
+		db, err := database.Open(context.databasePathArg)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+		defer db.Close()
+
+		importer := &database.Importer{Source: dataSource, DoIncludeTimetables: context.doIncludeTimetables}
+		err = importer.ImportContext(ctx, db)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, l10n.Translator[l10n.InvalidDBActionName])
+		context.command.Usage()
+		os.Exit(1)
+	}
+}
+
+func (context *commandContext) runSQL(ctx context.Context, output func(value interface{})) {
+	if len(context.positionalArgs) != 1 {
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	db, err := database.OpenReadOnly(context.databasePathArg)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	defer db.Close()
+
+	result, err := database.QueryContext(ctx, db, context.positionalArgs[0])
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	output(result)
+}
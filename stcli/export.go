@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/snapshot"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+func (context *commandContext) runExport(ctx context.Context, virtualClient *virtual.Client, scheduleClient *schedule.Client) {
+	if len(context.positionalArgs) != 1 {
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	// the snapshot should reflect the current state of the upstream data, so cached responses are revalidated
+	virtualClient.CachePolicy = upstream.AlwaysStaleCachePolicy
+	scheduleClient.CachePolicy = upstream.AlwaysStaleCachePolicy
+
+	writer, err := snapshot.Create(context.positionalArgs[0])
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	exporter := &snapshot.Exporter{
+		VirtualClient:       virtualClient,
+		ScheduleClient:      scheduleClient,
+		DoIncludeTimetables: context.doIncludeTimetables,
+	}
+	err = exporter.ExportContext(ctx, writer)
+	closeErr := writer.Close()
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	if closeErr != nil {
+		log.Fatalln(closeErr.Error())
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/gtfs"
+	"github.com/rgeorgiev583/sofiatraffic/stcli/l10n"
+)
+
+const dateLayout = "2006-01-02"
+
+func (context *commandContext) parseDateArg(dateArg string) (date time.Time) {
+	if dateArg == "" {
+		return
+	}
+
+	date, err := time.ParseInLocation(dateLayout, dateArg, time.Local)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, l10n.Translator[l10n.InvalidDate]+": "+dateArg)
+		context.command.Usage()
+		os.Exit(1)
+	}
+	return
+}
+
+// getCalendar returns the service calendar read from the file passed with the calendar flag (or the default calendar if there is none).
+func (context *commandContext) getCalendar() *calendar.Calendar {
+	if context.calendarPathArg == "" {
+		return calendar.DefaultCalendar
+	}
+
+	serviceCalendar, err := calendar.ReadFile(context.calendarPathArg)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	return serviceCalendar
+}
+
+func (context *commandContext) runGTFS(ctx context.Context, dataSource datasource.DataSource) {
+	if len(context.positionalArgs) != 1 {
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	generator := &gtfs.Generator{
+		Source:     dataSource,
+		StartDate:  context.parseDateArg(context.startDateArg),
+		EndDate:    context.parseDateArg(context.endDateArg),
+		GetDayType: context.getCalendar().GetDayType,
+
+		DoAllowMissingStopLocations: context.doAllowMissingStopLocations,
+	}
+	if context.stopLocationsPathArg != "" {
+		stopLocations, err := gtfs.ReadStopLocationsFile(context.stopLocationsPathArg)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		generator.StopLocations = stopLocations
+	}
+
+	feed, err := generator.GenerateContext(ctx)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	err = feed.Create(context.positionalArgs[0])
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
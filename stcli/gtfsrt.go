@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/rgeorgiev583/sofiatraffic/gtfsrt"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+func (context *commandContext) runGTFSRT(ctx context.Context, virtualClient *virtual.Client) {
+	if context.listenAddressArg == "" && len(context.positionalArgs) != 1 || context.listenAddressArg != "" && (len(context.positionalArgs) != 0 || context.stopCodesArg == "") {
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	fetcher := &gtfsrt.Fetcher{Client: virtualClient}
+	if context.stopCodesArg != "" {
+		fetcher.StopCodes = parseList(context.stopCodesArg)
+	}
+
+	if context.listenAddressArg != "" {
+		server := &http.Server{Addr: context.listenAddressArg, Handler: fetcher}
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalln(err.Error())
+		}
+		return
+	}
+
+	message, err := fetcher.FetchContext(ctx)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	err = gtfsrt.WriteFile(context.positionalArgs[0], message)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
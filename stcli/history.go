@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	diff_l10n "github.com/rgeorgiev583/sofiatraffic/diff/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/history"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/snapshot"
+	"github.com/rgeorgiev583/sofiatraffic/stcli/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+func (context *commandContext) runHistory(ctx context.Context, virtualClient *virtual.Client, scheduleClient *schedule.Client, output func(value interface{})) {
+	historyDir, err := history.GetDefaultDir()
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	store := history.NewStore(historyDir)
+	switch len(context.positionalArgs) {
+	case 0:
+		diff_l10n.InitTranslator()
+		vehicleType, ok := diff_l10n.ReverseTranslator[context.vehicleTypesArg]
+		if !ok {
+			vehicleType = context.vehicleTypesArg
+		}
+		changes, err := store.GetChangesContext(ctx, vehicleType, context.lineNumbersArg)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		output(changes)
+
+	case 1:
+		if context.positionalArgs[0] != l10n.Translator[l10n.HistoryRecordActionName] {
+			fmt.Fprintln(os.Stderr, l10n.Translator[l10n.InvalidHistoryActionName])
+			context.command.Usage()
+			os.Exit(1)
+		}
+
+		// the record should reflect the current state of the upstream data, so cached responses are revalidated
+		virtualClient.CachePolicy = upstream.AlwaysStaleCachePolicy
+		scheduleClient.CachePolicy = upstream.AlwaysStaleCachePolicy
+
+		_, err := store.AddRecordContext(ctx, &snapshot.Exporter{VirtualClient: virtualClient, ScheduleClient: scheduleClient})
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+	default:
+		context.command.Usage()
+		os.Exit(1)
+	}
+}
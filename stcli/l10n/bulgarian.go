@@ -14,7 +14,7 @@ var BulgarianTranslator = map[string]string{
 		"\n" +
 		"Употреба:\n" +
 		"\n" +
-		"        %s <команда> [аргументи]\n" +
+		"        %s [-формат формат] [-данни път | -gtfs път] <команда> [аргументи]\n" +
 		"\n" +
 		"Командите са:\n" +
 		"\n" +
@@ -22,8 +22,19 @@ var BulgarianTranslator = map[string]string{
 		"        спирки      показва спирките на градския транспорт\n" +
 		"        линии       показва линиите на градския транспорт\n" +
 		"        маршрути    показва маршрутите на градския транспорт\n" +
+		"        кеш         управлява локалния кеш на спирките, маршрутите и линиите\n" +
+		"        експорт     записва всички спирки, маршрути, линии и разписания в снимка на данните\n" +
+		"        gtfs        генерира статичен GTFS поток от разписанието\n" +
+		"        gtfsrt      генерира или предоставя GTFS-Realtime поток с очакваните времена на пристигане\n" +
+		"        netex       генерира NeTEx публикация на мрежата и разписанието\n" +
+		"        бд          импортира всички спирки, маршрути, линии и разписания в SQLite база данни\n" +
+		"        sql         изпълнява SQL заявка към SQLite базата данни\n" +
+		"        история     показва историята на промените в маршрутите и режимите\n" +
+		"        следващи    показва следващите часове на тръгване по разписание от спирка\n" +
 		"\n" +
-		"Използвайте \"%s <команда> -h\" за повече информация за дадената команда.\n",
+		"Използвайте \"%s <команда> -h\" за повече информация за дадената команда.\n" +
+		"\n" +
+		"Опционални аргументи:\n",
 
 	TimetablesSubcommandName: "табла",
 	TimetablesSubcommandUsage: "употреба: %s табла [-л номера на линии] [-т типове превозни средства] [-с кодове на спирки] [-м кодове на маршрути] [-р кодове на режими] [-покажиВремеНаГенериране] [-покажиОставащоВреме] [-покажиУсловия] [-покажиМаршрут] [-покажиРежим] [-използвайРазписание] [-сортирайСпирки] [-преведиИменаНаСпирки] [имена на спирки]\n" +
@@ -33,26 +44,108 @@ var BulgarianTranslator = map[string]string{
 		"\n" +
 		"Опционални аргументи:\n",
 	StopsSubcommandName: "спирки",
-	StopsSubcommandUsage: "употреба: %s спирки [-сортирайСпирки] [-преведиИменаНаСпирки]\n" +
+	StopsSubcommandUsage: "употреба: %s спирки [-сортирайСпирки] [-преведиИменаНаСпирки] [-кеширани | -обнови]\n" +
 		"\n" +
 		"Спирки показва списък, съдържащ кодовете и имената на всички спирки.\n" +
 		"\n" +
 		"Опционални аргументи:\n",
 	LinesSubcommandName: "линии",
-	LinesSubcommandUsage: "употреба: %s линии\n" +
+	LinesSubcommandUsage: "употреба: %s линии [-кеширани | -обнови]\n" +
+		"\n" +
+		"Линии показва списък, съдържащ номерата на всички линии, групирани по тип на превозното средство.\n" +
 		"\n" +
-		"Линии показва списък, съдържащ номерата на всички линии, групирани по тип на превозното средство.\n",
+		"Опционални аргументи:\n",
 	RoutesSubcommandName: "маршрути",
-	RoutesSubcommandUsage: "употреба: %s маршрути -л номера на линии [-т типове превозни средства] [-използвайРазписание] [-сортирайСпирки] [-преведиИменаНаСпирки]\n" +
+	RoutesSubcommandUsage: "употреба: %s маршрути -л номера на линии [-т типове превозни средства] [-използвайРазписание] [-сортирайСпирки] [-преведиИменаНаСпирки] [-кеширани | -обнови]\n" +
 		"\n" +
 		"Маршрути показва маршрутите за всяка линия. Ако е извикана подкомандата `маршрути`, програмата просто ще изведе списък, съдържащ маршрутите на всички линии, и ще приключи. Ако са зададени `номера на линии` чрез опционален аргумент, ще бъдат изведени само маршрутите на конкретните линии. Ако са зададени `типове превозни средства` чрез опционален аргумент, ще бъдат изведени само маршрутите на превозните средства от конкретните типове.\n" +
 		"\n" +
 		"Опционални аргументи:\n",
+	CacheSubcommandName: "кеш",
+	CacheSubcommandUsage: "употреба: %s кеш обнови|състояние|изчисти\n" +
+		"\n" +
+		"Кеш управлява локалния кеш на спирките (на двата езика), маршрутите и линиите от разписанието, който се съхранява в %s.\n" +
+		"\n" +
+		"Действията са:\n" +
+		"\n" +
+		"        обнови        извлича всички спирки, маршрути и линии от разписанието и ги съхранява в кеша\n" +
+		"        състояние     показва вида, възрастта и размера на всеки кеширан ресурс\n" +
+		"        изчисти       премахва всички кеширани ресурси\n",
+
+	ExportSubcommandName: "експорт",
+	ExportSubcommandUsage: "употреба: %s експорт [-разписания] път\n" +
+		"\n" +
+		"Експорт извлича спирките (на български и английски), маршрутите, списъка с линии от разписанието и всяка линия от разписанието и ги записва като JSON файлове в зададения `път` заедно с манифест, съдържащ адреса на източника и времето на извличане на всеки файл. Снимката на данните се записва като ZIP архив, ако пътят е с разширение .zip, и в директория в противен случай.\n" +
+		"\n" +
+		"Опционални аргументи:\n",
+
+	GTFSSubcommandName: "gtfs",
+	GTFSSubcommandUsage: "употреба: %s gtfs [-начало дата] [-край дата] [-местоположенияНаСпирки път | -безМестоположенияНаСпирки] [-календар път] [-кеширани | -обнови] път\n" +
+		"\n" +
+		"Gtfs генерира статичен GTFS поток от разписанието на всяка линия и го записва като ZIP архив в зададения `път`. Курсовете се извеждат от разписанията на спирките от всеки маршрут, а режимите се съпоставят с дните от седмицата (делник - с понеделник до петък, предпразник - със събота, а празник - с неделя). На официалните празници (включително свързаните с православния Великден) се използва празничният режим, а на почивните дни, заместващи празници, паднали се в събота или неделя - предпразничният; почивни дни между празници, работни дни и други изключения могат да се подадат като опционален аргумент. Тъй като разписанието не съдържа местоположенията на спирките, които GTFS изисква, чрез опционален аргумент трябва да се подаде CSV файл във формата на stops.txt с местоположенията им; генерирането е неуспешно, ако местоположението на някоя спирка е неизвестно, освен ако това не е изрично позволено (тогава координатите на тези спирки остават празни и потокът е непълен).\n" +
+		"\n" +
+		"Опционални аргументи:\n",
+
+	GTFSRTSubcommandName: "gtfsrt",
+	GTFSRTSubcommandUsage: "употреба: %s gtfsrt ([-с кодове на спирки] път | -с кодове на спирки -слушай адрес)\n" +
+		"\n" +
+		"Gtfsrt извлича очакваните времена на пристигане на спирките със зададените `кодове на спирки` (или на всички спирки, ако не са зададени такива, което отнема много време) и ги преобразува в GTFS-Realtime поток с обновление на курса за всяко пристигане. Потокът се записва във файла, намиращ се на зададения `път`, или, ако чрез опционален аргумент е зададен `адрес` за слушане, се предоставя по HTTP, като времената на пристигане се извличат наново най-много веднъж на 30 секунди (независимо от броя на заявките). Предоставянето на потока изисква кодове на спирки.\n" +
+		"\n" +
+		"Опционални аргументи:\n",
+
+	NeTExSubcommandName: "netex",
+	NeTExSubcommandUsage: "употреба: %s netex [-начало дата] [-край дата] [-календар път] [-кеширани | -обнови] път\n" +
+		"\n" +
+		"Netex генерира NeTEx публикация, съответстваща на Европейския профил за информация за пътниците, и я записва като XML документ в зададения `път`. Публикацията съдържа всички спирки и маршрути, както и линиите, маршрутите и курсовете от разписанието на всяка линия. Курсовете се извеждат от разписанията на спирките от всеки маршрут, а режимите се съпоставят с типове дни (делник - с понеделник до петък, предпразник - със събота, а празник - с неделя). На официалните празници (включително свързаните с православния Великден) се използва празничният режим, а на почивните дни, заместващи празници, паднали се в събота или неделя - предпразничният; почивни дни между празници, работни дни и други изключения могат да се подадат като опционален аргумент.\n" +
+		"\n" +
+		"Опционални аргументи:\n",
+
+	DBSubcommandName: "бд",
+	DBSubcommandUsage: "употреба: %s бд [-базаДанни път] [-разписания] [-кеширани | -обнови] импортирай\n" +
+		"\n" +
+		"Бд управлява SQLite базата данни със спирките, линиите, маршрутите (като наредени поредици от спирки), режимите и, по избор, часовете на тръгване по разписание, която се съхранява в зададения `път`.\n" +
+		"\n" +
+		"Действията са:\n" +
+		"\n" +
+		"        импортирай    заменя съдържанието на базата данни с информацията, извлечена на живо или прочетена от снимката на данните или GTFS потока, подадени чрез опционалния аргумент -данни или -gtfs\n" +
+		"\n" +
+		"Опционални аргументи:\n",
+
+	SQLSubcommandName: "sql",
+	SQLSubcommandUsage: "употреба: %s sql [-базаДанни път] заявка\n" +
+		"\n" +
+		"Sql изпълнява зададената SQL `заявка` към SQLite базата данни (попълнена чрез командата бд импортирай), намираща се на зададения `път`, и показва резултата от нея. Базата данни трябва вече да съществува и се отваря само за четене, така че заявката не може да я промени. Базата данни съдържа таблиците stops (code, name, name_en), lines (id, vehicle_type, line_number), operation_modes (id, line_id, code, name), routes (id, line_id, operation_mode_id, code, name), route_stops (route_id, stop_index, stop_code) и departures (route_id, stop_code, time, minutes, markers). Маршрутите от API-то за виртуалните табла нямат режим, код и име. Минутите на заминаването се броят от началото на деня на разписанието, така че заминаванията след полунощ имат поне 1440 минути.\n" +
+		"\n" +
+		"Опционални аргументи:\n",
+
+	HistorySubcommandName: "история",
+	HistorySubcommandUsage: "употреба: %s история [-л номер на линия] [-т тип превозно средство] [запиши]\n" +
+		"\n" +
+		"История извежда промените в линиите, установени между последователни записи на спирките, маршрутите и линиите от разписанието, които се съхраняват в %s: добавените и премахнатите линии и маршрути, спирките, вмъкнати във, изтрити от или преместени в рамките на всеки маршрут, промените в крайните спирки на всеки маршрут, добавените и премахнатите режими на всяка линия и (освен ако промените не са ограничени до линия или тип превозно средство) добавените, премахнатите и преименуваните спирки. Промените в линиите и маршрутите се определят от маршрутите от API-то за виртуалните табла, които се съпоставят по спирките си: първо се съпоставят маршрутите с еднакви крайни спирки, а всеки от останалите маршрути се съпоставя с маршрута на линията, с който има най-дългата обща последователност от спирки, така че пренареждането на маршрутите на линия не води до промени.\n" +
+		"\n" +
+		"Действията са:\n" +
+		"\n" +
+		"        запиши    извлича текущите спирки, маршрути и линии от разписанието и ги съхранява като нов запис (това трябва да се прави редовно, например ежедневно)\n" +
+		"\n" +
+		"Опционални аргументи:\n",
+
+	NextSubcommandName: "следващи",
+	NextSubcommandUsage: "употреба: %s следващи [-л номер на линия] [-т тип превозно средство] [-н брой] [-в час] [-календар път] [-покажиМаршрут] [-покажиРежим] [-кеширани | -обнови] код на спирка\n" +
+		"\n" +
+		"Следващи извежда следващите часове на тръгване по разписание от спирката със зададения `код на спирка` в или след текущия момент (или часа, подаден като опционален аргумент) според разписанието на всяка линия, която обслужва спирката (или само на линиите, отговарящи на номера на линия и типа превозно средство, подадени като опционални аргументи). Режимът на всяка линия се избира според деня от седмицата (делник за понеделник-петък, предпразник за събота и празник за неделя), а часовете на тръгване след полунощ се отнасят към предходния ден. На официалните празници (включително свързаните с православния Великден) се използва празничният режим, а на почивните дни, заместващи празници, паднали се в събота или неделя - предпразничният; почивни дни между празници, работни дни и други изключения могат да се подадат като опционален аргумент. Ако не е зададен номер на линия, трябва да се извлекат разписанията на всички линии, които обслужват спирката според списъка с маршрути (както и на всички линии на метрото, които не са включени в него), което отнема известно време, освен ако те не са кеширани или не се четат от снимка на данните или GTFS поток.\n" +
+		"\n" +
+		"Опционални аргументи:\n",
+
+	CacheRefreshActionName:  "обнови",
+	CacheStatusActionName:   "състояние",
+	CacheClearActionName:    "изчисти",
+	DBImportActionName:      "импортирай",
+	HistoryRecordActionName: "запиши",
 
 	LineNumbersFlagName:                        "л",
 	LineNumbersFlagUsage:                       "да се изведат времената на пристигане само за превозни средства със зададените `номера на линии`, разделени със запетая",
 	VehicleTypesFlagName:                       "т",
-	VehicleTypesFlagUsage:                      "да се изведат времената на пристигане само за превозни средства от зададените `типове превозни средства` (\"%s\", \"%s\" или \"%s\"), разделени със запетая",
+	VehicleTypesFlagUsage:                      "да се изведат времената на пристигане само за превозни средства от зададените `типове превозни средства` (\"%s\", \"%s\", \"%s\" или \"%s\", като последният е наличен само в разписанието), разделени със запетая",
 	StopCodesFlagName:                          "с",
 	StopCodesFlagUsage:                         "да се изведат времената на пристигане само за спирки със зададените `кодове на спирки`, разделени със запетая (в допълнение към спирките, зададени чрез позиционни аргументи)",
 	RouteCodesFlagName:                         "м",
@@ -79,9 +172,53 @@ var BulgarianTranslator = map[string]string{
 	DoSortStopsFlagUsage:                       "да се подредят вътрешно спирките по код",
 	DoTranslateStopNamesFlagName:               "преведиИменаНаСпирки",
 	DoTranslateStopNamesFlagUsage:              "да се преведат имената на спирките от български на локалния език",
+	FormatFlagName:                             "формат",
+	FormatFlagUsage:                            "`формат` на изхода (един от %s)",
+	DataPathFlagName:                           "данни",
+	DataPathFlagUsage:                          "цялата информация да се прочете от снимката на данните (създадена чрез командата за експорт), намираща се на зададения `път`, вместо да бъде извлечена",
+	GTFSPathFlagName:                           "gtfs",
+	GTFSPathFlagUsage:                          "спирките, маршрутите, линиите и разписанията да се прочетат от статичния GTFS поток, намиращ се на зададения `път` (ZIP архив или директория), вместо да бъдат извлечени",
+	DoUseCachedDataFlagName:                    "кеширани",
+	DoUseCachedDataFlagUsage:                   "да се използват кешираните данни независимо от възрастта им (данните, които липсват в кеша, все пак се извличат)",
+	DoRefreshCachedDataFlagName:                "обнови",
+	DoRefreshCachedDataFlagUsage:               "да се извлекат актуалните данни и да се обнови кешът независимо от възрастта на кешираните данни",
+	DoIncludeTimetablesFlagName:                "разписания",
+	DoIncludeTimetablesFlagUsage:               "да се запише и разписанието за всяка спирка от всеки маршрут на всяка линия (това отнема много време)",
+	StartDateFlagName:                          "начало",
+	StartDateFlagUsage:                         "първата `дата` (във формат ГГГГ-ММ-ДД), от която потокът е валиден (по подразбиране текущият ден)",
+	EndDateFlagName:                            "край",
+	EndDateFlagUsage:                           "последната `дата` (във формат ГГГГ-ММ-ДД), до която потокът е валиден (по подразбиране година след началната дата)",
+	StopLocationsPathFlagName:                  "местоположенияНаСпирки",
+	StopLocationsPathFlagUsage:                 "местоположенията на спирките да се прочетат от CSV файла, намиращ се на зададения `път` (с колони stop_code или stop_id, stop_lat и stop_lon)",
+	DoAllowMissingStopLocationsFlagName:        "безМестоположенияНаСпирки",
+	DoAllowMissingStopLocationsFlagUsage:       "потокът да се генерира дори ако местоположенията на някои спирки са неизвестни (тогава потокът е непълен, тъй като GTFS изисква местоположението на всяка спирка)",
+	FeedStopCodesFlagUsage:                     "в потока да се включат само времената на пристигане на спирки със зададените `кодове на спирки`, разделени със запетая",
+	ListenAddressFlagName:                      "слушай",
+	ListenAddressFlagUsage:                     "потокът да се предоставя по HTTP на зададения TCP `адрес` (например \":8080\") вместо да се запише във файл",
+	DatabasePathFlagName:                       "базаДанни",
+	DatabasePathFlagUsage:                      "да се използва SQLite базата данни, намираща се на зададения `път`",
+	ImportTimetablesFlagUsage:                  "да се импортират и часовете на тръгване по разписание от всяка спирка от всеки маршрут на всяка линия (това отнема много време, освен ако не се четат от снимка на данните)",
+	HistoryLineNumberFlagUsage:                 "да се изведат промените само за линията със зададения `номер на линия`",
+	HistoryVehicleTypeFlagUsage:                "да се изведат промените само за линиите със зададения `тип превозно средство` (\"%s\", \"%s\", \"%s\" или \"%s\")",
+	NextLineNumberFlagUsage:                    "да се изведат часовете на тръгване само на линиите със зададения `номер на линия`",
+	NextVehicleTypeFlagUsage:                   "да се изведат часовете на тръгване само на линиите със зададения `тип превозно средство` (\"%s\", \"%s\", \"%s\" или \"%s\")",
+	DepartureCountFlagName:                     "н",
+	DepartureCountFlagUsage:                    "да се изведе зададеният `брой` часове на тръгване",
+	DepartureTimeFlagName:                      "в",
+	DepartureTimeFlagUsage:                     "да се изведат часовете на тръгване в или след зададения `час` (във формат ЧЧ:ММ за текущия ден или във формат ГГГГ-ММ-ДД ЧЧ:ММ) вместо текущия момент",
+	CalendarPathFlagName:                       "календар",
+	CalendarPathFlagUsage:                      "почивните дни между празници, работните дни и изрично зададените типове на дните да се прочетат от CSV файла, намиращ се на зададения `път` (с дата във формат ГГГГ-ММ-ДД и вид, т.е. \"bridge\", \"working\", \"weekday\", \"pre-holiday\" или \"holiday\", във всеки запис)",
 
 	InvalidSubcommandName:     "невалидно име на команда",
 	IncompatibleFlagsDetected: "подадени са несъвместими опционални аргументи",
+	InvalidCacheActionName:    "невалидно име на действие с кеша",
+	InvalidDBActionName:       "невалидно име на действие с базата данни",
+	InvalidHistoryActionName:  "невалидно име на действие с историята",
+	CacheIsNotAvailable:       "кешът не е достъпен",
+	InvalidDate:               "невалидна дата",
+	InvalidTime:               "невалиден час",
+
+	ArrivalsAreNotAvailableOffline: "времената на пристигане в реално време не се съдържат в снимките на данните и в GTFS потоците (използвайте опционалния аргумент -използвайРазписание, за да се покажат разписанията)",
 
 	LineNumbers:        "номера на линии",
 	VehicleTypes:       "типове превозни средства",
@@ -90,4 +227,9 @@ var BulgarianTranslator = map[string]string{
 	OperationModeCodes: "кодове на режими",
 
 	NotEnoughDetailsSpecified: "не са зададени достатъчно подробности: има нужда от следната информация",
+
+	ResourceKind: "вид на ресурса",
+	Age:          "възраст",
+	Size:         "размер",
+	URL:          "URL",
 }
@@ -14,7 +14,7 @@ var EnglishTranslator = map[string]string{
 		"\n" +
 		"Usage:\n" +
 		"\n" +
-		"        %s <command> [arguments]\n" +
+		"        %s [-format format] [-data path | -gtfs path] <command> [arguments]\n" +
 		"\n" +
 		"The commands are:\n" +
 		"\n" +
@@ -22,8 +22,19 @@ var EnglishTranslator = map[string]string{
 		"        stops         show urban transit stops\n" +
 		"        lines         show urban transit lines\n" +
 		"        routes        show urban transit routes\n" +
+		"        cache         manage the local cache of stops, routes and lines\n" +
+		"        export        export all stops, routes, lines and timetables into a snapshot\n" +
+		"        gtfs          generate a GTFS static feed from the schedule\n" +
+		"        gtfsrt        generate or serve a GTFS-Realtime feed with the expected arrivals\n" +
+		"        netex         generate a NeTEx publication of the network and the schedule\n" +
+		"        db            import all stops, routes, lines and timetables into an SQLite database\n" +
+		"        sql           run an SQL query against the SQLite database\n" +
+		"        history       show the history of the changes of the routes and operation modes\n" +
+		"        next          show the next scheduled departures from a stop\n" +
 		"\n" +
-		"Use \"%s <command> -h\" for more information about a command.\n",
+		"Use \"%s <command> -h\" for more information about a command.\n" +
+		"\n" +
+		"Flags:\n",
 
 	TimetablesSubcommandName: "timetables",
 	TimetablesSubcommandUsage: "usage: %s timetables [-l line numbers] [-t vehicle types] [-s stop codes] [-r route codes] [-o operation mode codes] [-showGenerationTime] [-showRemainingTime] [-showFacilities] [-showRoute] [-showOperationMode] [-useSchedule] [-sortStops] [-translateStopNames] [stop names]\n" +
@@ -33,26 +44,108 @@ var EnglishTranslator = map[string]string{
 		"\n" +
 		"Flags:\n",
 	StopsSubcommandName: "stops",
-	StopsSubcommandUsage: "usage: %s stops [-sortStops] [-translateStopNames]\n" +
+	StopsSubcommandUsage: "usage: %s stops [-sortStops] [-translateStopNames] [-cached | -refresh]\n" +
 		"\n" +
 		"Stops shows a list containing the code and name of each stop.\n" +
 		"\n" +
 		"Flags:\n",
 	LinesSubcommandName: "lines",
-	LinesSubcommandUsage: "usage: %s lines\n" +
+	LinesSubcommandUsage: "usage: %s lines [-cached | -refresh]\n" +
+		"\n" +
+		"Lines shows a list containing the numbers of all lines grouped by vehicle type.\n" +
 		"\n" +
-		"Lines shows a list containing the numbers of all lines grouped by vehicle type.\n",
+		"Flags:\n",
 	RoutesSubcommandName: "routes",
-	RoutesSubcommandUsage: "usage: %s routes -l line numbers [-t vehicle types] [-useSchedule] [-sortStops] [-translateStopNames]\n" +
+	RoutesSubcommandUsage: "usage: %s routes -l line numbers [-t vehicle types] [-useSchedule] [-sortStops] [-translateStopNames] [-cached | -refresh]\n" +
 		"\n" +
 		"Routes shows the routes for each line. If `line numbers` are passed as an optional argument, only routes for the respective lines will be shown. If `vehicle types` are passed as an optional argument, only routes for the respective vehicle types will be shown.\n" +
 		"\n" +
 		"Flags:\n",
+	CacheSubcommandName: "cache",
+	CacheSubcommandUsage: "usage: %s cache refresh|status|clear\n" +
+		"\n" +
+		"Cache manages the local cache of stops (in both languages), routes and schedule lines, which is kept in %s.\n" +
+		"\n" +
+		"The actions are:\n" +
+		"\n" +
+		"        refresh    fetch all stops, routes and schedule lines and store them in the cache\n" +
+		"        status     show the kind, age and size of each cached resource\n" +
+		"        clear      remove all cached resources\n",
+
+	ExportSubcommandName: "export",
+	ExportSubcommandUsage: "usage: %s export [-timetables] path\n" +
+		"\n" +
+		"Export fetches the stops (in Bulgarian and English), the routes, the list of schedule lines and every schedule line and stores them as JSON files at the specified `path` together with a manifest containing the source URL and fetch time of each file. The snapshot is stored as a ZIP archive if the path has a .zip extension and in a directory otherwise.\n" +
+		"\n" +
+		"Flags:\n",
+
+	GTFSSubcommandName: "gtfs",
+	GTFSSubcommandUsage: "usage: %s gtfs [-start date] [-end date] [-stopLocations path | -allowMissingStopLocations] [-calendar path] [-cached | -refresh] path\n" +
+		"\n" +
+		"Gtfs generates a GTFS static feed from the schedule of every line and stores it as a ZIP archive at the specified `path`. The trips are inferred from the timetables of the stops of each route, and the operation modes are mapped to days of the week (weekday to Monday-Friday, pre-holiday to Saturday and holiday to Sunday). On the official holidays (including the ones related to Orthodox Easter) the holiday operation mode is used, while on the days off substituting for holidays falling on weekends the pre-holiday one is used; bridge days, working days and other exceptions can be passed as an optional argument. Since the schedule does not contain the locations of the stops, which GTFS requires, a stops.txt-style CSV file with their locations should be passed as an optional argument; the generation fails if the location of any stop is unknown, unless it is explicitly allowed (in which case the coordinates of those stops are left empty and the feed is incomplete).\n" +
+		"\n" +
+		"Flags:\n",
+
+	GTFSRTSubcommandName: "gtfsrt",
+	GTFSRTSubcommandUsage: "usage: %s gtfsrt ([-s stop codes] path | -s stop codes -listen address)\n" +
+		"\n" +
+		"Gtfsrt fetches the expected arrivals at the stops with the specified `stop codes` (or at all stops if none are specified, which takes a long time) and converts them into a GTFS-Realtime feed with a trip update for each arrival. The feed is written to the file at the specified `path` or, if a listen `address` is passed as an optional argument, served over HTTP with the arrivals fetched anew at most every 30 seconds (regardless of the number of requests). Serving the feed requires stop codes.\n" +
+		"\n" +
+		"Flags:\n",
+
+	NeTExSubcommandName: "netex",
+	NeTExSubcommandUsage: "usage: %s netex [-start date] [-end date] [-calendar path] [-cached | -refresh] path\n" +
+		"\n" +
+		"Netex generates a NeTEx publication conforming to the European Passenger Information Profile and writes it as an XML document to the specified `path`. The publication contains all stops and routes along with the lines, routes and service journeys from the schedule of every line. The service journeys are inferred from the timetables of the stops of each route, and the operation modes are mapped to day types (weekday to Monday-Friday, pre-holiday to Saturday and holiday to Sunday). On the official holidays (including the ones related to Orthodox Easter) the holiday operation mode is used, while on the days off substituting for holidays falling on weekends the pre-holiday one is used; bridge days, working days and other exceptions can be passed as an optional argument.\n" +
+		"\n" +
+		"Flags:\n",
+
+	DBSubcommandName: "db",
+	DBSubcommandUsage: "usage: %s db [-database path] [-timetables] [-cached | -refresh] import\n" +
+		"\n" +
+		"Db manages the SQLite database with the stops, lines, routes (as ordered sequences of stops), operation modes and, optionally, scheduled departures, which is stored at the specified `path`.\n" +
+		"\n" +
+		"The actions are:\n" +
+		"\n" +
+		"        import    replace the contents of the database with the information fetched live or read from the snapshot or GTFS feed passed with the -data or -gtfs flag\n" +
+		"\n" +
+		"Flags:\n",
+
+	SQLSubcommandName: "sql",
+	SQLSubcommandUsage: "usage: %s sql [-database path] query\n" +
+		"\n" +
+		"Sql runs the specified SQL `query` against the SQLite database (populated by the db import command) at the specified `path` and shows its result. The database must already exist and is opened in read-only mode, so the query cannot modify it. The database contains the tables stops (code, name, name_en), lines (id, vehicle_type, line_number), operation_modes (id, line_id, code, name), routes (id, line_id, operation_mode_id, code, name), route_stops (route_id, stop_index, stop_code) and departures (route_id, stop_code, time, minutes, markers). Routes from the virtual timetable API have no operation mode, code or name. The minutes of a departure are counted from the start of its service day, so departures after midnight have at least 1440 minutes.\n" +
+		"\n" +
+		"Flags:\n",
+
+	HistorySubcommandName: "history",
+	HistorySubcommandUsage: "usage: %s history [-l line number] [-t vehicle type] [record]\n" +
+		"\n" +
+		"History shows the changes of the lines observed between consecutive records of the stops, routes and schedule lines, which are kept in %s: the added and removed lines and routes, the stops inserted into, deleted from or reordered in each route, the changes of the terminal stops of each route, the added and removed operation modes of each line and (unless the changes are limited to a line or a vehicle type) the added, removed and renamed stops. The changes of the lines and routes are determined from the routes from the REST API for virtual timetables, which are matched by their stops: routes with the same terminal stops are matched first, and each of the remaining routes is matched to the route of the line with which it has the longest common sequence of stops, so reordering the routes of a line does not produce any changes.\n" +
+		"\n" +
+		"The actions are:\n" +
+		"\n" +
+		"        record    fetch the current stops, routes and schedule lines and keep them as a new record (this should be done regularly, e.g. daily)\n" +
+		"\n" +
+		"Flags:\n",
+
+	NextSubcommandName: "next",
+	NextSubcommandUsage: "usage: %s next [-l line number] [-t vehicle type] [-n count] [-at time] [-calendar path] [-showRoute] [-showOperationMode] [-cached | -refresh] stop code\n" +
+		"\n" +
+		"Next shows the next scheduled departures from the stop with the specified `stop code` at or after the current time (or the time passed as an optional argument) according to the schedule of every line serving the stop (or only of the lines matching the line number and vehicle type passed as optional arguments). The operation mode of each line is selected based on the day of the week (weekday for Monday-Friday, pre-holiday for Saturday and holiday for Sunday), and departures made after midnight are counted towards the previous day. On the official holidays (including the ones related to Orthodox Easter) the holiday operation mode is used, while on the days off substituting for holidays falling on weekends the pre-holiday one is used; bridge days, working days and other exceptions can be passed as an optional argument. Unless a line number is specified, the schedules of all lines serving the stop according to the list of routes (as well as of all metro lines, which are not included in it) have to be obtained, which takes a while unless they are cached or read from a snapshot or GTFS feed.\n" +
+		"\n" +
+		"Flags:\n",
+
+	CacheRefreshActionName:  "refresh",
+	CacheStatusActionName:   "status",
+	CacheClearActionName:    "clear",
+	DBImportActionName:      "import",
+	HistoryRecordActionName: "record",
 
 	LineNumbersFlagName:                        "l",
 	LineNumbersFlagUsage:                       "only output timetables for vehicles with the specified comma-separated `line numbers`",
 	VehicleTypesFlagName:                       "t",
-	VehicleTypesFlagUsage:                      "only output timetables for vehicles of the specified comma-separated `vehicle types` (\"%s\", \"%s\" or \"%s\")",
+	VehicleTypesFlagUsage:                      "only output timetables for vehicles of the specified comma-separated `vehicle types` (\"%s\", \"%s\", \"%s\" or \"%s\", the last of which is only available in the schedule)",
 	StopCodesFlagName:                          "s",
 	StopCodesFlagUsage:                         "only output timetables for stops with the specified comma-separated `stop codes` (in addition to stops passed as positional arguments)",
 	RouteCodesFlagName:                         "r",
@@ -79,9 +172,53 @@ var EnglishTranslator = map[string]string{
 	DoSortStopsFlagUsage:                       "sort list of stops by code internally",
 	DoTranslateStopNamesFlagName:               "translateStopNames",
 	DoTranslateStopNamesFlagUsage:              "translate names of stops from Bulgarian to the local language",
+	FormatFlagName:                             "format",
+	FormatFlagUsage:                            "output `format` (one of %s)",
+	DataPathFlagName:                           "data",
+	DataPathFlagUsage:                          "read all information from the snapshot (produced by the export command) at the specified `path` instead of fetching it",
+	GTFSPathFlagName:                           "gtfs",
+	GTFSPathFlagUsage:                          "read the stops, routes, lines and timetables from the GTFS static feed at the specified `path` (a ZIP archive or a directory) instead of fetching them",
+	DoUseCachedDataFlagName:                    "cached",
+	DoUseCachedDataFlagUsage:                   "use the cached data regardless of its age (data missing from the cache is still fetched)",
+	DoRefreshCachedDataFlagName:                "refresh",
+	DoRefreshCachedDataFlagUsage:               "fetch live data and update the cache regardless of the age of the cached data",
+	DoIncludeTimetablesFlagName:                "timetables",
+	DoIncludeTimetablesFlagUsage:               "also export the schedule timetable for each stop of each route of each line (this takes a long time)",
+	StartDateFlagName:                          "start",
+	StartDateFlagUsage:                         "first `date` (in the YYYY-MM-DD format) when the feed is valid (defaults to the current day)",
+	EndDateFlagName:                            "end",
+	EndDateFlagUsage:                           "last `date` (in the YYYY-MM-DD format) when the feed is valid (defaults to a year after the start date)",
+	StopLocationsPathFlagName:                  "stopLocations",
+	StopLocationsPathFlagUsage:                 "read the locations of the stops from the CSV file at the specified `path` (with stop_code or stop_id, stop_lat and stop_lon columns)",
+	DoAllowMissingStopLocationsFlagName:        "allowMissingStopLocations",
+	DoAllowMissingStopLocationsFlagUsage:       "generate the feed even if the locations of some stops are unknown (the feed is incomplete then, since GTFS requires the location of every stop)",
+	FeedStopCodesFlagUsage:                     "only include arrivals at stops with the specified comma-separated `stop codes` in the feed",
+	ListenAddressFlagName:                      "listen",
+	ListenAddressFlagUsage:                     "serve the feed over HTTP on the specified TCP `address` (e.g. \":8080\") instead of writing it to a file",
+	DatabasePathFlagName:                       "database",
+	DatabasePathFlagUsage:                      "use the SQLite database at the specified `path`",
+	ImportTimetablesFlagUsage:                  "also import the scheduled departures from each stop of each route of each line (this takes a long time unless they are read from a snapshot)",
+	HistoryLineNumberFlagUsage:                 "only show changes of lines with the specified `line number`",
+	HistoryVehicleTypeFlagUsage:                "only show changes of lines of the specified `vehicle type` (\"%s\", \"%s\", \"%s\" or \"%s\")",
+	NextLineNumberFlagUsage:                    "only show departures of lines with the specified `line number`",
+	NextVehicleTypeFlagUsage:                   "only show departures of lines of the specified `vehicle type` (\"%s\", \"%s\", \"%s\" or \"%s\")",
+	DepartureCountFlagName:                     "n",
+	DepartureCountFlagUsage:                    "show the specified `count` of departures",
+	DepartureTimeFlagName:                      "at",
+	DepartureTimeFlagUsage:                     "show the departures at or after the specified `time` (in the HH:MM format for the current day or in the YYYY-MM-DD HH:MM format) instead of the current time",
+	CalendarPathFlagName:                       "calendar",
+	CalendarPathFlagUsage:                      "read the bridge days, the working days and the overrides of the types of days from the CSV file at the specified `path` (with a date in the YYYY-MM-DD format and a kind, i.e. \"bridge\", \"working\", \"weekday\", \"pre-holiday\" or \"holiday\", in each record)",
 
 	InvalidSubcommandName:     "invalid command name",
 	IncompatibleFlagsDetected: "incompatible flags detected",
+	InvalidCacheActionName:    "invalid cache action name",
+	InvalidDBActionName:       "invalid db action name",
+	InvalidHistoryActionName:  "invalid history action name",
+	CacheIsNotAvailable:       "the cache is not available",
+	InvalidDate:               "invalid date",
+	InvalidTime:               "invalid time",
+
+	ArrivalsAreNotAvailableOffline: "real-time arrivals are not available in snapshots and GTFS feeds (use the -useSchedule flag to show the schedule timetables instead)",
 
 	LineNumbers:        "line numbers",
 	VehicleTypes:       "vehicle types",
@@ -90,4 +227,9 @@ var EnglishTranslator = map[string]string{
 	OperationModeCodes: "operation mode codes",
 
 	NotEnoughDetailsSpecified: "not enough details specified: need the following information",
+
+	ResourceKind: "resource kind",
+	Age:          "age",
+	Size:         "size",
+	URL:          "URL",
 }
@@ -19,6 +19,30 @@ const (
 	LinesSubcommandUsage      = `"lines" subcommand usage`
 	RoutesSubcommandName      = `"routes" subcommand name`
 	RoutesSubcommandUsage     = `"routes" subcommand usage`
+	CacheSubcommandName       = `"cache" subcommand name`
+	CacheSubcommandUsage      = `"cache" subcommand usage`
+	ExportSubcommandName      = `"export" subcommand name`
+	ExportSubcommandUsage     = `"export" subcommand usage`
+	GTFSSubcommandName        = `"gtfs" subcommand name`
+	GTFSSubcommandUsage       = `"gtfs" subcommand usage`
+	GTFSRTSubcommandName      = `"gtfsrt" subcommand name`
+	GTFSRTSubcommandUsage     = `"gtfsrt" subcommand usage`
+	NeTExSubcommandName       = `"netex" subcommand name`
+	NeTExSubcommandUsage      = `"netex" subcommand usage`
+	DBSubcommandName          = `"db" subcommand name`
+	DBSubcommandUsage         = `"db" subcommand usage`
+	SQLSubcommandName         = `"sql" subcommand name`
+	SQLSubcommandUsage        = `"sql" subcommand usage`
+	HistorySubcommandName     = `"history" subcommand name`
+	HistorySubcommandUsage    = `"history" subcommand usage`
+	NextSubcommandName        = `"next" subcommand name`
+	NextSubcommandUsage       = `"next" subcommand usage`
+
+	CacheRefreshActionName  = `"cache refresh" action name`
+	CacheStatusActionName   = `"cache status" action name`
+	CacheClearActionName    = `"cache clear" action name`
+	DBImportActionName      = `"db import" action name`
+	HistoryRecordActionName = `"history record" action name`
 
 	LineNumbersFlagName                        = `"line numbers" flag name`
 	LineNumbersFlagUsage                       = `"line numbers" flag usage`
@@ -50,9 +74,53 @@ const (
 	DoSortStopsFlagUsage                       = `"sort stops" flag usage`
 	DoTranslateStopNamesFlagName               = `"translate stop names" flag name`
 	DoTranslateStopNamesFlagUsage              = `"translate stop names" flag usage`
+	FormatFlagName                             = `"format" flag name`
+	FormatFlagUsage                            = `"format" flag usage`
+	DataPathFlagName                           = `"data path" flag name`
+	DataPathFlagUsage                          = `"data path" flag usage`
+	GTFSPathFlagName                           = `"gtfs path" flag name`
+	GTFSPathFlagUsage                          = `"gtfs path" flag usage`
+	DoUseCachedDataFlagName                    = `"use cached data" flag name`
+	DoUseCachedDataFlagUsage                   = `"use cached data" flag usage`
+	DoRefreshCachedDataFlagName                = `"refresh cached data" flag name`
+	DoRefreshCachedDataFlagUsage               = `"refresh cached data" flag usage`
+	DoIncludeTimetablesFlagName                = `"include timetables" flag name`
+	DoIncludeTimetablesFlagUsage               = `"include timetables" flag usage`
+	StartDateFlagName                          = `"start date" flag name`
+	StartDateFlagUsage                         = `"start date" flag usage`
+	EndDateFlagName                            = `"end date" flag name`
+	EndDateFlagUsage                           = `"end date" flag usage`
+	StopLocationsPathFlagName                  = `"stop locations path" flag name`
+	StopLocationsPathFlagUsage                 = `"stop locations path" flag usage`
+	DoAllowMissingStopLocationsFlagName        = `"allow missing stop locations" flag name`
+	DoAllowMissingStopLocationsFlagUsage       = `"allow missing stop locations" flag usage`
+	FeedStopCodesFlagUsage                     = `"feed stop codes" flag usage`
+	ListenAddressFlagName                      = `"listen address" flag name`
+	ListenAddressFlagUsage                     = `"listen address" flag usage`
+	DatabasePathFlagName                       = `"database path" flag name`
+	DatabasePathFlagUsage                      = `"database path" flag usage`
+	ImportTimetablesFlagUsage                  = `"import timetables" flag usage`
+	HistoryLineNumberFlagUsage                 = `"history line number" flag usage`
+	HistoryVehicleTypeFlagUsage                = `"history vehicle type" flag usage`
+	NextLineNumberFlagUsage                    = `"next line number" flag usage`
+	NextVehicleTypeFlagUsage                   = `"next vehicle type" flag usage`
+	DepartureCountFlagName                     = `"departure count" flag name`
+	DepartureCountFlagUsage                    = `"departure count" flag usage`
+	DepartureTimeFlagName                      = `"departure time" flag name`
+	DepartureTimeFlagUsage                     = `"departure time" flag usage`
+	CalendarPathFlagName                       = `"calendar path" flag name`
+	CalendarPathFlagUsage                      = `"calendar path" flag usage`
 
 	InvalidSubcommandName     = "invalid subcommand name"
 	IncompatibleFlagsDetected = "incompatible flags detected"
+	InvalidCacheActionName    = "invalid cache action name"
+	InvalidDBActionName       = "invalid db action name"
+	InvalidHistoryActionName  = "invalid history action name"
+	CacheIsNotAvailable       = "cache is not available"
+	InvalidDate               = "invalid date"
+	InvalidTime               = "invalid time"
+
+	ArrivalsAreNotAvailableOffline = "arrivals are not available offline"
 
 	LineNumbers        = "line numbers"
 	VehicleTypes       = "vehicle types"
@@ -61,4 +129,9 @@ const (
 	OperationModeCodes = "operation mode codes"
 
 	NotEnoughDetailsSpecified = "not enough details specified"
+
+	ResourceKind = "resource kind"
+	Age          = "age"
+	Size         = "size"
+	URL          = "URL"
 )
@@ -4,18 +4,25 @@ Package stcli implements a basic tool with a command-line interface for fetching
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"sort"
 	"strings"
 
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/format"
+	"github.com/rgeorgiev583/sofiatraffic/gtfs"
+	"github.com/rgeorgiev583/sofiatraffic/history"
 	"github.com/rgeorgiev583/sofiatraffic/schedule"
 
 	"github.com/rgeorgiev583/sofiatraffic/i18n"
 	schedule_l10n "github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
 	"github.com/rgeorgiev583/sofiatraffic/stcli/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
 	virtual_l10n "github.com/rgeorgiev583/sofiatraffic/virtual/l10n"
 
 	"github.com/rgeorgiev583/sofiatraffic/virtual"
@@ -28,13 +35,26 @@ const (
 	stopsMode
 	linesMode
 	routesMode
+	cacheMode
+	exportMode
+	gtfsMode
+	gtfsrtMode
+	netexMode
+	dbMode
+	sqlMode
+	historyMode
+	nextMode
 )
 
 type commandContext struct {
-	command                                                                                                                   *flag.FlagSet
-	lineNumbersArg, vehicleTypesArg, stopCodesArg, routeCodesArg, routeNamesArg, operationModeCodesArg, operationModeNamesArg string
-	doSortStops, doTranslateStopNames, doUseSchedule                                                                          bool
-	positionalArgs                                                                                                            []string
+	command                                                                                                                                  *flag.FlagSet
+	lineNumbersArg, vehicleTypesArg, stopCodesArg, routeCodesArg, routeNamesArg, operationModeCodesArg, operationModeNamesArg                string
+	startDateArg, endDateArg, stopLocationsPathArg, listenAddressArg, databasePathArg, departureTimeArg, calendarPathArg                     string
+	departureCount                                                                                                                           int
+	doSortStops, doTranslateStopNames, doUseSchedule, doUseCachedData, doRefreshCachedData, doIncludeTimetables, doAllowMissingStopLocations bool
+	positionalArgs                                                                                                                           []string
+	virtualRenderOptions                                                                                                                     virtual.RenderOptions
+	scheduleRenderOptions                                                                                                                    schedule.RenderOptions
 }
 
 func initCommandContextInMode(mode commandMode, args []string) (context *commandContext, err error) {
@@ -47,17 +67,17 @@ func initCommandContextInMode(mode commandMode, args []string) (context *command
 			context.command.PrintDefaults()
 		}
 		context.command.StringVar(&context.lineNumbersArg, l10n.Translator[l10n.LineNumbersFlagName], "", l10n.Translator[l10n.LineNumbersFlagUsage])
-		context.command.StringVar(&context.vehicleTypesArg, l10n.Translator[l10n.VehicleTypesFlagName], "", fmt.Sprintf(l10n.Translator[l10n.VehicleTypesFlagUsage], l10n.Translator[l10n.VehicleTypeBus], l10n.Translator[l10n.VehicleTypeTrolleybus], l10n.Translator[l10n.VehicleTypeTram]))
+		context.command.StringVar(&context.vehicleTypesArg, l10n.Translator[l10n.VehicleTypesFlagName], "", fmt.Sprintf(l10n.Translator[l10n.VehicleTypesFlagUsage], l10n.Translator[l10n.VehicleTypeBus], l10n.Translator[l10n.VehicleTypeTrolleybus], l10n.Translator[l10n.VehicleTypeTram], l10n.Translator[l10n.VehicleTypeMetro]))
 		context.command.StringVar(&context.stopCodesArg, l10n.Translator[l10n.StopCodesFlagName], "", l10n.Translator[l10n.StopCodesFlagUsage])
 		context.command.StringVar(&context.routeCodesArg, l10n.Translator[l10n.RouteCodesFlagName], "", l10n.Translator[l10n.RouteCodesFlagUsage])
 		context.command.StringVar(&context.routeNamesArg, l10n.Translator[l10n.RouteNamesFlagName], "", l10n.Translator[l10n.RouteNamesFlagUsage])
 		context.command.StringVar(&context.operationModeCodesArg, l10n.Translator[l10n.OperationModeCodesFlagName], "", l10n.Translator[l10n.OperationModeCodesFlagUsage])
 		context.command.StringVar(&context.operationModeNamesArg, l10n.Translator[l10n.OperationModeNamesFlagName], "", l10n.Translator[l10n.OperationModeNamesFlagUsage])
-		context.command.BoolVar(&virtual.DoShowGenerationTimeForTimetables, l10n.Translator[l10n.DoShowGenerationTimeForTimetablesFlagName], false, l10n.Translator[l10n.DoShowGenerationTimeForTimetablesFlagUsage])
-		context.command.BoolVar(&virtual.DoShowRemainingTimeUntilArrival, l10n.Translator[l10n.DoShowRemainingTimeUntilArrivalFlagName], false, l10n.Translator[l10n.DoShowRemainingTimeUntilArrivalFlagUsage])
-		context.command.BoolVar(&virtual.DoShowFacilities, l10n.Translator[l10n.DoShowFacilitiesFlagName], false, fmt.Sprintf(l10n.Translator[l10n.DoShowFacilitiesFlagUsage], l10n.Translator[l10n.AirConditioningAbbreviation], l10n.Translator[l10n.WheelchairAccessibilityAbbreviation]))
-		context.command.BoolVar(&schedule.DoShowOperationMode, l10n.Translator[l10n.DoShowOperationModeFlagName], false, l10n.Translator[l10n.DoShowOperationModeFlagUsage])
-		context.command.BoolVar(&schedule.DoShowRoute, l10n.Translator[l10n.DoShowRouteFlagName], false, l10n.Translator[l10n.DoShowRouteFlagUsage])
+		context.command.BoolVar(&context.virtualRenderOptions.DoShowGenerationTimeForTimetables, l10n.Translator[l10n.DoShowGenerationTimeForTimetablesFlagName], false, l10n.Translator[l10n.DoShowGenerationTimeForTimetablesFlagUsage])
+		context.command.BoolVar(&context.virtualRenderOptions.DoShowRemainingTimeUntilArrival, l10n.Translator[l10n.DoShowRemainingTimeUntilArrivalFlagName], false, l10n.Translator[l10n.DoShowRemainingTimeUntilArrivalFlagUsage])
+		context.command.BoolVar(&context.virtualRenderOptions.DoShowFacilities, l10n.Translator[l10n.DoShowFacilitiesFlagName], false, fmt.Sprintf(l10n.Translator[l10n.DoShowFacilitiesFlagUsage], l10n.Translator[l10n.AirConditioningAbbreviation], l10n.Translator[l10n.WheelchairAccessibilityAbbreviation]))
+		context.command.BoolVar(&context.scheduleRenderOptions.DoShowOperationMode, l10n.Translator[l10n.DoShowOperationModeFlagName], false, l10n.Translator[l10n.DoShowOperationModeFlagUsage])
+		context.command.BoolVar(&context.scheduleRenderOptions.DoShowRoute, l10n.Translator[l10n.DoShowRouteFlagName], false, l10n.Translator[l10n.DoShowRouteFlagUsage])
 		context.command.BoolVar(&context.doSortStops, l10n.Translator[l10n.DoSortStopsFlagName], false, l10n.Translator[l10n.DoSortStopsFlagUsage])
 		context.command.BoolVar(&context.doTranslateStopNames, l10n.Translator[l10n.DoTranslateStopNamesFlagName], false, l10n.Translator[l10n.DoTranslateStopNamesFlagUsage])
 		context.command.BoolVar(&context.doUseSchedule, l10n.Translator[l10n.DoUseScheduleFlagName], false, l10n.Translator[l10n.DoUseScheduleFlagUsage])
@@ -70,6 +90,8 @@ func initCommandContextInMode(mode commandMode, args []string) (context *command
 		}
 		context.command.BoolVar(&context.doSortStops, l10n.Translator[l10n.DoSortStopsFlagName], false, l10n.Translator[l10n.DoSortStopsFlagUsage])
 		context.command.BoolVar(&context.doTranslateStopNames, l10n.Translator[l10n.DoTranslateStopNamesFlagName], false, l10n.Translator[l10n.DoTranslateStopNamesFlagUsage])
+		context.command.BoolVar(&context.doUseCachedData, l10n.Translator[l10n.DoUseCachedDataFlagName], false, l10n.Translator[l10n.DoUseCachedDataFlagUsage])
+		context.command.BoolVar(&context.doRefreshCachedData, l10n.Translator[l10n.DoRefreshCachedDataFlagName], false, l10n.Translator[l10n.DoRefreshCachedDataFlagUsage])
 
 	case linesMode:
 		context.command = flag.NewFlagSet("lines", flag.ExitOnError)
@@ -77,6 +99,8 @@ func initCommandContextInMode(mode commandMode, args []string) (context *command
 			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.LinesSubcommandUsage], os.Args[0])
 			context.command.PrintDefaults()
 		}
+		context.command.BoolVar(&context.doUseCachedData, l10n.Translator[l10n.DoUseCachedDataFlagName], false, l10n.Translator[l10n.DoUseCachedDataFlagUsage])
+		context.command.BoolVar(&context.doRefreshCachedData, l10n.Translator[l10n.DoRefreshCachedDataFlagName], false, l10n.Translator[l10n.DoRefreshCachedDataFlagUsage])
 		context.doUseSchedule = true
 
 	case routesMode:
@@ -86,10 +110,107 @@ func initCommandContextInMode(mode commandMode, args []string) (context *command
 			context.command.PrintDefaults()
 		}
 		context.command.StringVar(&context.lineNumbersArg, l10n.Translator[l10n.LineNumbersFlagName], "", l10n.Translator[l10n.LineNumbersFlagUsage])
-		context.command.StringVar(&context.vehicleTypesArg, l10n.Translator[l10n.VehicleTypesFlagName], "", fmt.Sprintf(l10n.Translator[l10n.VehicleTypesFlagUsage], l10n.Translator[l10n.VehicleTypeBus], l10n.Translator[l10n.VehicleTypeTrolleybus], l10n.Translator[l10n.VehicleTypeTram]))
+		context.command.StringVar(&context.vehicleTypesArg, l10n.Translator[l10n.VehicleTypesFlagName], "", fmt.Sprintf(l10n.Translator[l10n.VehicleTypesFlagUsage], l10n.Translator[l10n.VehicleTypeBus], l10n.Translator[l10n.VehicleTypeTrolleybus], l10n.Translator[l10n.VehicleTypeTram], l10n.Translator[l10n.VehicleTypeMetro]))
 		context.command.BoolVar(&context.doSortStops, l10n.Translator[l10n.DoSortStopsFlagName], false, l10n.Translator[l10n.DoSortStopsFlagUsage])
 		context.command.BoolVar(&context.doTranslateStopNames, l10n.Translator[l10n.DoTranslateStopNamesFlagName], false, l10n.Translator[l10n.DoTranslateStopNamesFlagUsage])
 		context.command.BoolVar(&context.doUseSchedule, l10n.Translator[l10n.DoUseScheduleFlagName], false, l10n.Translator[l10n.DoUseScheduleFlagUsage])
+		context.command.BoolVar(&context.doUseCachedData, l10n.Translator[l10n.DoUseCachedDataFlagName], false, l10n.Translator[l10n.DoUseCachedDataFlagUsage])
+		context.command.BoolVar(&context.doRefreshCachedData, l10n.Translator[l10n.DoRefreshCachedDataFlagName], false, l10n.Translator[l10n.DoRefreshCachedDataFlagUsage])
+
+	case cacheMode:
+		context.command = flag.NewFlagSet("cache", flag.ExitOnError)
+		context.command.Usage = func() {
+			cacheDir, _ := upstream.GetDefaultCacheDir()
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.CacheSubcommandUsage], os.Args[0], cacheDir)
+		}
+
+	case exportMode:
+		context.command = flag.NewFlagSet("export", flag.ExitOnError)
+		context.command.Usage = func() {
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.ExportSubcommandUsage], os.Args[0])
+			context.command.PrintDefaults()
+		}
+		context.command.BoolVar(&context.doIncludeTimetables, l10n.Translator[l10n.DoIncludeTimetablesFlagName], false, l10n.Translator[l10n.DoIncludeTimetablesFlagUsage])
+
+	case gtfsMode:
+		context.command = flag.NewFlagSet("gtfs", flag.ExitOnError)
+		context.command.Usage = func() {
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.GTFSSubcommandUsage], os.Args[0])
+			context.command.PrintDefaults()
+		}
+		context.command.StringVar(&context.startDateArg, l10n.Translator[l10n.StartDateFlagName], "", l10n.Translator[l10n.StartDateFlagUsage])
+		context.command.StringVar(&context.endDateArg, l10n.Translator[l10n.EndDateFlagName], "", l10n.Translator[l10n.EndDateFlagUsage])
+		context.command.StringVar(&context.stopLocationsPathArg, l10n.Translator[l10n.StopLocationsPathFlagName], "", l10n.Translator[l10n.StopLocationsPathFlagUsage])
+		context.command.BoolVar(&context.doAllowMissingStopLocations, l10n.Translator[l10n.DoAllowMissingStopLocationsFlagName], false, l10n.Translator[l10n.DoAllowMissingStopLocationsFlagUsage])
+		context.command.StringVar(&context.calendarPathArg, l10n.Translator[l10n.CalendarPathFlagName], "", l10n.Translator[l10n.CalendarPathFlagUsage])
+		context.command.BoolVar(&context.doUseCachedData, l10n.Translator[l10n.DoUseCachedDataFlagName], false, l10n.Translator[l10n.DoUseCachedDataFlagUsage])
+		context.command.BoolVar(&context.doRefreshCachedData, l10n.Translator[l10n.DoRefreshCachedDataFlagName], false, l10n.Translator[l10n.DoRefreshCachedDataFlagUsage])
+
+	case gtfsrtMode:
+		context.command = flag.NewFlagSet("gtfsrt", flag.ExitOnError)
+		context.command.Usage = func() {
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.GTFSRTSubcommandUsage], os.Args[0])
+			context.command.PrintDefaults()
+		}
+		context.command.StringVar(&context.stopCodesArg, l10n.Translator[l10n.StopCodesFlagName], "", l10n.Translator[l10n.FeedStopCodesFlagUsage])
+		context.command.StringVar(&context.listenAddressArg, l10n.Translator[l10n.ListenAddressFlagName], "", l10n.Translator[l10n.ListenAddressFlagUsage])
+
+	case netexMode:
+		context.command = flag.NewFlagSet("netex", flag.ExitOnError)
+		context.command.Usage = func() {
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.NeTExSubcommandUsage], os.Args[0])
+			context.command.PrintDefaults()
+		}
+		context.command.StringVar(&context.startDateArg, l10n.Translator[l10n.StartDateFlagName], "", l10n.Translator[l10n.StartDateFlagUsage])
+		context.command.StringVar(&context.endDateArg, l10n.Translator[l10n.EndDateFlagName], "", l10n.Translator[l10n.EndDateFlagUsage])
+		context.command.StringVar(&context.calendarPathArg, l10n.Translator[l10n.CalendarPathFlagName], "", l10n.Translator[l10n.CalendarPathFlagUsage])
+		context.command.BoolVar(&context.doUseCachedData, l10n.Translator[l10n.DoUseCachedDataFlagName], false, l10n.Translator[l10n.DoUseCachedDataFlagUsage])
+		context.command.BoolVar(&context.doRefreshCachedData, l10n.Translator[l10n.DoRefreshCachedDataFlagName], false, l10n.Translator[l10n.DoRefreshCachedDataFlagUsage])
+
+	case dbMode:
+		context.command = flag.NewFlagSet("db", flag.ExitOnError)
+		context.command.Usage = func() {
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.DBSubcommandUsage], os.Args[0])
+			context.command.PrintDefaults()
+		}
+		context.command.StringVar(&context.databasePathArg, l10n.Translator[l10n.DatabasePathFlagName], defaultDatabasePath, l10n.Translator[l10n.DatabasePathFlagUsage])
+		context.command.BoolVar(&context.doIncludeTimetables, l10n.Translator[l10n.DoIncludeTimetablesFlagName], false, l10n.Translator[l10n.ImportTimetablesFlagUsage])
+		context.command.BoolVar(&context.doUseCachedData, l10n.Translator[l10n.DoUseCachedDataFlagName], false, l10n.Translator[l10n.DoUseCachedDataFlagUsage])
+		context.command.BoolVar(&context.doRefreshCachedData, l10n.Translator[l10n.DoRefreshCachedDataFlagName], false, l10n.Translator[l10n.DoRefreshCachedDataFlagUsage])
+
+	case sqlMode:
+		context.command = flag.NewFlagSet("sql", flag.ExitOnError)
+		context.command.Usage = func() {
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.SQLSubcommandUsage], os.Args[0])
+			context.command.PrintDefaults()
+		}
+		context.command.StringVar(&context.databasePathArg, l10n.Translator[l10n.DatabasePathFlagName], defaultDatabasePath, l10n.Translator[l10n.DatabasePathFlagUsage])
+
+	case historyMode:
+		context.command = flag.NewFlagSet("history", flag.ExitOnError)
+		context.command.Usage = func() {
+			historyDir, _ := history.GetDefaultDir()
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.HistorySubcommandUsage], os.Args[0], historyDir)
+			context.command.PrintDefaults()
+		}
+		context.command.StringVar(&context.lineNumbersArg, l10n.Translator[l10n.LineNumbersFlagName], "", l10n.Translator[l10n.HistoryLineNumberFlagUsage])
+		context.command.StringVar(&context.vehicleTypesArg, l10n.Translator[l10n.VehicleTypesFlagName], "", fmt.Sprintf(l10n.Translator[l10n.HistoryVehicleTypeFlagUsage], l10n.Translator[l10n.VehicleTypeBus], l10n.Translator[l10n.VehicleTypeTrolleybus], l10n.Translator[l10n.VehicleTypeTram], l10n.Translator[l10n.VehicleTypeMetro]))
+
+	case nextMode:
+		context.command = flag.NewFlagSet("next", flag.ExitOnError)
+		context.command.Usage = func() {
+			fmt.Fprintf(context.command.Output(), l10n.Translator[l10n.NextSubcommandUsage], os.Args[0])
+			context.command.PrintDefaults()
+		}
+		context.command.StringVar(&context.lineNumbersArg, l10n.Translator[l10n.LineNumbersFlagName], "", l10n.Translator[l10n.NextLineNumberFlagUsage])
+		context.command.StringVar(&context.vehicleTypesArg, l10n.Translator[l10n.VehicleTypesFlagName], "", fmt.Sprintf(l10n.Translator[l10n.NextVehicleTypeFlagUsage], l10n.Translator[l10n.VehicleTypeBus], l10n.Translator[l10n.VehicleTypeTrolleybus], l10n.Translator[l10n.VehicleTypeTram], l10n.Translator[l10n.VehicleTypeMetro]))
+		context.command.IntVar(&context.departureCount, l10n.Translator[l10n.DepartureCountFlagName], defaultDepartureCount, l10n.Translator[l10n.DepartureCountFlagUsage])
+		context.command.StringVar(&context.departureTimeArg, l10n.Translator[l10n.DepartureTimeFlagName], "", l10n.Translator[l10n.DepartureTimeFlagUsage])
+		context.command.StringVar(&context.calendarPathArg, l10n.Translator[l10n.CalendarPathFlagName], "", l10n.Translator[l10n.CalendarPathFlagUsage])
+		context.command.BoolVar(&context.scheduleRenderOptions.DoShowRoute, l10n.Translator[l10n.DoShowRouteFlagName], false, l10n.Translator[l10n.DoShowRouteFlagUsage])
+		context.command.BoolVar(&context.scheduleRenderOptions.DoShowOperationMode, l10n.Translator[l10n.DoShowOperationModeFlagName], false, l10n.Translator[l10n.DoShowOperationModeFlagUsage])
+		context.command.BoolVar(&context.doUseCachedData, l10n.Translator[l10n.DoUseCachedDataFlagName], false, l10n.Translator[l10n.DoUseCachedDataFlagUsage])
+		context.command.BoolVar(&context.doRefreshCachedData, l10n.Translator[l10n.DoRefreshCachedDataFlagName], false, l10n.Translator[l10n.DoRefreshCachedDataFlagUsage])
 	}
 
 	err = context.command.Parse(args)
@@ -97,8 +218,6 @@ func initCommandContextInMode(mode commandMode, args []string) (context *command
 		return
 	}
 
-	virtual.DoTranslateStopNames = context.doTranslateStopNames
-	schedule.DoTranslateStopNames = context.doTranslateStopNames
 	context.positionalArgs = context.command.Args()
 	return
 }
@@ -123,36 +242,44 @@ func parseList(listString string) (list []string) {
 	return
 }
 
-func initStopNameTranslatorIfNecessary() {
-	if schedule.DoTranslateStopNames && i18n.Language == i18n.LanguageCodeEnglish {
-		stopsInBulgarian, err := virtual.GetStopsInLanguage(i18n.LanguageCodeBulgarian)
+func (context *commandContext) initStopNameTranslatorIfNecessary(ctx context.Context, dataSource datasource.DataSource) {
+	if context.doTranslateStopNames && i18n.Language == i18n.LanguageCodeEnglish {
+		stopsInBulgarian, err := dataSource.GetStopsInLanguageContext(ctx, i18n.LanguageCodeBulgarian)
 		if err != nil {
 			log.Fatalln(err.Error())
 		}
 
-		stopsInEnglish, err := virtual.GetStopsInLanguage(i18n.LanguageCodeEnglish)
+		stopsInEnglish, err := dataSource.GetStopsInLanguageContext(ctx, i18n.LanguageCodeEnglish)
 		if err != nil {
 			log.Fatalln(err.Error())
 		}
 
-		schedule.StopNameTranslator = map[string]string{}
+		context.scheduleRenderOptions.StopNameTranslator = map[string]string{}
 		for i := 0; i < len(stopsInBulgarian) && i < len(stopsInEnglish); i++ {
-			schedule.StopNameTranslator[stopsInBulgarian[i].Name] = stopsInEnglish[i].Name
+			context.scheduleRenderOptions.StopNameTranslator[stopsInBulgarian[i].Name] = stopsInEnglish[i].Name
 		}
 	}
 }
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	i18n.Init()
 	l10n.InitTranslator()
 
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), l10n.Translator[l10n.Usage], os.Args[0], os.Args[0], os.Args[0])
+		flag.PrintDefaults()
 	}
+	formatName := flag.String(l10n.Translator[l10n.FormatFlagName], format.NameText, fmt.Sprintf(l10n.Translator[l10n.FormatFlagUsage], strings.Join(format.Names, ", ")))
+	dataPath := flag.String(l10n.Translator[l10n.DataPathFlagName], "", l10n.Translator[l10n.DataPathFlagUsage])
+	gtfsPath := flag.String(l10n.Translator[l10n.GTFSPathFlagName], "", l10n.Translator[l10n.GTFSPathFlagUsage])
+	flag.Parse()
 
 	var mode commandMode
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
 		case l10n.Translator[l10n.TimetablesSubcommandName]:
 			mode = timetablesMode
 
@@ -165,9 +292,34 @@ func main() {
 		case l10n.Translator[l10n.RoutesSubcommandName]:
 			mode = routesMode
 
-		default:
-			flag.Parse()
+		case l10n.Translator[l10n.CacheSubcommandName]:
+			mode = cacheMode
+
+		case l10n.Translator[l10n.ExportSubcommandName]:
+			mode = exportMode
+
+		case l10n.Translator[l10n.GTFSSubcommandName]:
+			mode = gtfsMode
 
+		case l10n.Translator[l10n.GTFSRTSubcommandName]:
+			mode = gtfsrtMode
+
+		case l10n.Translator[l10n.NeTExSubcommandName]:
+			mode = netexMode
+
+		case l10n.Translator[l10n.DBSubcommandName]:
+			mode = dbMode
+
+		case l10n.Translator[l10n.SQLSubcommandName]:
+			mode = sqlMode
+
+		case l10n.Translator[l10n.HistorySubcommandName]:
+			mode = historyMode
+
+		case l10n.Translator[l10n.NextSubcommandName]:
+			mode = nextMode
+
+		default:
 			fmt.Fprintln(os.Stderr, l10n.Translator[l10n.InvalidSubcommandName])
 			flag.Usage()
 			os.Exit(1)
@@ -176,18 +328,127 @@ func main() {
 		flag.Usage()
 		os.Exit(1)
 	}
-	context, err := initCommandContextInMode(mode, os.Args[2:])
+	context, err := initCommandContextInMode(mode, flag.Args()[1:])
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	if context.doUseSchedule && (virtual.DoShowGenerationTimeForTimetables || virtual.DoShowFacilities || context.doSortStops) {
+	formatter, err := format.New(*formatName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if textFormatter, ok := formatter.(*format.TextFormatter); ok {
+		textFormatter.VirtualRenderOptions = &context.virtualRenderOptions
+		textFormatter.ScheduleRenderOptions = &context.scheduleRenderOptions
+	}
+	output := func(value interface{}) {
+		err := formatter.Format(os.Stdout, value)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+	}
+
+	isOffline := *dataPath != "" || *gtfsPath != ""
+	if *dataPath != "" && *gtfsPath != "" || context.doUseSchedule && (context.virtualRenderOptions.DoShowGenerationTimeForTimetables || context.virtualRenderOptions.DoShowFacilities || context.doSortStops) || context.doUseCachedData && context.doRefreshCachedData || isOffline && (mode == cacheMode || mode == exportMode || mode == historyMode || context.doUseCachedData || context.doRefreshCachedData) {
 		fmt.Fprintln(os.Stderr, l10n.Translator[l10n.IncompatibleFlagsDetected])
 		context.command.Usage()
 		os.Exit(1)
 	}
 
+	if isOffline && (mode == timetablesMode && !context.doUseSchedule || mode == gtfsrtMode) {
+		fmt.Fprintln(os.Stderr, l10n.Translator[l10n.ArrivalsAreNotAvailableOffline])
+		os.Exit(1)
+	}
+
+	virtualClient := virtual.NewClient(nil)
+	virtualClient.DoTranslateStopNames = context.doTranslateStopNames
+	scheduleClient := schedule.NewClient(nil)
+
+	var diskCache *upstream.DiskCache
+	cacheDir, err := upstream.GetDefaultCacheDir()
+	if err != nil {
+		log.Println(l10n.Translator[l10n.CacheIsNotAvailable] + ": " + err.Error())
+	} else {
+		diskCache = upstream.NewDiskCache(cacheDir)
+		cache := &upstream.KindFilteredCache{Cache: diskCache, Kinds: cachedResourceKinds}
+		virtualClient.Cache = cache
+		scheduleClient.Cache = cache
+		if context.doUseCachedData {
+			virtualClient.CachePolicy = upstream.AlwaysFreshCachePolicy
+			scheduleClient.CachePolicy = upstream.AlwaysFreshCachePolicy
+		} else if context.doRefreshCachedData {
+			virtualClient.CachePolicy = upstream.AlwaysStaleCachePolicy
+			scheduleClient.CachePolicy = upstream.AlwaysStaleCachePolicy
+		}
+	}
+
+	if mode == cacheMode {
+		context.runCacheAction(diskCache, virtualClient, scheduleClient, output)
+		return
+	}
+
+	if mode == exportMode {
+		context.runExport(ctx, virtualClient, scheduleClient)
+		return
+	}
+
+	if mode == gtfsrtMode {
+		context.runGTFSRT(ctx, virtualClient)
+		return
+	}
+
+	if mode == historyMode {
+		context.runHistory(ctx, virtualClient, scheduleClient, output)
+		return
+	}
+
+	if mode == sqlMode {
+		context.runSQL(ctx, output)
+		return
+	}
+
+	var dataSource datasource.DataSource = datasource.NewLive(virtualClient, scheduleClient)
+	if *dataPath != "" {
+		snapshotSource, err := datasource.OpenSnapshot(*dataPath)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+		defer snapshotSource.Close()
+
+		dataSource = snapshotSource
+	} else if *gtfsPath != "" {
+		feedSource, err := gtfs.OpenFeedSource(*gtfsPath)
+		if err != nil {
+			log.Fatalln(err.Error())
+		}
+
+		dataSource = feedSource
+	}
+
+	if mode == gtfsMode {
+		context.runGTFS(ctx, dataSource)
+		return
+	}
+
+	if mode == netexMode {
+		context.runNeTEx(ctx, dataSource)
+		return
+	}
+
+	if mode == dbMode {
+		context.runDBAction(ctx, dataSource)
+		return
+	}
+
+	if mode == nextMode {
+		context.runNext(ctx, dataSource, output)
+		return
+	}
+
 	var libraryReverseTranslator map[string]string
 	if context.doUseSchedule {
 		schedule_l10n.InitTranslator()
@@ -222,12 +483,12 @@ func main() {
 	if context.doUseSchedule {
 		switch mode {
 		case linesMode:
-			lines, err := schedule.GetLines()
+			lines, err := dataSource.GetLinesContext(ctx)
 			if err != nil {
 				log.Fatalln(err.Error())
 			}
 
-			fmt.Println(lines)
+			output(lines)
 
 		case routesMode:
 			noVehicleTypesAreSpecified := len(vehicleTypes) == 1 && vehicleTypes[0] == ""
@@ -243,17 +504,19 @@ func main() {
 				log.Fatalln(l10n.Translator[l10n.NotEnoughDetailsSpecified] + ": " + strings.Join(detailsList, ", "))
 			}
 
-			initStopNameTranslatorIfNecessary()
-			printRoutesByLine := func(vehicleType string, lineNumber string) {
-				lineRoutes, err := schedule.GetLine(vehicleType, lineNumber)
+			context.initStopNameTranslatorIfNecessary(ctx, dataSource)
+			lineList := schedule.LineList{}
+			collectRoutesByLine := func(vehicleType string, lineNumber string) {
+				lineRoutes, err := dataSource.GetLineContext(ctx, vehicleType, lineNumber)
 				if err != nil {
 					log.Println(err.Error())
 					return
 				}
 
-				fmt.Print(lineRoutes)
+				lineList = append(lineList, lineRoutes)
 			}
-			forEachLine(printRoutesByLine)
+			forEachLine(collectRoutesByLine)
+			output(lineList)
 
 		case timetablesMode:
 			forEachRouteByStop := func(stopCode string, f func(stopCode string, operationModeCode string, routeCode string)) {
@@ -264,34 +527,33 @@ func main() {
 				}
 			}
 			if len(vehicleTypes) > 0 && vehicleTypes[0] != "" && len(lineNumbers) > 0 && lineNumbers[0] != "" {
-				initStopNameTranslatorIfNecessary()
-				var printTimetableByLineStopCodeAndRoute func(line *schedule.Line, stopCode string, operationModeCode string, routeCode string)
+				context.initStopNameTranslatorIfNecessary(ctx, dataSource)
+				detailedTimetableList := schedule.DetailedTimetableList{}
+				var collectTimetableByLineStopCodeAndRoute func(line *schedule.Line, stopCode string, operationModeCode string, routeCode string)
 				if len(stopCodes) == 1 && stopCodes[0] == "" || len(operationModeCodes) == 1 && operationModeCodes[0] == "" || len(routeCodes) == 1 && routeCodes[0] == "" {
-					printTimetableByLineStopCodeAndRoute = func(line *schedule.Line, stopCode string, operationModeCode string, routeCode string) {
-						stopTimetables, err := line.GetDetailedTimetableStrings(operationModeCode, routeCode, stopCode)
+					collectTimetableByLineStopCodeAndRoute = func(line *schedule.Line, stopCode string, operationModeCode string, routeCode string) {
+						stopTimetables, err := schedule.GetDetailedTimetablesFromSourceContext(ctx, dataSource, line, operationModeCode, routeCode, stopCode)
 						if err != nil {
 							log.Println(err.Error())
 							return
 						}
 
-						for stopTimetable := range stopTimetables {
-							fmt.Print(stopTimetable)
-						}
+						detailedTimetableList = append(detailedTimetableList, stopTimetables...)
 					}
 				} else {
-					printTimetableByLineStopCodeAndRoute = func(line *schedule.Line, stopCode string, operationModeCode string, routeCode string) {
-						stopTimetable, err := line.GetDetailedTimetableString(operationModeCode, routeCode, stopCode)
+					collectTimetableByLineStopCodeAndRoute = func(line *schedule.Line, stopCode string, operationModeCode string, routeCode string) {
+						stopTimetable, err := schedule.GetDetailedTimetableFromSourceContext(ctx, dataSource, line, operationModeCode, routeCode, stopCode)
 						if err != nil {
 							log.Println(err.Error())
 							return
 						}
 
-						fmt.Print(stopTimetable)
+						detailedTimetableList = append(detailedTimetableList, stopTimetable)
 					}
 				}
 				lines := []*schedule.Line{}
 				forEachLine(func(vehicleType string, lineNumber string) {
-					line, err := schedule.GetLine(vehicleType, lineNumber)
+					line, err := dataSource.GetLineContext(ctx, vehicleType, lineNumber)
 					if err != nil {
 						log.Println(err.Error())
 						return
@@ -363,19 +625,20 @@ func main() {
 				for _, line := range lines {
 					for _, stopCode := range stopCodes {
 						forEachRouteByStop(stopCode, func(stopCode string, operationModeCode string, routeCode string) {
-							printTimetableByLineStopCodeAndRoute(line, stopCode, operationModeCode, routeCode)
+							collectTimetableByLineStopCodeAndRoute(line, stopCode, operationModeCode, routeCode)
 						})
 					}
 				}
+				output(detailedTimetableList)
 			} else {
 				printTimetableByStopCodeAndRoute := func(stopCode string, operationModeCode string, routeCode string) {
-					stopTimetable, err := schedule.GetTimetable(operationModeCode, routeCode, stopCode)
+					stopTimetable, err := dataSource.GetTimetableContext(ctx, operationModeCode, routeCode, stopCode)
 					if err != nil {
 						log.Println(err.Error())
 						return
 					}
 
-					fmt.Print(stopTimetable)
+					output(stopTimetable)
 				}
 				for _, stopCode := range stopCodes {
 					forEachRouteByStop(stopCode, printTimetableByStopCodeAndRoute)
@@ -383,7 +646,11 @@ func main() {
 			}
 		}
 	} else {
-		stopList, err := virtual.GetStops()
+		stopsLanguage := i18n.LanguageCodeBulgarian
+		if context.doTranslateStopNames {
+			stopsLanguage = i18n.Language
+		}
+		stopList, err := dataSource.GetStopsInLanguageContext(ctx, stopsLanguage)
 		if err != nil {
 			log.Fatalln(err.Error())
 		}
@@ -394,10 +661,10 @@ func main() {
 
 		switch mode {
 		case stopsMode:
-			fmt.Print(stopList)
+			output(stopList)
 
 		case routesMode:
-			routes, err := virtual.GetRoutes()
+			routes, err := dataSource.GetRoutesContext(ctx)
 			if err != nil {
 				log.Fatalln(err.Error())
 			}
@@ -409,23 +676,26 @@ func main() {
 					log.Fatalln(err.Error())
 				}
 
-				fmt.Print(lineRouteListList)
+				output(lineRouteListList)
 			} else {
 				routeMap, err := routes.GetRouteMap(stopMap)
 				if err != nil {
 					log.Fatalln(err.Error())
 				}
 
-				printRoutesByLine := func(vehicleType string, lineNumber string) {
-					lineRoutes, ok := routeMap[virtual.Line{VehicleType: vehicleType, LineNumber: lineNumber}]
+				lineRouteListList := virtual.LineNamedRouteListList{}
+				collectRoutesByLine := func(vehicleType string, lineNumber string) {
+					line := virtual.Line{VehicleType: vehicleType, LineNumber: lineNumber}
+					lineRoutes, ok := routeMap[line]
 					if !ok {
 						log.Printf("could not find line with vehicle type %s and number %s in the route map\n", vehicleType, lineNumber)
 						return
 					}
 
-					fmt.Print(lineRoutes)
+					lineRouteListList = append(lineRouteListList, &virtual.LineNamedRouteList{Line: &line, NamedRouteList: lineRoutes})
 				}
-				forEachLine(printRoutesByLine)
+				forEachLine(collectRoutesByLine)
+				output(lineRouteListList)
 			}
 
 		case timetablesMode:
@@ -436,30 +706,55 @@ func main() {
 					}
 				}
 			}
-			printTimetableByStopCodeAndLine := func(stopCode string, vehicleType string, lineNumber string) {
-				stopTimetable, err := virtual.GetTimetableByStopCodeAndLine(stopCode, context.vehicleTypesArg, context.lineNumbersArg)
+			if siriFormatter, ok := formatter.(*format.SIRIFormatter); ok {
+				routes, err := dataSource.GetRoutesContext(ctx)
+				if err != nil {
+					log.Println(err.Error())
+				}
+
+				siriFormatter.Routes = routes
+			}
+			_, isStreamed := formatter.(*format.TextFormatter)
+			stopTimetableList := virtual.StopTimetableList{}
+			addTimetable := func(stopTimetable *virtual.StopTimetable) {
+				if isStreamed {
+					output(virtual.StopTimetableList{stopTimetable})
+				} else {
+					stopTimetableList = append(stopTimetableList, stopTimetable)
+				}
+			}
+			addTimetableByStopCodeAndLine := func(stopCode string, vehicleType string, lineNumber string) {
+				stopTimetable, err := virtualClient.GetTimetableByStopCodeAndLineContext(ctx, stopCode, context.vehicleTypesArg, context.lineNumbersArg)
 				if err != nil {
 					log.Println(err.Error())
 					return
 				}
 
-				fmt.Print(stopTimetable)
+				// unlike the timetables of the stops matching a name, the timetable of a stop looked up by its code is shown even if it has no arrivals
+				if isStreamed {
+					output(stopTimetable)
+				} else {
+					stopTimetableList = append(stopTimetableList, stopTimetable)
+				}
 			}
 			if len(stopCodes) > 0 && stopCodes[0] != "" {
 				for _, stopCode := range stopCodes {
-					forEachLineByStop(stopCode, printTimetableByStopCodeAndLine)
+					forEachLineByStop(stopCode, addTimetableByStopCodeAndLine)
 				}
 			}
-			printTimetablesByStopNameAndLine := func(stopName string, vehicleType string, lineNumber string) {
-				stopTimetables := stopList.GetTimetablesByStopNameAndLineAsync(stopName, context.vehicleTypesArg, context.lineNumbersArg, false)
-				fmt.Print(stopTimetables)
+			addTimetablesByStopNameAndLine := func(stopName string, vehicleType string, lineNumber string) {
+				stopTimetables := virtualClient.GetTimetablesByStopNameAndLineAsyncContext(ctx, stopList, stopName, context.vehicleTypesArg, context.lineNumbersArg, false)
+				stopTimetables.ForEach(addTimetable)
 			}
 			if len(context.positionalArgs) > 0 {
 				for _, stopName := range stopNames {
-					forEachLineByStop(stopName, printTimetablesByStopNameAndLine)
+					forEachLineByStop(stopName, addTimetablesByStopNameAndLine)
 				}
 			} else {
-				forEachLineByStop("", printTimetablesByStopNameAndLine)
+				forEachLineByStop("", addTimetablesByStopNameAndLine)
+			}
+			if !isStreamed {
+				output(stopTimetableList)
 			}
 		}
 	}
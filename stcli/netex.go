@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/netex"
+)
+
+func (context *commandContext) runNeTEx(ctx context.Context, dataSource datasource.DataSource) {
+	if len(context.positionalArgs) != 1 {
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	generator := &netex.Generator{
+		Source:     dataSource,
+		StartDate:  context.parseDateArg(context.startDateArg),
+		EndDate:    context.parseDateArg(context.endDateArg),
+		GetDayType: context.getCalendar().GetDayType,
+	}
+	publication, err := generator.GenerateContext(ctx)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	err = publication.Create(context.positionalArgs[0])
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
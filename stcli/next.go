@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/rgeorgiev583/sofiatraffic/calendar"
+	"github.com/rgeorgiev583/sofiatraffic/datasource"
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	schedule_l10n "github.com/rgeorgiev583/sofiatraffic/schedule/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/stcli/l10n"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+const (
+	defaultDepartureCount = 5
+	timeLayout            = "15:04"
+)
+
+// parseTimeArg parses the value of the departure time flag, which is either a time of the current day or a date and a time, and returns the current time if it is empty.
+func (context *commandContext) parseTimeArg(timeArg string) (at time.Time) {
+	now := time.Now()
+	if timeArg == "" {
+		return now
+	}
+
+	at, err := time.ParseInLocation(dateLayout+" "+timeLayout, timeArg, time.Local)
+	if err == nil {
+		return
+	}
+
+	timeOfDay, err := time.ParseInLocation(timeLayout, timeArg, time.Local)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, l10n.Translator[l10n.InvalidTime]+": "+timeArg)
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	year, month, day := now.Date()
+	at = time.Date(year, month, day, timeOfDay.Hour(), timeOfDay.Minute(), 0, 0, time.Local)
+	return
+}
+
+// isStopServed reports whether any route of line serves the stop with the specified stopCode.
+func isStopServed(line *schedule.Line, stopCode string) bool {
+	for _, operationModeRoutes := range line.OperationModeRoutesList {
+		for _, route := range operationModeRoutes.RouteList {
+			if _, ok := route.StopMap[stopCode]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getServingLineKeys returns the set of the schedule vehicle types and numbers (joined by a slash) of the lines whose routes in the virtual timetable API serve the stop with the specified stopCode.
+func getServingLineKeys(routes virtual.VehicleTypeLineNumberRouteListListList, stopCode string) (lineKeys map[string]bool) {
+	lineKeys = map[string]bool{}
+	for _, vehicleTypeLineNumberRouteListList := range routes {
+		scheduleVehicleType := virtual.GetScheduleVehicleType(vehicleTypeLineNumberRouteListList.VehicleType)
+		for _, lineNumberRouteList := range vehicleTypeLineNumberRouteListList.LineNumberRouteListList {
+			for _, route := range lineNumberRouteList.RouteList {
+				for _, routeStopCode := range route.StopCodes {
+					if routeStopCode == stopCode {
+						lineKeys[scheduleVehicleType+"/"+lineNumberRouteList.LineNumber] = true
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// getLines returns the schedule lines matching the specified vehicleType and lineNumber (each of which matches everything if empty) which serve the stop with the specified stopCode. Only the schedules of the lines whose routes serve the stop are obtained, except for the lines of vehicle types which the virtual timetable API does not cover (e.g. metro trains), all of which are scanned. Lines which cannot be obtained are skipped (and the errors are logged).
+func getLines(ctx context.Context, dataSource datasource.DataSource, vehicleType string, lineNumber string, stopCode string) (lines schedule.LineList, err error) {
+	lineNumbers, err := dataSource.GetLinesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	routes, err := dataSource.GetRoutesContext(ctx)
+	if err != nil {
+		return
+	}
+
+	servingLineKeys := getServingLineKeys(routes, stopCode)
+	lines = schedule.LineList{}
+	collectLines := func(lineVehicleType string, numbers []string) {
+		if vehicleType != "" && lineVehicleType != vehicleType {
+			return
+		}
+
+		_, isCoveredByRoutes := virtual.GetVehicleTypeFromSchedule(lineVehicleType)
+		for _, number := range numbers {
+			if lineNumber != "" && number != lineNumber {
+				continue
+			}
+			if isCoveredByRoutes && !servingLineKeys[lineVehicleType+"/"+number] {
+				continue
+			}
+
+			line, err := dataSource.GetLineContext(ctx, lineVehicleType, number)
+			if err != nil {
+				log.Println(err.Error())
+				continue
+			}
+
+			if isStopServed(line, stopCode) {
+				lines = append(lines, line)
+			}
+		}
+	}
+	for _, vehicleTypeLineNumbers := range lineNumbers.VehicleTypeLineNumbersList {
+		collectLines(vehicleTypeLineNumbers.VehicleType, vehicleTypeLineNumbers.LineNumbers)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return
+}
+
+// getUpcomingDepartures returns the departures of lines from the stop with the specified stopCode made at or after the specified time on the service day starting on the date of serviceDate, using the operation mode of each line which is in effect on that day according to serviceCalendar.
+func getUpcomingDepartures(ctx context.Context, dataSource datasource.DataSource, serviceCalendar *calendar.Calendar, lines schedule.LineList, stopCode string, serviceDate time.Time, at time.Time) (departures schedule.UpcomingDepartureList) {
+	departures = schedule.UpcomingDepartureList{}
+	for _, line := range lines {
+		operationModeRoutes := serviceCalendar.GetOperationMode(line, serviceDate)
+		if operationModeRoutes == nil {
+			continue
+		}
+
+		lineDepartures, err := schedule.GetUpcomingDeparturesFromSourceContext(ctx, dataSource, line, operationModeRoutes.Code, stopCode, serviceDate, at)
+		if err != nil {
+			log.Println(err.Error())
+		}
+
+		departures = append(departures, lineDepartures...)
+	}
+	return
+}
+
+func (context *commandContext) runNext(ctx context.Context, dataSource datasource.DataSource, output func(value interface{})) {
+	if len(context.positionalArgs) != 1 || context.departureCount <= 0 {
+		context.command.Usage()
+		os.Exit(1)
+	}
+
+	schedule_l10n.InitTranslator()
+	serviceCalendar := context.getCalendar()
+	stopCode := context.positionalArgs[0]
+	at := context.parseTimeArg(context.departureTimeArg)
+	vehicleType, ok := schedule_l10n.ReverseTranslator[context.vehicleTypesArg]
+	if !ok {
+		vehicleType = context.vehicleTypesArg
+	}
+
+	lines, err := getLines(ctx, dataSource, vehicleType, context.lineNumbersArg, stopCode)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	// departures after midnight belong to the previous service day, which ends before the start of the current one
+	year, month, day := at.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, at.Location())
+	departures := schedule.UpcomingDepartureList{}
+	if at.Before(today.Add(schedule.ServiceDayStart)) {
+		departures = append(departures, getUpcomingDepartures(ctx, dataSource, serviceCalendar, lines, stopCode, today.AddDate(0, 0, -1), at)...)
+	}
+	departures = append(departures, getUpcomingDepartures(ctx, dataSource, serviceCalendar, lines, stopCode, today, at)...)
+	if len(departures) < context.departureCount {
+		departures = append(departures, getUpcomingDepartures(ctx, dataSource, serviceCalendar, lines, stopCode, today.AddDate(0, 0, 1), at)...)
+	}
+	if ctx.Err() != nil {
+		log.Fatalln(ctx.Err().Error())
+	}
+
+	departures.Sort()
+	if len(departures) > context.departureCount {
+		departures = departures[:context.departureCount]
+	}
+	output(departures)
+}
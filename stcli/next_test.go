@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual"
+)
+
+// fakeDataSource is a DataSource serving the routes, schedule lines and timetables stored in its fields and recording the requested lines and timetables.
+type fakeDataSource struct {
+	routes            virtual.VehicleTypeLineNumberRouteListListList
+	lines             []*schedule.Line
+	timetables        map[string][]string // maps the operation mode, route and stop codes (joined by slashes) to the raw departure times
+	lineRequests      []string
+	timetableRequests []string
+}
+
+func (s *fakeDataSource) GetStopsInLanguageContext(ctx context.Context, language string) (virtual.StopList, error) {
+	return nil, nil
+}
+
+func (s *fakeDataSource) GetRoutesContext(ctx context.Context) (virtual.VehicleTypeLineNumberRouteListListList, error) {
+	return s.routes, nil
+}
+
+func (s *fakeDataSource) GetLinesContext(ctx context.Context) (lines *schedule.Lines, err error) {
+	lines = &schedule.Lines{}
+	for _, line := range s.lines {
+		lines.AddLineNumber(line.VehicleType, line.LineNumber)
+	}
+	return
+}
+
+func (s *fakeDataSource) GetLineContext(ctx context.Context, vehicleType string, lineNumber string) (*schedule.Line, error) {
+	s.lineRequests = append(s.lineRequests, vehicleType+"/"+lineNumber)
+	for _, line := range s.lines {
+		if line.VehicleType == vehicleType && line.LineNumber == lineNumber {
+			return line, nil
+		}
+	}
+	return nil, &schedule.UnknownLineError{VehicleType: vehicleType, LineNumber: lineNumber}
+}
+
+func (s *fakeDataSource) GetTimetableContext(ctx context.Context, operationModeCode string, routeCode string, stopCode string) (timetable schedule.Timetable, err error) {
+	key := operationModeCode + "/" + routeCode + "/" + stopCode
+	s.timetableRequests = append(s.timetableRequests, key)
+	for _, rawDeparture := range s.timetables[key] {
+		departure, err := schedule.ParseDeparture(rawDeparture)
+		if err != nil {
+			return nil, err
+		}
+
+		timetable = append(timetable, departure)
+	}
+	return
+}
+
+// newTestScheduleLine returns a line whose operation modes (mapping their codes to their names) each have a single route (whose code is the one of the operation mode followed by 0) serving the specified stops.
+func newTestScheduleLine(vehicleType string, lineNumber string, operationModeNames map[string]string, stopCodes ...string) (line *schedule.Line) {
+	line = &schedule.Line{VehicleType: vehicleType, LineNumber: lineNumber, OperationModeRoutesMap: schedule.OperationModeRoutesMap{}}
+	for _, operationModeCode := range []string{"1", "2", "3"} {
+		name, ok := operationModeNames[operationModeCode]
+		if !ok {
+			continue
+		}
+
+		route := &schedule.Route{Code: operationModeCode + "0", StopMap: schedule.StopMap{}}
+		for _, stopCode := range stopCodes {
+			stop := &schedule.Stop{Code: stopCode}
+			route.StopList = append(route.StopList, stop)
+			route.StopMap[stopCode] = stop
+		}
+		operationModeRoutes := &schedule.OperationModeRoutes{
+			OperationMode: &schedule.OperationMode{Code: operationModeCode, Name: name},
+			RouteList:     schedule.RouteList{route},
+			RouteMap:      schedule.RouteMap{route.Code: route},
+		}
+		line.OperationModeRoutesList = append(line.OperationModeRoutesList, operationModeRoutes)
+		line.OperationModeRoutesMap[operationModeCode] = operationModeRoutes
+	}
+	return
+}
+
+// newTestDataSource returns a fakeDataSource serving bus line 94, which serves stop 0002 on every day, bus line 280, which does not serve it, and metro line M1, which serves it on weekdays.
+func newTestDataSource() *fakeDataSource {
+	return &fakeDataSource{
+		routes: virtual.VehicleTypeLineNumberRouteListListList{{
+			VehicleType: virtual.VehicleTypeBus,
+			LineNumberRouteListList: virtual.LineNumberRouteListList{
+				{LineNumber: "94", RouteList: virtual.RouteList{{StopCodes: []string{"0001", "0002"}}}},
+				{LineNumber: "280", RouteList: virtual.RouteList{{StopCodes: []string{"0001", "0003"}}}},
+			},
+		}},
+		lines: []*schedule.Line{
+			newTestScheduleLine(schedule.VehicleTypeBus, "94", map[string]string{"1": "делник", "2": "предпразник, празник"}, "0001", "0002"),
+			newTestScheduleLine(schedule.VehicleTypeBus, "280", map[string]string{"1": "делник"}, "0001", "0003"),
+			newTestScheduleLine(schedule.VehicleTypeMetro, "M1", map[string]string{"3": "делник"}, "0002"),
+		},
+		timetables: map[string][]string{
+			"1/10/0002": {"23:50", "00:20"},
+			"2/20/0002": {"06:00", "00:30"},
+			"3/30/0002": {"05:00", "12:00"},
+		},
+	}
+}
+
+// runTestNext runs the next command in commandContext with dataSource and returns the rendered departures (the time, the vehicle type and the line number of each departure).
+func runTestNext(t *testing.T, dataSource *fakeDataSource, commandContext *commandContext) (departureStrings []string) {
+	commandContext.command = flag.NewFlagSet("next", flag.ContinueOnError)
+	commandContext.runNext(context.Background(), dataSource, func(value interface{}) {
+		departures, ok := value.(schedule.UpcomingDepartureList)
+		if !ok {
+			t.Fatalf("expected an UpcomingDepartureList, got %T", value)
+		}
+
+		departureStrings = []string{}
+		for _, departure := range departures {
+			departureStrings = append(departureStrings, departure.Time.Format(dateLayout+" "+timeLayout)+" "+departure.Line.VehicleType+" "+departure.Line.LineNumber)
+		}
+	})
+	return
+}
+
+func TestRunNext(t *testing.T) {
+	testCases := []struct {
+		name                      string
+		context                   *commandContext
+		expectedDepartures        []string
+		expectedTimetableRequests int
+	}{
+		{
+			name:                      "enough departures on the current service day",
+			context:                   &commandContext{positionalArgs: []string{"0002"}, departureCount: 3, departureTimeArg: "2024-05-13 12:00"},
+			expectedDepartures:        []string{"2024-05-13 12:00 metro M1", "2024-05-13 23:50 autobus 94", "2024-05-14 00:20 autobus 94"},
+			expectedTimetableRequests: 2,
+		},
+		{
+			name:                      "departures on the next service day",
+			context:                   &commandContext{positionalArgs: []string{"0002"}, departureCount: 5, departureTimeArg: "2024-05-13 12:00"},
+			expectedDepartures:        []string{"2024-05-13 12:00 metro M1", "2024-05-13 23:50 autobus 94", "2024-05-14 00:20 autobus 94", "2024-05-14 05:00 metro M1", "2024-05-14 12:00 metro M1"},
+			expectedTimetableRequests: 4,
+		},
+		{
+			name:                      "departures of the previous service day before the start of the current one",
+			context:                   &commandContext{positionalArgs: []string{"0002"}, departureCount: 3, departureTimeArg: "2024-05-13 00:10"},
+			expectedDepartures:        []string{"2024-05-13 00:30 autobus 94", "2024-05-13 05:00 metro M1", "2024-05-13 12:00 metro M1"},
+			expectedTimetableRequests: 3,
+		},
+		{
+			name:                      "vehicle type",
+			context:                   &commandContext{positionalArgs: []string{"0002"}, departureCount: 1, departureTimeArg: "2024-05-13 12:00", vehicleTypesArg: schedule.VehicleTypeBus},
+			expectedDepartures:        []string{"2024-05-13 23:50 autobus 94"},
+			expectedTimetableRequests: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dataSource := newTestDataSource()
+			departures := runTestNext(t, dataSource, testCase.context)
+			if !reflect.DeepEqual(departures, testCase.expectedDepartures) {
+				t.Errorf("expected departures %v, got %v", testCase.expectedDepartures, departures)
+			}
+			if len(dataSource.timetableRequests) != testCase.expectedTimetableRequests {
+				t.Errorf("expected %d timetable requests, got %v", testCase.expectedTimetableRequests, dataSource.timetableRequests)
+			}
+		})
+	}
+}
+
+func TestGetLines(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		vehicleType          string
+		lineNumber           string
+		stopCode             string
+		expectedLines        []string
+		expectedLineRequests []string
+	}{
+		{name: "all lines", stopCode: "0002", expectedLines: []string{"autobus/94", "metro/M1"}, expectedLineRequests: []string{"autobus/94", "metro/M1"}},
+		{name: "stop served only by a bus line", stopCode: "0003", expectedLines: []string{"autobus/280"}, expectedLineRequests: []string{"autobus/280", "metro/M1"}},
+		{name: "line number", lineNumber: "M1", stopCode: "0002", expectedLines: []string{"metro/M1"}, expectedLineRequests: []string{"metro/M1"}},
+		{name: "stop which is not served", vehicleType: schedule.VehicleTypeBus, stopCode: "9999", expectedLines: []string{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dataSource := newTestDataSource()
+			lines, err := getLines(context.Background(), dataSource, testCase.vehicleType, testCase.lineNumber, testCase.stopCode)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			lineStrings := []string{}
+			for _, line := range lines {
+				lineStrings = append(lineStrings, line.VehicleType+"/"+line.LineNumber)
+			}
+			if !reflect.DeepEqual(lineStrings, testCase.expectedLines) {
+				t.Errorf("expected lines %v, got %v", testCase.expectedLines, lineStrings)
+			}
+			if !reflect.DeepEqual(dataSource.lineRequests, testCase.expectedLineRequests) {
+				t.Errorf("expected the schedules of lines %v to be obtained, got %v", testCase.expectedLineRequests, dataSource.lineRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker keeps track of consecutive failures of requests to each upstream host and makes requests to a host fail fast while the host appears to be down. The same CircuitBreaker may be shared by several clients.
+type CircuitBreaker struct {
+	FailureThreshold int           // number of consecutive failures after which the circuit for a host is opened
+	OpenDuration     time.Duration // time for which the circuit stays open before a trial request is let through
+
+	mutex  sync.Mutex
+	states map[string]*circuitState
+}
+
+type circuitState struct {
+	failures    int       // number of consecutive failures
+	openedUntil time.Time // time until which requests are rejected
+	isTrialSent bool      // whether a trial request is in flight after the circuit has been open
+}
+
+// CircuitOpenError represents a request which was rejected because the circuit for its host is open.
+type CircuitOpenError struct {
+	Host        string    // hostname of the upstream server
+	OpenedUntil time.Time // time until which requests to the host are rejected
+}
+
+// ErrCircuitOpen indicates that a request was rejected because the upstream server has been failing repeatedly.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// DefaultCircuitBreaker is the CircuitBreaker shared by the default clients of the `schedule` and `virtual` packages.
+var DefaultCircuitBreaker = NewCircuitBreaker(5, 30*time.Second)
+
+// NewCircuitBreaker returns a CircuitBreaker which opens the circuit for a host after failureThreshold consecutive failures and keeps it open for openDuration.
+func NewCircuitBreaker(failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		OpenDuration:     openDuration,
+	}
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("requests to %s are suspended until %s due to repeated failures", e.Host, e.OpenedUntil.Format(time.RFC3339))
+}
+
+// Is reports whether target is either ErrCircuitOpen or ErrUnavailable.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen || target == ErrUnavailable
+}
+
+func (b *CircuitBreaker) getState(host string) *circuitState {
+	if b.states == nil {
+		b.states = map[string]*circuitState{}
+	}
+	state, ok := b.states[host]
+	if !ok {
+		state = &circuitState{}
+		b.states[host] = state
+	}
+	return state
+}
+
+// Allow returns a CircuitOpenError if requests to the specified host should currently be rejected. After the circuit has been open for OpenDuration, a single trial request is allowed, in which case isTrial is true. Only the outcome of the trial request can close or reopen the circuit afterwards, so the caller should pass isTrial on to RecordSuccess or RecordFailure.
+func (b *CircuitBreaker) Allow(host string) (isTrial bool, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state := b.getState(host)
+	if !b.isOpen(state) {
+		return
+	}
+
+	if now().Before(state.openedUntil) || state.isTrialSent {
+		err = &CircuitOpenError{Host: host, OpenedUntil: state.openedUntil}
+		return
+	}
+
+	state.isTrialSent = true
+	isTrial = true
+	return
+}
+
+func (b *CircuitBreaker) isOpen(state *circuitState) bool {
+	return b.FailureThreshold > 0 && state.failures >= b.FailureThreshold
+}
+
+// RecordSuccess registers a successful request to the specified host, which closes the circuit unless it is open and the request is not the trial one (i.e. it was allowed before the circuit was opened).
+func (b *CircuitBreaker) RecordSuccess(host string, isTrial bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state := b.getState(host)
+	if b.isOpen(state) && !isTrial {
+		return
+	}
+
+	state.failures = 0
+	state.isTrialSent = false
+}
+
+// release lets another trial request through to the specified host if the outcome of the trial request could not be determined (e.g. because it was canceled). It should only be called by the holder of the trial.
+func (b *CircuitBreaker) release(host string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.getState(host).isTrialSent = false
+}
+
+// RecordFailure registers a failed request to the specified host and opens the circuit if the number of consecutive failures reaches FailureThreshold. A failed trial request reopens the circuit, while failures of other requests are ignored while the circuit is open.
+func (b *CircuitBreaker) RecordFailure(host string, isTrial bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state := b.getState(host)
+	if b.isOpen(state) && !isTrial {
+		return
+	}
+
+	state.failures++
+	state.isTrialSent = false
+	if b.isOpen(state) {
+		state.openedUntil = now().Add(b.OpenDuration)
+	}
+}
@@ -0,0 +1,110 @@
+package upstream
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	type action int
+	const (
+		allow action = iota
+		recordSuccess
+		recordFailure
+		release
+		recordLateSuccess // records the success of a request allowed before the circuit was opened
+		recordLateFailure // records the failure of a request allowed before the circuit was opened
+	)
+
+	testCases := []struct {
+		name          string
+		actions       []action
+		advance       time.Duration // time elapsed after actions
+		trialActions  []action      // actions performed after advance
+		isCircuitOpen bool          // whether the final call to Allow is expected to be rejected
+	}{
+		{name: "closed", actions: []action{allow, recordFailure}},
+		{name: "opened after consecutive failures", actions: []action{allow, recordFailure, allow, recordFailure}, isCircuitOpen: true},
+		{name: "success resets the failures", actions: []action{allow, recordFailure, allow, recordSuccess, allow, recordFailure}},
+		{name: "still open before the open duration elapses", actions: []action{recordFailure, recordFailure}, advance: time.Minute - time.Second, isCircuitOpen: true},
+		{name: "half-open after the open duration", actions: []action{recordFailure, recordFailure}, advance: time.Minute},
+		{name: "single trial request while half-open", actions: []action{recordFailure, recordFailure}, advance: time.Minute, trialActions: []action{allow}, isCircuitOpen: true},
+		{name: "released trial request", actions: []action{recordFailure, recordFailure}, advance: time.Minute, trialActions: []action{allow, release}},
+		{name: "failed trial request", actions: []action{recordFailure, recordFailure}, advance: time.Minute, trialActions: []action{allow, recordFailure}, isCircuitOpen: true},
+		{name: "successful trial request", actions: []action{recordFailure, recordFailure}, advance: time.Minute, trialActions: []action{allow, recordSuccess}},
+		{name: "late success while open", actions: []action{allow, allow, allow, recordFailure, recordFailure, recordLateSuccess}, isCircuitOpen: true},
+		{name: "late success while the trial request is in flight", actions: []action{allow, allow, allow, recordFailure, recordFailure}, advance: time.Minute, trialActions: []action{allow, recordLateSuccess}, isCircuitOpen: true},
+		{name: "late failure while the trial request is in flight", actions: []action{allow, allow, allow, recordFailure, recordFailure}, advance: time.Minute, trialActions: []action{allow, recordLateFailure}, isCircuitOpen: true},
+		{name: "trial request succeeding after a late failure", actions: []action{allow, allow, allow, recordFailure, recordFailure}, advance: time.Minute, trialActions: []action{allow, recordLateFailure, recordSuccess}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			clock := installFakeClock(t)
+			breaker := NewCircuitBreaker(2, time.Minute)
+			const host = "example.com"
+			isTrial := false
+			perform := func(actions []action) {
+				for _, action := range actions {
+					switch action {
+					case allow:
+						var err error
+						isTrial, err = breaker.Allow(host)
+						if err != nil {
+							t.Fatalf("unexpected error: %s", err.Error())
+						}
+
+					case recordSuccess:
+						breaker.RecordSuccess(host, isTrial)
+
+					case recordFailure:
+						breaker.RecordFailure(host, isTrial)
+
+					case release:
+						breaker.release(host)
+
+					case recordLateSuccess:
+						breaker.RecordSuccess(host, false)
+
+					case recordLateFailure:
+						breaker.RecordFailure(host, false)
+					}
+				}
+			}
+
+			perform(testCase.actions)
+			clock.advance(testCase.advance)
+			perform(testCase.trialActions)
+			_, err := breaker.Allow(host)
+			if errors.Is(err, ErrCircuitOpen) != testCase.isCircuitOpen {
+				t.Errorf("expected errors.Is(err, ErrCircuitOpen) to be %t, got %v", testCase.isCircuitOpen, err)
+			}
+			if testCase.isCircuitOpen && !errors.Is(err, ErrUnavailable) {
+				t.Errorf("expected a rejected request to be reported as unavailable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHosts(t *testing.T) {
+	installFakeClock(t)
+	breaker := NewCircuitBreaker(1, time.Minute)
+	breaker.RecordFailure("a.example.com", false)
+	if _, err := breaker.Allow("a.example.com"); err == nil {
+		t.Error("expected the circuit for a.example.com to be open")
+	}
+	if _, err := breaker.Allow("b.example.com"); err != nil {
+		t.Errorf("expected the circuit for b.example.com to be closed, got %v", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := NewCircuitBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		breaker.RecordFailure("example.com", false)
+	}
+	if _, err := breaker.Allow("example.com"); err != nil {
+		t.Errorf("expected a breaker with no failure threshold to allow requests, got %v", err)
+	}
+}
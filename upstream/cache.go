@@ -0,0 +1,350 @@
+package upstream
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResourceKind classifies upstream resources by how often they change.
+type ResourceKind string
+
+const (
+	// ResourceKindStops represents the lists of stops of the virtual timetable API.
+	ResourceKindStops ResourceKind = "stops"
+	// ResourceKindRoutes represents the list of routes of the virtual timetable API.
+	ResourceKindRoutes ResourceKind = "routes"
+	// ResourceKindArrivals represents the arrivals at a stop from the virtual timetable API.
+	ResourceKindArrivals ResourceKind = "arrivals"
+	// ResourceKindScheduleLines represents the schedule page listing all lines.
+	ResourceKindScheduleLines ResourceKind = "schedule lines"
+	// ResourceKindScheduleLine represents the schedule page of a specific line.
+	ResourceKindScheduleLine ResourceKind = "schedule line"
+	// ResourceKindScheduleTimetable represents the schedule timetable for a specific stop.
+	ResourceKindScheduleTimetable ResourceKind = "schedule timetable"
+)
+
+// CacheEntry represents a cached upstream response.
+type CacheEntry struct {
+	URL          string       `json:"url"`           // URL of the resource
+	Kind         ResourceKind `json:"kind"`          // kind of the resource
+	Body         []byte       `json:"body"`          // body of the response
+	ETag         string       `json:"etag"`          // value of the ETag header of the response
+	LastModified string       `json:"last_modified"` // value of the Last-Modified header of the response
+	StoredAt     time.Time    `json:"stored_at"`     // time at which the response was received or last revalidated
+}
+
+// Cache stores upstream responses by key (which is the URL of the resource).
+type Cache interface {
+	// Get returns the entry stored under key (or nil if there is no such entry).
+	Get(key string) (entry *CacheEntry, err error)
+	// Set stores entry under key.
+	Set(key string, entry *CacheEntry) error
+	// Delete removes the entry stored under key (if any).
+	Delete(key string) error
+}
+
+// CachePolicy determines for how long cached upstream responses are used without revalidation.
+type CachePolicy struct {
+	TTLs       map[ResourceKind]time.Duration // time to live for each kind of resource
+	DefaultTTL time.Duration                  // time to live for kinds of resources missing from TTLs
+}
+
+// MemoryCache is a Cache which keeps the entries in memory. It is safe for concurrent use.
+type MemoryCache struct {
+	mutex   sync.RWMutex
+	entries map[string]*CacheEntry
+}
+
+// DiskCache is a Cache which keeps each entry in a separate file in a directory. It is safe for concurrent use.
+type DiskCache struct {
+	Dir string // directory containing the cache entries
+}
+
+// KindFilteredCache is a Cache which only stores entries for the specified kinds of resources in the underlying Cache and ignores all others.
+type KindFilteredCache struct {
+	Cache Cache          // underlying cache
+	Kinds []ResourceKind // kinds of resources which are stored
+}
+
+// DefaultCachePolicy is the CachePolicy used if none is specified: stops, routes and lines are considered fresh for a day, schedule line pages and timetables for six hours and arrivals for ten seconds.
+var DefaultCachePolicy = &CachePolicy{
+	TTLs: map[ResourceKind]time.Duration{
+		ResourceKindStops:             24 * time.Hour,
+		ResourceKindRoutes:            24 * time.Hour,
+		ResourceKindArrivals:          10 * time.Second,
+		ResourceKindScheduleLines:     24 * time.Hour,
+		ResourceKindScheduleLine:      6 * time.Hour,
+		ResourceKindScheduleTimetable: 6 * time.Hour,
+	},
+}
+
+// DefaultCache is the MemoryCache shared by the clients which are created with the default settings, so that their responses are cached transparently for the duration of the process.
+var DefaultCache = NewMemoryCache()
+
+var (
+	// AlwaysFreshCachePolicy is a CachePolicy under which cached responses never expire (i.e. requests are only issued for resources missing from the cache).
+	AlwaysFreshCachePolicy = &CachePolicy{DefaultTTL: math.MaxInt64}
+	// AlwaysStaleCachePolicy is a CachePolicy under which cached responses are always revalidated before being used.
+	AlwaysStaleCachePolicy = &CachePolicy{}
+)
+
+const (
+	diskCacheEntryExtension = ".json"
+	defaultCacheDirName     = "sofiatraffic"
+)
+
+// GetTTL returns the time to live for resources of the specified kind.
+func (p *CachePolicy) GetTTL(kind ResourceKind) time.Duration {
+	ttl, ok := p.TTLs[kind]
+	if !ok {
+		return p.DefaultTTL
+	}
+	return ttl
+}
+
+// IsFresh reports whether entry may be used without revalidation at the specified time.
+func (p *CachePolicy) IsFresh(entry *CacheEntry, now time.Time) bool {
+	return now.Sub(entry.StoredAt) < p.GetTTL(entry.Kind)
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]*CacheEntry{}}
+}
+
+// Get returns the entry stored under key (or nil if there is no such entry).
+func (c *MemoryCache) Get(key string) (entry *CacheEntry, err error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, nil
+	}
+
+	entryCopy := *entry
+	return &entryCopy, nil
+}
+
+// Set stores entry under key.
+func (c *MemoryCache) Set(key string, entry *CacheEntry) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entryCopy := *entry
+	c.entries[key] = &entryCopy
+	return nil
+}
+
+// Delete removes the entry stored under key (if any).
+func (c *MemoryCache) Delete(key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, key)
+	return nil
+}
+
+// GetEntries returns all entries in the cache sorted by URL.
+func (c *MemoryCache) GetEntries() (entries []*CacheEntry, err error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entries = make([]*CacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entryCopy := *entry
+		entries = append(entries, &entryCopy)
+	}
+	sortCacheEntries(entries)
+	return
+}
+
+// Clear removes all entries from the cache.
+func (c *MemoryCache) Clear() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = map[string]*CacheEntry{}
+	return nil
+}
+
+// GetDefaultCacheDir returns the directory for cached upstream responses within the user cache directory (i.e. $XDG_CACHE_HOME/sofiatraffic or ~/.cache/sofiatraffic on Unix systems).
+func GetDefaultCacheDir() (dir string, err error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return
+	}
+
+	dir = filepath.Join(userCacheDir, defaultCacheDirName)
+	return
+}
+
+// NewDiskCache returns a DiskCache which keeps the entries in the specified directory (which is created when the first entry is stored).
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{Dir: dir}
+}
+
+func (c *DiskCache) getEntryPath(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(hash[:])+diskCacheEntryExtension)
+}
+
+func readDiskCacheEntry(entryPath string) (entry *CacheEntry, err error) {
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		return
+	}
+
+	entry = &CacheEntry{}
+	err = json.Unmarshal(data, entry)
+	return
+}
+
+// Get returns the entry stored under key (or nil if there is no such entry).
+func (c *DiskCache) Get(key string) (entry *CacheEntry, err error) {
+	entry, err = readDiskCacheEntry(c.getEntryPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return
+}
+
+// Set stores entry under key.
+func (c *DiskCache) Set(key string, entry *CacheEntry) (err error) {
+	err = os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	file, err := os.CreateTemp(c.Dir, ".entry-*")
+	if err != nil {
+		return
+	}
+
+	_, err = file.Write(data)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return
+	}
+
+	err = os.Rename(file.Name(), c.getEntryPath(key))
+	return
+}
+
+// Delete removes the entry stored under key (if any).
+func (c *DiskCache) Delete(key string) (err error) {
+	err = os.Remove(c.getEntryPath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	return
+}
+
+// GetEntries returns all entries in the cache sorted by URL.
+func (c *DiskCache) GetEntries() (entries []*CacheEntry, err error) {
+	entries = []*CacheEntry{}
+	dirEntries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), diskCacheEntryExtension) {
+			continue
+		}
+
+		entry, err := readDiskCacheEntry(filepath.Join(c.Dir, dirEntry.Name()))
+		if err != nil {
+			return entries, err
+		}
+
+		entries = append(entries, entry)
+	}
+	sortCacheEntries(entries)
+	return
+}
+
+// Clear removes all entries from the cache.
+func (c *DiskCache) Clear() (err error) {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), diskCacheEntryExtension) {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(c.Dir, dirEntry.Name()))
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *KindFilteredCache) isStored(kind ResourceKind) bool {
+	for _, storedKind := range c.Kinds {
+		if kind == storedKind {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the entry stored under key in the underlying cache (or nil if there is no such entry or it is for a resource of a kind which is not stored).
+func (c *KindFilteredCache) Get(key string) (entry *CacheEntry, err error) {
+	entry, err = c.Cache.Get(key)
+	if err != nil || entry == nil || !c.isStored(entry.Kind) {
+		return nil, err
+	}
+
+	return
+}
+
+// Set stores entry under key in the underlying cache if it is for a resource of one of the stored kinds.
+func (c *KindFilteredCache) Set(key string, entry *CacheEntry) error {
+	if !c.isStored(entry.Kind) {
+		return nil
+	}
+
+	return c.Cache.Set(key, entry)
+}
+
+// Delete removes the entry stored under key in the underlying cache (if any).
+func (c *KindFilteredCache) Delete(key string) error {
+	return c.Cache.Delete(key)
+}
+
+func sortCacheEntries(entries []*CacheEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+}
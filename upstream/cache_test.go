@@ -0,0 +1,198 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listableCache is a Cache whose entries can be listed and cleared.
+type listableCache interface {
+	Cache
+	GetEntries() ([]*CacheEntry, error)
+	Clear() error
+}
+
+func TestCaches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		newCache func(t *testing.T) listableCache
+	}{
+		{
+			name: "memory",
+			newCache: func(t *testing.T) listableCache {
+				return NewMemoryCache()
+			},
+		},
+		{
+			name: "disk",
+			newCache: func(t *testing.T) listableCache {
+				return NewDiskCache(filepath.Join(t.TempDir(), "cache"))
+			},
+		},
+	}
+
+	storedAt := time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC)
+	firstEntry := &CacheEntry{URL: "https://example.com/b", Kind: ResourceKindStops, Body: []byte("b"), ETag: `"b"`, StoredAt: storedAt}
+	secondEntry := &CacheEntry{URL: "https://example.com/a", Kind: ResourceKindRoutes, Body: []byte("a"), LastModified: "Mon, 06 May 2024 11:00:00 GMT", StoredAt: storedAt}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cache := testCase.newCache(t)
+			entries, err := cache.GetEntries()
+			if err != nil || len(entries) != 0 {
+				t.Fatalf("expected an empty cache, got %v (%v)", entries, err)
+			}
+			entry, err := cache.Get(firstEntry.URL)
+			if err != nil || entry != nil {
+				t.Fatalf("expected a missing entry, got %v (%v)", entry, err)
+			}
+
+			for _, entry := range []*CacheEntry{firstEntry, secondEntry} {
+				err = cache.Set(entry.URL, entry)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+			}
+			entry, err = cache.Get(firstEntry.URL)
+			if err != nil || !reflect.DeepEqual(entry, firstEntry) {
+				t.Errorf("expected %v, got %v (%v)", firstEntry, entry, err)
+			}
+			entries, err = cache.GetEntries()
+			if err != nil || !reflect.DeepEqual(entries, []*CacheEntry{secondEntry, firstEntry}) {
+				t.Errorf("expected the entries sorted by URL, got %v (%v)", entries, err)
+			}
+
+			err = cache.Delete(firstEntry.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			err = cache.Delete(firstEntry.URL)
+			if err != nil {
+				t.Errorf("expected deleting a missing entry to succeed, got %s", err.Error())
+			}
+			entry, _ = cache.Get(firstEntry.URL)
+			if entry != nil {
+				t.Errorf("expected the entry to be deleted, got %v", entry)
+			}
+
+			err = cache.Clear()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			entries, _ = cache.GetEntries()
+			if len(entries) != 0 {
+				t.Errorf("expected the cache to be cleared, got %v", entries)
+			}
+		})
+	}
+}
+
+func TestKindFilteredCache(t *testing.T) {
+	cache := &KindFilteredCache{Cache: NewMemoryCache(), Kinds: []ResourceKind{ResourceKindStops}}
+	cache.Set("stops", &CacheEntry{URL: "stops", Kind: ResourceKindStops})
+	cache.Set("arrivals", &CacheEntry{URL: "arrivals", Kind: ResourceKindArrivals})
+	if entry, _ := cache.Get("stops"); entry == nil {
+		t.Error("expected the stops to be cached")
+	}
+	if entry, _ := cache.Get("arrivals"); entry != nil {
+		t.Errorf("expected the arrivals not to be cached, got %v", entry)
+	}
+}
+
+func TestCachePolicyIsFresh(t *testing.T) {
+	storedAt := time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		policy   *CachePolicy
+		kind     ResourceKind
+		age      time.Duration
+		expected bool
+	}{
+		{name: "fresh arrivals", policy: DefaultCachePolicy, kind: ResourceKindArrivals, age: 9 * time.Second, expected: true},
+		{name: "stale arrivals", policy: DefaultCachePolicy, kind: ResourceKindArrivals, age: 10 * time.Second},
+		{name: "fresh stops", policy: DefaultCachePolicy, kind: ResourceKindStops, age: 23 * time.Hour, expected: true},
+		{name: "unknown kind", policy: DefaultCachePolicy, kind: "other", age: time.Nanosecond},
+		{name: "always fresh", policy: AlwaysFreshCachePolicy, kind: ResourceKindArrivals, age: 365 * 24 * time.Hour, expected: true},
+		{name: "always stale", policy: AlwaysStaleCachePolicy, kind: ResourceKindStops},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			entry := &CacheEntry{Kind: testCase.kind, StoredAt: storedAt}
+			if testCase.policy.IsFresh(entry, storedAt.Add(testCase.age)) != testCase.expected {
+				t.Errorf("expected IsFresh to be %t", testCase.expected)
+			}
+		})
+	}
+}
+
+func TestFetcherCache(t *testing.T) {
+	clock := installFakeClock(t)
+	var mutex sync.Mutex
+	var conditionalHeaders []string
+	body := "first"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		conditionalHeaders = append(conditionalHeaders, r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+		etag := `"` + body + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 06 May 2024 11:00:00 GMT")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cache := NewMemoryCache()
+	fetcher := &Fetcher{HTTPClient: server.Client(), Cache: cache, CachePolicy: &CachePolicy{DefaultTTL: time.Minute}}
+	request := &Request{URL: server.URL, Kind: ResourceKindStops}
+	steps := []struct {
+		name               string
+		advance            time.Duration
+		newBody            string
+		expectedBody       string
+		expectedConditions []string // conditional headers of the requests issued so far
+	}{
+		{name: "miss", expectedBody: "first", expectedConditions: []string{"|"}},
+		{name: "fresh hit", advance: 30 * time.Second, expectedBody: "first", expectedConditions: []string{"|"}},
+		{name: "revalidated", advance: time.Minute, expectedBody: "first", expectedConditions: []string{"|", `"first"|Mon, 06 May 2024 11:00:00 GMT`}},
+		{name: "fresh after revalidation", advance: 30 * time.Second, expectedBody: "first", expectedConditions: []string{"|", `"first"|Mon, 06 May 2024 11:00:00 GMT`}},
+		{name: "modified", advance: time.Minute, newBody: "second", expectedBody: "second", expectedConditions: []string{"|", `"first"|Mon, 06 May 2024 11:00:00 GMT`, `"first"|Mon, 06 May 2024 11:00:00 GMT`}},
+	}
+	for _, step := range steps {
+		clock.advance(step.advance)
+		if step.newBody != "" {
+			mutex.Lock()
+			body = step.newBody
+			mutex.Unlock()
+		}
+
+		fetchedBody, err := fetcher.Fetch(context.Background(), request)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", step.name, err.Error())
+		}
+		if string(fetchedBody) != step.expectedBody {
+			t.Errorf("%s: expected body %q, got %q", step.name, step.expectedBody, fetchedBody)
+		}
+		mutex.Lock()
+		if !reflect.DeepEqual(conditionalHeaders, step.expectedConditions) {
+			t.Errorf("%s: expected requests with conditions %v, got %v", step.name, step.expectedConditions, conditionalHeaders)
+		}
+		mutex.Unlock()
+	}
+
+	entry, _ := cache.Get(server.URL)
+	if entry == nil || !entry.StoredAt.Equal(clock.now()) || entry.ETag != `"second"` {
+		t.Errorf("expected the cached entry to be updated, got %v", entry)
+	}
+}
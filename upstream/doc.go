@@ -0,0 +1,4 @@
+/*
+Package upstream implements facilities shared by the `schedule` and `virtual` packages for issuing requests to the Urban Mobility Centre Web APIs and classifying their failures.
+*/
+package upstream
@@ -0,0 +1,100 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnavailable indicates that the upstream server could not be reached or failed to handle the request.
+	ErrUnavailable = errors.New("upstream unavailable")
+	// ErrNotFound indicates that the requested resource does not exist on the upstream server.
+	ErrNotFound = errors.New("upstream resource not found")
+	// ErrMalformedResponse indicates that the upstream server returned a response which could not be processed.
+	ErrMalformedResponse = errors.New("malformed upstream response")
+)
+
+// RequestError represents a failure to obtain a response from the upstream server.
+type RequestError struct {
+	URL string // URL of the requested resource
+	Err error  // underlying error
+}
+
+// StatusError represents an unexpected HTTP status code returned by the upstream server.
+type StatusError struct {
+	URL        string        // URL of the requested resource
+	StatusCode int           // HTTP status code of the response
+	Status     string        // HTTP status line of the response
+	RetryAfter time.Duration // delay requested by the Retry-After header of the response (zero if missing)
+}
+
+// ContentTypeError represents an unexpected media type of a response returned by the upstream server.
+type ContentTypeError struct {
+	URL         string   // URL of the requested resource
+	ContentType string   // value of the Content-Type header of the response
+	MediaTypes  []string // media types which were expected
+}
+
+// MalformedResponseError represents a failure to decode a response returned by the upstream server.
+type MalformedResponseError struct {
+	URL string // URL of the requested resource
+	Err error  // underlying error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("could not initiate HTTP GET request to %s: %s", e.URL, e.Err.Error())
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the request failed because the upstream server is unavailable (as opposed to the request being canceled).
+func (e *RequestError) Is(target error) bool {
+	return target == ErrUnavailable && !errors.Is(e.Err, context.Canceled) && !errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status returned for %s: %s", e.URL, e.Status)
+}
+
+// Is reports whether the status code indicates a missing resource (ErrNotFound), a server-side failure (ErrUnavailable) or any other unexpected response (ErrMalformedResponse).
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+
+	case ErrUnavailable:
+		return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout
+
+	case ErrMalformedResponse:
+		return e.StatusCode < 400
+	}
+	return false
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type returned for %s: %s (expected %s)", e.URL, e.ContentType, strings.Join(e.MediaTypes, ", "))
+}
+
+// Is reports whether target is ErrMalformedResponse.
+func (e *ContentTypeError) Is(target error) bool {
+	return target == ErrMalformedResponse
+}
+
+func (e *MalformedResponseError) Error() string {
+	return fmt.Sprintf("could not decode data returned for %s: %s", e.URL, e.Err.Error())
+}
+
+func (e *MalformedResponseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrMalformedResponse.
+func (e *MalformedResponseError) Is(target error) bool {
+	return target == ErrMalformedResponse
+}
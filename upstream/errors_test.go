@@ -0,0 +1,125 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTestResponse(statusCode int, header http.Header) *http.Response {
+	requestURL, _ := url.Parse("https://example.com/resource")
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Header:     header,
+		Request:    &http.Request{URL: requestURL},
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		statusCode             int
+		contentType            string
+		mediaTypes             []string
+		isOK                   bool
+		isNotFound             bool
+		isUnavailable          bool
+		isMalformedResponse    bool
+		expectedStatusError    bool
+		expectedContentTypeErr bool
+	}{
+		{name: "OK", statusCode: http.StatusOK, contentType: "application/json", mediaTypes: JSONMediaTypes, isOK: true},
+		{name: "OK with parameters", statusCode: http.StatusOK, contentType: "text/html; charset=utf-8", mediaTypes: HTMLMediaTypes, isOK: true},
+		{name: "OK without content type", statusCode: http.StatusOK, mediaTypes: JSONMediaTypes, isOK: true},
+		{name: "OK without media types", statusCode: http.StatusOK, contentType: "image/png", isOK: true},
+		{name: "unexpected content type", statusCode: http.StatusOK, contentType: "text/html", mediaTypes: JSONMediaTypes, isMalformedResponse: true, expectedContentTypeErr: true},
+		{name: "invalid content type", statusCode: http.StatusOK, contentType: "/", mediaTypes: JSONMediaTypes, isMalformedResponse: true, expectedContentTypeErr: true},
+		{name: "not found", statusCode: http.StatusNotFound, isNotFound: true, expectedStatusError: true},
+		{name: "gone", statusCode: http.StatusGone, isNotFound: true, expectedStatusError: true},
+		{name: "internal server error", statusCode: http.StatusInternalServerError, isUnavailable: true, expectedStatusError: true},
+		{name: "bad gateway", statusCode: http.StatusBadGateway, isUnavailable: true, expectedStatusError: true},
+		{name: "too many requests", statusCode: http.StatusTooManyRequests, isUnavailable: true, expectedStatusError: true},
+		{name: "request timeout", statusCode: http.StatusRequestTimeout, isUnavailable: true, expectedStatusError: true},
+		{name: "no content", statusCode: http.StatusNoContent, isMalformedResponse: true, expectedStatusError: true},
+		{name: "forbidden", statusCode: http.StatusForbidden, expectedStatusError: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			header := http.Header{}
+			if testCase.contentType != "" {
+				header.Set("Content-Type", testCase.contentType)
+			}
+			err := CheckResponse(newTestResponse(testCase.statusCode, header), testCase.mediaTypes)
+			if testCase.isOK {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err.Error())
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrNotFound) != testCase.isNotFound {
+				t.Errorf("expected errors.Is(err, ErrNotFound) to be %t for %v", testCase.isNotFound, err)
+			}
+			if errors.Is(err, ErrUnavailable) != testCase.isUnavailable {
+				t.Errorf("expected errors.Is(err, ErrUnavailable) to be %t for %v", testCase.isUnavailable, err)
+			}
+			if errors.Is(err, ErrMalformedResponse) != testCase.isMalformedResponse {
+				t.Errorf("expected errors.Is(err, ErrMalformedResponse) to be %t for %v", testCase.isMalformedResponse, err)
+			}
+			var statusError *StatusError
+			if errors.As(err, &statusError) != testCase.expectedStatusError {
+				t.Errorf("expected errors.As(err, *StatusError) to be %t for %v", testCase.expectedStatusError, err)
+			}
+			var contentTypeError *ContentTypeError
+			if errors.As(err, &contentTypeError) != testCase.expectedContentTypeErr {
+				t.Errorf("expected errors.As(err, *ContentTypeError) to be %t for %v", testCase.expectedContentTypeErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckResponseRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+	err := CheckResponse(newTestResponse(http.StatusServiceUnavailable, header), nil)
+	var statusError *StatusError
+	if !errors.As(err, &statusError) || statusError.RetryAfter != 7*time.Second {
+		t.Errorf("expected a StatusError with a Retry-After delay of 7s, got %v", err)
+	}
+}
+
+func TestRequestErrorIsUnavailable(t *testing.T) {
+	testCases := []struct {
+		name          string
+		err           error
+		isUnavailable bool
+	}{
+		{name: "connection refused", err: errors.New("connection refused"), isUnavailable: true},
+		{name: "canceled", err: context.Canceled},
+		{name: "deadline exceeded", err: fmt.Errorf("timeout: %w", context.DeadlineExceeded)},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := &RequestError{URL: "https://example.com/", Err: testCase.err}
+			if errors.Is(err, ErrUnavailable) != testCase.isUnavailable {
+				t.Errorf("expected errors.Is(err, ErrUnavailable) to be %t", testCase.isUnavailable)
+			}
+			if !errors.Is(err, testCase.err) {
+				t.Error("expected the error to wrap the underlying error")
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// Fetcher issues requests to the upstream servers and validates their responses.
+type Fetcher struct {
+	HTTPClient     *http.Client    // HTTP client used to issue requests (http.DefaultClient is used if nil)
+	RetryPolicy    *RetryPolicy    // policy for retrying failed requests (requests are not retried if nil)
+	CircuitBreaker *CircuitBreaker // circuit breaker guarding the upstream hosts (not used if nil)
+	RateLimiter    *RateLimiter    // rate limiter for the requests to the upstream hosts (requests are not limited if nil)
+	Cache          Cache           // cache for the responses (responses are not cached if nil)
+	CachePolicy    *CachePolicy    // policy for using cached responses (DefaultCachePolicy is used if nil)
+}
+
+// Request represents a request for an upstream resource.
+type Request struct {
+	URL        string                      // URL of the requested resource
+	Kind       ResourceKind                // kind of the requested resource (determines for how long the response is cached)
+	MediaTypes []string                    // media types which are acceptable for the response (any media type is acceptable if empty)
+	Decorate   func(request *http.Request) // function applied to the HTTP request before it is issued (optional)
+}
+
+var (
+	// JSONMediaTypes lists the media types acceptable for JSON resources.
+	JSONMediaTypes = []string{"application/json", "text/json", "text/javascript", "application/javascript"}
+	// HTMLMediaTypes lists the media types acceptable for HTML pages.
+	HTMLMediaTypes = []string{"text/html", "application/xhtml+xml"}
+)
+
+// Fetch issues an HTTP GET request for the resource described by request and returns the body of the response. An error is returned if the response has a status code other than 200 or a media type which is not acceptable. Failed requests are retried according to the RetryPolicy, in which case the returned error is an AttemptsError. Requests to hosts which are failing repeatedly are rejected by the CircuitBreaker with a CircuitOpenError. Each attempt waits for the RateLimiter to allow a request to the host. If a Cache is set, fresh cached responses are returned without issuing a request and stale ones are revalidated using a conditional request.
+func (f *Fetcher) Fetch(ctx context.Context, request *Request) (body []byte, err error) {
+	var cachedEntry *CacheEntry
+	if f.Cache != nil {
+		cachedEntry, err = f.Cache.Get(request.URL)
+		if err != nil {
+			cachedEntry = nil
+		}
+		if cachedEntry != nil && f.getCachePolicy().IsFresh(cachedEntry, now()) {
+			return cachedEntry.Body, nil
+		}
+	}
+
+	entry, err := f.fetchWithRetries(ctx, request, cachedEntry)
+	if err != nil {
+		return
+	}
+
+	if f.Cache != nil {
+		f.Cache.Set(request.URL, entry)
+	}
+	body = entry.Body
+	return
+}
+
+func (f *Fetcher) getCachePolicy() *CachePolicy {
+	if f.CachePolicy == nil {
+		return DefaultCachePolicy
+	}
+	return f.CachePolicy
+}
+
+func (f *Fetcher) fetchWithRetries(ctx context.Context, request *Request, cachedEntry *CacheEntry) (entry *CacheEntry, err error) {
+	requestURL, err := url.Parse(request.URL)
+	if err != nil {
+		err = &RequestError{URL: request.URL, Err: err}
+		return
+	}
+
+	host := requestURL.Host
+	attempts := 0
+	for {
+		isTrial := false
+		if f.CircuitBreaker != nil {
+			isTrial, err = f.CircuitBreaker.Allow(host)
+			if err != nil {
+				break
+			}
+		}
+
+		if f.RateLimiter != nil {
+			err = f.RateLimiter.Wait(ctx, host)
+			if err != nil {
+				if isTrial {
+					f.CircuitBreaker.release(host)
+				}
+				err = &RequestError{URL: request.URL, Err: err}
+				break
+			}
+		}
+
+		attempts++
+		entry, err = f.fetchOnce(ctx, request, cachedEntry)
+		if f.CircuitBreaker != nil {
+			switch {
+			case err == nil || !errors.Is(err, ErrUnavailable) && ctx.Err() == nil:
+				f.CircuitBreaker.RecordSuccess(host, isTrial)
+
+			case errors.Is(err, ErrUnavailable):
+				f.CircuitBreaker.RecordFailure(host, isTrial)
+
+			case isTrial:
+				f.CircuitBreaker.release(host)
+			}
+		}
+		if err == nil || f.RetryPolicy == nil {
+			break
+		}
+
+		delay, ok := f.RetryPolicy.delay(attempts, err)
+		if !ok {
+			break
+		}
+
+		sleepErr := sleep(ctx, delay)
+		if sleepErr != nil {
+			err = &RequestError{URL: request.URL, Err: sleepErr}
+			break
+		}
+	}
+	if err != nil && f.RetryPolicy != nil && attempts > 0 {
+		err = &AttemptsError{Attempts: attempts, Err: err}
+	}
+	return
+}
+
+// fetchOnce issues a single request for the resource described by request. If cachedEntry is not nil, the request is conditional and cachedEntry is returned (with an updated storage time) if the resource has not been modified.
+func (f *Fetcher) fetchOnce(ctx context.Context, request *Request, cachedEntry *CacheEntry) (entry *CacheEntry, err error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, request.URL, nil)
+	if err != nil {
+		err = &RequestError{URL: request.URL, Err: err}
+		return
+	}
+
+	if request.Decorate != nil {
+		request.Decorate(httpRequest)
+	}
+	if cachedEntry != nil {
+		if cachedEntry.ETag != "" {
+			httpRequest.Header.Set("If-None-Match", cachedEntry.ETag)
+		}
+		if cachedEntry.LastModified != "" {
+			httpRequest.Header.Set("If-Modified-Since", cachedEntry.LastModified)
+		}
+	}
+
+	httpClient := f.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(httpRequest)
+	if err != nil {
+		err = &RequestError{URL: request.URL, Err: err}
+		return
+	}
+	defer response.Body.Close()
+
+	if cachedEntry != nil && response.StatusCode == http.StatusNotModified {
+		entryCopy := *cachedEntry
+		entryCopy.StoredAt = now()
+		return &entryCopy, nil
+	}
+
+	err = CheckResponse(response, request.MediaTypes)
+	if err != nil {
+		return
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		err = &RequestError{URL: request.URL, Err: err}
+		return
+	}
+
+	entry = &CacheEntry{
+		URL:          request.URL,
+		Kind:         request.Kind,
+		Body:         body,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		StoredAt:     now(),
+	}
+	return
+}
+
+// CheckResponse returns an error if the response has a status code other than 200 or a media type not contained in mediaTypes (an empty mediaTypes list or a missing Content-Type header are always acceptable).
+func CheckResponse(response *http.Response, mediaTypes []string) error {
+	responseURL := response.Request.URL.String()
+	if response.StatusCode != http.StatusOK {
+		return &StatusError{URL: responseURL, StatusCode: response.StatusCode, Status: response.Status, RetryAfter: parseRetryAfter(response)}
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	if len(mediaTypes) == 0 || contentType == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return &ContentTypeError{URL: responseURL, ContentType: contentType, MediaTypes: mediaTypes}
+	}
+
+	for _, acceptableMediaType := range mediaTypes {
+		if mediaType == acceptableMediaType {
+			return nil
+		}
+	}
+	return &ContentTypeError{URL: responseURL, ContentType: contentType, MediaTypes: mediaTypes}
+}
@@ -0,0 +1,301 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock replaces the clock of the package with one which only advances when the package sleeps.
+type fakeClock struct {
+	mutex   sync.Mutex
+	time    time.Time
+	sleeps  []time.Duration                 // delays for which the package has slept
+	onSleep func(delay time.Duration) error // function called before each sleep whose error is returned instead of sleeping (optional)
+}
+
+// installFakeClock replaces the clock of the package with a fakeClock for the duration of the test.
+func installFakeClock(t *testing.T) *fakeClock {
+	clock := &fakeClock{time: time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC)}
+	previousNow, previousSleep := now, sleep
+	now, sleep = clock.now, clock.sleep
+	t.Cleanup(func() {
+		now, sleep = previousNow, previousSleep
+	})
+	return clock
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.time
+}
+
+func (c *fakeClock) sleep(ctx context.Context, delay time.Duration) error {
+	if c.onSleep != nil {
+		err := c.onSleep(delay)
+		if err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	c.advance(delay)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.sleeps = append(c.sleeps, delay)
+	return nil
+}
+
+func (c *fakeClock) advance(delay time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.time = c.time.Add(delay)
+}
+
+// testResponse describes a response of the test server.
+type testResponse struct {
+	statusCode int
+	retryAfter string
+}
+
+// newScriptedServer returns a server which responds to consecutive requests as described by responses (repeating the last response once they are exhausted) and a function returning the number of requests it has received.
+func newScriptedServer(t *testing.T, responses []testResponse) (server *httptest.Server, getRequestCount func() int) {
+	var mutex sync.Mutex
+	requestCount := 0
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		response := responses[len(responses)-1]
+		if requestCount < len(responses) {
+			response = responses[requestCount]
+		}
+		requestCount++
+		mutex.Unlock()
+
+		if response.retryAfter != "" {
+			w.Header().Set("Retry-After", response.retryAfter)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(response.statusCode)
+		w.Write([]byte(`{"status":` + strconv.Itoa(response.statusCode) + `}`))
+	}))
+	t.Cleanup(server.Close)
+	getRequestCount = func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return requestCount
+	}
+	return
+}
+
+var testRetryPolicy = &RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+}
+
+func TestFetcherRetries(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		responses            []testResponse
+		expectedRequests     int
+		expectedSleeps       []time.Duration
+		expectedAttempts     int // attempts reported by the AttemptsError (0 if the fetch succeeds)
+		isUnavailable        bool
+		isNotFound           bool
+		isMalformedResponse  bool
+		expectedBodyContains string
+	}{
+		{
+			name:             "success",
+			responses:        []testResponse{{statusCode: http.StatusOK}},
+			expectedRequests: 1,
+		},
+		{
+			name:             "server error then success",
+			responses:        []testResponse{{statusCode: http.StatusInternalServerError}, {statusCode: http.StatusOK}},
+			expectedRequests: 2,
+			expectedSleeps:   []time.Duration{time.Second},
+		},
+		{
+			name:             "bad gateway twice then success",
+			responses:        []testResponse{{statusCode: http.StatusBadGateway}, {statusCode: http.StatusServiceUnavailable}, {statusCode: http.StatusOK}},
+			expectedRequests: 3,
+			expectedSleeps:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:             "too many requests with Retry-After",
+			responses:        []testResponse{{statusCode: http.StatusTooManyRequests, retryAfter: "3"}, {statusCode: http.StatusOK}},
+			expectedRequests: 2,
+			expectedSleeps:   []time.Duration{3 * time.Second},
+		},
+		{
+			name:             "too many requests with Retry-After date",
+			responses:        []testResponse{{statusCode: http.StatusTooManyRequests, retryAfter: "Mon, 06 May 2024 12:00:05 GMT"}, {statusCode: http.StatusOK}},
+			expectedRequests: 2,
+			expectedSleeps:   []time.Duration{5 * time.Second},
+		},
+		{
+			name:             "Retry-After longer than the maximum backoff",
+			responses:        []testResponse{{statusCode: http.StatusTooManyRequests, retryAfter: "60"}},
+			expectedRequests: 1,
+			expectedAttempts: 1,
+			isUnavailable:    true,
+		},
+		{
+			name:             "attempts exhausted",
+			responses:        []testResponse{{statusCode: http.StatusServiceUnavailable}},
+			expectedRequests: 3,
+			expectedSleeps:   []time.Duration{time.Second, 2 * time.Second},
+			expectedAttempts: 3,
+			isUnavailable:    true,
+		},
+		{
+			name:             "not found is not retried",
+			responses:        []testResponse{{statusCode: http.StatusNotFound}},
+			expectedRequests: 1,
+			expectedAttempts: 1,
+			isNotFound:       true,
+		},
+		{
+			name:                "unexpected status is not retried",
+			responses:           []testResponse{{statusCode: http.StatusNoContent}},
+			expectedRequests:    1,
+			expectedAttempts:    1,
+			isMalformedResponse: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			clock := installFakeClock(t)
+			server, getRequestCount := newScriptedServer(t, testCase.responses)
+			fetcher := &Fetcher{HTTPClient: server.Client(), RetryPolicy: testRetryPolicy}
+			body, err := fetcher.Fetch(context.Background(), &Request{URL: server.URL, MediaTypes: JSONMediaTypes})
+
+			if getRequestCount() != testCase.expectedRequests {
+				t.Errorf("expected %d requests, got %d", testCase.expectedRequests, getRequestCount())
+			}
+			if !reflect.DeepEqual(clock.sleeps, testCase.expectedSleeps) {
+				t.Errorf("expected sleeps %v, got %v", testCase.expectedSleeps, clock.sleeps)
+			}
+			if testCase.expectedAttempts == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+				if string(body) != `{"status":200}` {
+					t.Errorf("unexpected body: %s", body)
+				}
+				return
+			}
+
+			var attemptsError *AttemptsError
+			if !errors.As(err, &attemptsError) || attemptsError.Attempts != testCase.expectedAttempts {
+				t.Fatalf("expected an AttemptsError after %d attempts, got %v", testCase.expectedAttempts, err)
+			}
+			if errors.Is(err, ErrUnavailable) != testCase.isUnavailable {
+				t.Errorf("expected errors.Is(err, ErrUnavailable) to be %t for %v", testCase.isUnavailable, err)
+			}
+			if errors.Is(err, ErrNotFound) != testCase.isNotFound {
+				t.Errorf("expected errors.Is(err, ErrNotFound) to be %t for %v", testCase.isNotFound, err)
+			}
+			if errors.Is(err, ErrMalformedResponse) != testCase.isMalformedResponse {
+				t.Errorf("expected errors.Is(err, ErrMalformedResponse) to be %t for %v", testCase.isMalformedResponse, err)
+			}
+		})
+	}
+}
+
+func TestFetcherCancellationDuringBackoff(t *testing.T) {
+	clock := installFakeClock(t)
+	server, getRequestCount := newScriptedServer(t, []testResponse{{statusCode: http.StatusServiceUnavailable}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock.onSleep = func(delay time.Duration) error {
+		cancel()
+		return nil
+	}
+
+	breaker := NewCircuitBreaker(2, time.Minute)
+	fetcher := &Fetcher{HTTPClient: server.Client(), RetryPolicy: testRetryPolicy, CircuitBreaker: breaker}
+	_, err := fetcher.Fetch(ctx, &Request{URL: server.URL})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the error to match context.Canceled, got %v", err)
+	}
+	if errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected a canceled fetch not to be reported as unavailable, got %v", err)
+	}
+	if getRequestCount() != 1 {
+		t.Errorf("expected 1 request, got %d", getRequestCount())
+	}
+	if len(clock.sleeps) != 0 {
+		t.Errorf("expected the backoff to be interrupted, got sleeps %v", clock.sleeps)
+	}
+	if _, err := breaker.Allow(mustGetHost(t, server)); err != nil {
+		t.Error("expected the circuit to stay closed after a single failure")
+	}
+}
+
+func TestFetcherCircuitBreaker(t *testing.T) {
+	clock := installFakeClock(t)
+	server, getRequestCount := newScriptedServer(t, []testResponse{
+		{statusCode: http.StatusServiceUnavailable},
+		{statusCode: http.StatusServiceUnavailable},
+		{statusCode: http.StatusServiceUnavailable},
+		{statusCode: http.StatusOK},
+	})
+	breaker := NewCircuitBreaker(2, 30*time.Second)
+	fetcher := &Fetcher{HTTPClient: server.Client(), CircuitBreaker: breaker}
+	request := &Request{URL: server.URL}
+
+	steps := []struct {
+		name             string
+		advance          time.Duration
+		isCircuitOpen    bool
+		isSuccess        bool
+		expectedRequests int
+	}{
+		{name: "first failure", expectedRequests: 1},
+		{name: "second failure opens the circuit", expectedRequests: 2},
+		{name: "open circuit rejects requests", isCircuitOpen: true, expectedRequests: 2},
+		{name: "still open before the open duration elapses", advance: 29 * time.Second, isCircuitOpen: true, expectedRequests: 2},
+		{name: "failed trial request reopens the circuit", advance: time.Second, expectedRequests: 3},
+		{name: "reopened circuit rejects requests", isCircuitOpen: true, expectedRequests: 3},
+		{name: "successful trial request closes the circuit", advance: 30 * time.Second, isSuccess: true, expectedRequests: 4},
+		{name: "closed circuit lets requests through", isSuccess: true, expectedRequests: 5},
+	}
+	for _, step := range steps {
+		clock.advance(step.advance)
+		_, err := fetcher.Fetch(context.Background(), request)
+		if step.isSuccess != (err == nil) {
+			t.Errorf("%s: unexpected result: %v", step.name, err)
+		}
+		if errors.Is(err, ErrCircuitOpen) != step.isCircuitOpen {
+			t.Errorf("%s: expected errors.Is(err, ErrCircuitOpen) to be %t for %v", step.name, step.isCircuitOpen, err)
+		}
+		if getRequestCount() != step.expectedRequests {
+			t.Errorf("%s: expected %d requests, got %d", step.name, step.expectedRequests, getRequestCount())
+		}
+	}
+}
+
+func mustGetHost(t *testing.T, server *httptest.Server) string {
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return request.URL.Host
+}
@@ -0,0 +1,110 @@
+package upstream
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate represents the rate at which requests to an upstream host are allowed.
+type Rate struct {
+	RequestsPerSecond float64 // sustained number of requests per second (requests are not limited if not positive)
+	Burst             int     // maximum number of requests which may be issued at once (treated as 1 if not positive)
+}
+
+// RateLimiter limits the rate of requests to each upstream host using a token bucket per host. The same RateLimiter may be shared by several clients, in which case the limits apply to their requests combined.
+type RateLimiter struct {
+	mutex       sync.Mutex
+	defaultRate Rate
+	hostRates   map[string]Rate
+	buckets     map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	rate       Rate
+	tokens     float64
+	lastRefill time.Time
+}
+
+// DefaultRateLimiter is the RateLimiter shared by the default clients of the `schedule` and `virtual` packages.
+var DefaultRateLimiter = NewRateLimiter(Rate{RequestsPerSecond: 5, Burst: 10})
+
+// NewRateLimiter returns a RateLimiter which allows requests to each host at defaultRate unless a different rate is set for the host.
+func NewRateLimiter(defaultRate Rate) *RateLimiter {
+	return &RateLimiter{
+		defaultRate: defaultRate,
+		hostRates:   map[string]Rate{},
+		buckets:     map[string]*tokenBucket{},
+	}
+}
+
+// SetHostRate sets the rate at which requests to the specified host are allowed.
+func (l *RateLimiter) SetHostRate(host string, rate Rate) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.hostRates[host] = rate
+	delete(l.buckets, host)
+}
+
+// GetHostRate returns the rate at which requests to the specified host are allowed.
+func (l *RateLimiter) GetHostRate(host string) Rate {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.getHostRate(host)
+}
+
+func (l *RateLimiter) getHostRate(host string) Rate {
+	rate, ok := l.hostRates[host]
+	if !ok {
+		return l.defaultRate
+	}
+	return rate
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	burst := float64(b.rate.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.lastRefill).Seconds()*b.rate.RequestsPerSecond)
+	b.lastRefill = now
+}
+
+// Wait blocks until a request to the specified host is allowed or ctx is done (in which case the error of ctx is returned).
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	l.mutex.Lock()
+	rate := l.getHostRate(host)
+	if rate.RequestsPerSecond <= 0 {
+		l.mutex.Unlock()
+		return nil
+	}
+
+	currentTime := now()
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &tokenBucket{rate: rate, tokens: math.Max(float64(rate.Burst), 1), lastRefill: currentTime}
+		l.buckets[host] = bucket
+	}
+	bucket.refill(currentTime)
+	bucket.tokens--
+	delay := time.Duration(0)
+	if bucket.tokens < 0 {
+		delay = time.Duration(-bucket.tokens / rate.RequestsPerSecond * float64(time.Second))
+	}
+	l.mutex.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	err := sleep(ctx, delay)
+	if err != nil {
+		l.mutex.Lock()
+		bucket.tokens++
+		l.mutex.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	testCases := []struct {
+		name           string
+		rate           Rate
+		requests       int
+		interval       time.Duration // time elapsed between consecutive requests
+		expectedSleeps []time.Duration
+	}{
+		{name: "unlimited", rate: Rate{}, requests: 10},
+		{name: "within the burst", rate: Rate{RequestsPerSecond: 1, Burst: 3}, requests: 3},
+		{name: "beyond the burst", rate: Rate{RequestsPerSecond: 1, Burst: 2}, requests: 4, expectedSleeps: []time.Duration{time.Second, time.Second}},
+		{name: "burst below 1", rate: Rate{RequestsPerSecond: 2}, requests: 3, expectedSleeps: []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}},
+		{name: "refilled between requests", rate: Rate{RequestsPerSecond: 1, Burst: 1}, requests: 3, interval: time.Second},
+		{name: "partially refilled between requests", rate: Rate{RequestsPerSecond: 1, Burst: 1}, requests: 2, interval: 250 * time.Millisecond, expectedSleeps: []time.Duration{750 * time.Millisecond}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			clock := installFakeClock(t)
+			limiter := NewRateLimiter(testCase.rate)
+			for i := 0; i < testCase.requests; i++ {
+				if i > 0 {
+					clock.advance(testCase.interval)
+				}
+				err := limiter.Wait(context.Background(), "example.com")
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+			}
+			if !reflect.DeepEqual(clock.sleeps, testCase.expectedSleeps) {
+				t.Errorf("expected sleeps %v, got %v", testCase.expectedSleeps, clock.sleeps)
+			}
+		})
+	}
+}
+
+func TestRateLimiterHostRates(t *testing.T) {
+	clock := installFakeClock(t)
+	limiter := NewRateLimiter(Rate{RequestsPerSecond: 1, Burst: 1})
+	limiter.SetHostRate("fast.example.com", Rate{})
+	if limiter.GetHostRate("fast.example.com") != (Rate{}) {
+		t.Errorf("unexpected rate for fast.example.com: %v", limiter.GetHostRate("fast.example.com"))
+	}
+	if limiter.GetHostRate("slow.example.com") != (Rate{RequestsPerSecond: 1, Burst: 1}) {
+		t.Errorf("unexpected rate for slow.example.com: %v", limiter.GetHostRate("slow.example.com"))
+	}
+
+	for i := 0; i < 3; i++ {
+		limiter.Wait(context.Background(), "fast.example.com")
+		limiter.Wait(context.Background(), "slow.example.com")
+	}
+	expectedSleeps := []time.Duration{time.Second, time.Second}
+	if !reflect.DeepEqual(clock.sleeps, expectedSleeps) {
+		t.Errorf("expected sleeps %v, got %v", expectedSleeps, clock.sleeps)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	clock := installFakeClock(t)
+	limiter := NewRateLimiter(Rate{RequestsPerSecond: 1, Burst: 1})
+	limiter.Wait(context.Background(), "example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := limiter.Wait(ctx, "example.com")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the error to match context.Canceled, got %v", err)
+	}
+
+	clock.advance(time.Second)
+	limiter.Wait(context.Background(), "example.com")
+	if len(clock.sleeps) != 0 {
+		t.Errorf("expected the token of the canceled request to be returned, got sleeps %v", clock.sleeps)
+	}
+}
@@ -0,0 +1,140 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy determines whether and when failed requests to the upstream servers are retried. Only failures indicating that the upstream server is unavailable (i.e. errors matching ErrUnavailable) are retried.
+type RetryPolicy struct {
+	MaxAttempts    int           // maximum number of attempts including the first one (values below 2 disable retrying)
+	InitialBackoff time.Duration // delay before the first retry
+	MaxBackoff     time.Duration // upper bound of the delay between attempts (unbounded if not positive; a longer Retry-After delay requested by the server ends the retrying)
+	Multiplier     float64       // factor by which the delay grows after each retry (values below 1 are treated as 1)
+	Jitter         float64       // fraction of each delay which is randomized (between 0 and 1)
+}
+
+// AttemptsError represents a request which failed after the specified number of attempts.
+type AttemptsError struct {
+	Attempts int   // number of attempts made
+	Err      error // error returned by the last attempt
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by the default clients of the `schedule` and `virtual` packages.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     8 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.5,
+}
+
+func (e *AttemptsError) Error() string {
+	if e.Attempts == 1 {
+		return e.Err.Error() + " (after 1 attempt)"
+	}
+	return fmt.Sprintf("%s (after %d attempts)", e.Err.Error(), e.Attempts)
+}
+
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether a request which failed with err may succeed if retried.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrUnavailable) && !errors.Is(err, ErrCircuitOpen)
+}
+
+// Backoff returns the delay before the attempt following the specified number of failed attempts, not taking jitter into account.
+func (p *RetryPolicy) Backoff(attempts int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempts && (p.MaxBackoff <= 0 || backoff < float64(p.MaxBackoff)); i++ {
+		backoff *= multiplier
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	return time.Duration(backoff)
+}
+
+// delay returns the delay before the attempt following the specified number of failed attempts (the last of which failed with err) and whether another attempt should be made at all.
+func (p *RetryPolicy) delay(attempts int, err error) (delay time.Duration, ok bool) {
+	if attempts >= p.MaxAttempts || !IsRetryable(err) {
+		return
+	}
+
+	var statusError *StatusError
+	if errors.As(err, &statusError) && statusError.RetryAfter > 0 {
+		if p.MaxBackoff > 0 && statusError.RetryAfter > p.MaxBackoff {
+			return
+		}
+
+		return statusError.RetryAfter, true
+	}
+
+	delay = p.Backoff(attempts)
+	jitter := p.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+	if jitter > 0 {
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+	return delay, true
+}
+
+var (
+	// now returns the current time (replaced by a fake clock in tests).
+	now = time.Now
+	// sleep waits for the specified delay or until ctx is done (replaced by a fake clock in tests).
+	sleep = sleepContext
+)
+
+// sleepContext waits for the specified delay or until ctx is done, whichever happens first.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter returns the delay requested by the Retry-After header of the response (or zero if the header is missing or invalid).
+func parseRetryAfter(response *http.Response) time.Duration {
+	retryAfter := response.Header.Get("Retry-After")
+	if retryAfter == "" {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(retryAfter)
+	if err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	retryTime, err := http.ParseTime(retryAfter)
+	if err != nil {
+		return 0
+	}
+
+	delay := retryTime.Sub(now())
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
@@ -0,0 +1,100 @@
+package upstream
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   *RetryPolicy
+		attempts int
+		expected time.Duration
+	}{
+		{name: "first retry", policy: testRetryPolicy, attempts: 1, expected: time.Second},
+		{name: "second retry", policy: testRetryPolicy, attempts: 2, expected: 2 * time.Second},
+		{name: "third retry", policy: testRetryPolicy, attempts: 3, expected: 4 * time.Second},
+		{name: "capped at the maximum backoff", policy: testRetryPolicy, attempts: 10, expected: 10 * time.Second},
+		{name: "multiplier below 1", policy: &RetryPolicy{InitialBackoff: time.Second, Multiplier: 0.5}, attempts: 3, expected: time.Second},
+		{name: "no maximum backoff", policy: &RetryPolicy{InitialBackoff: time.Second, Multiplier: 3}, attempts: 3, expected: 9 * time.Second},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			backoff := testCase.policy.Backoff(testCase.attempts)
+			if backoff != testCase.expected {
+				t.Errorf("expected backoff %s, got %s", testCase.expected, backoff)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	unavailableError := &StatusError{StatusCode: http.StatusServiceUnavailable}
+	testCases := []struct {
+		name          string
+		attempts      int
+		err           error
+		expectedDelay time.Duration
+		expectedOK    bool
+	}{
+		{name: "unavailable", attempts: 1, err: unavailableError, expectedDelay: time.Second, expectedOK: true},
+		{name: "attempts exhausted", attempts: 3, err: unavailableError},
+		{name: "not found", attempts: 1, err: &StatusError{StatusCode: http.StatusNotFound}},
+		{name: "malformed response", attempts: 1, err: &MalformedResponseError{Err: errors.New("invalid JSON")}},
+		{name: "circuit open", attempts: 1, err: &CircuitOpenError{Host: "example.com"}},
+		{name: "Retry-After", attempts: 2, err: &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Second}, expectedDelay: 5 * time.Second, expectedOK: true},
+		{name: "Retry-After too long", attempts: 1, err: &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			delay, ok := testRetryPolicy.delay(testCase.attempts, testCase.err)
+			if ok != testCase.expectedOK || delay != testCase.expectedDelay {
+				t.Errorf("expected (%s, %t), got (%s, %t)", testCase.expectedDelay, testCase.expectedOK, delay, ok)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelayJitter(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		delay, ok := policy.delay(1, &StatusError{StatusCode: http.StatusBadGateway})
+		if !ok || delay < 500*time.Millisecond || delay > time.Second {
+			t.Fatalf("expected a delay between 500ms and 1s, got (%s, %t)", delay, ok)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	installFakeClock(t)
+	testCases := []struct {
+		name       string
+		retryAfter string
+		expected   time.Duration
+	}{
+		{name: "missing", retryAfter: "", expected: 0},
+		{name: "seconds", retryAfter: "120", expected: 2 * time.Minute},
+		{name: "negative seconds", retryAfter: "-1", expected: 0},
+		{name: "date", retryAfter: "Mon, 06 May 2024 12:01:00 GMT", expected: time.Minute},
+		{name: "date in the past", retryAfter: "Mon, 06 May 2024 11:00:00 GMT", expected: 0},
+		{name: "invalid", retryAfter: "soon", expected: 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			header := http.Header{}
+			if testCase.retryAfter != "" {
+				header.Set("Retry-After", testCase.retryAfter)
+			}
+			delay := parseRetryAfter(newTestResponse(http.StatusTooManyRequests, header))
+			if delay != testCase.expected {
+				t.Errorf("expected %s, got %s", testCase.expected, delay)
+			}
+		})
+	}
+}
@@ -30,19 +30,44 @@ type LineVehicleArrivalList struct {
 // LineVehicleArrivalListList represents a list of LineVehicleArrivalList objects.
 type LineVehicleArrivalListList []*LineVehicleArrivalList
 
-// DoShowFacilities determines whether info about the available facilities in the vehicles should be displayed for VehicleArrival objects.
-var DoShowFacilities bool
-
-// DoShowRemainingTimeUntilArrival determines whether the remaining time until arrival should be displayed for VehicleArrival objects instead of the specific time of arrival.
-var DoShowRemainingTimeUntilArrival bool
-
 const (
 	timeHMS = "15:04:05"
 )
 
-func (a *VehicleArrival) String() (str string) {
+// GetTime resolves the time of day of the vehicle arrival relative to now. Times of day more than 12 hours before now are considered to belong to the next day (i.e. to be after midnight).
+func (a *VehicleArrival) GetTime(now time.Time) (arrivalTime time.Time, err error) {
+	arrivalTimeZeroOffset, err := time.Parse(timeHMS, a.Time)
+	if err != nil {
+		return
+	}
+
+	arrivalTime = time.Date(now.Year(), now.Month(), now.Day(), arrivalTimeZeroOffset.Hour(), arrivalTimeZeroOffset.Minute(), arrivalTimeZeroOffset.Second(), 0, now.Location())
+	if arrivalTime.Before(now.Add(-12 * time.Hour)) {
+		arrivalTime = arrivalTime.AddDate(0, 0, 1)
+	}
+	return
+}
+
+// Render returns the display representation of the vehicle arrival as determined by the specified options.
+func (a *VehicleArrival) Render(options *RenderOptions) (str string) {
 	str += a.Time
-	if DoShowFacilities {
+	if options.DoShowRemainingTimeUntilArrival {
+		arrivalTimeZeroOffset, err := time.Parse(timeHMS, a.Time)
+		if err != nil {
+			log.Printf("could not parse arrival time (%s): %s", a.Time, err)
+			return ""
+		}
+
+		now := options.getNow()
+		arrivalTime := time.Date(now.Year(), now.Month(), now.Day(), arrivalTimeZeroOffset.Hour(), arrivalTimeZeroOffset.Minute(), 0, 0, now.Location())
+		remainingTimeDuration := arrivalTime.Sub(now)
+		str, err = durationfmt.Format(remainingTimeDuration, "%0h:%0m:%0s")
+		if err != nil {
+			log.Printf("could not format remaining time duration (%s): %s", remainingTimeDuration.String(), err)
+			return ""
+		}
+	}
+	if options.DoShowFacilities {
 		str += " ("
 		if a.HasAirConditioning {
 			str += "+"
@@ -60,31 +85,33 @@ func (a *VehicleArrival) String() (str string) {
 	return
 }
 
-func (al VehicleArrivalList) String() string {
+func (a *VehicleArrival) String() string {
+	return a.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the list of vehicle arrivals as determined by the specified options.
+func (al VehicleArrivalList) Render(options *RenderOptions) string {
 	arrivalStrings := make([]string, len(al))
 	for i, arrival := range al {
-		arrivalString := arrival.String()
-		if DoShowRemainingTimeUntilArrival {
-			arrivalTimeZeroOffset, err := time.Parse(timeHMS, arrivalString)
-			if err != nil {
-				log.Printf("could not parse arrival time (%s): %s", arrivalString, err)
-				return ""
-			}
-
-			now := time.Now()
-			arrivalTime := time.Date(now.Year(), now.Month(), now.Day(), arrivalTimeZeroOffset.Hour(), arrivalTimeZeroOffset.Minute(), 0, 0, time.Local)
-			remainingTimeDuration := arrivalTime.Sub(now)
-			arrivalString, err = durationfmt.Format(remainingTimeDuration, "%0h:%0m:%0s")
-			if err != nil {
-				log.Printf("could not format remaining time duration (%s): %s", remainingTimeDuration.String(), err)
-				return ""
-			}
+		arrivalString := arrival.Render(options)
+		if arrivalString == "" {
+			return ""
 		}
+
 		arrivalStrings[i] = arrivalString
 	}
 	return strings.Join(arrivalStrings, ", ")
 }
 
+func (al VehicleArrivalList) String() string {
+	return al.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the list of vehicle arrivals for the line as determined by the specified options.
+func (la *LineVehicleArrivalList) Render(options *RenderOptions) string {
+	return "* " + l10n.Translator[la.VehicleType] + " " + la.LineNumber + ": " + la.VehicleArrivalList.Render(options)
+}
+
 func (la *LineVehicleArrivalList) String() string {
-	return "* " + l10n.Translator[la.VehicleType] + " " + la.LineNumber + ": " + la.VehicleArrivalList.String()
+	return la.Render(DefaultRenderOptions)
 }
@@ -0,0 +1,59 @@
+package virtual
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVehicleArrivalGetTime(t *testing.T) {
+	testCases := []struct {
+		name                string
+		now                 time.Time
+		time                string
+		expectedArrivalTime time.Time
+		isErrorExpected     bool
+	}{
+		{
+			name:                "later on the same day",
+			now:                 time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC),
+			time:                "12:05:30",
+			expectedArrivalTime: time.Date(2024, time.May, 6, 12, 5, 30, 0, time.UTC),
+		},
+		{
+			name:                "slightly before now",
+			now:                 time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC),
+			time:                "11:59:00",
+			expectedArrivalTime: time.Date(2024, time.May, 6, 11, 59, 0, 0, time.UTC),
+		},
+		{
+			name:                "after midnight",
+			now:                 time.Date(2024, time.May, 6, 23, 50, 0, 0, time.UTC),
+			time:                "00:05:00",
+			expectedArrivalTime: time.Date(2024, time.May, 7, 0, 5, 0, 0, time.UTC),
+		},
+		{
+			name:                "end of the year",
+			now:                 time.Date(2024, time.December, 31, 23, 59, 0, 0, time.UTC),
+			time:                "00:01:00",
+			expectedArrivalTime: time.Date(2025, time.January, 1, 0, 1, 0, 0, time.UTC),
+		},
+		{
+			name:            "invalid time",
+			now:             time.Date(2024, time.May, 6, 12, 0, 0, 0, time.UTC),
+			time:            "12:05",
+			isErrorExpected: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			arrivalTime, err := (&VehicleArrival{Time: testCase.time}).GetTime(testCase.now)
+			if (err != nil) != testCase.isErrorExpected {
+				t.Fatalf("expected error to be %t, got %v", testCase.isErrorExpected, err)
+			}
+			if !arrivalTime.Equal(testCase.expectedArrivalTime) {
+				t.Errorf("expected arrival time %s, got %s", testCase.expectedArrivalTime, arrivalTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package virtual
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+)
+
+// Client represents a client for the REST APIs for the virtual timetables on the Urban Mobility Centre website.
+type Client struct {
+	HTTPClient       *http.Client             // HTTP client used to issue requests (http.DefaultClient is used if nil)
+	ResourcesBaseURL string                   // base URL of the API serving the lists of stops and routes
+	ArrivalsBaseURL  string                   // base URL of the API serving the arrivals at stops
+	UserAgent        string                   // value of the User-Agent header sent with each request (the Go default is used if empty)
+	Header           http.Header              // headers sent with each request
+	RetryPolicy      *upstream.RetryPolicy    // policy for retrying failed requests (requests are not retried if nil)
+	CircuitBreaker   *upstream.CircuitBreaker // circuit breaker guarding the API hosts (not used if nil)
+	RateLimiter      *upstream.RateLimiter    // rate limiter for the requests to the API hosts (requests are not limited if nil)
+	Cache            upstream.Cache           // cache for the API responses (responses are not cached if nil)
+	CachePolicy      *upstream.CachePolicy    // policy for using cached API responses (upstream.DefaultCachePolicy is used if nil)
+
+	DoTranslateStopNames bool // whether stop names should be translated from Bulgarian to the local language
+}
+
+const (
+	// DefaultResourcesBaseURL represents the base URL of the API serving the lists of stops and routes.
+	DefaultResourcesBaseURL = "https://routes.sofiatraffic.bg/resources"
+	// DefaultArrivalsBaseURL represents the base URL of the API serving the arrivals at stops.
+	DefaultArrivalsBaseURL = "https://api-arrivals.sofiatraffic.bg/api/v1"
+)
+
+// DefaultClient is the Client used by the package-level functions and by the methods of the StopList type.
+var DefaultClient = NewClient(nil)
+
+// NewClient returns a Client which issues requests to the default API base URLs through the specified httpClient (or through http.DefaultClient if httpClient is nil). The client uses upstream.DefaultRetryPolicy, upstream.DefaultCircuitBreaker, upstream.DefaultRateLimiter and upstream.DefaultCache.
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		HTTPClient:       httpClient,
+		ResourcesBaseURL: DefaultResourcesBaseURL,
+		ArrivalsBaseURL:  DefaultArrivalsBaseURL,
+		Header:           http.Header{},
+		RetryPolicy:      upstream.DefaultRetryPolicy,
+		CircuitBreaker:   upstream.DefaultCircuitBreaker,
+		RateLimiter:      upstream.DefaultRateLimiter,
+		Cache:            upstream.DefaultCache,
+	}
+}
+
+func (c *Client) getFetcher() *upstream.Fetcher {
+	return &upstream.Fetcher{
+		HTTPClient:     c.HTTPClient,
+		RetryPolicy:    c.RetryPolicy,
+		CircuitBreaker: c.CircuitBreaker,
+		RateLimiter:    c.RateLimiter,
+		Cache:          c.Cache,
+		CachePolicy:    c.CachePolicy,
+	}
+}
+
+func (c *Client) getEndpointURL(baseURL string, endpointPath string, query url.Values) (endpointURL *url.URL, err error) {
+	endpointURL, err = url.Parse(baseURL)
+	if err != nil {
+		err = fmt.Errorf("could not parse API base URL %s: %s", baseURL, err.Error())
+		return
+	}
+
+	endpointURL.Path = strings.TrimSuffix(endpointURL.Path, "/") + endpointPath
+	if query != nil {
+		endpointURL.RawQuery = query.Encode()
+	}
+	return
+}
+
+func (c *Client) getJSON(ctx context.Context, endpointURL *url.URL, kind upstream.ResourceKind, value interface{}) (err error) {
+	request := &upstream.Request{
+		URL:        endpointURL.String(),
+		Kind:       kind,
+		MediaTypes: upstream.JSONMediaTypes,
+		Decorate:   c.decorateRequest,
+	}
+	body, err := c.getFetcher().Fetch(ctx, request)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(body, value)
+	if err != nil {
+		err = &upstream.MalformedResponseError{URL: request.URL, Err: err}
+		return
+	}
+
+	return
+}
+
+func (c *Client) decorateRequest(request *http.Request) {
+	for name, values := range c.Header {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	if c.UserAgent != "" {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+}
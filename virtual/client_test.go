@@ -0,0 +1,250 @@
+package virtual
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestClient returns a Client whose base URLs point to the specified paths on server and which neither retries requests nor uses a circuit breaker or a rate limiter.
+func newTestClient(server *httptest.Server, resourcesPath string, arrivalsPath string) *Client {
+	return &Client{
+		HTTPClient:       server.Client(),
+		ResourcesBaseURL: server.URL + resourcesPath,
+		ArrivalsBaseURL:  server.URL + arrivalsPath,
+		Header:           http.Header{},
+	}
+}
+
+func TestClientBaseURLs(t *testing.T) {
+	testCases := []struct {
+		name          string
+		resourcesPath string
+		arrivalsPath  string
+		call          func(c *Client) error
+		expectedURL   string
+	}{
+		{
+			name:          "routes",
+			resourcesPath: "/resources",
+			call: func(c *Client) (err error) {
+				_, err = c.GetRoutes()
+				return
+			},
+			expectedURL: "/resources/routes.json",
+		},
+		{
+			name:          "routes with trailing slash",
+			resourcesPath: "/resources/",
+			call: func(c *Client) (err error) {
+				_, err = c.GetRoutes()
+				return
+			},
+			expectedURL: "/resources/routes.json",
+		},
+		{
+			name:          "stops in Bulgarian",
+			resourcesPath: "/resources",
+			call: func(c *Client) (err error) {
+				_, err = c.GetStopsInLanguage("bg")
+				return
+			},
+			expectedURL: "/resources/stops-bg.json",
+		},
+		{
+			name:          "stops in English",
+			resourcesPath: "/resources",
+			call: func(c *Client) (err error) {
+				_, err = c.GetStopsInLanguage("en")
+				return
+			},
+			expectedURL: "/resources/stops-en.json",
+		},
+		{
+			name:         "arrivals",
+			arrivalsPath: "/api/v1",
+			call: func(c *Client) (err error) {
+				_, err = c.GetTimetableByStopCodeAndLine("0001", "", "")
+				return
+			},
+			expectedURL: "/api/v1/arrivals/0001/",
+		},
+		{
+			name:         "arrivals filtered by line",
+			arrivalsPath: "/api/v1",
+			call: func(c *Client) (err error) {
+				_, err = c.GetTimetableByStopCodeAndLine("0001", VehicleTypeBus, "94")
+				return
+			},
+			expectedURL: "/api/v1/arrivals/0001/?line=94&type=bus",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var requestURL string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestURL = r.URL.RequestURI()
+				w.Header().Set("Content-Type", "application/json")
+				if r.URL.Path == "/api/v1/arrivals/0001/" {
+					w.Write([]byte(`{"code":"0001","name":"Stop","lines":[]}`))
+				} else {
+					w.Write([]byte(`[]`))
+				}
+			}))
+			defer server.Close()
+
+			err := testCase.call(newTestClient(server, testCase.resourcesPath, testCase.arrivalsPath))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if requestURL != testCase.expectedURL {
+				t.Errorf("expected request to %s, got %s", testCase.expectedURL, requestURL)
+			}
+		})
+	}
+}
+
+func TestClientHeaders(t *testing.T) {
+	testCases := []struct {
+		name              string
+		userAgent         string
+		header            http.Header
+		expectedUserAgent string
+		expectedHeader    http.Header
+	}{
+		{
+			name:              "custom User-Agent",
+			userAgent:         "sofiatraffic-test/1.0",
+			expectedUserAgent: "sofiatraffic-test/1.0",
+		},
+		{
+			name:              "User-Agent overrides header",
+			userAgent:         "sofiatraffic-test/1.0",
+			header:            http.Header{"User-Agent": {"other/2.0"}},
+			expectedUserAgent: "sofiatraffic-test/1.0",
+		},
+		{
+			name:              "User-Agent from header",
+			header:            http.Header{"User-Agent": {"other/2.0"}},
+			expectedUserAgent: "other/2.0",
+		},
+		{
+			name:           "custom headers",
+			header:         http.Header{"X-Api-Key": {"secret"}, "Accept-Language": {"bg"}},
+			expectedHeader: http.Header{"X-Api-Key": {"secret"}, "Accept-Language": {"bg"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var requestHeader http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestHeader = r.Header.Clone()
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`[]`))
+			}))
+			defer server.Close()
+
+			client := newTestClient(server, "", "")
+			client.UserAgent = testCase.userAgent
+			if testCase.header != nil {
+				client.Header = testCase.header
+			}
+			_, err := client.GetRoutes()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if testCase.expectedUserAgent != "" && requestHeader.Get("User-Agent") != testCase.expectedUserAgent {
+				t.Errorf("expected User-Agent %q, got %q", testCase.expectedUserAgent, requestHeader.Get("User-Agent"))
+			}
+			for name, values := range testCase.expectedHeader {
+				if got := requestHeader.Values(name); len(got) != len(values) || got[0] != values[0] {
+					t.Errorf("expected header %s to be %q, got %q", name, values, got)
+				}
+			}
+		})
+	}
+}
+
+func TestClientDecodesResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/stops-bg.json":
+			w.Write([]byte(`[{"c":"0001","n":"Спирка"}]`))
+
+		case "/routes.json":
+			w.Write([]byte(`[{"type":"bus","lines":[{"name":"94","routes":[{"codes":["0001","0002"]}]}]}]`))
+
+		case "/arrivals/0001/":
+			w.Write([]byte(`{"code":"0001","name":"Спирка","lines":[{"vehicle_type":"bus","name":"94","arrivals":[{"time":"12:00:00","has_air_conditioning":true}]}],"timestamp_calculated":"11:59:00"}`))
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(server, "", "")
+	stops, err := client.GetStopsInLanguage("bg")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(stops) != 1 || stops[0].Code != "0001" || stops[0].Name != "Спирка" {
+		t.Errorf("unexpected stops: %v", stops)
+	}
+
+	routes, err := client.GetRoutes()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(routes) != 1 || routes[0].VehicleType != VehicleTypeBus || len(routes[0].LineNumberRouteListList) != 1 || len(routes[0].LineNumberRouteListList[0].RouteList[0].StopCodes) != 2 {
+		t.Errorf("unexpected routes: %v", routes)
+	}
+
+	timetable, err := client.GetTimetableByStopCodeAndLine("0001", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if len(timetable.LineVehicleArrivalListList) != 1 || !timetable.LineVehicleArrivalListList[0].VehicleArrivalList[0].HasAirConditioning {
+		t.Errorf("unexpected timetable: %v", timetable)
+	}
+
+	_, err = client.GetTimetableByStopCodeAndLine("9999", "", "")
+	var unknownStopError *UnknownStopError
+	if !errors.As(err, &unknownStopError) || unknownStopError.Code != "9999" {
+		t.Errorf("expected UnknownStopError for stop 9999, got %v", err)
+	}
+}
+
+func TestClientUnsupportedStopLanguage(t *testing.T) {
+	_, err := NewClient(nil).GetStopsInLanguageURL("de")
+	if err == nil {
+		t.Error("expected an error for an unsupported language")
+	}
+}
+
+func TestNewClientCachesResponses(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"type":"bus","lines":[{"name":"94","routes":[{"codes":["0001","0002"]}]}]}]`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	client.ResourcesBaseURL = server.URL
+	for i := 0; i < 2; i++ {
+		_, err := client.GetRoutes()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	if requestCount != 1 {
+		t.Errorf("expected the routes to be served from the default cache, got %d requests", requestCount)
+	}
+}
@@ -0,0 +1,55 @@
+package virtual
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
+)
+
+var (
+	// ErrUnknownStop indicates that an urban transit stop with the requested code does not exist.
+	ErrUnknownStop = errors.New("unknown stop")
+	// ErrUnknownLine indicates that an urban transit line with the requested vehicle type and number does not exist.
+	ErrUnknownLine = errors.New("unknown line")
+	// ErrUpstreamUnavailable indicates that the API could not be reached or failed to handle the request.
+	ErrUpstreamUnavailable = upstream.ErrUnavailable
+	// ErrMalformedResponse indicates that the API returned a response which could not be processed.
+	ErrMalformedResponse = upstream.ErrMalformedResponse
+)
+
+// UnknownStopError represents a reference to an urban transit stop which does not exist.
+type UnknownStopError struct {
+	Code string // numerical code of the stop
+	Err  error  // underlying error (nil if the stop is missing from a local list of stops)
+}
+
+// UnknownLineError represents a reference to an urban transit line which does not exist.
+type UnknownLineError struct {
+	VehicleType, LineNumber string
+}
+
+func (e *UnknownStopError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("unknown stop with code %s: %s", e.Code, e.Err.Error())
+	}
+	return fmt.Sprintf("unknown stop with code %s", e.Code)
+}
+
+func (e *UnknownStopError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrUnknownStop.
+func (e *UnknownStopError) Is(target error) bool {
+	return target == ErrUnknownStop
+}
+
+func (e *UnknownLineError) Error() string {
+	return fmt.Sprintf("unknown line with vehicle type %s and number %s", e.VehicleType, e.LineNumber)
+}
+
+// Is reports whether target is ErrUnknownLine.
+func (e *UnknownLineError) Is(target error) bool {
+	return target == ErrUnknownLine
+}
@@ -1,6 +1,9 @@
 package virtual
 
-import "github.com/rgeorgiev583/sofiatraffic/virtual/l10n"
+import (
+	"github.com/rgeorgiev583/sofiatraffic/schedule"
+	"github.com/rgeorgiev583/sofiatraffic/virtual/l10n"
+)
 
 const (
 	// VehicleTypeBus represents a bus.
@@ -11,9 +14,36 @@ const (
 	VehicleTypeTram = "tram"
 )
 
+// scheduleVehicleTypes maps the vehicle types used by the virtual timetable API to the ones used by the schedule.
+var scheduleVehicleTypes = map[string]string{
+	VehicleTypeBus:        schedule.VehicleTypeBus,
+	VehicleTypeTrolleybus: schedule.VehicleTypeTrolleybus,
+	VehicleTypeTram:       schedule.VehicleTypeTram,
+}
+
+// GetScheduleVehicleType returns the vehicle type used by the schedule which corresponds to the specified vehicleType used by the virtual timetable API (or vehicleType itself if there is no such vehicle type).
+func GetScheduleVehicleType(vehicleType string) string {
+	scheduleVehicleType, ok := scheduleVehicleTypes[vehicleType]
+	if !ok {
+		return vehicleType
+	}
+	return scheduleVehicleType
+}
+
+// GetVehicleTypeFromSchedule returns the vehicle type used by the virtual timetable API which corresponds to the specified scheduleVehicleType used by the schedule. False is returned if the virtual timetable API does not support vehicles of that type (e.g. metro trains).
+func GetVehicleTypeFromSchedule(scheduleVehicleType string) (vehicleType string, ok bool) {
+	for virtualVehicleType, vehicleTypeInSchedule := range scheduleVehicleTypes {
+		if vehicleTypeInSchedule == scheduleVehicleType {
+			return virtualVehicleType, true
+		}
+	}
+	return
+}
+
 // Line represents an urban transit line.
 type Line struct {
-	VehicleType, LineNumber string
+	VehicleType string `json:"vehicle_type"` // type of the vehicle
+	LineNumber  string `json:"line_number"`  // number of the line
 }
 
 func (l *Line) String() string {
@@ -0,0 +1,21 @@
+package virtual
+
+import "time"
+
+// RenderOptions determines how urban transit information is rendered in its display representation.
+type RenderOptions struct {
+	DoShowFacilities                  bool      // whether info about the available facilities in the vehicles should be displayed for VehicleArrival objects
+	DoShowRemainingTimeUntilArrival   bool      // whether the remaining time until arrival should be displayed for VehicleArrival objects instead of the specific time of arrival
+	DoShowGenerationTimeForTimetables bool      // whether the generation time of an urban transit stop timetable should be included in its display representation
+	Now                               time.Time // time relative to which the remaining time until arrival is computed (the current time is used if zero)
+}
+
+// DefaultRenderOptions are the options used by the String methods (i.e. only the essential information is displayed).
+var DefaultRenderOptions = &RenderOptions{}
+
+func (o *RenderOptions) getNow() time.Time {
+	if o.Now.IsZero() {
+		return time.Now()
+	}
+	return o.Now
+}
@@ -1,12 +1,12 @@
 package virtual
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
 )
 
 // Route represents the list of stops where an urban transit line stops when traveling in a specific direction.
@@ -37,8 +37,8 @@ type VehicleTypeLineNumberRouteListListList []*VehicleTypeLineNumberRouteListLis
 
 // NamedRoute represents a route with a name.
 type NamedRoute struct {
-	Name string
-	StopList
+	Name     string `json:"name"` // name of the route
+	StopList `json:"stops"`
 }
 
 // NamedRouteList represents a list of NamedRoute objects.
@@ -47,7 +47,7 @@ type NamedRouteList []*NamedRoute
 // LineNamedRouteList represents the list of named routes for the urban transit line with the specified VehicleType and LineNumber.
 type LineNamedRouteList struct {
 	*Line
-	NamedRouteList
+	NamedRouteList `json:"routes"`
 }
 
 // LineNamedRouteListList represents a list of LineNamedRouteList objects.
@@ -57,9 +57,6 @@ type LineNamedRouteListList []*LineNamedRouteList
 type LineNamedRouteListMap map[Line]NamedRouteList
 
 const (
-	apiRoutesScheme   = "https"
-	apiRoutesHostname = "routes.sofiatraffic.bg"
-	apiRoutesPath     = "/resources"
 	apiRoutesEndpoint = "/routes.json"
 )
 
@@ -73,14 +70,14 @@ func (r *Route) GetName(stops StopMap) (name string, err error) {
 	firstStopCode := r.StopCodes[0]
 	firstStop, ok := stops[firstStopCode]
 	if !ok {
-		err = fmt.Errorf("could not determine name for stop with code %s", firstStopCode)
+		err = fmt.Errorf("could not determine name for route: %w", &UnknownStopError{Code: firstStopCode})
 		return
 	}
 
 	lastStopCode := r.StopCodes[len(r.StopCodes)-1]
 	lastStop, ok := stops[lastStopCode]
 	if !ok {
-		err = fmt.Errorf("could not determine name for stop with code %s", lastStopCode)
+		err = fmt.Errorf("could not determine name for route: %w", &UnknownStopError{Code: lastStopCode})
 		return
 	}
 
@@ -88,31 +85,44 @@ func (r *Route) GetName(stops StopMap) (name string, err error) {
 	return
 }
 
-// GetRoutes fetches and returns the VehicleTypeLineNumberRouteListListList of all urban transit routes.
-func GetRoutes() (routes VehicleTypeLineNumberRouteListListList, err error) {
-	apiRoutesEndpointURL := &url.URL{
-		Scheme: apiRoutesScheme,
-		Host:   apiRoutesHostname,
-		Path:   apiRoutesPath + apiRoutesEndpoint,
-	}
-	response, err := http.Get(apiRoutesEndpointURL.String())
+// GetRoutesURL returns the URL of the list of all urban transit routes.
+func (c *Client) GetRoutesURL() (endpointURL string, err error) {
+	parsedEndpointURL, err := c.getEndpointURL(c.ResourcesBaseURL, apiRoutesEndpoint, nil)
 	if err != nil {
-		err = fmt.Errorf("could not initiate HTTP GET request to the API endpoint: %s", err.Error())
 		return
 	}
-	defer response.Body.Close()
 
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&routes)
+	endpointURL = parsedEndpointURL.String()
+	return
+}
+
+// GetRoutesContext fetches and returns the VehicleTypeLineNumberRouteListListList of all urban transit routes. The request is canceled when ctx is done.
+func (c *Client) GetRoutesContext(ctx context.Context) (routes VehicleTypeLineNumberRouteListListList, err error) {
+	apiRoutesEndpointURL, err := c.getEndpointURL(c.ResourcesBaseURL, apiRoutesEndpoint, nil)
 	if err != nil {
-		err = fmt.Errorf("could not decode JSON data returned by the API endpoint: %s", err.Error())
 		return
 	}
 
+	err = c.getJSON(ctx, apiRoutesEndpointURL, upstream.ResourceKindRoutes, &routes)
 	return
 }
 
-// GetNamedRoutesByLine returns the list of named routes for the urban transit line with the specified vehicleType and lineNumber wrapped in a list (or, alternatively, for all lines matching the other criterion if one of them is empty; or for all lines if both are empty). The stops argument is used to determine the names of the stops.
+// GetRoutes fetches and returns the VehicleTypeLineNumberRouteListListList of all urban transit routes.
+func (c *Client) GetRoutes() (routes VehicleTypeLineNumberRouteListListList, err error) {
+	return c.GetRoutesContext(context.Background())
+}
+
+// GetRoutes fetches and returns the VehicleTypeLineNumberRouteListListList of all urban transit routes using the DefaultClient.
+func GetRoutes() (routes VehicleTypeLineNumberRouteListListList, err error) {
+	return DefaultClient.GetRoutes()
+}
+
+// GetRoutesContext is the context-aware version of GetRoutes.
+func GetRoutesContext(ctx context.Context) (routes VehicleTypeLineNumberRouteListListList, err error) {
+	return DefaultClient.GetRoutesContext(ctx)
+}
+
+// GetNamedRoutesByLine returns the list of named routes for the urban transit line with the specified vehicleType and lineNumber wrapped in a list (or, alternatively, for all lines matching the other criterion if one of them is empty; or for all lines if both are empty). The stops argument is used to determine the names of the stops. An UnknownLineError is returned if lineNumber is non-empty and no line matches the criteria.
 func (rl VehicleTypeLineNumberRouteListListList) GetNamedRoutesByLine(vehicleType string, lineNumber string, stops StopMap) (namedRouteListList LineNamedRouteListList, err error) {
 	namedRouteListList = LineNamedRouteListList{}
 	for _, vehicleTypeRoutes := range rl {
@@ -141,6 +151,9 @@ func (rl VehicleTypeLineNumberRouteListListList) GetNamedRoutesByLine(vehicleTyp
 			}
 		}
 	}
+	if lineNumber != "" && len(namedRouteListList) == 0 {
+		err = &UnknownLineError{VehicleType: vehicleType, LineNumber: lineNumber}
+	}
 	return
 }
 
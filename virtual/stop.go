@@ -1,14 +1,15 @@
 package virtual
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/rgeorgiev583/sofiatraffic/i18n"
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
 )
 
 // Stop represents an urban transit stop.
@@ -23,19 +24,18 @@ type StopList []*Stop
 // StopMap represents a map from the code of each urban transit stop to its corresponding Stop object.
 type StopMap map[string]*Stop
 
+// stopJSON is the JSON representation of a Stop produced by this package, which uses more descriptive keys than the API does.
+type stopJSON struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
 const (
-	apiStopsScheme            = "https"
-	apiStopsHostname          = "routes.sofiatraffic.bg"
-	apiStopsPath              = "/resources"
 	apiStopsEndpointBulgarian = "/stops-bg.json"
 	apiStopsEndpointEnglish   = "/stops-en.json"
 )
 
-// DoTranslateStopNames determines whether stop names should be translated from Bulgarian to the local language.
-var DoTranslateStopNames bool
-
-// GetStopsInLanguage fetches and returns the list of all urban transit stops with name in the specified language.
-func GetStopsInLanguage(language string) (stops StopList, err error) {
+func (c *Client) getStopsEndpointURL(language string) (endpointURL *url.URL, err error) {
 	var apiStopsEndpoint string
 	switch language {
 	case i18n.LanguageCodeBulgarian:
@@ -43,38 +43,75 @@ func GetStopsInLanguage(language string) (stops StopList, err error) {
 
 	case i18n.LanguageCodeEnglish:
 		apiStopsEndpoint = apiStopsEndpointEnglish
+
+	default:
+		err = fmt.Errorf("unsupported language for stop names: %s", language)
+		return
 	}
-	apiStopsEndpointURL := &url.URL{
-		Scheme: apiStopsScheme,
-		Host:   apiStopsHostname,
-		Path:   apiStopsPath + apiStopsEndpoint,
-	}
-	response, err := http.Get(apiStopsEndpointURL.String())
+	return c.getEndpointURL(c.ResourcesBaseURL, apiStopsEndpoint, nil)
+}
+
+// GetStopsInLanguageURL returns the URL of the list of all urban transit stops with name in the specified language.
+func (c *Client) GetStopsInLanguageURL(language string) (endpointURL string, err error) {
+	parsedEndpointURL, err := c.getStopsEndpointURL(language)
 	if err != nil {
-		err = fmt.Errorf("could not initiate HTTP GET request to the API endpoint: %s", err.Error())
 		return
 	}
-	defer response.Body.Close()
 
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&stops)
+	endpointURL = parsedEndpointURL.String()
+	return
+}
+
+// GetStopsInLanguageContext fetches and returns the list of all urban transit stops with name in the specified language. The request is canceled when ctx is done.
+func (c *Client) GetStopsInLanguageContext(ctx context.Context, language string) (stops StopList, err error) {
+	apiStopsEndpointURL, err := c.getStopsEndpointURL(language)
 	if err != nil {
-		err = fmt.Errorf("could not decode JSON data returned by the API endpoint: %s", err.Error())
 		return
 	}
 
+	err = c.getJSON(ctx, apiStopsEndpointURL, upstream.ResourceKindStops, &stops)
 	return
 }
 
-// GetStops fetches and returns the list of all urban transit stops.
-func GetStops() (stops StopList, err error) {
+// GetStopsInLanguage fetches and returns the list of all urban transit stops with name in the specified language.
+func (c *Client) GetStopsInLanguage(language string) (stops StopList, err error) {
+	return c.GetStopsInLanguageContext(context.Background(), language)
+}
+
+// GetStopsContext fetches and returns the list of all urban transit stops (with names in the local language if DoTranslateStopNames is set for the client and in Bulgarian otherwise). The request is canceled when ctx is done.
+func (c *Client) GetStopsContext(ctx context.Context) (stops StopList, err error) {
 	var language string
-	if DoTranslateStopNames {
+	if c.DoTranslateStopNames {
 		language = i18n.Language
 	} else {
 		language = i18n.LanguageCodeBulgarian
 	}
-	return GetStopsInLanguage(language)
+	return c.GetStopsInLanguageContext(ctx, language)
+}
+
+// GetStops fetches and returns the list of all urban transit stops.
+func (c *Client) GetStops() (stops StopList, err error) {
+	return c.GetStopsContext(context.Background())
+}
+
+// GetStopsInLanguage fetches and returns the list of all urban transit stops with name in the specified language using the DefaultClient.
+func GetStopsInLanguage(language string) (stops StopList, err error) {
+	return DefaultClient.GetStopsInLanguage(language)
+}
+
+// GetStopsInLanguageContext is the context-aware version of GetStopsInLanguage.
+func GetStopsInLanguageContext(ctx context.Context, language string) (stops StopList, err error) {
+	return DefaultClient.GetStopsInLanguageContext(ctx, language)
+}
+
+// GetStops fetches and returns the list of all urban transit stops using the DefaultClient.
+func GetStops() (stops StopList, err error) {
+	return DefaultClient.GetStops()
+}
+
+// GetStopsContext is the context-aware version of GetStops.
+func GetStopsContext(ctx context.Context) (stops StopList, err error) {
+	return DefaultClient.GetStopsContext(ctx)
 }
 
 // GetStopMap returns a StopMap object containing all stops in the StopList.
@@ -92,7 +129,7 @@ func (sm StopMap) GetStopsByCodes(codes []string) (stops StopList, err error) {
 	for i, code := range codes {
 		stop, ok := sm[code]
 		if !ok {
-			err = fmt.Errorf("could not find stop code %s in stop map", code)
+			err = &UnknownStopError{Code: code}
 			return stops, err
 		}
 
@@ -105,6 +142,33 @@ func (s *Stop) String() string {
 	return s.Name + " (" + s.Code + ")"
 }
 
+// MarshalJSON encodes the stop as a JSON object with descriptive keys.
+func (s *Stop) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&stopJSON{Code: s.Code, Name: s.Name})
+}
+
+// UnmarshalJSON decodes the stop from either its representation in the API or the one produced by MarshalJSON.
+func (s *Stop) UnmarshalJSON(data []byte) (err error) {
+	var value struct {
+		APICode string `json:"c"`
+		APIName string `json:"n"`
+		stopJSON
+	}
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return
+	}
+
+	s.Code, s.Name = value.Code, value.Name
+	if s.Code == "" {
+		s.Code = value.APICode
+	}
+	if s.Name == "" {
+		s.Name = value.APIName
+	}
+	return
+}
+
 func (sl StopList) String() string {
 	var builder strings.Builder
 	for i, stop := range sl {
@@ -1,15 +1,15 @@
 package virtual
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
 	"log"
-	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 	"unicode/utf8"
 
+	"github.com/rgeorgiev583/sofiatraffic/upstream"
 	"github.com/rgeorgiev583/sofiatraffic/virtual/l10n"
 )
 
@@ -33,23 +33,30 @@ type StopTimetableFetchResult struct {
 // StopTimetableChannel is to be used for asynchronous processing of fetched urban transit stop timetables.
 type StopTimetableChannel <-chan *StopTimetableFetchResult
 
+// AsyncOptions determines how urban transit stop timetables are fetched asynchronously.
+type AsyncOptions struct {
+	Concurrency   int  // maximum number of concurrent requests (DefaultConcurrency is used if not positive)
+	IsOrdered     bool // whether results are delivered in the order of the stops in the list (instead of as soon as they are fetched), holding back at most Concurrency results
+	DoStopOnError bool // whether fetching should stop after the first failed request (whose result is still delivered)
+}
+
+type indexedStopTimetableFetchResult struct {
+	*StopTimetableFetchResult
+	index int
+}
+
 const (
-	apiArrivalsScheme   = "https"
-	apiArrivalsHostname = "api-arrivals.sofiatraffic.bg"
-	apiArrivalsPath     = "/api/v1"
 	apiArrivalsEndpoint = "/arrivals"
+
+	// DefaultConcurrency is the default maximum number of concurrent requests issued when fetching timetables asynchronously.
+	DefaultConcurrency = 8
 )
 
-// DoShowGenerationTimeForTimetables determines whether the generation time of an urban transit stop timetable should be included in its display representation.
-var DoShowGenerationTimeForTimetables bool
+// DefaultAsyncOptions are the options used for fetching timetables asynchronously if no options are specified. Results are delivered as soon as they are fetched.
+var DefaultAsyncOptions = &AsyncOptions{Concurrency: DefaultConcurrency}
 
-// GetTimetableByStopCodeAndLine fetches and returns the timetable for the urban transit stop with the specified code. If the vehicleType argument is non-empty, only arrivals of vehicles of the specified type will be listed. If the lineNumber argument is non-empty, only arrivals of vehicles from the line with the specified code will be listed.
-func GetTimetableByStopCodeAndLine(stopCode string, vehicleType string, lineNumber string) (stopTimetable *StopTimetable, err error) {
-	apiArrivalsEndpointURL := &url.URL{
-		Scheme: apiArrivalsScheme,
-		Host:   apiArrivalsHostname,
-		Path:   apiArrivalsPath + apiArrivalsEndpoint + "/" + stopCode + "/",
-	}
+// GetTimetableByStopCodeAndLineContext fetches and returns the timetable for the urban transit stop with the specified code. If the vehicleType argument is non-empty, only arrivals of vehicles of the specified type will be listed. If the lineNumber argument is non-empty, only arrivals of vehicles from the line with the specified code will be listed. The request is canceled when ctx is done.
+func (c *Client) GetTimetableByStopCodeAndLineContext(ctx context.Context, stopCode string, vehicleType string, lineNumber string) (stopTimetable *StopTimetable, err error) {
 	query := url.Values{}
 	if lineNumber != "" {
 		query.Set("line", lineNumber)
@@ -57,39 +64,38 @@ func GetTimetableByStopCodeAndLine(stopCode string, vehicleType string, lineNumb
 	if vehicleType != "" {
 		query.Set("type", vehicleType)
 	}
-	apiArrivalsEndpointURL.RawQuery = query.Encode()
-	response, err := http.Get(apiArrivalsEndpointURL.String())
+	apiArrivalsEndpointURL, err := c.getEndpointURL(c.ArrivalsBaseURL, apiArrivalsEndpoint+"/"+stopCode+"/", query)
 	if err != nil {
-		err = fmt.Errorf("could not initiate HTTP GET request to the API endpoint: %s", err.Error())
 		return
 	}
-	defer response.Body.Close()
 
-	decoder := json.NewDecoder(response.Body)
 	stopTimetable = &StopTimetable{}
-	err = decoder.Decode(stopTimetable)
-	if err != nil {
-		err = fmt.Errorf("could not decode JSON data returned by the API endpoint: %s", err.Error())
-		return
+	err = c.getJSON(ctx, apiArrivalsEndpointURL, upstream.ResourceKindArrivals, stopTimetable)
+	if errors.Is(err, upstream.ErrNotFound) {
+		err = &UnknownStopError{Code: stopCode, Err: err}
 	}
-
 	return
 }
 
-// GetTimetablesByStopNameAndLine fetches and returns a list containing all timetables for urban transit stops with the specified name. The vehicleType and lineNumber arguments behave as in GetTimetableByStopCodeAndLine. The isExactMatch argument determines whether the specified stopName should be matched exactly or as a substring.
-func (sl StopList) GetTimetablesByStopNameAndLine(stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableList, err error) {
+// GetTimetableByStopCodeAndLine fetches and returns the timetable for the urban transit stop with the specified code. The vehicleType and lineNumber arguments behave as in GetTimetableByStopCodeAndLineContext.
+func (c *Client) GetTimetableByStopCodeAndLine(stopCode string, vehicleType string, lineNumber string) (stopTimetable *StopTimetable, err error) {
+	return c.GetTimetableByStopCodeAndLineContext(context.Background(), stopCode, vehicleType, lineNumber)
+}
+
+// GetTimetablesByStopNameAndLineContext fetches and returns a list containing all timetables for urban transit stops from the stops list with the specified name. The vehicleType and lineNumber arguments behave as in GetTimetableByStopCodeAndLineContext. The isExactMatch argument determines whether the specified stopName should be matched exactly or as a substring. Fetching stops at the first failed request or when ctx is done.
+func (c *Client) GetTimetablesByStopNameAndLineContext(ctx context.Context, stops StopList, stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableList, err error) {
 	if !isExactMatch {
 		stopName = strings.ToUpper(stopName)
 	}
 	timetables = StopTimetableList{}
-	for _, stop := range sl {
+	for _, stop := range stops {
 		if isExactMatch && stop.Name == stopName || !isExactMatch && strings.Contains(stop.Name, stopName) {
-			timetable, err := GetTimetableByStopCodeAndLine(stop.Code, vehicleType, lineNumber)
+			timetable, err := c.GetTimetableByStopCodeAndLineContext(ctx, stop.Code, vehicleType, lineNumber)
 			if err != nil {
 				return timetables, err
 			}
 
-			if DoTranslateStopNames {
+			if c.DoTranslateStopNames {
 				timetable.StopName = stop.Name
 			}
 			timetables = append(timetables, timetable)
@@ -98,61 +104,201 @@ func (sl StopList) GetTimetablesByStopNameAndLine(stopName string, vehicleType s
 	return
 }
 
-// GetTimetablesByStopNameAndLineAsync is the asynchronous version of GetTimetablesByStopNameAndLine.
-func (sl StopList) GetTimetablesByStopNameAndLineAsync(stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableChannel) {
+// GetTimetablesByStopNameAndLine fetches and returns a list containing all timetables for urban transit stops from the stops list with the specified name. The arguments behave as in GetTimetablesByStopNameAndLineContext.
+func (c *Client) GetTimetablesByStopNameAndLine(stops StopList, stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableList, err error) {
+	return c.GetTimetablesByStopNameAndLineContext(context.Background(), stops, stopName, vehicleType, lineNumber, isExactMatch)
+}
+
+// GetTimetablesByStopNameAndLineAsyncWithOptions is the asynchronous version of GetTimetablesByStopNameAndLineContext. The timetables are fetched by a bounded pool of goroutines as determined by the specified options (or by DefaultAsyncOptions if options is nil). When ctx is done (or, if options.DoStopOnError is set, after the first failed request), the in-flight requests are canceled, no further requests are initiated and the pending results are discarded, after which the returned channel is closed.
+func (c *Client) GetTimetablesByStopNameAndLineAsyncWithOptions(ctx context.Context, stops StopList, stopName string, vehicleType string, lineNumber string, isExactMatch bool, options *AsyncOptions) (timetables StopTimetableChannel) {
+	if options == nil {
+		options = DefaultAsyncOptions
+	}
 	if !isExactMatch {
 		stopName = strings.ToUpper(stopName)
 	}
-	fetchResults := make(chan *StopTimetableFetchResult)
-	timetables = fetchResults
-	var timetableFetchers sync.WaitGroup
-	for _, stop := range sl {
+	matchingStops := StopList{}
+	for _, stop := range stops {
 		if isExactMatch && stop.Name == stopName || !isExactMatch && strings.Contains(stop.Name, stopName) {
-			timetableFetchers.Add(1)
-			go func(stop *Stop) {
-				timetable, err := GetTimetableByStopCodeAndLine(stop.Code, vehicleType, lineNumber)
-				if DoTranslateStopNames && timetable != nil {
-					timetable.StopName = stop.Name
+			matchingStops = append(matchingStops, stop)
+		}
+	}
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	if concurrency > len(matchingStops) {
+		concurrency = len(matchingStops)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stopIndices := make(chan int)
+	orderedWindow := make(chan struct{}, concurrency)
+	go func() {
+		defer close(stopIndices)
+		for i := range matchingStops {
+			if options.IsOrdered {
+				select {
+				case orderedWindow <- struct{}{}:
+				case <-ctx.Done():
+					return
 				}
-				fetchResults <- &StopTimetableFetchResult{StopTimetable: timetable, Err: err}
-				timetableFetchers.Done()
-			}(stop)
+			}
+
+			select {
+			case stopIndices <- i:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+
+	indexedFetchResults := make(chan *indexedStopTimetableFetchResult)
+	var timetableFetchers sync.WaitGroup
+	timetableFetchers.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer timetableFetchers.Done()
+			for stopIndex := range stopIndices {
+				stop := matchingStops[stopIndex]
+				timetable, err := c.GetTimetableByStopCodeAndLineContext(ctx, stop.Code, vehicleType, lineNumber)
+				if c.DoTranslateStopNames && timetable != nil {
+					timetable.StopName = stop.Name
+				}
+				select {
+				case indexedFetchResults <- &indexedStopTimetableFetchResult{index: stopIndex, StopTimetableFetchResult: &StopTimetableFetchResult{StopTimetable: timetable, Err: err}}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
 	}
 	go func() {
 		timetableFetchers.Wait()
-		close(fetchResults)
+		close(indexedFetchResults)
+	}()
+
+	fetchResults := make(chan *StopTimetableFetchResult)
+	timetables = fetchResults
+	go func() {
+		defer close(fetchResults)
+		defer cancel()
+		pendingFetchResults := map[int]*StopTimetableFetchResult{}
+		nextIndex := 0
+		deliver := func(fetchResult *StopTimetableFetchResult) bool {
+			select {
+			case fetchResults <- fetchResult:
+			case <-ctx.Done():
+				return false
+			}
+			return fetchResult.Err == nil || !options.DoStopOnError
+		}
+		for indexedFetchResult := range indexedFetchResults {
+			if !options.IsOrdered {
+				if !deliver(indexedFetchResult.StopTimetableFetchResult) {
+					return
+				}
+
+				continue
+			}
+
+			pendingFetchResults[indexedFetchResult.index] = indexedFetchResult.StopTimetableFetchResult
+			for fetchResult, ok := pendingFetchResults[nextIndex]; ok; fetchResult, ok = pendingFetchResults[nextIndex] {
+				delete(pendingFetchResults, nextIndex)
+				nextIndex++
+				if !deliver(fetchResult) {
+					return
+				}
+
+				<-orderedWindow
+			}
+		}
 	}()
 	return
 }
 
-func (t *StopTimetable) String() string {
+// GetTimetablesByStopNameAndLineAsyncContext is the asynchronous version of GetTimetablesByStopNameAndLineContext. It behaves as GetTimetablesByStopNameAndLineAsyncWithOptions called with DefaultAsyncOptions.
+func (c *Client) GetTimetablesByStopNameAndLineAsyncContext(ctx context.Context, stops StopList, stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableChannel) {
+	return c.GetTimetablesByStopNameAndLineAsyncWithOptions(ctx, stops, stopName, vehicleType, lineNumber, isExactMatch, DefaultAsyncOptions)
+}
+
+// GetTimetablesByStopNameAndLineAsync is the asynchronous version of GetTimetablesByStopNameAndLine. The returned channel has to be drained, as otherwise the goroutines fetching the timetables will block forever.
+func (c *Client) GetTimetablesByStopNameAndLineAsync(stops StopList, stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableChannel) {
+	return c.GetTimetablesByStopNameAndLineAsyncContext(context.Background(), stops, stopName, vehicleType, lineNumber, isExactMatch)
+}
+
+// GetTimetableByStopCodeAndLine fetches and returns the timetable for the urban transit stop with the specified code using the DefaultClient. The vehicleType and lineNumber arguments behave as in Client.GetTimetableByStopCodeAndLineContext.
+func GetTimetableByStopCodeAndLine(stopCode string, vehicleType string, lineNumber string) (stopTimetable *StopTimetable, err error) {
+	return DefaultClient.GetTimetableByStopCodeAndLine(stopCode, vehicleType, lineNumber)
+}
+
+// GetTimetableByStopCodeAndLineContext is the context-aware version of GetTimetableByStopCodeAndLine.
+func GetTimetableByStopCodeAndLineContext(ctx context.Context, stopCode string, vehicleType string, lineNumber string) (stopTimetable *StopTimetable, err error) {
+	return DefaultClient.GetTimetableByStopCodeAndLineContext(ctx, stopCode, vehicleType, lineNumber)
+}
+
+// GetTimetablesByStopNameAndLine fetches and returns a list containing all timetables for urban transit stops with the specified name using the DefaultClient. The arguments behave as in Client.GetTimetablesByStopNameAndLineContext.
+func (sl StopList) GetTimetablesByStopNameAndLine(stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableList, err error) {
+	return DefaultClient.GetTimetablesByStopNameAndLine(sl, stopName, vehicleType, lineNumber, isExactMatch)
+}
+
+// GetTimetablesByStopNameAndLineContext is the context-aware version of GetTimetablesByStopNameAndLine.
+func (sl StopList) GetTimetablesByStopNameAndLineContext(ctx context.Context, stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableList, err error) {
+	return DefaultClient.GetTimetablesByStopNameAndLineContext(ctx, sl, stopName, vehicleType, lineNumber, isExactMatch)
+}
+
+// GetTimetablesByStopNameAndLineAsync is the asynchronous version of GetTimetablesByStopNameAndLine.
+func (sl StopList) GetTimetablesByStopNameAndLineAsync(stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableChannel) {
+	return DefaultClient.GetTimetablesByStopNameAndLineAsync(sl, stopName, vehicleType, lineNumber, isExactMatch)
+}
+
+// GetTimetablesByStopNameAndLineAsyncContext is the context-aware version of GetTimetablesByStopNameAndLineAsync.
+func (sl StopList) GetTimetablesByStopNameAndLineAsyncContext(ctx context.Context, stopName string, vehicleType string, lineNumber string, isExactMatch bool) (timetables StopTimetableChannel) {
+	return DefaultClient.GetTimetablesByStopNameAndLineAsyncContext(ctx, sl, stopName, vehicleType, lineNumber, isExactMatch)
+}
+
+// GetTimetablesByStopNameAndLineAsyncWithOptions is the version of GetTimetablesByStopNameAndLineAsyncContext which fetches the timetables as determined by the specified options.
+func (sl StopList) GetTimetablesByStopNameAndLineAsyncWithOptions(ctx context.Context, stopName string, vehicleType string, lineNumber string, isExactMatch bool, options *AsyncOptions) (timetables StopTimetableChannel) {
+	return DefaultClient.GetTimetablesByStopNameAndLineAsyncWithOptions(ctx, sl, stopName, vehicleType, lineNumber, isExactMatch, options)
+}
+
+// Render returns the display representation of the timetable as determined by the specified options.
+func (t *StopTimetable) Render(options *RenderOptions) string {
 	var builder strings.Builder
 	stopTitle := t.StopName + " (" + t.StopCode + ")"
 	builder.WriteString(stopTitle + "\n" + strings.Repeat("=", utf8.RuneCountInString(stopTitle)) + "\n")
-	if DoShowGenerationTimeForTimetables {
+	if options.DoShowGenerationTimeForTimetables {
 		builder.WriteString("(" + l10n.Translator[l10n.GenerationTime] + ": " + t.GenerationTime + ")\n")
 	}
 	for _, line := range t.LineVehicleArrivalListList {
-		builder.WriteString(line.String() + "\n")
+		builder.WriteString(line.Render(options) + "\n")
 	}
 	return builder.String()
 }
 
-func (tl StopTimetableList) String() string {
+func (t *StopTimetable) String() string {
+	return t.Render(DefaultRenderOptions)
+}
+
+// Render returns the display representation of the list of timetables as determined by the specified options. Timetables without arrivals are omitted.
+func (tl StopTimetableList) Render(options *RenderOptions) string {
 	var builder strings.Builder
 	for _, timetable := range tl {
 		if len(timetable.LineVehicleArrivalListList) == 0 {
 			continue
 		}
 
-		builder.WriteString(timetable.String() + "\n")
+		builder.WriteString(timetable.Render(options) + "\n")
 	}
 	return builder.String()
 }
 
-func (tc StopTimetableChannel) String() string {
-	var builder strings.Builder
+func (tl StopTimetableList) String() string {
+	return tl.Render(DefaultRenderOptions)
+}
+
+// ForEach consumes the channel and calls f for each received timetable in the order of their arrival. Timetables without arrivals are omitted and fetch errors are logged.
+func (tc StopTimetableChannel) ForEach(f func(timetable *StopTimetable)) {
 	for fetchResult := range tc {
 		if fetchResult.Err != nil {
 			log.Println(fetchResult.Err.Error())
@@ -163,7 +309,28 @@ func (tc StopTimetableChannel) String() string {
 			continue
 		}
 
-		builder.WriteString(fetchResult.StopTimetable.String() + "\n")
+		f(fetchResult.StopTimetable)
 	}
+}
+
+// Collect consumes the channel and returns the received timetables in the order of their arrival. Timetables without arrivals are omitted and fetch errors are logged.
+func (tc StopTimetableChannel) Collect() (timetables StopTimetableList) {
+	timetables = StopTimetableList{}
+	tc.ForEach(func(timetable *StopTimetable) {
+		timetables = append(timetables, timetable)
+	})
+	return
+}
+
+// Render consumes the channel and returns the display representation of the received timetables as determined by the specified options. Timetables without arrivals are omitted and fetch errors are logged.
+func (tc StopTimetableChannel) Render(options *RenderOptions) string {
+	var builder strings.Builder
+	tc.ForEach(func(timetable *StopTimetable) {
+		builder.WriteString(timetable.Render(options) + "\n")
+	})
 	return builder.String()
 }
+
+func (tc StopTimetableChannel) String() string {
+	return tc.Render(DefaultRenderOptions)
+}
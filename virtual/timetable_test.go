@@ -0,0 +1,220 @@
+package virtual
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// timetableServer serves the arrivals at stops, responding with 500 Internal Server Error for the stops listed in failingStopCodes and blocking the responses for the stops listed in blockedStopCodes until unblock is called.
+type timetableServer struct {
+	*httptest.Server
+	failingStopCodes map[string]bool
+	blockedStopCodes map[string]bool
+
+	mutex            sync.Mutex
+	requestedCodes   []string
+	inFlight         int
+	maxInFlight      int
+	unblocked        chan struct{}
+	unblockOnce      sync.Once
+	blockedRequested chan struct{}
+}
+
+func newTimetableServer(t *testing.T, failingStopCodes map[string]bool, blockedStopCodes map[string]bool) *timetableServer {
+	server := &timetableServer{
+		failingStopCodes: failingStopCodes,
+		blockedStopCodes: blockedStopCodes,
+		unblocked:        make(chan struct{}),
+		blockedRequested: make(chan struct{}, 100),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	t.Cleanup(func() {
+		server.unblock()
+		server.Close()
+	})
+	return server
+}
+
+func (s *timetableServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	stopCode := strings.Trim(strings.TrimPrefix(r.URL.Path, apiArrivalsEndpoint), "/")
+	s.mutex.Lock()
+	s.requestedCodes = append(s.requestedCodes, stopCode)
+	s.inFlight++
+	if s.inFlight > s.maxInFlight {
+		s.maxInFlight = s.inFlight
+	}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.inFlight--
+		s.mutex.Unlock()
+	}()
+
+	if s.blockedStopCodes[stopCode] {
+		s.blockedRequested <- struct{}{}
+		select {
+		case <-s.unblocked:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if s.failingStopCodes[stopCode] {
+		http.Error(w, "failure", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"code":"` + stopCode + `","name":"Stop","lines":[{"vehicle_type":"bus","name":"94","arrivals":[{"time":"12:00:00"}]}]}`))
+}
+
+func (s *timetableServer) unblock() {
+	s.unblockOnce.Do(func() { close(s.unblocked) })
+}
+
+func (s *timetableServer) getMaxInFlight() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.maxInFlight
+}
+
+func (s *timetableServer) getRequestedCodes() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string{}, s.requestedCodes...)
+}
+
+func newTestStops(count int) (stops StopList) {
+	for i := 0; i < count; i++ {
+		code := string(rune('A' + i))
+		stops = append(stops, &Stop{Code: code, Name: "STOP " + code})
+	}
+	return
+}
+
+func getStopCodes(timetables StopTimetableList) (codes []string) {
+	for _, timetable := range timetables {
+		codes = append(codes, timetable.StopCode)
+	}
+	return
+}
+
+func TestGetTimetablesAsyncOrdered(t *testing.T) {
+	server := newTimetableServer(t, nil, nil)
+	client := newTestClient(server.Server, "", "")
+	timetables := client.GetTimetablesByStopNameAndLineAsyncWithOptions(context.Background(), newTestStops(10), "stop", "", "", false, &AsyncOptions{Concurrency: 3, IsOrdered: true}).Collect()
+	codes := strings.Join(getStopCodes(timetables), "")
+	if codes != "ABCDEFGHIJ" {
+		t.Errorf("expected the timetables in the order of the stops, got %s", codes)
+	}
+	if server.getMaxInFlight() > 3 {
+		t.Errorf("expected at most 3 concurrent requests, got %d", server.getMaxInFlight())
+	}
+}
+
+func TestGetTimetablesAsyncUnordered(t *testing.T) {
+	server := newTimetableServer(t, nil, nil)
+	client := newTestClient(server.Server, "", "")
+	timetables := client.GetTimetablesByStopNameAndLineAsyncWithOptions(context.Background(), newTestStops(10), "STOP", "", "", true, &AsyncOptions{Concurrency: 4}).Collect()
+	if len(timetables) != 0 {
+		t.Errorf("expected no exact matches, got %d timetables", len(timetables))
+	}
+
+	timetables = client.GetTimetablesByStopNameAndLineAsyncWithOptions(context.Background(), newTestStops(10), "STOP", "", "", false, &AsyncOptions{Concurrency: 4}).Collect()
+	if len(timetables) != 10 {
+		t.Errorf("expected 10 timetables, got %d", len(timetables))
+	}
+	if server.getMaxInFlight() > 4 {
+		t.Errorf("expected at most 4 concurrent requests, got %d", server.getMaxInFlight())
+	}
+}
+
+func TestGetTimetablesAsyncOrderedWindow(t *testing.T) {
+	server := newTimetableServer(t, nil, map[string]bool{"A": true})
+	client := newTestClient(server.Server, "", "")
+	timetables := client.GetTimetablesByStopNameAndLineAsyncWithOptions(context.Background(), newTestStops(10), "STOP", "", "", false, &AsyncOptions{Concurrency: 3, IsOrdered: true})
+
+	<-server.blockedRequested
+	time.Sleep(50 * time.Millisecond)
+	requestedCodes := server.getRequestedCodes()
+	if len(requestedCodes) > 3 {
+		t.Errorf("expected at most 3 stops to be requested while the first one is blocked, got %v", requestedCodes)
+	}
+
+	server.unblock()
+	codes := strings.Join(getStopCodes(timetables.Collect()), "")
+	if codes != "ABCDEFGHIJ" {
+		t.Errorf("expected the timetables in the order of the stops, got %s", codes)
+	}
+}
+
+func TestGetTimetablesAsyncStopOnError(t *testing.T) {
+	server := newTimetableServer(t, map[string]bool{"B": true}, nil)
+	client := newTestClient(server.Server, "", "")
+	var results []*StopTimetableFetchResult
+	for result := range client.GetTimetablesByStopNameAndLineAsyncWithOptions(context.Background(), newTestStops(10), "STOP", "", "", false, &AsyncOptions{Concurrency: 1, IsOrdered: true, DoStopOnError: true}) {
+		results = append(results, result)
+	}
+	if len(results) != 2 || results[0].Err != nil || results[1].Err == nil {
+		t.Fatalf("expected a timetable followed by an error, got %v", results)
+	}
+	if len(server.getRequestedCodes()) > 3 {
+		t.Errorf("expected fetching to stop after the error, got requests for %v", server.getRequestedCodes())
+	}
+}
+
+func TestGetTimetablesAsyncCancel(t *testing.T) {
+	server := newTimetableServer(t, nil, map[string]bool{"A": true})
+	client := newTestClient(server.Server, "", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	timetables := client.GetTimetablesByStopNameAndLineAsyncWithOptions(ctx, newTestStops(10), "STOP", "", "", false, &AsyncOptions{Concurrency: 2, IsOrdered: true})
+
+	<-server.blockedRequested
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		timetables.Collect()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the channel to be closed after the context is canceled")
+	}
+}
+
+func TestStopTimetableChannelForEach(t *testing.T) {
+	channel := make(chan *StopTimetableFetchResult, 4)
+	arrivals := LineVehicleArrivalListList{{VehicleType: VehicleTypeBus, LineNumber: "94", VehicleArrivalList: VehicleArrivalList{{Time: "12:00:00"}}}}
+	channel <- &StopTimetableFetchResult{StopTimetable: &StopTimetable{StopCode: "A", LineVehicleArrivalListList: arrivals}}
+	channel <- &StopTimetableFetchResult{StopTimetable: &StopTimetable{StopCode: "B"}}
+	channel <- &StopTimetableFetchResult{Err: &UnknownStopError{Code: "C"}}
+	channel <- &StopTimetableFetchResult{StopTimetable: &StopTimetable{StopCode: "D", LineVehicleArrivalListList: arrivals}}
+	close(channel)
+
+	var codes []string
+	StopTimetableChannel(channel).ForEach(func(timetable *StopTimetable) {
+		codes = append(codes, timetable.StopCode)
+	})
+	if strings.Join(codes, "") != "AD" {
+		t.Errorf("expected the timetables with arrivals to be passed in order, got %v", codes)
+	}
+}
+
+func TestStopTimetableRenderWithoutArrivals(t *testing.T) {
+	timetable := &StopTimetable{StopCode: "0001", StopName: "СПИРКА"}
+	expectedHeader := "СПИРКА (0001)\n=============\n"
+	if timetable.Render(DefaultRenderOptions) != expectedHeader {
+		t.Errorf("expected a single timetable without arrivals to be rendered as its header %q, got %q", expectedHeader, timetable.Render(DefaultRenderOptions))
+	}
+	timetables := StopTimetableList{timetable}
+	if timetables.Render(DefaultRenderOptions) != "" {
+		t.Errorf("expected timetables without arrivals to be omitted from lists, got %q", timetables.Render(DefaultRenderOptions))
+	}
+}